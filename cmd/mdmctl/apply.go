@@ -69,8 +69,18 @@ func (cmd *applyCommand) Run(args []string) error {
 		run = cmd.applyBlock
 	case "users":
 		run = cmd.applyUser
+	case "devices":
+		run = cmd.applyDevices
+	case "device-attributes":
+		run = cmd.applyDeviceAttributes
 	case "dep-autoassigner":
 		run = cmd.applyDEPAutoAssigner
+	case "enrollment-config":
+		run = cmd.applyEnrollmentConfig
+	case "invites":
+		run = cmd.applyInvites
+	case "admission-policy":
+		run = cmd.applyAdmissionPolicy
 	default:
 		cmd.Usage()
 		os.Exit(1)
@@ -87,11 +97,16 @@ Valid resource types:
   * blueprints
   * profiles
   * users
+  * devices
+  * device-attributes
   * dep-tokens
   * dep-profiles
   * dep-autoassigner
   * app
   * block
+  * enrollment-config
+  * invites
+  * admission-policy
 
 Examples:
   # Apply a Blueprint.
@@ -100,6 +115,23 @@ Examples:
   # Apply a DEP Profile.
   mdmctl apply dep-profiles -f /path/to/dep-profile.json
 
+  # Set attributes on a device.
+  mdmctl apply device-attributes -serials=C02ABCDEF -set owner=jane@example.com
+
+  # Import device attributes from a CSV file.
+  mdmctl apply device-attributes -f /path/to/attributes.csv
+
+  # Update asset tags and descriptions from a CSV file.
+  mdmctl apply devices -f /path/to/devices.csv
+
+  # Change the enrollment profile and embed a root CA.
+  mdmctl apply enrollment-config -f /path/to/enrollment.json -certs /path/to/ca.pem
+
+  # Create an enrollment invite for two devices owned by jane.
+  mdmctl apply invites -max-uses 2 -owner jane@example.com -blueprint staff
+
+  # Only admit known devices, adding serial numbers from a file.
+  mdmctl apply admission-policy -f /path/to/policy.json -serials-file serials.txt
 
 `
 	fmt.Print(applyUsage)
@@ -111,6 +143,8 @@ func (cmd *applyCommand) applyBlueprint(args []string) error {
 	var (
 		flBlueprintPath = flagset.String("f", "", "filename of blueprint JSON to apply")
 		flTemplate      = flagset.Bool("template", false, "print a new blueprint template")
+		flName          = flagset.String("name", "", "name of the blueprint to roll back")
+		flRollback      = flagset.Int("rollback", 0, "apply this earlier revision of the blueprint given with -name")
 	)
 	flagset.Usage = usageFor(flagset, "mdmctl apply blueprints [flags]")
 	if err := flagset.Parse(args); err != nil {
@@ -135,9 +169,21 @@ func (cmd *applyCommand) applyBlueprint(args []string) error {
 		return nil
 	}
 
+	if *flRollback != 0 {
+		if *flName == "" {
+			return errors.New("bad input: -rollback requires -name")
+		}
+		rev, err := cmd.blueprintsvc.RollbackBlueprint(context.Background(), *flName, *flRollback)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back blueprint %s to version %d as version %d\n", *flName, *flRollback, rev.Version)
+		return nil
+	}
+
 	if *flBlueprintPath == "" {
 		flagset.Usage()
-		return errors.New("bad input: must provide -f or -template flag")
+		return errors.New("bad input: must provide -f, -template or -rollback flag")
 	}
 
 	if *flBlueprintPath != "" {
@@ -294,6 +340,12 @@ func (cmd *applyCommand) applyProfile(args []string) error {
 		flKeyPass     = flagset.String("password", "", "Password to encrypt/read the signing key(optional) or p12 file.")
 		flKeyPath     = flagset.String("private-key", "", "Path to the signing private key. Don't use with p12 file.")
 		flCertPath    = flagset.String("cert", "", "Path to the signing certificate or p12 file.")
+		flSkipSigning = flagset.Bool("skip-server-signing", false, "Install the profile as uploaded, without signing it with the server signing identity.")
+		flEncrypt     = flagset.Bool("encrypt", false, "Encrypt the profile payloads to each device it is installed on. Can not be used with -sign.")
+		flForce       = flagset.Bool("force", false, "Upload the profile even if linting it on the server returns errors.")
+		flIdentifier  = flagset.String("id", "", "Identifier of the profile to promote.")
+		flPromote     = flagset.Int("promote", 0, "Make this revision of the profile given with -id the current one.")
+		flPush        = flagset.Bool("push", false, "With -promote, install the profile again on devices which have it installed.")
 	)
 	flagset.Usage = func() {
 		fmt.Fprintf(os.Stderr, "%s\n",
@@ -302,6 +354,7 @@ func (cmd *applyCommand) applyProfile(args []string) error {
 Uploaded profiles can also be specified in a blueprint, which will be applied on device enrollment.
 This command can also be used to replace the enrollment profile.
 Profiles can be signed before upload.
+Profiles are linted by the server before upload. Uploading a profile with lint errors requires -force.
 
 Examples
 
@@ -315,21 +368,42 @@ Examples
   # Use "-out -" print the output to stdout instead of a file.
   mdmctl apply profiles -f /path/to/profile.mobileconfig -private-key key.pem -cert certificate.pem -password secret -sign -out signed.mobileconfig
 
+  # Promote revision 3 of a profile and install it again on devices
+  mdmctl apply profiles -id com.example.wifi -promote 3 -push
+
 `)
 		usageFor(flagset, "mdmctl apply profiles [flags]")()
 	}
 	if err := flagset.Parse(args); err != nil {
 		return err
 	}
+	if *flPromote != 0 {
+		if *flIdentifier == "" {
+			return errors.New("bad input: -promote requires -id")
+		}
+		result, err := cmd.profilesvc.PromoteRevision(context.Background(), *flIdentifier, *flPromote, *flPush)
+		if result != nil && result.Revision != nil {
+			fmt.Printf("promoted profile %s version %d as version %d\n", *flIdentifier, *flPromote, result.Revision.Version)
+			for _, udid := range result.Pushed {
+				fmt.Printf("installing profile again on %s\n", udid)
+			}
+		}
+		return err
+	}
+
 	if *flProfilePath == "" {
 		flagset.Usage()
-		return errors.New("bad input: must provide -f parameter. use - for stdin")
+		return errors.New("bad input: must provide -f or -promote parameter. use - for stdin")
 	}
 	profileBytes, err := readBytesFromPath(*flProfilePath)
 	if err != nil {
 		return err
 	}
 
+	if *flSign && *flEncrypt {
+		return errors.New("bad input: signed profiles can not be encrypted, the server signs encrypted profiles")
+	}
+
 	if *flSign {
 		priv, pub, err := loadSigningKey(*flKeyPass, *flKeyPath, *flCertPath)
 		if err != nil {
@@ -354,12 +428,25 @@ Examples
 	// Profile struct and doing init server side)
 	var p profile.Profile
 	p.Mobileconfig = profileBytes
+	p.SkipSigning = *flSkipSigning
+	p.Encrypt = *flEncrypt
 	p.Identifier, err = p.Mobileconfig.GetPayloadIdentifier()
 	if err != nil {
 		return err
 	}
 
 	ctx := context.Background()
+	result, err := cmd.profilesvc.LintProfile(ctx, p.Mobileconfig)
+	if err != nil {
+		return errors.Wrap(err, "lint profile")
+	}
+	for _, issue := range result.Issues {
+		fmt.Fprintln(os.Stderr, issue)
+	}
+	if result.HasErrors() && !*flForce {
+		return errors.New("profile has lint errors, use -force to upload it anyway")
+	}
+
 	err = cmd.profilesvc.ApplyProfile(ctx, &p)
 	if err != nil {
 		return err
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/config"
+)
+
+func (cmd *applyCommand) applyAdmissionPolicy(args []string) error {
+	flagset := flag.NewFlagSet("admission-policy", flag.ExitOnError)
+	var (
+		flPolicyPath = flagset.String("f", "", "filename of admission policy JSON to apply")
+		flTemplate   = flagset.Bool("template", false, "print an admission policy template")
+		flSerials    = flagset.String("serials-file", "", "filename of a list of known serial numbers, one per line, added to the policy")
+	)
+	flagset.Usage = usageFor(flagset, "mdmctl apply admission-policy [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	if *flTemplate {
+		return printAdmissionPolicy(&config.AdmissionPolicy{
+			KnownSerialsOnly:    true,
+			Serials:             []string{"C02ABCDEF"},
+			MinOSVersions:       map[string]string{"Mac": "13.0", "iPhone": "16.0", "iPad": "16.0"},
+			ProductNamePrefixes: []string{"Mac", "iPhone", "iPad"},
+		})
+	}
+
+	if *flPolicyPath == "" {
+		flagset.Usage()
+		return errors.New("bad input: must provide -f or -template flag")
+	}
+
+	data, err := readBytesFromPath(*flPolicyPath)
+	if err != nil {
+		return err
+	}
+	var policy config.AdmissionPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return errors.Wrap(err, "unmarshal admission policy")
+	}
+	if *flSerials != "" {
+		data, err := readBytesFromPath(*flSerials)
+		if err != nil {
+			return err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			if serial := strings.TrimSpace(scanner.Text()); serial != "" {
+				policy.Serials = append(policy.Serials, serial)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return errors.Wrap(err, "read serials file")
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	if err := cmd.configsvc.ApplyAdmissionPolicy(context.TODO(), policy); err != nil {
+		return errors.Wrap(err, "apply admission policy")
+	}
+	fmt.Println("applied admission policy")
+	return nil
+}
+
+func printAdmissionPolicy(policy *config.AdmissionPolicy) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(policy), "encode admission policy")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/device"
+)
+
+func (cmd *applyCommand) applyDeviceAttributes(args []string) error {
+	flagset := flag.NewFlagSet("device-attributes", flag.ExitOnError)
+	var (
+		flIdentifier = flagset.String("udid", "", "device UDID, optionally comma-separated")
+		flSerials    = flagset.String("serials", "", "device serial, optionally comma-separated")
+		flSet        = flagset.String("set", "", "key=value attributes to set, optionally comma-separated")
+		flNotes      = flagset.String("notes", "", "notes to set on the device(s)")
+		flCSVPath    = flagset.String("f", "", "filename of a CSV file with a udid or serial_number column and one column per attribute")
+	)
+	flagset.Usage = usageFor(flagset, "mdmctl apply device-attributes [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if *flCSVPath != "" {
+		data, err := readBytesFromPath(*flCSVPath)
+		if err != nil {
+			return err
+		}
+		result, err := cmd.devicesvc.ImportDeviceAttributes(ctx, data)
+		if err != nil {
+			return errors.Wrap(err, "import device attributes")
+		}
+		fmt.Printf("updated attributes on %d device(s)\n", result.Updated)
+		if len(result.NotFound) > 0 {
+			fmt.Printf("devices not found: %s\n", strings.Join(result.NotFound, ", "))
+		}
+		return nil
+	}
+
+	if *flIdentifier == "" && *flSerials == "" {
+		flagset.Usage()
+		return errors.New("bad input: device UDID or Serial must be provided")
+	}
+
+	attrs, err := parseAttributeFlag(*flSet)
+	if err != nil {
+		return err
+	}
+	notesSet := false
+	flagset.Visit(func(f *flag.Flag) {
+		notesSet = notesSet || f.Name == "notes"
+	})
+	if len(attrs) == 0 && !notesSet {
+		flagset.Usage()
+		return errors.New("bad input: must provide -set, -notes or -f flag")
+	}
+
+	opts := device.SetDeviceAttributesOptions{Attributes: attrs}
+	if notesSet {
+		opts.Notes = flNotes
+	}
+	if *flIdentifier != "" {
+		opts.UDIDs = strings.Split(*flIdentifier, ",")
+	}
+	if *flSerials != "" {
+		opts.Serials = strings.Split(*flSerials, ",")
+	}
+
+	if err := cmd.devicesvc.SetDeviceAttributes(ctx, opts); err != nil {
+		return errors.Wrap(err, "set device attributes")
+	}
+
+	fmt.Printf("set attributes on device(s): %s\n", strings.Join(append(opts.UDIDs, opts.Serials...), ", "))
+	return nil
+}
+
+// parseAttributeFlag converts a comma-separated list of key=value pairs to a map.
+func parseAttributeFlag(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	attrs := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("bad input: attribute %q is not in key=value format", pair)
+		}
+		attrs[kv[0]] = kv[1]
+	}
+	return attrs, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func (cmd *applyCommand) applyDevices(args []string) error {
+	flagset := flag.NewFlagSet("devices", flag.ExitOnError)
+	var (
+		flCSVPath = flagset.String("f", "", "filename of a CSV file with a serial_number column and asset_tag and/or description columns")
+	)
+	flagset.Usage = usageFor(flagset, "mdmctl apply devices [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	if *flCSVPath == "" {
+		flagset.Usage()
+		return errors.New("bad input: must provide -f flag")
+	}
+
+	data, err := readBytesFromPath(*flCSVPath)
+	if err != nil {
+		return err
+	}
+	result, err := cmd.devicesvc.ImportDevices(context.Background(), data)
+	if err != nil {
+		return errors.Wrap(err, "import devices")
+	}
+	fmt.Printf("updated %d device(s)\n", result.Updated)
+	if len(result.NotFound) > 0 {
+		fmt.Printf("devices not found: %s\n", strings.Join(result.NotFound, ", "))
+	}
+	return nil
+}
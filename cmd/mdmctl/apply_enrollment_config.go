@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/config"
+)
+
+func (cmd *applyCommand) applyEnrollmentConfig(args []string) error {
+	flagset := flag.NewFlagSet("enrollment-config", flag.ExitOnError)
+	var (
+		flConfigPath = flagset.String("f", "", "filename of enrollment config JSON to apply")
+		flTemplate   = flagset.Bool("template", false, "print an enrollment config template")
+		flCerts      = flagset.String("certs", "", "comma separated paths of PEM certificates, like a root CA, to add to the enrollment profile")
+		flPayloads   = flagset.String("payloads", "", "comma separated paths of plist payload dictionaries to add to the enrollment profile")
+	)
+	flagset.Usage = usageFor(flagset, "mdmctl apply enrollment-config [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	if *flTemplate {
+		checkOut := true
+		return printEnrollmentConfig(&config.EnrollmentConfig{
+			Organization:        "Example Inc.",
+			DisplayName:         "Example Inc. Device Management",
+			Description:         "Enrolls your device with Example Inc.",
+			AccessRights:        8191,
+			CheckOutWhenRemoved: &checkOut,
+			KeySize:             2048,
+			KeyUsage:            5,
+		})
+	}
+
+	if *flConfigPath == "" {
+		flagset.Usage()
+		return errors.New("bad input: must provide -f or -template flag")
+	}
+
+	data, err := readBytesFromPath(*flConfigPath)
+	if err != nil {
+		return err
+	}
+	var conf config.EnrollmentConfig
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return errors.Wrap(err, "unmarshal enrollment config")
+	}
+	for _, path := range splitPaths(*flCerts) {
+		data, err := readBytesFromPath(path)
+		if err != nil {
+			return err
+		}
+		conf.Certificates = append(conf.Certificates, string(data))
+	}
+	for _, path := range splitPaths(*flPayloads) {
+		data, err := readBytesFromPath(path)
+		if err != nil {
+			return err
+		}
+		conf.Payloads = append(conf.Payloads, data)
+	}
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	if err := cmd.configsvc.ApplyEnrollmentConfig(context.TODO(), conf); err != nil {
+		return errors.Wrap(err, "apply enrollment config")
+	}
+	fmt.Println("applied enrollment config, the enrollment profile will be regenerated")
+	return nil
+}
+
+func splitPaths(s string) []string {
+	var paths []string
+	for _, path := range strings.Split(s, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func printEnrollmentConfig(conf *config.EnrollmentConfig) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(conf), "encode enrollment config")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/invite"
+)
+
+func (cmd *applyCommand) applyInvites(args []string) error {
+	flagset := flag.NewFlagSet("invites", flag.ExitOnError)
+	var (
+		flMaxUses   = flagset.Int("max-uses", 1, "number of devices which can enroll with the invite")
+		flExpiresIn = flagset.Duration("expires-in", 7*24*time.Hour, "duration after which the invite expires, 0 never expires")
+		flOwner     = flagset.String("owner", "", "email address set as the owner attribute of enrolled devices")
+		flAttrs     = flagset.String("attrs", "", "key=value attributes to set on enrolled devices, optionally comma-separated")
+		flBlueprint = flagset.String("blueprint", "", "name of a blueprint to apply to enrolled devices")
+	)
+	flagset.Usage = usageFor(flagset, "mdmctl apply invites [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+	if *flMaxUses < 1 {
+		flagset.Usage()
+		return errors.New("bad input: -max-uses must be at least 1")
+	}
+
+	attrs, err := parseAttributeFlag(*flAttrs)
+	if err != nil {
+		return err
+	}
+	inv := invite.Invite{
+		MaxUses:       *flMaxUses,
+		OwnerEmail:    *flOwner,
+		Attributes:    attrs,
+		BlueprintName: *flBlueprint,
+	}
+	if *flExpiresIn > 0 {
+		inv.ExpiresAt = time.Now().Add(*flExpiresIn).UTC()
+	}
+
+	created, err := cmd.invitesvc.CreateInvite(context.TODO(), inv)
+	if err != nil {
+		return errors.Wrap(err, "create invite")
+	}
+	fmt.Printf("token:      %s\n", created.Token)
+	fmt.Printf("enroll URL: %s\n", inviteURL(cmd.config.ServerURL, created.Token))
+	return nil
+}
+
+// inviteURL returns the enrollment URL for an invite token.
+func inviteURL(serverURL, token string) string {
+	return strings.TrimSuffix(serverURL, "/") + "/mdm/enroll?" + invite.TokenParam + "=" + url.QueryEscape(token)
+}
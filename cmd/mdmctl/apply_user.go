@@ -8,10 +8,8 @@ import (
 	"io/ioutil"
 	"os"
 
-	"github.com/micromdm/plist"
 	"github.com/pkg/errors"
 
-	"github.com/micromdm/micromdm/pkg/crypto/password"
 	"github.com/micromdm/micromdm/platform/user"
 )
 
@@ -21,6 +19,9 @@ func (cmd *applyCommand) applyUser(args []string) error {
 		flUserManifest = flagset.String("f", "", "Path to user manifest")
 		flTemplate     = flagset.Bool("template", false, "Print a JSON example of a user manifest.")
 		flPassword     = flagset.String("password", "", "Password of the user. Only required when creating a new user.")
+		flRotate       = flagset.Bool("rotate", false, "Replace the password of an existing user and change it on the devices which have the user as an admin account.")
+		flDirSync      = flagset.Bool("directory-sync", false, "Sync users from the directory group configured on the server.")
+		flDryRun       = flagset.Bool("dry-run", false, "With -directory-sync, only report the changes.")
 	)
 	flagset.Usage = usageFor(flagset, "mdmctl apply users [flags]")
 	if err := flagset.Parse(args); err != nil {
@@ -32,6 +33,14 @@ func (cmd *applyCommand) applyUser(args []string) error {
 		return nil
 	}
 
+	if *flDirSync {
+		report, err := cmd.usersvc.SyncDirectory(context.TODO(), *flDryRun)
+		if report != nil {
+			printDirectorySyncReport(report)
+		}
+		return errors.Wrap(err, "sync users from directory")
+	}
+
 	manifestData, err := ioutil.ReadFile(*flUserManifest)
 	if err != nil {
 		return errors.Wrap(err, "read user manifest file")
@@ -42,25 +51,32 @@ func (cmd *applyCommand) applyUser(args []string) error {
 		return errors.Wrap(err, "unmarshal user manifest")
 	}
 
-	if manifest.UUID == "" && *flPassword == "" {
+	if *flRotate {
+		if manifest.UUID == "" || *flPassword == "" {
+			return errors.New("bad input: -rotate requires a user manifest with an uuid and -password")
+		}
+		result, err := cmd.usersvc.RotatePassword(context.TODO(), manifest.UUID, *flPassword)
+		if result != nil {
+			for _, udid := range result.Queued {
+				fmt.Printf("queued SetAutoAdminPassword for %s\n", udid)
+			}
+			for _, udid := range result.Pending {
+				fmt.Printf("queued UserList for %s, the password is sent when it responds\n", udid)
+			}
+		}
+		return errors.Wrap(err, "rotate user password")
+	}
+
+	if manifest.UUID == "" && *flPassword == "" && manifest.Password == "" {
 		return errors.New("password argument must be provided when creating a user")
 	}
 
 	if *flPassword != "" {
-		salted, err := password.SaltedSHA512PBKDF2(*flPassword)
-		if err != nil {
-			return errors.Wrap(err, "salting plaintext password")
-		}
-		hashDict := struct {
-			SaltedSHA512PBKDF2 password.SaltedSHA512PBKDF2Dictionary `plist:"SALTED-SHA512-PBKDF2"`
-		}{
-			SaltedSHA512PBKDF2: salted,
-		}
-		hashPlist, err := plist.Marshal(hashDict)
+		hash, err := user.HashPassword(*flPassword)
 		if err != nil {
-			return errors.Wrap(err, "marshal salted password to plist")
+			return err
 		}
-		manifest.PasswordHash = hashPlist
+		manifest.PasswordHash = hash
 	}
 
 	usr, err := cmd.usersvc.ApplyUser(context.TODO(), manifest)
@@ -13,6 +13,7 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/micromdm/micromdm/pkg/crypto"
+	"github.com/micromdm/micromdm/pkg/export"
 	"github.com/micromdm/micromdm/platform/blueprint"
 	"github.com/micromdm/micromdm/platform/device"
 	"github.com/micromdm/micromdm/platform/profile"
@@ -54,6 +55,10 @@ func (cmd *getCommand) Run(args []string) error {
 	switch strings.ToLower(args[0]) {
 	case "dev", "device", "devices":
 		run = cmd.getDevices
+	case "device-events":
+		run = cmd.getDeviceEvents
+	case "stale-devices":
+		run = cmd.getStaleDevices
 	case "dep-devices":
 		run = cmd.getDEPDevices
 	case "dep-account":
@@ -72,6 +77,12 @@ func (cmd *getCommand) Run(args []string) error {
 		run = cmd.getApps
 	case "dep-autoassigners":
 		run = cmd.getDEPAutoAssigners
+	case "enrollment-config":
+		run = cmd.getEnrollmentConfig
+	case "invites":
+		run = cmd.getInvites
+	case "admission-policy":
+		run = cmd.getAdmissionPolicy
 	default:
 		cmd.Usage()
 		os.Exit(1)
@@ -87,6 +98,8 @@ Display one or many resources.
 Valid resource types:
 
   * devices
+  * device-events
+  * stale-devices
   * blueprints
   * dep-tokens
   * dep-devices
@@ -96,6 +109,9 @@ Valid resource types:
   * users
   * profiles
   * apps
+  * enrollment-config
+  * invites
+  * admission-policy
 
 Examples:
   # Get a list of devices
@@ -104,6 +120,15 @@ Examples:
   # Get a device by serial (TODO implement filtering)
   mdmctl get devices -serials=C02ABCDEF
 
+  # Get the event timeline of a device
+  mdmctl get device-events -serial=C02ABCDEF
+
+  # Get devices by custom attribute
+  mdmctl get devices -attributes=department=sales
+
+  # Export all devices as CSV
+  mdmctl get devices -o csv > devices.csv
+
 `
 	fmt.Print(getUsage)
 	return nil
@@ -123,15 +148,13 @@ func (cmd *getCommand) getDevices(args []string) error {
 	flagset := flag.NewFlagSet("devices", flag.ExitOnError)
 	var (
 		flFilterSerials = flagset.String("serials", "", "device serial, optionally comma-separated")
+		flFilterAttrs   = flagset.String("attributes", "", "key=value device attributes to match, optionally comma-separated")
+		flOutput        = flagset.String("o", "table", "output format: table, csv or ndjson")
 	)
 	flagset.Usage = usageFor(flagset, "mdmctl get devices [flags]")
 	if err := flagset.Parse(args); err != nil {
 		return err
 	}
-	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-	out := &devicesTableOutput{w}
-	out.BasicHeader()
-	defer out.BasicFooter()
 	ctx := context.Background()
 
 	// convert string to []string in case a filter serial is defined
@@ -141,7 +164,24 @@ func (cmd *getCommand) getDevices(args []string) error {
 		flFilterSerialsSlice = strings.Split(*flFilterSerials, ",")
 	}
 
-	devices, err := cmd.devicesvc.ListDevices(ctx, device.ListDevicesOption{FilterSerial: flFilterSerialsSlice})
+	filterAttrs, err := parseAttributeFlag(*flFilterAttrs)
+	if err != nil {
+		return err
+	}
+
+	if *flOutput != "table" {
+		return cmd.exportDevices(ctx, *flOutput, flFilterSerialsSlice, filterAttrs)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	out := &devicesTableOutput{w}
+	out.BasicHeader()
+	defer out.BasicFooter()
+
+	devices, err := cmd.devicesvc.ListDevices(ctx, device.ListDevicesOption{
+		FilterSerial:     flFilterSerialsSlice,
+		FilterAttributes: filterAttrs,
+	})
 	if err != nil {
 		return err
 	}
@@ -151,6 +191,29 @@ func (cmd *getCommand) getDevices(args []string) error {
 	return nil
 }
 
+// exportDevices writes every device record matching the filters to stdout.
+func (cmd *getCommand) exportDevices(ctx context.Context, format string, serials []string, attrs map[string]string) error {
+	format, err := export.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	w, err := export.NewWriter(os.Stdout, format, device.DeviceRecordHeader)
+	if err != nil {
+		return err
+	}
+	opt := device.ListDevicesOption{
+		FilterSerial:     serials,
+		FilterAttributes: attrs,
+	}
+	err = cmd.devicesvc.ExportDevices(ctx, opt, func(r device.DeviceRecord) error {
+		return w.Write(r)
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
 const defaultmdmctlFilesPath = "mdm-files"
 
 func (cmd *getCommand) getDepTokens(args []string) error {
@@ -239,6 +302,8 @@ func (cmd *getCommand) getBlueprints(args []string) error {
 	var (
 		flBlueprintName = flagset.String("name", "", "name of blueprint")
 		flJSONName      = flagset.String("f", "-", "filename of JSON to save to")
+		flHistory       = flagset.Bool("history", false, "list the revisions of the blueprint given with -name")
+		flDiff          = flagset.String("diff", "", "with -history, print the changes between two revisions given as FROM:TO, or \"last\" for the last two")
 	)
 	flagset.Usage = usageFor(flagset, "mdmctl get blueprints [flags]")
 	if err := flagset.Parse(args); err != nil {
@@ -246,6 +311,13 @@ func (cmd *getCommand) getBlueprints(args []string) error {
 	}
 
 	ctx := context.Background()
+	if *flHistory {
+		if *flBlueprintName == "" {
+			return errors.New("bad input: -history requires -name")
+		}
+		return cmd.getBlueprintHistory(ctx, *flBlueprintName, *flDiff)
+	}
+
 	blueprints, err := cmd.blueprintsvc.GetBlueprints(ctx, blueprint.GetBlueprintsOption{FilterName: *flBlueprintName})
 	if err != nil {
 		return err
@@ -253,7 +325,7 @@ func (cmd *getCommand) getBlueprints(args []string) error {
 
 	if *flBlueprintName == "" || len(blueprints) < 1 {
 		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-		fmt.Fprintf(w, "Name\tUUID\tManifests\tProfiles\tApply At\n")
+		fmt.Fprintf(w, "Name\tUUID\tManifests\tProfiles\tApply At\tScope\n")
 		for _, bp := range blueprints {
 			var applyAtStr string
 			if len(bp.ApplyAt) > 0 {
@@ -263,12 +335,13 @@ func (cmd *getCommand) getBlueprints(args []string) error {
 			}
 			fmt.Fprintf(
 				w,
-				"%s\t%s\t%d\t%d\t%s\n",
+				"%s\t%s\t%d\t%d\t%s\t%s\n",
 				bp.Name,
 				bp.UUID,
 				len(bp.ApplicationURLs),
 				len(bp.ProfileIdentifiers),
 				applyAtStr,
+				bp.Scope.String(),
 			)
 		}
 		w.Flush()
@@ -312,6 +385,9 @@ func (cmd *getCommand) getProfiles(args []string) error {
 	var (
 		flProfilePath = flagset.String("f", "-", "filename of profile to write")
 		flIdentifier  = flagset.String("id", "", "profile Identifier")
+		flHistory     = flagset.Bool("history", false, "list the revisions of the profile given with -id")
+		flDiff        = flagset.String("diff", "", "with -history, print the changes between two revisions given as FROM:TO, or \"last\" for the last two")
+		flVersion     = flagset.Int("version", 0, "write this revision of the profile given with -id instead of the current one")
 	)
 	flagset.Usage = usageFor(flagset, "mdmctl get profiles [flags]")
 	if err := flagset.Parse(args); err != nil {
@@ -319,6 +395,15 @@ func (cmd *getCommand) getProfiles(args []string) error {
 	}
 
 	ctx := context.Background()
+	if *flHistory || *flVersion != 0 {
+		if *flIdentifier == "" {
+			return errors.New("bad input: -history and -version require -id")
+		}
+		if *flVersion != 0 {
+			return cmd.getProfileRevision(ctx, *flIdentifier, *flVersion, *flProfilePath)
+		}
+		return cmd.getProfileHistory(ctx, *flIdentifier, *flDiff)
+	}
 	profiles, err := cmd.profilesvc.GetProfiles(ctx, profile.GetProfilesOption{Identifier: *flIdentifier})
 	if err != nil {
 		return err
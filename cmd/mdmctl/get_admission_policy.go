@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/pkg/errors"
+)
+
+func (cmd *getCommand) getAdmissionPolicy(args []string) error {
+	flagset := flag.NewFlagSet("admission-policy", flag.ExitOnError)
+	flagset.Usage = usageFor(flagset, "mdmctl get admission-policy [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	policy, err := cmd.configsvc.GetAdmissionPolicy(context.TODO())
+	if err != nil {
+		return errors.Wrap(err, "get admission policy")
+	}
+	return printAdmissionPolicy(policy)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func (cmd *getCommand) getBlueprintHistory(ctx context.Context, name, diff string) error {
+	if diff != "" {
+		return cmd.diffBlueprintRevisions(ctx, name, diff)
+	}
+
+	revisions, err := cmd.blueprintsvc.GetRevisions(ctx, name)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Version\tTime\tAPI Key\tManifests\tProfiles\tApply At\n")
+	for _, rev := range revisions {
+		apiKey := rev.APIKey
+		if apiKey == "" {
+			apiKey = "(None)"
+		}
+		fmt.Fprintf(
+			w,
+			"%d\t%s\t%s\t%d\t%d\t%s\n",
+			rev.Version,
+			rev.Time.Local().Format(time.RFC3339),
+			apiKey,
+			len(rev.Blueprint.ApplicationURLs),
+			len(rev.Blueprint.ProfileIdentifiers),
+			strings.Join(rev.Blueprint.ApplyAt, ","),
+		)
+	}
+	return w.Flush()
+}
+
+// diffBlueprintRevisions prints the changes between two revisions given as
+// FROM:TO, or between the last two revisions for "last".
+func (cmd *getCommand) diffBlueprintRevisions(ctx context.Context, name, diff string) error {
+	from, to, err := parseDiffVersions(diff)
+	if err != nil {
+		return err
+	}
+	changes, err := cmd.blueprintsvc.DiffRevisions(ctx, name, from, to)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(changes)
+}
+
+// parseDiffVersions parses a -diff flag given as FROM:TO. Both versions are 0
+// for "last", which the server resolves to the last two revisions.
+func parseDiffVersions(diff string) (from, to int, err error) {
+	if diff == "last" {
+		return 0, 0, nil
+	}
+	parts := strings.SplitN(diff, ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.Errorf("bad input: -diff must be FROM:TO or last, got %q", diff)
+	}
+	if from, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, errors.Wrap(err, "parse FROM version")
+	}
+	if to, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, errors.Wrap(err, "parse TO version")
+	}
+	return from, to, nil
+}
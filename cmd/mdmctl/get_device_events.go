@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/device"
+)
+
+func (cmd *getCommand) getDeviceEvents(args []string) error {
+	flagset := flag.NewFlagSet("device-events", flag.ExitOnError)
+	var (
+		flUDID   = flagset.String("udid", "", "device UDID")
+		flSerial = flagset.String("serial", "", "device serial number")
+	)
+	flagset.Usage = usageFor(flagset, "mdmctl get device-events [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	if *flUDID == "" && *flSerial == "" {
+		flagset.Usage()
+		return errors.New("bad input: device UDID or Serial must be provided")
+	}
+
+	ctx := context.Background()
+	udid := *flUDID
+	if udid == "" {
+		devices, err := cmd.devicesvc.ListDevices(ctx, device.ListDevicesOption{FilterSerial: []string{*flSerial}})
+		if err != nil {
+			return errors.Wrap(err, "find device by serial")
+		}
+		if len(devices) == 0 || devices[0].UDID == "" {
+			return fmt.Errorf("no enrolled device with serial %s", *flSerial)
+		}
+		udid = devices[0].UDID
+	}
+
+	events, err := cmd.timelinesvc.DeviceEvents(ctx, udid)
+	if err != nil {
+		return errors.Wrap(err, "get device events")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintf(w, "Time\tType\tRequestType\tStatus\tCommandUUID\tDetail\n")
+	for _, ev := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			ev.Time.Format(time.RFC3339),
+			ev.Type,
+			ev.RequestType,
+			ev.Status,
+			ev.CommandUUID,
+			ev.Detail,
+		)
+	}
+	return nil
+}
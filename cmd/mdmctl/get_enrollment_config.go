@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/pkg/errors"
+)
+
+func (cmd *getCommand) getEnrollmentConfig(args []string) error {
+	flagset := flag.NewFlagSet("enrollment-config", flag.ExitOnError)
+	flagset.Usage = usageFor(flagset, "mdmctl get enrollment-config [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	conf, err := cmd.configsvc.GetEnrollmentConfig(context.TODO())
+	if err != nil {
+		return errors.Wrap(err, "get enrollment config")
+	}
+	return printEnrollmentConfig(conf)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func (cmd *getCommand) getInvites(args []string) error {
+	flagset := flag.NewFlagSet("invites", flag.ExitOnError)
+	flagset.Usage = usageFor(flagset, "mdmctl get invites [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	invites, err := cmd.invitesvc.ListInvites(context.TODO())
+	if err != nil {
+		return errors.Wrap(err, "list invites")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Token\tUses\tExpires\tOwner\tBlueprint\n")
+	for _, inv := range invites {
+		expires := "never"
+		if !inv.ExpiresAt.IsZero() {
+			expires = inv.ExpiresAt.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%d/%d\t%s\t%s\t%s\n", inv.Token, len(inv.UDIDs), inv.MaxUses, expires, inv.OwnerEmail, inv.BlueprintName)
+	}
+	return w.Flush()
+}
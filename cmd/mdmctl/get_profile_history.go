@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func (cmd *getCommand) getProfileHistory(ctx context.Context, id, diff string) error {
+	if diff != "" {
+		from, to, err := parseDiffVersions(diff)
+		if err != nil {
+			return err
+		}
+		changes, err := cmd.profilesvc.DiffRevisions(ctx, id, from, to)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(changes)
+	}
+
+	revisions, err := cmd.profilesvc.GetRevisions(ctx, id)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Version\tTime\tAPI Key\tPayloadUUID\tSHA256\n")
+	for _, rev := range revisions {
+		apiKey := rev.APIKey
+		if apiKey == "" {
+			apiKey = "(None)"
+		}
+		fmt.Fprintf(
+			w,
+			"%d\t%s\t%s\t%s\t%s\n",
+			rev.Version,
+			rev.Time.Local().Format(time.RFC3339),
+			apiKey,
+			rev.PayloadUUID,
+			rev.SHA256,
+		)
+	}
+	return w.Flush()
+}
+
+func (cmd *getCommand) getProfileRevision(ctx context.Context, id string, version int, path string) error {
+	rev, err := cmd.profilesvc.GetRevision(ctx, id, version)
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		_, err = os.Stdout.Write(rev.Profile.Mobileconfig)
+		return err
+	}
+	if err := os.WriteFile(path, rev.Profile.Mobileconfig, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("wrote profile id %s version %d to: %s\n", id, version, path)
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func (cmd *getCommand) getStaleDevices(args []string) error {
+	flagset := flag.NewFlagSet("stale-devices", flag.ExitOnError)
+	flagset.Usage = usageFor(flagset, "mdmctl get stale-devices [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	devices, err := cmd.devicesvc.StaleDevices(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get stale devices")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintf(w, "UDID\tSerialNumber\tLastSeen\tPendingCommands\n")
+	for _, d := range devices {
+		var pending []string
+		for _, cmd := range d.PendingCommands {
+			pending = append(pending, cmd.RequestType)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			d.UDID,
+			d.SerialNumber,
+			d.LastSeen.Format(time.RFC3339),
+			strings.Join(pending, ","),
+		)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"text/tabwriter"
+	"time"
 
 	"github.com/micromdm/micromdm/platform/user"
 	"github.com/pkg/errors"
@@ -23,10 +24,27 @@ func (out *usersTableOutput) BasicFooter() {
 
 func (cmd *getCommand) getUsers(args []string) error {
 	flagset := flag.NewFlagSet("users", flag.ExitOnError)
+	flUDID := flagset.String("udid", "", "list the managed users of the device, which checked in on its user channel")
+	flDirectorySync := flagset.Bool("directory-sync", false, "show the report of the last sync of users from the directory")
 	flagset.Usage = usageFor(flagset, "mdmctl get users [flags]")
 	if err := flagset.Parse(args); err != nil {
 		return err
 	}
+	if *flUDID != "" {
+		return cmd.getDeviceUsers(*flUDID)
+	}
+	if *flDirectorySync {
+		report, err := cmd.usersvc.DirectorySyncReport(context.TODO())
+		if err != nil {
+			return errors.Wrap(err, "get directory sync report")
+		}
+		if report == nil {
+			fmt.Println("the directory was not synced yet")
+			return nil
+		}
+		printDirectorySyncReport(report)
+		return nil
+	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
 	out := &usersTableOutput{w}
@@ -44,3 +62,35 @@ func (cmd *getCommand) getUsers(args []string) error {
 
 	return nil
 }
+
+func (cmd *getCommand) getDeviceUsers(udid string) error {
+	users, err := cmd.usersvc.DeviceUsers(context.TODO(), udid)
+	if err != nil {
+		return errors.Wrap(err, "get device users")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "UserID\tUserShortName\tUserLongName\tLastTokenUpdate\n")
+	for _, u := range users {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.UserID, u.UserShortname, u.UserLongname, u.LastTokenUpdate.Local().Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+func printDirectorySyncReport(report *user.DirectorySyncReport) {
+	fmt.Printf("Started:   %s\n", report.StartedAt.Local().Format(time.RFC3339))
+	fmt.Printf("Completed: %s\n", report.CompletedAt.Local().Format(time.RFC3339))
+	fmt.Printf("Dry run:   %v\n", report.DryRun)
+	fmt.Printf("Members:   %d\n", report.Members)
+	if report.Err != "" {
+		fmt.Printf("Error:     %s\n", report.Err)
+	}
+	for _, name := range report.Created {
+		fmt.Printf("created  %s\n", name)
+	}
+	for _, name := range report.Updated {
+		fmt.Printf("updated  %s\n", name)
+	}
+	for _, name := range report.Disabled {
+		fmt.Printf("disabled %s\n", name)
+	}
+}
@@ -44,12 +44,16 @@ func (cmd *removeCommand) Run(args []string) error {
 		run = cmd.removeBlueprints
 	case "devices":
 		run = cmd.removeDevices
+	case "device-attributes":
+		run = cmd.removeDeviceAttributes
 	case "profiles":
 		run = cmd.removeProfiles
 	case "block":
 		run = cmd.removeBlock
 	case "dep-autoassigner":
 		run = cmd.removeDEPAutoAssigner
+	case "invites":
+		run = cmd.removeInvites
 	default:
 		cmd.Usage()
 		os.Exit(1)
@@ -66,9 +70,11 @@ Valid resource types:
 
   * blueprints
   * devices
+  * device-attributes
   * profiles
   * block
   * dep-autoassigner
+  * invites
 
 `
 
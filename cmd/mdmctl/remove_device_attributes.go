@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/device"
+)
+
+func (cmd *removeCommand) removeDeviceAttributes(args []string) error {
+	flagset := flag.NewFlagSet("device-attributes", flag.ExitOnError)
+	var (
+		flIdentifier = flagset.String("udid", "", "device UDID, optionally comma-separated")
+		flSerials    = flagset.String("serials", "", "device serial, optionally comma-separated")
+		flKeys       = flagset.String("keys", "", "attribute keys to remove, optionally comma-separated")
+	)
+	flagset.Usage = usageFor(flagset, "mdmctl remove device-attributes [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	if *flIdentifier == "" && *flSerials == "" {
+		flagset.Usage()
+		return errors.New("bad input: device UDID or Serial must be provided")
+	}
+	if *flKeys == "" {
+		flagset.Usage()
+		return errors.New("bad input: attribute keys must be provided")
+	}
+
+	opts := device.RemoveDeviceAttributesOptions{Keys: strings.Split(*flKeys, ",")}
+	if *flIdentifier != "" {
+		opts.UDIDs = strings.Split(*flIdentifier, ",")
+	}
+	if *flSerials != "" {
+		opts.Serials = strings.Split(*flSerials, ",")
+	}
+
+	ctx := context.Background()
+	if err := cmd.devicesvc.RemoveDeviceAttributes(ctx, opts); err != nil {
+		return errors.Wrap(err, "remove device attributes")
+	}
+
+	fmt.Printf("removed attributes %s from device(s): %s\n",
+		strings.Join(opts.Keys, ", "),
+		strings.Join(append(opts.UDIDs, opts.Serials...), ", "))
+	return nil
+}
@@ -16,6 +16,8 @@ func (cmd *removeCommand) removeDevices(args []string) error {
 	var (
 		flIdentifier = flagset.String("udid", "", "device UDID, optionally comma-separated")
 		flSerials    = flagset.String("serials", "", "device serial, optionally comma-separated")
+		flPurge      = flagset.Bool("purge", false, "also remove push info, command queue, users, cert hash and block list entry")
+		flRmProfile  = flagset.Bool("remove-profile", false, "with -purge, remove the enrollment profile from the device first")
 	)
 	flagset.Usage = usageFor(flagset, "mdmctl remove devices [flags]")
 	if err := flagset.Parse(args); err != nil {
@@ -27,6 +29,11 @@ func (cmd *removeCommand) removeDevices(args []string) error {
 		return errors.New("bad input: device UDID or Serial must be provided")
 	}
 
+	if *flRmProfile && !*flPurge {
+		flagset.Usage()
+		return errors.New("bad input: -remove-profile requires -purge")
+	}
+
 	opts := device.RemoveDevicesOptions{}
 	if *flIdentifier != "" {
 		opts.UDIDs = strings.Split(*flIdentifier, ",")
@@ -36,6 +43,23 @@ func (cmd *removeCommand) removeDevices(args []string) error {
 	}
 
 	ctx := context.Background()
+	if *flPurge {
+		err := cmd.devicesvc.PurgeDevices(ctx, device.PurgeDevicesOptions{
+			UDIDs:                   opts.UDIDs,
+			Serials:                 opts.Serials,
+			RemoveEnrollmentProfile: *flRmProfile,
+		})
+		if err != nil {
+			return err
+		}
+		if *flRmProfile {
+			fmt.Printf("queued enrollment profile removal, devices(s) will be purged once removed: %s\n", strings.Join(append(opts.UDIDs, opts.Serials...), ", "))
+			return nil
+		}
+		fmt.Printf("purged devices(s): %s\n", strings.Join(append(opts.UDIDs, opts.Serials...), ", "))
+		return nil
+	}
+
 	err := cmd.devicesvc.RemoveDevices(ctx, opts)
 	if err != nil {
 		return err
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+func (cmd *removeCommand) removeInvites(args []string) error {
+	flagset := flag.NewFlagSet("invites", flag.ExitOnError)
+	var (
+		flToken = flagset.String("token", "", "token of the invite to remove")
+	)
+	flagset.Usage = usageFor(flagset, "mdmctl remove invites [flags]")
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+
+	if *flToken == "" {
+		flagset.Usage()
+		return errors.New("bad input: must provide -token")
+	}
+
+	if err := cmd.invitesvc.RemoveInvite(context.TODO(), *flToken); err != nil {
+		return errors.Wrap(err, "remove invite")
+	}
+
+	fmt.Printf("removed invite %s\n", *flToken)
+	return nil
+}
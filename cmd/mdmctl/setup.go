@@ -10,8 +10,10 @@ import (
 	"github.com/micromdm/micromdm/platform/dep"
 	"github.com/micromdm/micromdm/platform/dep/sync"
 	"github.com/micromdm/micromdm/platform/device"
+	"github.com/micromdm/micromdm/platform/invite"
 	"github.com/micromdm/micromdm/platform/profile"
 	"github.com/micromdm/micromdm/platform/remove"
+	"github.com/micromdm/micromdm/platform/timeline"
 	"github.com/micromdm/micromdm/platform/user"
 )
 
@@ -25,6 +27,8 @@ type remoteServices struct {
 	appsvc       appstore.Service
 	depsvc       dep.Service
 	depsyncsvc   sync.Service
+	timelinesvc  timeline.Service
+	invitesvc    invite.Service
 }
 
 func setupClient(logger log.Logger) (*remoteServices, error) {
@@ -96,6 +100,20 @@ func setupClient(logger log.Logger) (*remoteServices, error) {
 		return nil, err
 	}
 
+	timelinesvc, err := timeline.NewHTTPClient(
+		cfg.ServerURL, cfg.APIToken, logger,
+		httptransport.SetClient(skipVerifyHTTPClient(cfg.SkipVerify)))
+	if err != nil {
+		return nil, err
+	}
+
+	invitesvc, err := invite.NewHTTPClient(
+		cfg.ServerURL, cfg.APIToken, logger,
+		httptransport.SetClient(skipVerifyHTTPClient(cfg.SkipVerify)))
+	if err != nil {
+		return nil, err
+	}
+
 	return &remoteServices{
 		profilesvc:   profilesvc,
 		blueprintsvc: blueprintsvc,
@@ -106,5 +124,7 @@ func setupClient(logger log.Logger) (*remoteServices, error) {
 		appsvc:       appsvc,
 		depsvc:       depsvc,
 		depsyncsvc:   depsyncsvc,
+		timelinesvc:  timelinesvc,
+		invitesvc:    invitesvc,
 	}, nil
 }
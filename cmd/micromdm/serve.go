@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	stdcrypto "crypto"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"io"
@@ -29,9 +33,11 @@ import (
 	depapi "github.com/micromdm/micromdm/platform/dep"
 	"github.com/micromdm/micromdm/platform/dep/sync"
 	"github.com/micromdm/micromdm/platform/device"
-	devicebuiltin "github.com/micromdm/micromdm/platform/device/builtin"
+	"github.com/micromdm/micromdm/platform/invite"
 	"github.com/micromdm/micromdm/platform/profile"
 	block "github.com/micromdm/micromdm/platform/remove"
+	"github.com/micromdm/micromdm/platform/timeline"
+	timelinebuiltin "github.com/micromdm/micromdm/platform/timeline/builtin"
 	"github.com/micromdm/micromdm/platform/user"
 	userbuiltin "github.com/micromdm/micromdm/platform/user/builtin"
 	"github.com/micromdm/micromdm/server"
@@ -48,6 +54,7 @@ import (
 	scep "github.com/micromdm/scep/v2/server"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/crypto/pkcs12"
 )
 
 const homePage = `<!doctype html>
@@ -130,6 +137,30 @@ func serve(args []string) error {
 		flLogTime                = flagset.Bool("log-time", false, "Include timestamp in log messages")
 		flP7Skew                 = flagset.Int("device-signature-skew", env.Int("MICROMDM_DEVICE_SIGNATURE_SKEW", 0), "Sets the allowable clock skew (in seconds) when verifying device signatures")
 		flDisableRedirect        = flagset.Bool("disable-redirect", env.Bool("MICROMDM_DISABLE_REDIRECT", false), "disable the :80 -> :443 redirect if listening on :443")
+		flDeviceEventsMax        = flagset.Int("device-events-max", env.Int("MICROMDM_DEVICE_EVENTS_MAX", timelinebuiltin.DefaultMaxEvents), "Maximum number of timeline events kept per device, 0 keeps all events")
+		flDeviceEventsMaxAge     = flagset.Int("device-events-max-age", env.Int("MICROMDM_DEVICE_EVENTS_MAX_AGE", 90), "Number of days timeline events are kept, 0 keeps all events")
+		flDeviceRePushAfter      = flagset.Int("device-repush-after", env.Int("MICROMDM_DEVICE_REPUSH_AFTER", 24), "Hours without a check-in after which a device is sent a push notification, 0 disables")
+		flDeviceStaleAfter       = flagset.Int("device-stale-after", env.Int("MICROMDM_DEVICE_STALE_AFTER", 168), "Hours without a check-in after which a device is marked as stale, 0 disables")
+		flDevicePurgeTimeout     = flagset.Int("device-purge-timeout", env.Int("MICROMDM_DEVICE_PURGE_TIMEOUT", 72), "Hours after which a device waiting for the removal of its enrollment profile is purged anyway, 0 disables")
+		flReconcileInterval      = flagset.Int("blueprint-reconcile-interval", env.Int("MICROMDM_BLUEPRINT_RECONCILE_INTERVAL", 0), "Hours between reconciling enrolled devices with their blueprints, 0 only reconciles on demand")
+		flReconcileReportOnly    = flagset.Bool("blueprint-reconcile-report-only", env.Bool("MICROMDM_BLUEPRINT_RECONCILE_REPORT_ONLY", false), "Only report blueprint drift on scheduled reconciles, without repairing devices")
+		flProfileSigningIdentity = flagset.String("profile-signing-identity", env.String("MICROMDM_PROFILE_SIGNING_IDENTITY", ""), "Path to a PKCS#12 identity used to sign unsigned profiles before they are installed")
+		flProfileSigningPass     = flagset.String("profile-signing-identity-pass", env.String("MICROMDM_PROFILE_SIGNING_IDENTITY_PASS", ""), "Password of the profile signing identity")
+		flProfileSigningTLS      = flagset.Bool("profile-signing-tls", env.Bool("MICROMDM_PROFILE_SIGNING_TLS", false), "Sign unsigned profiles with the -tls-cert and -tls-key before they are installed")
+		flEnrollSigningIdentity  = flagset.String("enrollment-signing-identity", env.String("MICROMDM_ENROLLMENT_SIGNING_IDENTITY", ""), "Path to a PKCS#12 identity used to sign the enrollment and OTA profiles")
+		flEnrollSigningPass      = flagset.String("enrollment-signing-identity-pass", env.String("MICROMDM_ENROLLMENT_SIGNING_IDENTITY_PASS", ""), "Password of the enrollment signing identity")
+		flEnrollSigningTLS       = flagset.Bool("enrollment-signing-tls", env.Bool("MICROMDM_ENROLLMENT_SIGNING_TLS", false), "Sign the enrollment and OTA profiles with the -tls-cert and -tls-key")
+		flInviteRequired         = flagset.Bool("enrollment-invite-required", env.Bool("MICROMDM_ENROLLMENT_INVITE_REQUIRED", false), "Only serve the enrollment profile for a valid enrollment invite token, and disable OTA enrollment")
+		flLDAPURL                = flagset.String("ldap-url", env.String("MICROMDM_LDAP_URL", ""), "URL of an LDAP server to sync users from, like ldaps://ldap.example.com")
+		flLDAPBindDN             = flagset.String("ldap-bind-dn", env.String("MICROMDM_LDAP_BIND_DN", ""), "DN to bind to the LDAP server as")
+		flLDAPBindPassword       = flagset.String("ldap-bind-password", env.String("MICROMDM_LDAP_BIND_PASSWORD", ""), "Password of the LDAP bind DN")
+		flLDAPBaseDN             = flagset.String("ldap-base-dn", env.String("MICROMDM_LDAP_BASE_DN", ""), "DN of the subtree searched for the members of the LDAP group")
+		flLDAPGroupDN            = flagset.String("ldap-group-dn", env.String("MICROMDM_LDAP_GROUP_DN", ""), "DN of the LDAP group whose members are synced to users")
+		flLDAPGroupFilter        = flagset.String("ldap-group-filter", env.String("MICROMDM_LDAP_GROUP_FILTER", "(memberOf={group})"), "LDAP filter for the members of the group, {group} is replaced with the group DN")
+		flLDAPShortnameAttr      = flagset.String("ldap-shortname-attr", env.String("MICROMDM_LDAP_SHORTNAME_ATTR", "uid"), "LDAP attribute mapped to the short name of users")
+		flLDAPLongnameAttr       = flagset.String("ldap-longname-attr", env.String("MICROMDM_LDAP_LONGNAME_ATTR", "cn"), "LDAP attribute mapped to the long name of users")
+		flLDAPSyncInterval       = flagset.Int("ldap-sync-interval", env.Int("MICROMDM_LDAP_SYNC_INTERVAL", 24), "Hours between syncing users from the LDAP group, 0 only syncs on demand")
+		flLDAPSyncDryRun         = flagset.Bool("ldap-sync-dry-run", env.Bool("MICROMDM_LDAP_SYNC_DRY_RUN", false), "Only report the changes of scheduled LDAP syncs, without saving users")
 	)
 	flagset.Usage = usageFor(flagset, "micromdm serve [flags]")
 	if err := flagset.Parse(args); err != nil {
@@ -156,6 +187,18 @@ func serve(args []string) error {
 	if !*flTLS && (*flTLSCert != "" || *flTLSKey != "") {
 		return errors.New("cannot set -tls=false and supply -tls-cert or -tls-key")
 	}
+	if *flProfileSigningIdentity != "" && *flProfileSigningTLS {
+		return errors.New("cannot set both -profile-signing-identity and -profile-signing-tls")
+	}
+	if *flProfileSigningTLS && (*flTLSCert == "" || *flTLSKey == "") {
+		return errors.New("-profile-signing-tls requires -tls-cert and -tls-key")
+	}
+	if *flEnrollSigningIdentity != "" && *flEnrollSigningTLS {
+		return errors.New("cannot set both -enrollment-signing-identity and -enrollment-signing-tls")
+	}
+	if *flEnrollSigningTLS && (*flTLSCert == "" || *flTLSKey == "") {
+		return errors.New("-enrollment-signing-tls requires -tls-cert and -tls-key")
+	}
 
 	logger := log.NewLogfmtLogger(os.Stderr)
 	if *flLogTime {
@@ -168,6 +211,12 @@ func serve(args []string) error {
 	if err := os.MkdirAll(*flConfigPath, 0755); err != nil {
 		return errors.Wrapf(err, "creating config directory %s", *flConfigPath)
 	}
+
+	enrollmentSigner, err := loadProfileSigner(*flEnrollSigningIdentity, *flEnrollSigningPass, *flEnrollSigningTLS, *flTLSCert, *flTLSKey)
+	if err != nil {
+		return errors.Wrap(err, "load enrollment signing identity")
+	}
+
 	sm := &server.Server{
 		ConfigPath:             *flConfigPath,
 		ServerPublicURL:        strings.TrimRight(*flServerURL, "/"),
@@ -186,6 +235,8 @@ func serve(args []string) error {
 		SCEPClientValidity: *flSCEPClientValidity,
 		Queue:              *flQueue,
 		DMURL:              *flDMURL,
+		EnrollmentSigner:   enrollmentSigner,
+		InviteRequired:     *flInviteRequired,
 	}
 	if !sm.UseDynSCEPChallenge {
 		// TODO: we have a static SCEP challenge password here to prevent
@@ -205,20 +256,14 @@ func serve(args []string) error {
 
 	var removeService block.Service
 	{
-		svc, err := block.New(sm.RemoveDB)
+		svc, err := block.New(sm.RemoveDB, block.WithPublisher(sm.PubClient))
 		if err != nil {
 			stdlog.Fatal(err)
 		}
 		removeService = block.LoggingMiddleware(logger)(svc)
 	}
 
-	devDB, err := devicebuiltin.NewDB(sm.DB)
-	if err != nil {
-		stdlog.Fatal(err)
-	}
-
-	devWorker := device.NewWorker(devDB, sm.PubClient, logger)
-	go devWorker.Run(context.Background())
+	devDB := sm.DeviceDB
 
 	userDB, err := userbuiltin.NewDB(sm.DB)
 	if err != nil {
@@ -227,21 +272,101 @@ func serve(args []string) error {
 	userWorker := user.NewWorker(userDB, sm.PubClient, logger)
 	go userWorker.Run(context.Background())
 
+	var directorySync *user.DirectorySync
+	if *flLDAPURL != "" {
+		source := user.NewLDAPSource(user.LDAPConfig{
+			URL:                *flLDAPURL,
+			BindDN:             *flLDAPBindDN,
+			BindPassword:       *flLDAPBindPassword,
+			BaseDN:             *flLDAPBaseDN,
+			GroupDN:            *flLDAPGroupDN,
+			Filter:             *flLDAPGroupFilter,
+			ShortnameAttribute: *flLDAPShortnameAttr,
+			LongnameAttribute:  *flLDAPLongnameAttr,
+		})
+		directorySync = user.NewDirectorySync(userDB, source, logger,
+			user.WithDirectorySyncInterval(time.Duration(*flLDAPSyncInterval)*time.Hour),
+			user.WithDirectorySyncDryRun(*flLDAPSyncDryRun),
+		)
+		go directorySync.Run(context.Background())
+	}
+
+	devicesvc := device.New(devDB,
+		device.WithCommandQueue(sm.CommandQueue),
+		device.WithCommandSender(sm.CommandService),
+		device.WithPublisher(sm.PubClient),
+		device.WithPurgeFuncs(
+			sm.PushDB.DeletePushInfo,
+			sm.PurgeCommandQueue,
+			userDB.PurgeDeviceFunc(sm.PurgeCommandQueue),
+			sm.RemoveDB.PurgeDevice,
+		),
+	)
+	staleWorker := device.NewStaleWorker(devDB, sm.APNSPushService, sm.PubClient, logger,
+		device.WithPushAfter(time.Duration(*flDeviceRePushAfter)*time.Hour),
+		device.WithStaleAfter(time.Duration(*flDeviceStaleAfter)*time.Hour),
+		device.WithPurgeTimeout(devicesvc, time.Duration(*flDevicePurgeTimeout)*time.Hour),
+	)
+	go staleWorker.Run(context.Background())
+	devWorker := device.NewWorker(devDB, sm.PubClient, logger,
+		device.WithPurger(devicesvc),
+		device.WithInvites(sm.InviteDB),
+	)
+	go devWorker.Run(context.Background())
+
 	bpDB, err := blueprintbuiltin.NewDB(sm.DB, sm.ProfileDB)
 	if err != nil {
 		stdlog.Fatal(err)
 	}
 
+	profileSigner, err := loadProfileSigner(*flProfileSigningIdentity, *flProfileSigningPass, *flProfileSigningTLS, *flTLSCert, *flTLSKey)
+	if err != nil {
+		return err
+	}
+
+	profileVars := blueprint.NewProfileVariables(devDB, userDB)
+	profileEncrypter := blueprint.NewProfileEncrypter(devDB)
+
 	blueprintWorker := blueprint.NewWorker(
 		bpDB,
 		userDB,
+		devDB,
 		sm.ProfileDB,
 		sm.CommandService,
 		sm.PubClient,
 		logger,
+		blueprint.WithProfileSigner(profileSigner),
+		blueprint.WithProfileVariables(profileVars),
+		blueprint.WithProfileEncrypter(profileEncrypter),
+		blueprint.WithInvites(sm.InviteDB),
 	)
 	go blueprintWorker.Run(context.Background())
 
+	reconciler := blueprint.NewReconciler(
+		bpDB,
+		devDB,
+		sm.ProfileDB,
+		sm.CommandService,
+		sm.PubClient,
+		logger,
+		blueprint.WithReconcileInterval(time.Duration(*flReconcileInterval)*time.Hour),
+		blueprint.WithReportOnly(*flReconcileReportOnly),
+		blueprint.WithReconcileProfileSigner(profileSigner),
+		blueprint.WithReconcileProfileVariables(profileVars),
+		blueprint.WithReconcileProfileEncrypter(profileEncrypter),
+	)
+	go reconciler.Run(context.Background())
+
+	timelineDB, err := timelinebuiltin.NewDB(sm.DB,
+		timelinebuiltin.WithMaxEvents(*flDeviceEventsMax),
+		timelinebuiltin.WithMaxAge(time.Duration(*flDeviceEventsMaxAge)*24*time.Hour),
+	)
+	if err != nil {
+		stdlog.Fatal(err)
+	}
+	timelineWorker := timeline.NewWorker(timelineDB, devDB, sm.PubClient, logger)
+	go timelineWorker.Run(context.Background())
+
 	ctx := context.Background()
 	httpLogger := log.With(logger, "transport", "http")
 
@@ -284,22 +409,36 @@ func serve(args []string) error {
 		apnsEndpoints := apns.MakeServerEndpoints(sm.APNSPushService, basicAuthEndpointMiddleware)
 		apns.RegisterHTTPHandlers(r, apnsEndpoints, options...)
 
-		devicesvc := device.New(devDB)
 		deviceEndpoints := device.MakeServerEndpoints(devicesvc, basicAuthEndpointMiddleware)
 		device.RegisterHTTPHandlers(r, deviceEndpoints, options...)
 
-		profilesvc := profile.New(sm.ProfileDB)
+		profilesvc := profile.New(sm.ProfileDB, profile.WithReinstaller(blueprintWorker))
 		profileEndpoints := profile.MakeServerEndpoints(profilesvc, basicAuthEndpointMiddleware)
 		profile.RegisterHTTPHandlers(r, profileEndpoints, options...)
 
-		blueprintsvc := blueprint.New(bpDB)
+		blueprintsvc := blueprint.New(bpDB,
+			blueprint.WithApplier(blueprintWorker, devDB),
+			blueprint.WithReconciler(reconciler),
+			blueprint.WithCleanup(sm.CommandService),
+		)
 		blueprintEndpoints := blueprint.MakeServerEndpoints(blueprintsvc, basicAuthEndpointMiddleware)
 		blueprint.RegisterHTTPHandlers(r, blueprintEndpoints, options...)
 
 		blockEndpoints := block.MakeServerEndpoints(removeService, basicAuthEndpointMiddleware)
 		block.RegisterHTTPHandlers(r, blockEndpoints, options...)
 
-		usersvc := user.New(userDB)
+		invitesvc := invite.New(sm.InviteDB)
+		inviteEndpoints := invite.MakeServerEndpoints(invitesvc, basicAuthEndpointMiddleware)
+		invite.RegisterHTTPHandlers(r, inviteEndpoints, options...)
+
+		timelinesvc := timeline.New(timelineDB)
+		timelineEndpoints := timeline.MakeServerEndpoints(timelinesvc, basicAuthEndpointMiddleware)
+		timeline.RegisterHTTPHandlers(r, timelineEndpoints, options...)
+
+		usersvc := user.New(userDB,
+			user.WithPasswordRotator(blueprintWorker),
+			user.WithDirectorySync(directorySync),
+		)
 		userEndpoints := user.MakeServerEndpoints(usersvc, basicAuthEndpointMiddleware)
 		user.RegisterHTTPHandlers(r, userEndpoints, options...)
 
@@ -307,7 +446,10 @@ func serve(args []string) error {
 		appEndpoints := appstore.MakeServerEndpoints(appsvc, basicAuthEndpointMiddleware)
 		appstore.RegisterHTTPHandlers(r, appEndpoints, options...)
 
-		commandEndpoints := command.MakeServerEndpoints(sm.CommandService, basicAuthEndpointMiddleware)
+		commandsvc := command.UserTargetMiddleware(userDB)(
+			command.ProfileVariablesMiddleware(profileVars, profileSigner)(sm.CommandService),
+		)
+		commandEndpoints := command.MakeServerEndpoints(commandsvc, basicAuthEndpointMiddleware)
 		command.RegisterHTTPHandlers(r, commandEndpoints, options...)
 
 		var dc depapi.DEPClient
@@ -406,6 +548,80 @@ func serveOptions(
 	return serveOpts
 }
 
+// loadProfileSigner loads the identity used to sign profiles from a PKCS#12
+// file or the TLS certificate and key. It returns nil if neither is set.
+func loadProfileSigner(identityPath, identityPass string, useTLS bool, certPath, keyPath string) (*profile.Signer, error) {
+	switch {
+	case identityPath != "":
+		data, err := os.ReadFile(identityPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "read profile signing identity")
+		}
+		return decodeSigningIdentity(data, identityPass)
+	case useTLS:
+		pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "load TLS key pair for profile signing")
+		}
+		certs := make([]*x509.Certificate, len(pair.Certificate))
+		for i, der := range pair.Certificate {
+			if certs[i], err = x509.ParseCertificate(der); err != nil {
+				return nil, errors.Wrap(err, "parse TLS certificate for profile signing")
+			}
+		}
+		return profile.NewSigner(pair.PrivateKey, certs[0], certs[1:]...), nil
+	default:
+		return nil, nil
+	}
+}
+
+// decodeSigningIdentity returns a signer for a PKCS#12 identity. pkcs12.Decode
+// rejects identities with more than one certificate, so the key and the
+// certificates are read from the PEM blocks of the identity instead. The leaf
+// is the certificate of the key, and the others are the chain.
+func decodeSigningIdentity(data []byte, password string) (*profile.Signer, error) {
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode profile signing identity")
+	}
+	var (
+		key   interface{}
+		certs []*x509.Certificate
+	)
+	for _, block := range blocks {
+		switch block.Type {
+		case "PRIVATE KEY":
+			// ToPEM encodes RSA keys as PKCS #1 and EC keys as SEC 1.
+			if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+				if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+					return nil, errors.Wrap(err, "parse profile signing identity key")
+				}
+			}
+		case "CERTIFICATE":
+			c, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, errors.Wrap(err, "parse profile signing identity certificate")
+			}
+			certs = append(certs, c)
+		}
+	}
+	if key == nil {
+		return nil, errors.New("profile signing identity has no private key")
+	}
+	pub, err := x509.MarshalPKIXPublicKey(key.(stdcrypto.Signer).Public())
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal profile signing identity public key")
+	}
+	for i, c := range certs {
+		if !bytes.Equal(c.RawSubjectPublicKeyInfo, pub) {
+			continue
+		}
+		chain := append(append([]*x509.Certificate{}, certs[:i]...), certs[i+1:]...)
+		return profile.NewSigner(key, c, chain...), nil
+	}
+	return nil, errors.New("profile signing identity has no certificate for its private key")
+}
+
 func boltBackup(db *bolt.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := db.View(func(tx *bolt.Tx) error {
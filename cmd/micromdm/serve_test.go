@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/smallstep/pkcs7"
+
+	"github.com/micromdm/micromdm/platform/profile"
+)
+
+func TestLoadProfileSignerChain(t *testing.T) {
+	// the identity has a leaf and an intermediate certificate, which
+	// pkcs12.Decode rejects.
+	signer, err := loadProfileSigner("testdata/profile_signing_chain.p12", "secret", false, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := profile.Mobileconfig(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>PayloadIdentifier</key><string>com.example.test</string></dict></plist>`)
+	signed, err := signer.Sign(mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p7, err := pkcs7.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := p7.GetOnlySigner().Subject.CommonName, "MicroMDM Test Profile Signing"; have != want {
+		t.Errorf("have signer %q, want %q", have, want)
+	}
+	if len(p7.Certificates) != 2 {
+		t.Errorf("have %d certificates in the signature, want the leaf and the intermediate", len(p7.Certificates))
+	}
+
+	data, err := os.ReadFile("testdata/profile_signing_chain.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeSigningIdentity(data, "wrong"); err == nil {
+		t.Error("expected an error for a wrong password")
+	}
+}
@@ -0,0 +1,115 @@
+package mdm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/invite"
+)
+
+// EnrollmentRejectedTopic is published with a JSON encoded
+// EnrollmentRejectedEvent when a device is not admitted at Authenticate.
+const EnrollmentRejectedTopic = "mdm.EnrollmentRejected"
+
+type EnrollmentRejectedEvent struct {
+	Time         time.Time `json:"time"`
+	UDID         string    `json:"udid"`
+	SerialNumber string    `json:"serial_number"`
+	ProductName  string    `json:"product_name"`
+	OSVersion    string    `json:"os_version"`
+	Reasons      []string  `json:"reasons"`
+}
+
+// Admitter decides whether a device may enroll.
+type Admitter interface {
+	// AdmitDevice returns the reasons the device is rejected, or none if
+	// the device is admitted.
+	AdmitDevice(ctx context.Context, cmd CheckinCommand) (reasons []string, err error)
+}
+
+// InviteRedeemer records the enrollment of a device with an invite.
+type InviteRedeemer interface {
+	Redeem(ctx context.Context, token, udid string) (*invite.Invite, error)
+}
+
+type Option func(*MDMService)
+
+// WithAdmitter evaluates the Authenticate check-in of every device with the
+// admitter, and rejects the enrollment of devices which are not admitted.
+// User Enrollments are not evaluated.
+func WithAdmitter(a Admitter) Option {
+	return func(svc *MDMService) {
+		svc.admitter = a
+	}
+}
+
+// WithInvites redeems the invite token of the check-in URL when a device
+// authenticates. The enrollment is rejected if the invite is unknown,
+// expired or used up.
+func WithInvites(r InviteRedeemer) Option {
+	return func(svc *MDMService) {
+		svc.invites = r
+	}
+}
+
+func (svc *MDMService) admit(ctx context.Context, event CheckinEvent) error {
+	if event.Command.EnrollmentID != "" {
+		return nil
+	}
+	var reasons []string
+	if svc.admitter != nil {
+		var err error
+		reasons, err = svc.admitter.AdmitDevice(ctx, event.Command)
+		if err != nil {
+			return errors.Wrap(err, "evaluate enrollment admission")
+		}
+	}
+	// the invite is only redeemed by admitted devices, so that rejected
+	// devices do not use it up.
+	if token := event.Params[invite.TokenParam]; len(reasons) == 0 && token != "" && svc.invites != nil {
+		if _, err := svc.invites.Redeem(ctx, token, event.Command.UDID); err != nil {
+			reasons = append(reasons, inviteRejection(err))
+		}
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+	msg, err := json.Marshal(&EnrollmentRejectedEvent{
+		Time:         event.Time,
+		UDID:         event.Command.UDID,
+		SerialNumber: event.Command.SerialNumber,
+		ProductName:  event.Command.ProductName,
+		OSVersion:    event.Command.OSVersion,
+		Reasons:      reasons,
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshal enrollment rejected event")
+	}
+	if err := svc.pub.Publish(ctx, EnrollmentRejectedTopic, msg); err != nil {
+		return errors.Wrapf(err, "publish on topic: %s", EnrollmentRejectedTopic)
+	}
+	return &rejectEnrollment{reasons: reasons}
+}
+
+func inviteRejection(err error) string {
+	if invite.IsNotFound(err) {
+		return "enrollment invite is not known"
+	}
+	return "enrollment invite: " + errors.Cause(err).Error()
+}
+
+type rejectEnrollment struct {
+	reasons []string
+}
+
+func (e *rejectEnrollment) Error() string {
+	return "enrollment rejected: " + strings.Join(e.reasons, "; ")
+}
+
+func (e *rejectEnrollment) EnrollmentRejected() bool {
+	return true
+}
@@ -43,6 +43,9 @@ func (svc *MDMService) Checkin(ctx context.Context, event CheckinEvent) ([]byte,
 
 	switch topic {
 	case AuthenticateTopic:
+		if err := svc.admit(ctx, event); err != nil {
+			return nil, err
+		}
 		if err := svc.queue.Clear(ctx, event); err != nil {
 			return nil, errors.Wrap(err, "clearing queue on enrollment attempt")
 		}
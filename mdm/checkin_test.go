@@ -3,8 +3,13 @@ package mdm
 import (
 	"bytes"
 	"context"
+	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/micromdm/micromdm/platform/invite"
 )
 
 func Test_decodeCheckinRequest(t *testing.T) {
@@ -40,3 +45,92 @@ const sampleCheckinRequest = `<?xml version="1.0" encoding="UTF-8"?>
 	<string>BC5E2DA4-7FB6-5E70-9928-4981680DAFBF</string>
 </dict>
 </plist>`
+
+type testAdmitter []string
+
+func (a testAdmitter) AdmitDevice(ctx context.Context, cmd CheckinCommand) ([]string, error) {
+	return a, nil
+}
+
+type testPublisher struct {
+	topics []string
+}
+
+func (p *testPublisher) Publish(ctx context.Context, topic string, msg []byte) error {
+	p.topics = append(p.topics, topic)
+	return nil
+}
+
+func TestCheckinRejectedEnrollment(t *testing.T) {
+	pub := new(testPublisher)
+	svc := NewService(pub, nil, nil, nil, WithAdmitter(testAdmitter{"serial number is not known"}))
+	event := CheckinEvent{Command: CheckinCommand{MessageType: "Authenticate", UDID: "UDID-1"}}
+
+	_, err := svc.Checkin(context.Background(), event)
+	if err == nil {
+		t.Fatal("expected enrollment to be rejected")
+	}
+	w := httptest.NewRecorder()
+	encodeError(context.Background(), err, w)
+	if have, want := w.Code, http.StatusForbidden; have != want {
+		t.Errorf("have status %d, want %d", have, want)
+	}
+	if have, want := pub.topics, []string{EnrollmentRejectedTopic}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have published topics %v, want %v", have, want)
+	}
+}
+
+func TestCheckinInviteMaxUses(t *testing.T) {
+	pub := new(testPublisher)
+	invites := &testInvites{inv: &invite.Invite{Token: "token", MaxUses: 1}}
+	svc := NewService(pub, testQueue{}, nil, nil, WithInvites(invites))
+	ctx := context.Background()
+
+	for _, udid := range []string{"UDID-1", "UDID-1"} {
+		event := CheckinEvent{
+			Command: CheckinCommand{MessageType: "Authenticate", UDID: udid},
+			Params:  map[string]string{invite.TokenParam: "token"},
+		}
+		if _, err := svc.Checkin(ctx, event); err != nil {
+			t.Fatalf("enrollment of %s: %s", udid, err)
+		}
+	}
+
+	event := CheckinEvent{
+		Command: CheckinCommand{MessageType: "Authenticate", UDID: "UDID-2"},
+		Params:  map[string]string{invite.TokenParam: "token"},
+	}
+	_, err := svc.Checkin(ctx, event)
+	if err == nil {
+		t.Fatal("expected second device to be rejected")
+	}
+	w := httptest.NewRecorder()
+	encodeError(ctx, err, w)
+	if have, want := w.Code, http.StatusForbidden; have != want {
+		t.Errorf("have status %d, want %d", have, want)
+	}
+	if have, want := invites.inv.UDIDs, []string{"UDID-1"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have redeemed UDIDs %v, want %v", have, want)
+	}
+}
+
+type testInvites struct {
+	inv *invite.Invite
+}
+
+func (s *testInvites) Redeem(ctx context.Context, token, udid string) (*invite.Invite, error) {
+	if s.inv.Redeemed(udid) {
+		return s.inv, nil
+	}
+	if err := s.inv.Valid(time.Now()); err != nil {
+		return nil, err
+	}
+	s.inv.UDIDs = append(s.inv.UDIDs, udid)
+	return s.inv, nil
+}
+
+type testQueue struct{}
+
+func (testQueue) Next(context.Context, Response) ([]byte, error)              { return nil, nil }
+func (testQueue) Clear(context.Context, CheckinEvent) error                   { return nil }
+func (testQueue) ViewQueue(context.Context, CheckinEvent) ([]*Command, error) { return nil, nil }
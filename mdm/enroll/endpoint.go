@@ -47,7 +47,9 @@ type otaEnrollmentRequest struct {
 	UserShortName string
 }
 
-type mdmEnrollRequest struct{}
+type mdmEnrollRequest struct {
+	Token string
+}
 
 type mobileconfigResponse struct {
 	profile.Mobileconfig
@@ -71,7 +73,7 @@ func MakeGetEnrollEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		switch req := request.(type) {
 		case mdmEnrollRequest:
-			mc, err := s.Enroll(ctx)
+			mc, err := s.EnrollWithInvite(ctx, req.Token)
 			return mobileconfigResponse{mc, err}, nil
 		case depEnrollmentRequest:
 			fmt.Printf("got DEP enrollment request from %s\n", req.Serial)
@@ -1,7 +1,25 @@
 package enroll
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/jessepeterson/cfgprofiles"
+	"github.com/micromdm/plist"
+
+	"github.com/micromdm/micromdm/platform/config"
+	"github.com/micromdm/micromdm/platform/invite"
+	"github.com/micromdm/micromdm/platform/profile"
 )
 
 func TestEnrollProfile(t *testing.T) {
@@ -32,3 +50,187 @@ func TestEnrollProfile(t *testing.T) {
 		t.Errorf("missing ServerCapabilities: macOS enrollment profile requires %s", perUserConnections)
 	}
 }
+
+type testConfigStore struct {
+	conf config.EnrollmentConfig
+}
+
+func (s *testConfigStore) EnrollmentConfig() (*config.EnrollmentConfig, error) {
+	conf := s.conf
+	return &conf, nil
+}
+
+func selfSignedCertPEM(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Example Root CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestEnrollProfileConfig(t *testing.T) {
+	checkOut := false
+	store := &testConfigStore{conf: config.EnrollmentConfig{
+		Organization:        "Example Inc.",
+		AccessRights:        4095,
+		CheckOutWhenRemoved: &checkOut,
+		KeySize:             4096,
+		Certificates:        []string{selfSignedCertPEM(t)},
+		Payloads: [][]byte{[]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>PayloadType</key><string>com.apple.example</string>
+<key>PayloadIdentifier</key><string>com.example.payload</string>
+<key>PayloadUUID</key><string>4F8C1E43-0B7E-4A2B-9A57-36F07A33E3F1</string>
+<key>PayloadVersion</key><integer>1</integer>
+</dict></plist>`)},
+	}}
+	if err := store.conf.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	svc := &service{SCEPURL: "https://mdm.example.com/scep", configStore: store}
+	if err := svc.loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	mc, err := svc.makeMobileconfig(EnrollmentProfileId, svc.MakeEnrollmentProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p cfgprofiles.Profile
+	if err := plist.Unmarshal(mc, &p); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := p.PayloadOrganization, "Example Inc."; have != want {
+		t.Errorf("have organization %q, want %q", have, want)
+	}
+	if have, want := p.PayloadDisplayName, profilePayloadDisplayName; have != want {
+		t.Errorf("have display name %q, want default %q", have, want)
+	}
+	mdmPayload := p.MDMPayloads()[0]
+	if mdmPayload.AccessRights != 4095 || mdmPayload.CheckOutWhenRemoved {
+		t.Errorf("have access rights %d, check out %v", mdmPayload.AccessRights, mdmPayload.CheckOutWhenRemoved)
+	}
+	scepPayload := p.SCEPPayloads()[0]
+	if scepPayload.PayloadContent.KeySize != 4096 || scepPayload.PayloadContent.KeyUsage != scepPayloadKeyUsage {
+		t.Errorf("have key size %d, key usage %d", scepPayload.PayloadContent.KeySize, scepPayload.PayloadContent.KeyUsage)
+	}
+	// the root certificate and the extra payload.
+	var types []string
+	for _, pld := range p.UnknownPayloads() {
+		types = append(types, pld.PayloadType)
+	}
+	if want := []string{"com.apple.security.root", "com.apple.example"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("have payloads %v, want %v", types, want)
+	}
+
+	// the profile is cached until the config changes.
+	cached, err := svc.makeMobileconfig(EnrollmentProfileId, svc.MakeEnrollmentProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(mc, cached) {
+		t.Error("profile was not cached")
+	}
+	// a saved config applies to the next request.
+	store.conf.Organization = "Example Corp."
+	regenerated, err := svc.makeMobileconfig(EnrollmentProfileId, svc.MakeEnrollmentProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(regenerated, []byte("Example Corp.")) {
+		t.Error("profile was not regenerated after the config changed")
+	}
+}
+
+func TestSignedEnrollProfile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Example Enrollment Signing"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &service{signer: profile.NewSigner(key, cert)}
+	for _, id := range []string{EnrollmentProfileId, OTAProfileId} {
+		f := interface{}(svc.MakeEnrollmentProfile)
+		if id == OTAProfileId {
+			f = svc.MakeOTAEnrollPayload
+		}
+		mc, err := svc.makeMobileconfig(id, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !mc.IsSigned() {
+			t.Errorf("%s profile is not signed", id)
+		}
+	}
+}
+
+type testInviteStore map[string]*invite.Invite
+
+func (s testInviteStore) Invite(ctx context.Context, token string) (*invite.Invite, error) {
+	inv, ok := s[token]
+	if !ok {
+		return nil, &testNotFound{}
+	}
+	return inv, nil
+}
+
+type testNotFound struct{}
+
+func (e *testNotFound) Error() string  { return "not found" }
+func (e *testNotFound) NotFound() bool { return true }
+
+func TestEnrollWithInvite(t *testing.T) {
+	invites := testInviteStore{
+		"valid":   {Token: "valid", MaxUses: 1},
+		"used":    {Token: "used", MaxUses: 1, UDIDs: []string{"UDID-1"}},
+		"expired": {Token: "expired", MaxUses: 1, ExpiresAt: time.Now().Add(-time.Hour)},
+	}
+	svc := &service{URL: "https://mdm.example.com", invites: invites, inviteRequired: true}
+	ctx := context.Background()
+
+	mc, err := svc.EnrollWithInvite(ctx, "valid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p cfgprofiles.Profile
+	if err := plist.Unmarshal(mc, &p); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := p.MDMPayloads()[0].CheckInURL, "https://mdm.example.com/mdm/checkin?token=valid"; have != want {
+		t.Errorf("have check-in URL %s, want %s", have, want)
+	}
+
+	for _, token := range []string{"", "used", "expired", "unknown"} {
+		_, err := svc.EnrollWithInvite(ctx, token)
+		sc, ok := err.(interface{ StatusCode() int })
+		if !ok || sc.StatusCode() != http.StatusForbidden {
+			t.Errorf("token %q: have %v, want forbidden error", token, err)
+		}
+	}
+}
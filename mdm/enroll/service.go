@@ -3,12 +3,18 @@ package enroll
 import (
 	"bytes"
 	"crypto/x509"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"net/url"
+	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/micromdm/micromdm/platform/config"
+	"github.com/micromdm/micromdm/platform/invite"
 	"github.com/micromdm/micromdm/platform/profile"
 	"github.com/micromdm/micromdm/platform/pubsub"
 	"github.com/micromdm/scep/v2/challenge"
@@ -27,6 +33,10 @@ const (
 	profilePayloadDisplayName  = "Enrollment Profile"
 	profilePayloadDescription  = "The server may alter your settings"
 
+	mdmPayloadAccessRights = 8191
+	scepPayloadKeySize     = 2048
+	scepPayloadKeyUsage    = int(x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment)
+
 	mdmPayloadDescription     = "Enrolls with the MDM server"
 	mdmPayloadServerEndpoint  = "/mdm/connect"
 	mdmPayloadCheckInEndpoint = "/mdm/checkin"
@@ -37,12 +47,68 @@ const (
 
 type Service interface {
 	Enroll(ctx context.Context) (profile.Mobileconfig, error)
+	EnrollWithInvite(ctx context.Context, token string) (profile.Mobileconfig, error)
 	OTAEnroll(ctx context.Context) (profile.Mobileconfig, error)
 	OTAPhase2(ctx context.Context) (profile.Mobileconfig, error)
 	OTAPhase3(ctx context.Context) (profile.Mobileconfig, error)
 }
 
-func NewService(topic TopicProvider, sub pubsub.Subscriber, scepURL, scepChallenge, url, tlsCertPath, scepSubject string, profileDB profile.Store, challengeStore challenge.Store) (Service, error) {
+// EnrollmentConfigStore returns the configuration of the enrollment profile.
+type EnrollmentConfigStore interface {
+	EnrollmentConfig() (*config.EnrollmentConfig, error)
+}
+
+type Option func(*service)
+
+// WithEnrollmentConfig changes the contents of the enrollment profiles with
+// the saved enrollment config. The config is read whenever a profile is
+// requested, and the profiles are regenerated once it changed.
+func WithEnrollmentConfig(store EnrollmentConfigStore) Option {
+	return func(svc *service) {
+		svc.configStore = store
+	}
+}
+
+// ProfileSigner signs the enrollment profiles.
+type ProfileSigner interface {
+	Sign(profile.Mobileconfig) (profile.Mobileconfig, error)
+}
+
+// WithProfileSigner signs the enrollment and OTA profiles, both generated
+// profiles and the profiles saved with their identifiers, so that they show
+// as verified when they are installed.
+func WithProfileSigner(signer ProfileSigner) Option {
+	return func(svc *service) {
+		svc.signer = signer
+	}
+}
+
+// InviteStore returns the enrollment invite for a token.
+type InviteStore interface {
+	Invite(ctx context.Context, token string) (*invite.Invite, error)
+}
+
+// WithInvites accepts invite tokens on the enrollment URL. The token is
+// added to the check-in URL of the enrollment profile so that the device
+// redeems it when it enrolls. If required is set, the enrollment profile is
+// only served for a valid token and OTA enrollment is disabled.
+func WithInvites(store InviteStore, required bool) Option {
+	return func(svc *service) {
+		svc.invites = store
+		svc.inviteRequired = required
+	}
+}
+
+// statusError is an enrollment error with an HTTP status code.
+type statusError struct {
+	err    error
+	status int
+}
+
+func (e statusError) Error() string   { return e.err.Error() }
+func (e statusError) StatusCode() int { return e.status }
+
+func NewService(topic TopicProvider, sub pubsub.Subscriber, scepURL, scepChallenge, url, tlsCertPath, scepSubject string, profileDB profile.Store, challengeStore challenge.Store, opts ...Option) (Service, error) {
 	var tlsCert []byte
 	var err error
 
@@ -82,12 +148,22 @@ func NewService(topic TopicProvider, sub pubsub.Subscriber, scepURL, scepChallen
 		ProfileDB:          profileDB,
 		Topic:              pushTopic,
 		topicProvier:       topic,
+		cache:              make(map[string]profile.Mobileconfig),
+	}
+	for _, opt := range opts {
+		opt(svc)
 	}
 
 	if err := updateTopic(svc, sub); err != nil {
 		return nil, errors.Wrap(err, "enroll: start topic update goroutine")
 	}
 
+	if svc.configStore != nil {
+		if err := svc.loadConfig(); err != nil {
+			return nil, err
+		}
+	}
+
 	return svc, nil
 }
 
@@ -106,6 +182,7 @@ func updateTopic(svc *service, sub pubsub.Subscriber) error {
 				}
 				svc.mu.Lock()
 				svc.Topic = topic
+				svc.cache = make(map[string]profile.Mobileconfig)
 				svc.mu.Unlock()
 
 				// terminate the loop here because the topic should never change
@@ -118,6 +195,57 @@ func updateTopic(svc *service, sub pubsub.Subscriber) error {
 	return nil
 }
 
+// loadConfig loads the enrollment config and drops the cached profiles if
+// the config changed. It is called before a profile is generated or taken
+// from the cache, so a saved config applies to the next enrollment.
+func (svc *service) loadConfig() error {
+	if svc.configStore == nil {
+		return nil
+	}
+	conf, err := svc.configStore.EnrollmentConfig()
+	if err != nil {
+		return errors.Wrap(err, "load enrollment config")
+	}
+	svc.mu.Lock()
+	if !reflect.DeepEqual(svc.config, *conf) || svc.cache == nil {
+		svc.config = *conf
+		svc.cache = make(map[string]profile.Mobileconfig)
+	}
+	svc.mu.Unlock()
+	return nil
+}
+
+// enrollmentConfig returns the enrollment config with defaults for the unset
+// values.
+func (svc *service) enrollmentConfig() config.EnrollmentConfig {
+	svc.mu.RLock()
+	conf := svc.config
+	svc.mu.RUnlock()
+	if conf.Organization == "" {
+		conf.Organization = profilePayloadOrganization
+	}
+	if conf.DisplayName == "" {
+		conf.DisplayName = profilePayloadDisplayName
+	}
+	if conf.Description == "" {
+		conf.Description = profilePayloadDescription
+	}
+	if conf.AccessRights == 0 {
+		conf.AccessRights = mdmPayloadAccessRights
+	}
+	if conf.CheckOutWhenRemoved == nil {
+		checkOut := true
+		conf.CheckOutWhenRemoved = &checkOut
+	}
+	if conf.KeySize == 0 {
+		conf.KeySize = scepPayloadKeySize
+	}
+	if conf.KeyUsage == 0 {
+		conf.KeyUsage = scepPayloadKeyUsage
+	}
+	return conf
+}
+
 type service struct {
 	URL                string
 	SCEPURL            string
@@ -128,9 +256,18 @@ type service struct {
 	ProfileDB          profile.Store
 
 	topicProvier TopicProvider
-
-	mu    sync.RWMutex
-	Topic string // APNS Topic for MDM notifications
+	configStore  EnrollmentConfigStore
+	signer       ProfileSigner
+
+	invites        InviteStore
+	inviteRequired bool
+
+	mu     sync.RWMutex
+	Topic  string // APNS Topic for MDM notifications
+	config config.EnrollmentConfig
+	// cache holds the generated profiles by identifier. Profiles with a
+	// dynamic SCEP challenge are not cached.
+	cache map[string]profile.Mobileconfig
 }
 
 type TopicProvider interface {
@@ -164,21 +301,94 @@ func (svc *service) findOrMakeMobileconfig(ctx context.Context, id string, f int
 	p, err := svc.ProfileDB.ProfileById(ctx, id)
 	if err != nil {
 		if profile.IsNotFound(err) {
-			profile, err := profileOrPayloadFromFunc(f)
-			if err != nil {
-				return nil, err
-			}
-			return profileOrPayloadToMobileconfig(profile)
+			return svc.makeMobileconfig(id, f)
+		}
+		return nil, err
+	}
+	return svc.sign(p.Mobileconfig)
+}
+
+// sign signs the profile if the service has a signer.
+func (svc *service) sign(mc profile.Mobileconfig) (profile.Mobileconfig, error) {
+	if svc.signer == nil {
+		return mc, nil
+	}
+	signed, err := svc.signer.Sign(mc)
+	return signed, errors.Wrap(err, "sign enrollment profile")
+}
+
+// makeMobileconfig returns the cached profile or generates it. The cache is
+// dropped when the push topic or the enrollment config changes.
+func (svc *service) makeMobileconfig(id string, f interface{}) (profile.Mobileconfig, error) {
+	if err := svc.loadConfig(); err != nil {
+		return nil, err
+	}
+	cacheable := svc.SCEPChallengeStore == nil
+	if cacheable {
+		svc.mu.RLock()
+		mc, ok := svc.cache[id]
+		svc.mu.RUnlock()
+		if ok {
+			return mc, nil
 		}
+	}
+	p, err := profileOrPayloadFromFunc(f)
+	if err != nil {
+		return nil, err
+	}
+	mc, err := profileOrPayloadToMobileconfig(p)
+	if err != nil {
 		return nil, err
 	}
-	return p.Mobileconfig, nil
+	if mc, err = svc.sign(mc); err != nil || !cacheable {
+		return mc, err
+	}
+	svc.mu.Lock()
+	if svc.cache != nil {
+		svc.cache[id] = mc
+	}
+	svc.mu.Unlock()
+	return mc, nil
 }
 
 func (svc *service) Enroll(ctx context.Context) (profile.Mobileconfig, error) {
 	return svc.findOrMakeMobileconfig(ctx, EnrollmentProfileId, svc.MakeEnrollmentProfile)
 }
 
+// EnrollWithInvite returns the enrollment profile for an enrollment URL
+// which may carry an invite token. The profile for an invite is generated
+// for every request, as its check-in URL includes the token.
+func (svc *service) EnrollWithInvite(ctx context.Context, token string) (profile.Mobileconfig, error) {
+	if token == "" || svc.invites == nil {
+		if svc.inviteRequired {
+			return nil, statusError{errors.New("enrollment requires an invite"), http.StatusForbidden}
+		}
+		return svc.Enroll(ctx)
+	}
+	inv, err := svc.invites.Invite(ctx, token)
+	if err != nil {
+		if invite.IsNotFound(err) {
+			return nil, statusError{errors.New("unknown enrollment invite"), http.StatusForbidden}
+		}
+		return nil, errors.Wrap(err, "get enrollment invite")
+	}
+	if err := inv.Valid(time.Now()); err != nil {
+		return nil, statusError{err, http.StatusForbidden}
+	}
+	if err := svc.loadConfig(); err != nil {
+		return nil, err
+	}
+	p, err := svc.makeEnrollmentProfile(token)
+	if err != nil {
+		return nil, err
+	}
+	mc, err := profileOrPayloadToMobileconfig(p)
+	if err != nil {
+		return nil, err
+	}
+	return svc.sign(mc)
+}
+
 func (svc *service) scepChallenge() (challenge string, err error) {
 	if svc.SCEPChallengeStore != nil {
 		challenge, err = svc.SCEPChallengeStore.SCEPChallenge()
@@ -192,19 +402,29 @@ const perUserConnections = "com.apple.mdm.per-user-connections"
 const bootstrapToken = "com.apple.mdm.bootstraptoken"
 
 func (svc *service) MakeEnrollmentProfile() (*cfgprofiles.Profile, error) {
+	return svc.makeEnrollmentProfile("")
+}
+
+// makeEnrollmentProfile generates the enrollment profile. A non-empty invite
+// token is added to the check-in URL.
+func (svc *service) makeEnrollmentProfile(token string) (*cfgprofiles.Profile, error) {
+	conf := svc.enrollmentConfig()
 	profile := cfgprofiles.NewProfile(EnrollmentProfileId)
-	profile.PayloadOrganization = profilePayloadOrganization
-	profile.PayloadDisplayName = profilePayloadDisplayName
-	profile.PayloadDescription = profilePayloadDescription
+	profile.PayloadOrganization = conf.Organization
+	profile.PayloadDisplayName = conf.DisplayName
+	profile.PayloadDescription = conf.Description
 
 	mdmPayload := cfgprofiles.NewMDMPayload(EnrollmentProfileId + ".mdm")
-	mdmPayload.PayloadOrganization = profilePayloadOrganization
+	mdmPayload.PayloadOrganization = conf.Organization
 	mdmPayload.PayloadDescription = mdmPayloadDescription
 
 	mdmPayload.ServerURL = svc.URL + mdmPayloadServerEndpoint
 	mdmPayload.CheckInURL = svc.URL + mdmPayloadCheckInEndpoint
-	mdmPayload.CheckOutWhenRemoved = true
-	mdmPayload.AccessRights = 8191
+	if token != "" {
+		mdmPayload.CheckInURL += "?" + invite.TokenParam + "=" + url.QueryEscape(token)
+	}
+	mdmPayload.CheckOutWhenRemoved = *conf.CheckOutWhenRemoved
+	mdmPayload.AccessRights = conf.AccessRights
 
 	svc.mu.Lock()
 	mdmPayload.Topic = svc.Topic
@@ -217,13 +437,13 @@ func (svc *service) MakeEnrollmentProfile() (*cfgprofiles.Profile, error) {
 		scepPayload := cfgprofiles.NewSCEPPayload(EnrollmentProfileId + ".scep")
 		scepPayload.PayloadDescription = scepPayloadDescription
 		scepPayload.PayloadDisplayName = scepPayloadDisplayName
-		scepPayload.PayloadOrganization = profilePayloadOrganization
+		scepPayload.PayloadOrganization = conf.Organization
 
 		scepPayload.PayloadContent = cfgprofiles.SCEPPayloadContent{
 			URL:      svc.SCEPURL,
-			KeySize:  2048,
+			KeySize:  conf.KeySize,
 			KeyType:  "RSA",
-			KeyUsage: int(x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment),
+			KeyUsage: conf.KeyUsage,
 			Name:     "Device Management Identity Certificate",
 			Subject:  svc.SCEPSubject,
 		}
@@ -249,11 +469,45 @@ func (svc *service) MakeEnrollmentProfile() (*cfgprofiles.Profile, error) {
 		profile.AddPayload(tlsPayload)
 	}
 
+	if err := addConfigPayloads(profile, conf); err != nil {
+		return nil, err
+	}
+
 	return profile, nil
 }
 
+// addConfigPayloads adds the certificates and payloads of the enrollment
+// config to the profile. Self-signed certificates are added as trusted roots.
+func addConfigPayloads(p *cfgprofiles.Profile, conf config.EnrollmentConfig) error {
+	certs, err := conf.ParseCertificates()
+	if err != nil {
+		return err
+	}
+	for i, cert := range certs {
+		certPayload := cfgprofiles.NewCertificatePKCS1Payload(fmt.Sprintf("%s.cert.%d", EnrollmentProfileId, i))
+		if cert.IsCA && bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+			certPayload.PayloadType = "com.apple.security.root"
+		}
+		certPayload.PayloadDisplayName = cert.Subject.CommonName
+		certPayload.PayloadOrganization = conf.Organization
+		certPayload.PayloadContent = cert.Raw
+		p.AddPayload(certPayload)
+	}
+	for i, data := range conf.Payloads {
+		var payload map[string]interface{}
+		if err := plist.Unmarshal(data, &payload); err != nil {
+			return errors.Wrapf(err, "enrollment config payload %d", i)
+		}
+		p.AddPayload(payload)
+	}
+	return nil
+}
+
 // OTAEnroll returns an Over-the-Air "Profile Service" Payload for enrollment.
 func (svc *service) OTAEnroll(ctx context.Context) (profile.Mobileconfig, error) {
+	if svc.inviteRequired {
+		return nil, statusError{errors.New("enrollment requires an invite"), http.StatusForbidden}
+	}
 	return svc.findOrMakeMobileconfig(ctx, OTAProfileId, svc.MakeOTAEnrollPayload)
 }
 
@@ -277,9 +531,10 @@ func (svc *service) MakeOTAEnrollPayload() (*ProfileServicePayload, error) {
 			DeviceAttributes: []string{"UDID", "VERSION", "PRODUCT", "SERIAL", "MEID", "IMEI"},
 		},
 	}
-	payload.PayloadOrganization = profilePayloadOrganization
+	conf := svc.enrollmentConfig()
+	payload.PayloadOrganization = conf.Organization
 	payload.PayloadDescription = "Profile Service enrollment"
-	payload.PayloadDisplayName = "MicroMDM Profile Service"
+	payload.PayloadDisplayName = conf.Organization + " Profile Service"
 
 	// yes, this is a bare Payload, not a Profile
 	return payload, nil
@@ -291,22 +546,23 @@ func (svc *service) OTAPhase2(ctx context.Context) (profile.Mobileconfig, error)
 }
 
 func (svc *service) MakeOTAPhase2Profile() (*cfgprofiles.Profile, error) {
+	conf := svc.enrollmentConfig()
 	profile := cfgprofiles.NewProfile(OTAProfileId + ".phase2")
-	profile.PayloadOrganization = profilePayloadOrganization
+	profile.PayloadOrganization = conf.Organization
 	profile.PayloadDisplayName = "OTA Phase 2"
-	profile.PayloadDescription = profilePayloadDescription
+	profile.PayloadDescription = conf.Description
 	profile.PayloadScope = "System"
 
 	scepPayload := cfgprofiles.NewSCEPPayload(OTAProfileId + ".phase2.scep")
 	scepPayload.PayloadDescription = scepPayloadDescription
 	scepPayload.PayloadDisplayName = scepPayloadDisplayName
-	scepPayload.PayloadOrganization = profilePayloadOrganization
+	scepPayload.PayloadOrganization = conf.Organization
 
 	scepPayload.PayloadContent = cfgprofiles.SCEPPayloadContent{
 		URL:      svc.SCEPURL,
-		KeySize:  2048, // NOTE: OTA docs recommend 1024
+		KeySize:  conf.KeySize, // NOTE: OTA docs recommend 1024
 		KeyType:  "RSA",
-		KeyUsage: int(x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment),
+		KeyUsage: conf.KeyUsage,
 		Name:     "OTA Phase 2 Certificate",
 		Subject:  svc.SCEPSubject,
 	}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/micromdm/micromdm/pkg/crypto"
+	"github.com/micromdm/micromdm/platform/invite"
 
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/micromdm/plist"
@@ -59,7 +60,7 @@ type verifier struct {
 func (v verifier) decodeMDMEnrollRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	switch r.Method {
 	case "GET":
-		return mdmEnrollRequest{}, nil
+		return mdmEnrollRequest{Token: r.URL.Query().Get(invite.TokenParam)}, nil
 	case "POST": // DEP request
 		data, err := ioutil.ReadAll(r.Body)
 		if err != nil {
@@ -95,6 +96,14 @@ func (v verifier) decodeMDMEnrollRequest(_ context.Context, r *http.Request) (in
 func encodeMobileconfigResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	w.Header().Set("Content-Type", "application/x-apple-aspen-config")
 	mcResp := response.(mobileconfigResponse)
+	if mcResp.Err != nil {
+		code := http.StatusInternalServerError
+		if sc, ok := mcResp.Err.(httptransport.StatusCoder); ok {
+			code = sc.StatusCode()
+		}
+		http.Error(w, mcResp.Err.Error(), code)
+		return nil
+	}
 	_, err := w.Write(mcResp.Mobileconfig)
 	return err
 }
@@ -8,8 +8,13 @@ import (
 )
 
 type CommandRequest struct {
-	UDID        string `json:"udid"`
-	CommandUUID string `json:"command_uuid"`
+	UDID string `json:"udid"`
+	// UserID targets the user channel of a managed user instead of the
+	// device channel. UserShortname targets the managed user with the short
+	// name on the device UDID.
+	UserID        string `json:"user_id,omitempty"`
+	UserShortname string `json:"user_shortname,omitempty"`
+	CommandUUID   string `json:"command_uuid"`
 	*Command
 }
 
@@ -9,14 +9,18 @@ import (
 
 func (c *CommandRequest) UnmarshalJSON(data []byte) error {
 	var request = struct {
-		UDID        string `json:"udid"`
-		RequestType string `json:"request_type"`
-		CommandUUID string `json:"command_uuid"`
+		UDID          string `json:"udid"`
+		UserID        string `json:"user_id"`
+		UserShortname string `json:"user_shortname"`
+		RequestType   string `json:"request_type"`
+		CommandUUID   string `json:"command_uuid"`
 	}{}
 	if err := json.Unmarshal(data, &request); err != nil {
 		return errors.Wrap(err, "mdm: unmarshal json command request")
 	}
 	c.UDID = request.UDID
+	c.UserID = request.UserID
+	c.UserShortname = request.UserShortname
 	c.Command = &Command{}
 	c.CommandUUID = request.CommandUUID
 	return c.Command.UnmarshalJSON(data)
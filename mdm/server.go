@@ -170,6 +170,15 @@ func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
 		return
 	}
 
+	type rejectEnrollmentErr interface {
+		error
+		EnrollmentRejected() bool
+	}
+	if e, ok := err.(rejectEnrollmentErr); ok && e.EnrollmentRejected() {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
 	type checkoutErr interface {
 		error
 		Checkout() bool
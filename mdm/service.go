@@ -50,17 +50,23 @@ type Queue interface {
 }
 
 type MDMService struct {
-	dev   BootstrapTokenRetriever
-	pub   pubsub.Publisher
-	queue Queue
-	dm    DeclarativeManagement
+	dev      BootstrapTokenRetriever
+	pub      pubsub.Publisher
+	queue    Queue
+	dm       DeclarativeManagement
+	admitter Admitter
+	invites  InviteRedeemer
 }
 
-func NewService(pub pubsub.Publisher, queue Queue, dev BootstrapTokenRetriever, dm DeclarativeManagement) *MDMService {
-	return &MDMService{
+func NewService(pub pubsub.Publisher, queue Queue, dev BootstrapTokenRetriever, dm DeclarativeManagement, opts ...Option) *MDMService {
+	svc := &MDMService{
 		dev:   dev,
 		pub:   pub,
 		queue: queue,
 		dm:    dm,
 	}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
 }
@@ -11,12 +11,19 @@ import (
 
 // Sign takes an unsigned payload and signs it with the provided private key and certificate.
 func Sign(key crypto.PrivateKey, cert *x509.Certificate, mobileconfig []byte) ([]byte, error) {
+	return SignWithChain(key, cert, nil, mobileconfig)
+}
+
+// SignWithChain signs the payload like Sign and includes the intermediate
+// certificates of the chain in the signature, so that devices can verify
+// the signature up to a trusted root.
+func SignWithChain(key crypto.PrivateKey, cert *x509.Certificate, chain []*x509.Certificate, mobileconfig []byte) ([]byte, error) {
 	sd, err := pkcs7.NewSignedData(mobileconfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "create signed data for mobileconfig")
 	}
 
-	if err := sd.AddSigner(cert, key, pkcs7.SignerInfoConfig{}); err != nil {
+	if err := sd.AddSignerChain(cert, key, chain, pkcs7.SignerInfoConfig{}); err != nil {
 		return nil, errors.Wrap(err, "add crypto signer to mobileconfig signed data")
 	}
 
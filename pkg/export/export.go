@@ -0,0 +1,157 @@
+// Package export writes records in the CSV and NDJSON formats used by the
+// export API endpoints.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// Supported export formats.
+const (
+	CSV    = "csv"
+	NDJSON = "ndjson"
+)
+
+// flushEvery is the number of records written before the response is flushed
+// to the client.
+const flushEvery = 100
+
+// Record is a single exported row.
+type Record interface {
+	// CSVRecord returns the record as CSV column values, in the same order
+	// as the header passed to NewWriter.
+	CSVRecord() []string
+}
+
+// ParseFormat validates a format name. An empty name selects NDJSON.
+func ParseFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", NDJSON, "json":
+		return NDJSON, nil
+	case CSV:
+		return CSV, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// ContentType returns the MIME type of an export format.
+func ContentType(format string) string {
+	if format == CSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Writer writes records one at a time.
+type Writer struct {
+	format string
+	w      io.Writer
+	csv    *csv.Writer
+	json   *json.Encoder
+	count  int
+}
+
+// NewWriter creates a Writer. For CSV the header row is written immediately.
+func NewWriter(w io.Writer, format string, header []string) (*Writer, error) {
+	ew := &Writer{format: format, w: w}
+	switch format {
+	case CSV:
+		ew.csv = csv.NewWriter(w)
+		if err := ew.csv.Write(header); err != nil {
+			return nil, err
+		}
+	case NDJSON:
+		ew.json = json.NewEncoder(w)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+	return ew, nil
+}
+
+// Write writes a single record.
+func (ew *Writer) Write(r Record) error {
+	var err error
+	if ew.csv != nil {
+		err = ew.csv.Write(r.CSVRecord())
+	} else {
+		err = ew.json.Encode(r)
+	}
+	if err != nil {
+		return err
+	}
+	ew.count++
+	if ew.count%flushEvery == 0 {
+		return ew.Flush()
+	}
+	return nil
+}
+
+// Flush writes any buffered data to the underlying writer, and to the client
+// if the underlying writer is an http.ResponseWriter.
+func (ew *Writer) Flush() error {
+	if ew.csv != nil {
+		ew.csv.Flush()
+		if err := ew.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if f, ok := ew.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// Response is implemented by the responses of export endpoints.
+type Response interface {
+	Failed() error
+	// Format is the format requested by the client.
+	Format() string
+	// Header returns the CSV column names.
+	Header() []string
+	// WriteRecords writes every record of the response to w.
+	WriteRecords(w *Writer) error
+}
+
+// EncodeResponse is a go-kit EncodeResponseFunc which streams an export
+// Response to the client. Errors are encoded as JSON.
+func EncodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(Response)
+	if err := resp.Failed(); err != nil {
+		httputil.ErrorEncoder(ctx, err, w)
+		return nil
+	}
+	w.Header().Set("Content-Type", ContentType(resp.Format()))
+	ew, err := NewWriter(w, resp.Format(), resp.Header())
+	if err != nil {
+		return err
+	}
+	if err := resp.WriteRecords(ew); err != nil {
+		return err
+	}
+	return ew.Flush()
+}
+
+// DecodeNDJSON calls fn with a decoder for every record in an NDJSON response
+// body.
+func DecodeNDJSON(r *http.Response, fn func(dec *json.Decoder) error) error {
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return httputil.JSONErrorDecoder(r)
+	}
+	dec := json.NewDecoder(r.Body)
+	for dec.More() {
+		if err := fn(dec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,7 +2,10 @@ package httputil
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -131,6 +134,28 @@ func DecodeJSONResponse(r *http.Response, into interface{}) error {
 	return err
 }
 
+// APIKeyID identifies the API key used to authenticate a request without
+// revealing it. It returns a short SHA-256 fingerprint of the basic auth
+// password, or an empty string if the request had no basic auth. The context
+// must be populated with httptransport.PopulateRequestContext.
+func APIKeyID(ctx context.Context) string {
+	auth, _ := ctx.Value(httptransport.ContextKeyRequestAuthorization).(string)
+	const prefix = "Basic "
+	if !strings.HasPrefix(auth, prefix) {
+		return ""
+	}
+	decoded, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return ""
+	}
+	i := strings.IndexByte(string(decoded), ':')
+	if i < 0 {
+		return ""
+	}
+	sum := sha256.Sum256(decoded[i+1:])
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
 func RequireBasicAuth(h http.HandlerFunc, username, password, realm string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		u, p, ok := r.BasicAuth()
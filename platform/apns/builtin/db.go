@@ -75,3 +75,11 @@ func (db *DB) Save(ctx context.Context, info *apns.PushInfo) error {
 	}
 	return tx.Commit()
 }
+
+func (db *DB) DeletePushInfo(ctx context.Context, udid string) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(PushBucket))
+		return b.Delete([]byte(udid))
+	})
+	return errors.Wrapf(err, "delete PushInfo for udid %s", udid)
+}
@@ -18,6 +18,10 @@ import (
 	"github.com/micromdm/micromdm/pkg/httputil"
 )
 
+// PushSentTopic is published with the device UDID after a push notification
+// was accepted by APNs.
+const PushSentTopic = "mdm.PushSent"
+
 type pushOpts struct {
 	expiration time.Time
 }
@@ -66,6 +70,11 @@ func (svc *PushService) Push(ctx context.Context, deviceUDID string, opts ...Pus
 		// https://github.com/micromdm/micromdm/issues/150
 		return result, errors.Wrap(err, "push error: possibly expired or invalid APNs certificate")
 	}
+	if err == nil && svc.pub != nil {
+		if err := svc.pub.Publish(ctx, PushSentTopic, []byte(deviceUDID)); err != nil {
+			return result, errors.Wrapf(err, "publish on topic %s", PushSentTopic)
+		}
+	}
 	return result, err
 }
 
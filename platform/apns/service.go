@@ -33,6 +33,8 @@ type PushService struct {
 
 	mu      sync.RWMutex
 	pushsvc *push.Service
+
+	pub pubsub.Publisher
 }
 
 type PushCertificateProvider interface {
@@ -47,6 +49,13 @@ func WithPushService(svc *push.Service) Option {
 	}
 }
 
+// WithPublisher publishes an event on PushSentTopic for each successful push.
+func WithPublisher(pub pubsub.Publisher) Option {
+	return func(p *PushService) {
+		p.pub = pub
+	}
+}
+
 func New(db Store, provider PushCertificateProvider, sub pubsub.Subscriber, opts ...Option) (*PushService, error) {
 	pushSvc := PushService{
 		store:    db,
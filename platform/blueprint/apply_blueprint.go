@@ -9,7 +9,22 @@ import (
 )
 
 func (svc *BlueprintService) ApplyBlueprint(ctx context.Context, bp *Blueprint) error {
-	return svc.store.Save(bp)
+	_, err := svc.saveRevision(ctx, bp)
+	return err
+}
+
+// saveRevision saves the blueprint and cleans up the items which devices
+// got from earlier revisions and which are no longer part of it if the
+// blueprint has Cleanup set.
+func (svc *BlueprintService) saveRevision(ctx context.Context, bp *Blueprint) (*Revision, error) {
+	rev, err := svc.store.SaveRevision(bp, httputil.APIKeyID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if !bp.Cleanup {
+		return rev, nil
+	}
+	return rev, svc.cleanupDevices(ctx, bp.Name, bp)
 }
 
 type applyBlueprintRequest struct {
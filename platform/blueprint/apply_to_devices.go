@@ -0,0 +1,149 @@
+package blueprint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+	"github.com/micromdm/micromdm/platform/device"
+)
+
+// ApplyToDevicesOptions selects the devices a Manual blueprint is applied to.
+type ApplyToDevicesOptions struct {
+	UDIDs       []string `json:"udids"`
+	Serials     []string `json:"serials"`
+	AllEnrolled bool     `json:"all_enrolled"`
+}
+
+// ApplyToDevicesResult lists the devices a blueprint was applied to.
+type ApplyToDevicesResult struct {
+	Applied []string `json:"applied"`
+	// Skipped are the requested devices which are unknown, not enrolled or
+	// not in the scope of the blueprint.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// Applier queues the commands of a blueprint for devices.
+type Applier interface {
+	ApplyToDevices(ctx context.Context, bp Blueprint, udids []string) error
+}
+
+func (svc *BlueprintService) ApplyBlueprintToDevices(ctx context.Context, name string, opt ApplyToDevicesOptions) (*ApplyToDevicesResult, error) {
+	if svc.applier == nil || svc.devices == nil {
+		return nil, errors.New("applying blueprints is not supported")
+	}
+	bp, err := svc.store.BlueprintByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if !bp.HasApplyAt(ApplyAtManual) {
+		return nil, fmt.Errorf("blueprint %s does not have ApplyAt %s", name, ApplyAtManual)
+	}
+
+	var result ApplyToDevicesResult
+	seen := make(map[string]bool)
+	add := func(dev *device.Device) {
+		if !seen[dev.UDID] {
+			seen[dev.UDID] = true
+			result.Applied = append(result.Applied, dev.UDID)
+		}
+	}
+
+	for _, udid := range opt.UDIDs {
+		dev, err := svc.devices.DeviceByUDID(ctx, udid)
+		if err != nil || !dev.Enrolled || !bp.Scope.Matches(dev) {
+			result.Skipped = append(result.Skipped, udid)
+			continue
+		}
+		add(dev)
+	}
+	for _, serial := range opt.Serials {
+		dev, err := svc.devices.DeviceBySerial(ctx, serial)
+		if err != nil || !dev.Enrolled || dev.UDID == "" || !bp.Scope.Matches(dev) {
+			result.Skipped = append(result.Skipped, serial)
+			continue
+		}
+		add(dev)
+	}
+	if opt.AllEnrolled {
+		devices, err := svc.devices.List(ctx, device.ListDevicesOption{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range devices {
+			if devices[i].Enrolled && devices[i].UDID != "" && bp.Scope.Matches(&devices[i]) {
+				add(&devices[i])
+			}
+		}
+	}
+	if len(result.Applied) == 0 && len(result.Skipped) == 0 {
+		return nil, errors.New("no udids, serials or all_enrolled specified")
+	}
+
+	if err := svc.applier.ApplyToDevices(ctx, *bp, result.Applied); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+type applyToDevicesRequest struct {
+	Name string                `json:"-"`
+	Opts ApplyToDevicesOptions `json:"opts"`
+}
+
+type applyToDevicesResponse struct {
+	*ApplyToDevicesResult
+	Err error `json:"err,omitempty"`
+}
+
+func (r applyToDevicesResponse) Failed() error { return r.Err }
+
+func decodeApplyToDevicesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req applyToDevicesRequest
+	if err := httputil.DecodeJSONRequest(r, &req); err != nil {
+		return nil, err
+	}
+	name, ok := mux.Vars(r)["name"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	req.Name = name
+	return req, nil
+}
+
+func encodeApplyToDevicesRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(applyToDevicesRequest)
+	r.Method, r.URL.Path = "POST", "/v1/blueprints/"+url.PathEscape(req.Name)+"/apply"
+	return httptransport.EncodeJSONRequest(ctx, r, request)
+}
+
+func decodeApplyToDevicesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp applyToDevicesResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeApplyToDevicesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(applyToDevicesRequest)
+		result, err := svc.ApplyBlueprintToDevices(ctx, req.Name, req.Opts)
+		return applyToDevicesResponse{ApplyToDevicesResult: result, Err: err}, nil
+	}
+}
+
+func (e Endpoints) ApplyBlueprintToDevices(ctx context.Context, name string, opt ApplyToDevicesOptions) (*ApplyToDevicesResult, error) {
+	request := applyToDevicesRequest{Name: name, Opts: opt}
+	resp, err := e.ApplyToDevicesEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(applyToDevicesResponse)
+	return response.ApplyToDevicesResult, response.Err
+}
@@ -2,51 +2,193 @@ package blueprint
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 
+	"github.com/micromdm/micromdm/mdm/mdm"
+
 	"github.com/micromdm/micromdm/platform/blueprint/internal/blueprintproto"
 )
 
 // ApplyAt is a case-insensitive string that specifies at which point the
 // system should apply a Blueprint to devices. For example if a Blueprint has
 // an ApplyAt of "Enroll" then that profile will be applied immediately after
-// a device's enrollment in the MDM system. Currently "Enroll" is the only
-// supported value but more are planned.
+// a device's enrollment in the MDM system.
 const (
+	// ApplyAtEnroll applies the Blueprint after a device enrolls.
 	ApplyAtEnroll string = "Enroll"
+	// ApplyAtDEPSync applies the Blueprint once to every serial number which
+	// first appears in a DEP sync, as soon as the device has enrolled.
+	ApplyAtDEPSync string = "DEPSync"
+	// ApplyAtCheckin applies the Blueprint on every device TokenUpdate.
+	ApplyAtCheckin string = "Checkin"
+	// ApplyAtManual allows the Blueprint to be applied through the API.
+	ApplyAtManual string = "Manual"
 )
 
+var applyAtValues = []string{ApplyAtEnroll, ApplyAtDEPSync, ApplyAtCheckin, ApplyAtManual}
+
+// HasApplyAt reports whether the Blueprint has the given ApplyAt value.
+func (bp *Blueprint) HasApplyAt(applyAt string) bool {
+	for _, v := range bp.ApplyAt {
+		if strings.EqualFold(v, applyAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// AppliedTopic is published with a JSON encoded AppliedEvent after the
+// commands of a blueprint were queued for a device.
+const AppliedTopic = "mdm.BlueprintApplied"
+
+type AppliedEvent struct {
+	UDID          string `json:"udid"`
+	BlueprintName string `json:"blueprint_name"`
+}
+
 type Blueprint struct {
-	UUID                                string   `json:"uuid"`
-	Name                                string   `json:"name"`
-	ApplicationURLs                     []string `json:"install_application_manifest_urls"`
-	ProfileIdentifiers                  []string `json:"profile_ids"`
+	UUID               string   `json:"uuid"`
+	Name               string   `json:"name"`
+	ApplicationURLs    []string `json:"install_application_manifest_urls"`
+	ProfileIdentifiers []string `json:"profile_ids"`
+	// UserProfileIdentifiers are installed on the user channel of every
+	// managed user of the device, when the user first checks in.
+	UserProfileIdentifiers              []string `json:"user_profile_ids,omitempty"`
 	UserUUID                            []string `json:"user_uuids"`
 	SkipPrimarySetupAccountCreation     bool     `json:"skip_primary_setup_account_creation"`
 	SetPrimarySetupAccountAsRegularUser bool     `json:"set_primary_setup_account_as_regular_user"`
 	ApplyAt                             []string `json:"apply_at"`
+	Scope                               *Scope   `json:"scope,omitempty"`
+
+	// ReleaseOnCompletion holds a device which is awaiting configuration
+	// in Setup Assistant until the commands queued for it at enrollment
+	// are acknowledged, instead of sending DeviceConfigured right away.
+	ReleaseOnCompletion bool `json:"release_on_completion,omitempty"`
+	// ReleaseTimeout is a duration like "30m" after which the device is
+	// released even if commands are still outstanding or have failed.
+	// It is required with ReleaseOnCompletion.
+	ReleaseTimeout string `json:"release_timeout,omitempty"`
+	// ReleaseAllowedFailures is the number of commands which may fail
+	// before the release of the device is abandoned.
+	ReleaseAllowedFailures int `json:"release_allowed_failures,omitempty"`
+
+	// Commands are queued in order after the commands generated from the
+	// users, applications and profiles of the Blueprint.
+	Commands []*mdm.Command `json:"commands,omitempty"`
+
+	// Cleanup queues RemoveProfile and RemoveApplication commands for
+	// devices which got a profile or application through the Blueprint
+	// when it is dropped from the Blueprint or the Blueprint is removed.
+	Cleanup bool `json:"cleanup,omitempty"`
+
+	// EncryptProfiles encrypts the payloads of all profiles of the
+	// Blueprint to the identity certificate of each device, as if the
+	// profiles had Encrypt set.
+	EncryptProfiles bool `json:"encrypt_profiles,omitempty"`
 }
 
 func (bp *Blueprint) Verify() error {
 	if bp.Name == "" || bp.UUID == "" {
 		return errors.New("Blueprint must have Name and UUID")
 	}
-	return nil
+	for _, v := range bp.ApplyAt {
+		if !validApplyAt(v) {
+			return fmt.Errorf("Blueprint %s has unsupported ApplyAt value %q, must be one of %s",
+				bp.Name, v, strings.Join(applyAtValues, ", "))
+		}
+	}
+	if bp.ReleaseTimeout != "" {
+		d, err := time.ParseDuration(bp.ReleaseTimeout)
+		if err != nil {
+			return fmt.Errorf("Blueprint %s has invalid release_timeout: %s", bp.Name, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("Blueprint %s release_timeout must be positive", bp.Name)
+		}
+	}
+	if bp.ReleaseOnCompletion && bp.ReleaseTimeout == "" {
+		// A failed release is only released by its deadline.
+		return fmt.Errorf("Blueprint %s sets release_on_completion without release_timeout", bp.Name)
+	}
+	if bp.ReleaseAllowedFailures < 0 {
+		return fmt.Errorf("Blueprint %s has negative release_allowed_failures", bp.Name)
+	}
+	for i, cmd := range bp.Commands {
+		if cmd == nil || cmd.RequestType == "" {
+			return fmt.Errorf("Blueprint %s command %d has no request_type", bp.Name, i)
+		}
+		if destructiveRequestTypes[cmd.RequestType] {
+			return fmt.Errorf("Blueprint %s command %d: %s is not allowed in a blueprint", bp.Name, i, cmd.RequestType)
+		}
+		if _, err := mdm.MarshalCommandPayload(&mdm.CommandPayload{Command: cmd}); err != nil {
+			return fmt.Errorf("Blueprint %s command %d: %s", bp.Name, i, err)
+		}
+	}
+	return bp.Scope.Verify()
+}
+
+// destructiveRequestTypes are the commands which wipe, lock or unenroll a
+// device or remove its data. Blueprints are applied to whole groups of devices
+// on enrollment, check-in and DEP sync, so these have to be sent with the
+// command API instead.
+var destructiveRequestTypes = map[string]bool{
+	"EraseDevice":               true,
+	"DeviceLock":                true,
+	"ClearPasscode":             true,
+	"ClearRestrictionsPassword": true,
+	"EnableLostMode":            true,
+	"RestartDevice":             true,
+	"ShutDownDevice":            true,
+	"DeleteUser":                true,
+	"LogOutUser":                true,
+	"SetFirmwarePassword":       true,
+	"SetRecoveryLock":           true,
+}
+
+// releaseTimeout returns the parsed ReleaseTimeout, or 0 if none is set.
+func (bp *Blueprint) releaseTimeout() time.Duration {
+	d, _ := time.ParseDuration(bp.ReleaseTimeout)
+	return d
 }
 
 func MarshalBlueprint(bp *Blueprint) ([]byte, error) {
-	protobp := blueprintproto.Blueprint{
+	pb, err := blueprintToProto(bp)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(pb)
+}
+
+func blueprintToProto(bp *Blueprint) (*blueprintproto.Blueprint, error) {
+	pb := &blueprintproto.Blueprint{
 		Uuid:                                bp.UUID,
 		Name:                                bp.Name,
 		ManifestUrls:                        bp.ApplicationURLs,
 		ProfileIds:                          bp.ProfileIdentifiers,
+		UserProfileIds:                      bp.UserProfileIdentifiers,
 		UserUuid:                            bp.UserUUID,
 		SkipPrimarySetupAccountCreation:     bp.SkipPrimarySetupAccountCreation,
 		SetPrimarySetupAccountAsRegularUser: bp.SetPrimarySetupAccountAsRegularUser,
 		ApplyAt:                             bp.ApplyAt,
+		Scope:                               scopeToProto(bp.Scope),
+		ReleaseOnCompletion:                 bp.ReleaseOnCompletion,
+		ReleaseTimeout:                      bp.ReleaseTimeout,
+		ReleaseAllowedFailures:              int32(bp.ReleaseAllowedFailures),
+		Cleanup:                             bp.Cleanup,
+		EncryptProfiles:                     bp.EncryptProfiles,
+	}
+	for _, cmd := range bp.Commands {
+		data, err := mdm.MarshalCommandPayload(&mdm.CommandPayload{Command: cmd})
+		if err != nil {
+			return nil, err
+		}
+		pb.Commands = append(pb.Commands, data)
 	}
-	return proto.Marshal(&protobp)
+	return pb, nil
 }
 
 func UnmarshalBlueprint(data []byte, bp *Blueprint) error {
@@ -54,13 +196,41 @@ func UnmarshalBlueprint(data []byte, bp *Blueprint) error {
 	if err := proto.Unmarshal(data, &pb); err != nil {
 		return err
 	}
+	return blueprintFromProto(&pb, bp)
+}
+
+func blueprintFromProto(pb *blueprintproto.Blueprint, bp *Blueprint) error {
 	bp.UUID = pb.GetUuid()
 	bp.Name = pb.GetName()
 	bp.ApplicationURLs = pb.GetManifestUrls()
 	bp.ProfileIdentifiers = pb.GetProfileIds()
+	bp.UserProfileIdentifiers = pb.GetUserProfileIds()
 	bp.ApplyAt = pb.GetApplyAt()
 	bp.UserUUID = pb.GetUserUuid()
 	bp.SkipPrimarySetupAccountCreation = pb.GetSkipPrimarySetupAccountCreation()
 	bp.SetPrimarySetupAccountAsRegularUser = pb.GetSetPrimarySetupAccountAsRegularUser()
+	bp.Scope = scopeFromProto(pb.GetScope())
+	bp.ReleaseOnCompletion = pb.GetReleaseOnCompletion()
+	bp.ReleaseTimeout = pb.GetReleaseTimeout()
+	bp.ReleaseAllowedFailures = int(pb.GetReleaseAllowedFailures())
+	bp.Cleanup = pb.GetCleanup()
+	bp.EncryptProfiles = pb.GetEncryptProfiles()
+	bp.Commands = nil
+	for _, data := range pb.GetCommands() {
+		var payload mdm.CommandPayload
+		if err := mdm.UnmarshalCommandPayload(data, &payload); err != nil {
+			return err
+		}
+		bp.Commands = append(bp.Commands, payload.Command)
+	}
 	return nil
 }
+
+func validApplyAt(v string) bool {
+	for _, known := range applyAtValues {
+		if strings.EqualFold(v, known) {
+			return true
+		}
+	}
+	return false
+}
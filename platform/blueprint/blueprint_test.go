@@ -0,0 +1,25 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/micromdm/micromdm/mdm/mdm"
+)
+
+func TestVerifyRejectsDestructiveCommands(t *testing.T) {
+	bp := &Blueprint{
+		Name: "lab",
+		UUID: "a-b-c-d",
+		Commands: []*mdm.Command{
+			{RequestType: "SecurityInfo"},
+			{RequestType: "EraseDevice", EraseDevice: &mdm.EraseDevice{}},
+		},
+	}
+	if err := bp.Verify(); err == nil {
+		t.Fatal("expected EraseDevice to be rejected")
+	}
+	bp.Commands = bp.Commands[:1]
+	if err := bp.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,9 +1,11 @@
 package builtin
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/pkg/errors"
@@ -15,6 +17,36 @@ import (
 const (
 	BlueprintBucket      = "mdm.Blueprint"
 	blueprintIndexBucket = "mdm.BlueprintIdx"
+
+	// blueprintDEPSerialBucket records the serials seen in DEP syncs for
+	// the DEPSync ApplyAt trigger.
+	blueprintDEPSerialBucket = "mdm.BlueprintDEPSerials"
+
+	// blueprintReleaseBucket holds the release progress of devices
+	// awaiting configuration, keyed by UDID.
+	blueprintReleaseBucket = "mdm.BlueprintReleases"
+
+	// blueprintRotationBucket holds the password rotations waiting for the
+	// UserList response of a device, keyed by the UserList command UUID.
+	blueprintRotationBucket = "mdm.BlueprintPasswordRotations"
+
+	// blueprintDriftBucket holds the latest drift report of each device,
+	// keyed by UDID.
+	blueprintDriftBucket = "mdm.BlueprintDrift"
+
+	// blueprintRevisionBucket holds a nested bucket of revisions for each
+	// blueprint name, keyed by big endian version.
+	blueprintRevisionBucket = "mdm.BlueprintRevisions"
+
+	// blueprintStatusBucket holds a nested bucket of device statuses for
+	// each blueprint name, keyed by UDID.
+	blueprintStatusBucket = "mdm.BlueprintStatus"
+)
+
+// values stored in the blueprintDEPSerialBucket.
+var (
+	depSerialPending = []byte("pending")
+	depSerialApplied = []byte("applied")
 )
 
 type DB struct {
@@ -31,6 +63,30 @@ func NewDB(
 		if err != nil {
 			return err
 		}
+		_, err = tx.CreateBucketIfNotExists([]byte(blueprintDEPSerialBucket))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte(blueprintReleaseBucket))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte(blueprintRotationBucket))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte(blueprintDriftBucket))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte(blueprintRevisionBucket))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte(blueprintStatusBucket))
+		if err != nil {
+			return err
+		}
 		_, err = tx.CreateBucketIfNotExists([]byte(BlueprintBucket))
 		return err
 	})
@@ -63,56 +119,133 @@ func (db *DB) List() ([]blueprint.Blueprint, error) {
 }
 
 func (db *DB) Save(bp *blueprint.Blueprint) error {
+	_, err := db.SaveRevision(bp, "")
+	return err
+}
+
+// SaveRevision saves the blueprint and records it as a new revision applied
+// with the API key.
+func (db *DB) SaveRevision(bp *blueprint.Blueprint, apiKey string) (*blueprint.Revision, error) {
 	if bp == nil {
-		return errors.New("no blueprint supplied")
+		return nil, errors.New("no blueprint supplied")
 	}
 	ctx := context.TODO()
 	err := bp.Verify()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	check_bp, err := db.BlueprintByName(bp.Name)
 	if err != nil && !isNotFound(err) {
-		return err
+		return nil, err
 	}
 	if err == nil && bp.UUID != check_bp.UUID {
-		return fmt.Errorf("Blueprint not saved: same name %s exists", bp.Name)
+		return nil, fmt.Errorf("Blueprint not saved: same name %s exists", bp.Name)
 	}
 	// verify that each Profile ID represents a profile we know about
-	for _, p := range bp.ProfileIdentifiers {
+	var profileIDs []string
+	profileIDs = append(profileIDs, bp.ProfileIdentifiers...)
+	profileIDs = append(profileIDs, bp.UserProfileIdentifiers...)
+	for _, p := range profileIDs {
 		if _, err := db.profDB.ProfileById(ctx, p); err != nil {
 			if profile.IsNotFound(err) {
-				return fmt.Errorf("Profile ID %s in Blueprint %s does not exist", p, bp.Name)
+				return nil, fmt.Errorf("Profile ID %s in Blueprint %s does not exist", p, bp.Name)
 			}
-			return errors.Wrap(err, "fetching profile")
+			return nil, errors.Wrap(err, "fetching profile")
 		}
 	}
 	tx, err := db.DB.Begin(true)
 	if err != nil {
-		return errors.Wrap(err, "begin transaction")
+		return nil, errors.Wrap(err, "begin transaction")
 	}
+	defer tx.Rollback()
 	bkt := tx.Bucket([]byte(BlueprintBucket))
 	if bkt == nil {
-		return fmt.Errorf("bucket %q not found!", BlueprintBucket)
+		return nil, fmt.Errorf("bucket %q not found!", BlueprintBucket)
 	}
 	bpproto, err := blueprint.MarshalBlueprint(bp)
 	if err != nil {
-		return errors.Wrap(err, "marshalling blueprint")
+		return nil, errors.Wrap(err, "marshalling blueprint")
 	}
 	idxBucket := tx.Bucket([]byte(blueprintIndexBucket))
 	if idxBucket == nil {
-		return fmt.Errorf("bucket %v not found!", idxBucket)
+		return nil, fmt.Errorf("bucket %v not found!", idxBucket)
 	}
 	key := []byte(bp.Name)
 	if err := idxBucket.Put(key, []byte(bp.UUID)); err != nil {
-		return errors.Wrap(err, "put blueprint idx to boltdb")
+		return nil, errors.Wrap(err, "put blueprint idx to boltdb")
 	}
 
 	key = []byte(bp.UUID)
 	if err := bkt.Put(key, bpproto); err != nil {
-		return errors.Wrap(err, "put blueprint to boltdb")
+		return nil, errors.Wrap(err, "put blueprint to boltdb")
+	}
+
+	revBkt, err := tx.Bucket([]byte(blueprintRevisionBucket)).CreateBucketIfNotExists([]byte(bp.Name))
+	if err != nil {
+		return nil, errors.Wrap(err, "create blueprint revision bucket")
+	}
+	version, err := revBkt.NextSequence()
+	if err != nil {
+		return nil, errors.Wrap(err, "get next blueprint revision")
+	}
+	rev := &blueprint.Revision{
+		Version:   int(version),
+		Time:      time.Now().UTC(),
+		APIKey:    apiKey,
+		Blueprint: *bp,
+	}
+	revproto, err := blueprint.MarshalRevision(rev)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling blueprint revision")
+	}
+	if err := revBkt.Put(revisionKey(rev.Version), revproto); err != nil {
+		return nil, errors.Wrap(err, "put blueprint revision to boltdb")
+	}
+	return rev, tx.Commit()
+}
+
+func revisionKey(version int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(version))
+	return key
+}
+
+// Revisions returns every revision of a blueprint, oldest first.
+func (db *DB) Revisions(name string) ([]blueprint.Revision, error) {
+	var revisions []blueprint.Revision
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blueprintRevisionBucket)).Bucket([]byte(name))
+		if b == nil {
+			return &notFound{"Blueprint revisions", fmt.Sprintf("name %s", name)}
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var rev blueprint.Revision
+			if err := blueprint.UnmarshalRevision(v, &rev); err != nil {
+				return err
+			}
+			revisions = append(revisions, rev)
+			return nil
+		})
+	})
+	return revisions, err
+}
+
+func (db *DB) Revision(name string, version int) (*blueprint.Revision, error) {
+	var rev blueprint.Revision
+	err := db.View(func(tx *bolt.Tx) error {
+		var v []byte
+		if b := tx.Bucket([]byte(blueprintRevisionBucket)).Bucket([]byte(name)); b != nil {
+			v = b.Get(revisionKey(version))
+		}
+		if v == nil {
+			return &notFound{"Blueprint revision", fmt.Sprintf("name %s version %d", name, version)}
+		}
+		return blueprint.UnmarshalRevision(v, &rev)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return tx.Commit()
+	return &rev, nil
 }
 
 func (db *DB) BlueprintByName(name string) (*blueprint.Blueprint, error) {
@@ -136,6 +269,272 @@ func (db *DB) BlueprintByName(name string) (*blueprint.Blueprint, error) {
 	return &bp, nil
 }
 
+// DEPSerial reports whether the serial was seen in a DEP sync before, and
+// whether the DEPSync blueprints are still waiting for the device to enroll.
+func (db *DB) DEPSerial(serial string) (seen, pending bool, err error) {
+	err = db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(blueprintDEPSerialBucket)).Get([]byte(serial))
+		seen = v != nil
+		pending = bytes.Equal(v, depSerialPending)
+		return nil
+	})
+	return seen, pending, err
+}
+
+func (db *DB) SaveDEPSerial(serial string, pending bool) error {
+	v := depSerialApplied
+	if pending {
+		v = depSerialPending
+	}
+	err := db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(blueprintDEPSerialBucket)).Put([]byte(serial), v)
+	})
+	return errors.Wrapf(err, "save DEP serial %s", serial)
+}
+
+// Release returns the release progress of a device, or nil if the device
+// has none.
+func (db *DB) Release(udid string) (*blueprint.Release, error) {
+	var r *blueprint.Release
+	err := db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(blueprintReleaseBucket)).Get([]byte(udid))
+		if v == nil {
+			return nil
+		}
+		r = new(blueprint.Release)
+		return blueprint.UnmarshalRelease(v, r)
+	})
+	return r, errors.Wrapf(err, "get release for udid %s", udid)
+}
+
+func (db *DB) Releases() ([]blueprint.Release, error) {
+	var releases []blueprint.Release
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(blueprintReleaseBucket)).ForEach(func(k, v []byte) error {
+			var r blueprint.Release
+			if err := blueprint.UnmarshalRelease(v, &r); err != nil {
+				return err
+			}
+			releases = append(releases, r)
+			return nil
+		})
+	})
+	return releases, errors.Wrap(err, "list releases")
+}
+
+func (db *DB) SaveRelease(r *blueprint.Release) error {
+	v, err := blueprint.MarshalRelease(r)
+	if err != nil {
+		return errors.Wrap(err, "marshal release")
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(blueprintReleaseBucket)).Put([]byte(r.UDID), v)
+	})
+	return errors.Wrapf(err, "save release for udid %s", r.UDID)
+}
+
+// PasswordRotation returns the password rotation waiting for the response to
+// the UserList command, or nil if there is none.
+func (db *DB) PasswordRotation(commandUUID string) (*blueprint.PasswordRotation, error) {
+	var r *blueprint.PasswordRotation
+	err := db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(blueprintRotationBucket)).Get([]byte(commandUUID))
+		if v == nil {
+			return nil
+		}
+		r = new(blueprint.PasswordRotation)
+		return blueprint.UnmarshalPasswordRotation(v, r)
+	})
+	return r, errors.Wrapf(err, "get password rotation for command %s", commandUUID)
+}
+
+func (db *DB) SavePasswordRotation(r *blueprint.PasswordRotation) error {
+	v, err := blueprint.MarshalPasswordRotation(r)
+	if err != nil {
+		return errors.Wrap(err, "marshal password rotation")
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(blueprintRotationBucket)).Put([]byte(r.CommandUUID), v)
+	})
+	return errors.Wrapf(err, "save password rotation for command %s", r.CommandUUID)
+}
+
+func (db *DB) DeletePasswordRotation(commandUUID string) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(blueprintRotationBucket)).Delete([]byte(commandUUID))
+	})
+	return errors.Wrapf(err, "delete password rotation for command %s", commandUUID)
+}
+
+// DriftReport returns the latest drift report of a device, or nil if the
+// device was never reconciled.
+func (db *DB) DriftReport(udid string) (*blueprint.DriftReport, error) {
+	var r *blueprint.DriftReport
+	err := db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(blueprintDriftBucket)).Get([]byte(udid))
+		if v == nil {
+			return nil
+		}
+		r = new(blueprint.DriftReport)
+		return blueprint.UnmarshalDriftReport(v, r)
+	})
+	return r, errors.Wrapf(err, "get drift report for udid %s", udid)
+}
+
+func (db *DB) DriftReports() ([]blueprint.DriftReport, error) {
+	var reports []blueprint.DriftReport
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(blueprintDriftBucket)).ForEach(func(k, v []byte) error {
+			var r blueprint.DriftReport
+			if err := blueprint.UnmarshalDriftReport(v, &r); err != nil {
+				return err
+			}
+			reports = append(reports, r)
+			return nil
+		})
+	})
+	return reports, errors.Wrap(err, "list drift reports")
+}
+
+func (db *DB) SaveDriftReport(r *blueprint.DriftReport) error {
+	v, err := blueprint.MarshalDriftReport(r)
+	if err != nil {
+		return errors.Wrap(err, "marshal drift report")
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(blueprintDriftBucket)).Put([]byte(r.UDID), v)
+	})
+	return errors.Wrapf(err, "save drift report for udid %s", r.UDID)
+}
+
+func (db *DB) SaveDeviceStatus(s *blueprint.DeviceStatus) error {
+	v, err := blueprint.MarshalDeviceStatus(s)
+	if err != nil {
+		return errors.Wrap(err, "marshal blueprint device status")
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket([]byte(blueprintStatusBucket)).CreateBucketIfNotExists([]byte(s.BlueprintName))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(s.UDID), v)
+	})
+	return errors.Wrapf(err, "save status of blueprint %s for udid %s", s.BlueprintName, s.UDID)
+}
+
+// UpdateDeviceStatus calls update with the status of the named blueprint for
+// the device, or with a new status if none was saved, and saves the status if
+// update returns true. The status is read and saved in a single transaction,
+// so concurrent updates of the same status are not lost.
+func (db *DB) UpdateDeviceStatus(name, udid string, update func(*blueprint.DeviceStatus) bool) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket([]byte(blueprintStatusBucket)).CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return err
+		}
+		s := blueprint.DeviceStatus{BlueprintName: name, UDID: udid}
+		if v := b.Get([]byte(udid)); v != nil {
+			if err := blueprint.UnmarshalDeviceStatus(v, &s); err != nil {
+				return err
+			}
+		}
+		if !update(&s) {
+			return nil
+		}
+		v, err := blueprint.MarshalDeviceStatus(&s)
+		if err != nil {
+			return errors.Wrap(err, "marshal blueprint device status")
+		}
+		return b.Put([]byte(udid), v)
+	})
+	return errors.Wrapf(err, "update status of blueprint %s for udid %s", name, udid)
+}
+
+// UpdateDeviceStatuses calls update with the status of every blueprint
+// applied to the device, and saves the statuses for which update returns
+// true, in a single transaction.
+func (db *DB) UpdateDeviceStatuses(udid string, update func(*blueprint.DeviceStatus) bool) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		statuses := tx.Bucket([]byte(blueprintStatusBucket))
+		var names [][]byte
+		statuses.ForEach(func(name, _ []byte) error {
+			names = append(names, name)
+			return nil
+		})
+		for _, name := range names {
+			b := statuses.Bucket(name)
+			if b == nil {
+				continue
+			}
+			v := b.Get([]byte(udid))
+			if v == nil {
+				continue
+			}
+			var s blueprint.DeviceStatus
+			if err := blueprint.UnmarshalDeviceStatus(v, &s); err != nil {
+				return err
+			}
+			if !update(&s) {
+				continue
+			}
+			v, err := blueprint.MarshalDeviceStatus(&s)
+			if err != nil {
+				return errors.Wrap(err, "marshal blueprint device status")
+			}
+			if err := b.Put([]byte(udid), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrapf(err, "update blueprint statuses for udid %s", udid)
+}
+
+// BlueprintStatuses returns the status of a blueprint on every device it was
+// applied to.
+func (db *DB) BlueprintStatuses(name string) ([]blueprint.DeviceStatus, error) {
+	var statuses []blueprint.DeviceStatus
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blueprintStatusBucket)).Bucket([]byte(name))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var s blueprint.DeviceStatus
+			if err := blueprint.UnmarshalDeviceStatus(v, &s); err != nil {
+				return err
+			}
+			statuses = append(statuses, s)
+			return nil
+		})
+	})
+	return statuses, errors.Wrapf(err, "list statuses of blueprint %s", name)
+}
+
+// DeviceStatuses returns the status of every blueprint applied to a device.
+func (db *DB) DeviceStatuses(udid string) ([]blueprint.DeviceStatus, error) {
+	var statuses []blueprint.DeviceStatus
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(blueprintStatusBucket)).ForEach(func(name, _ []byte) error {
+			b := tx.Bucket([]byte(blueprintStatusBucket)).Bucket(name)
+			if b == nil {
+				return nil
+			}
+			v := b.Get([]byte(udid))
+			if v == nil {
+				return nil
+			}
+			var s blueprint.DeviceStatus
+			if err := blueprint.UnmarshalDeviceStatus(v, &s); err != nil {
+				return err
+			}
+			statuses = append(statuses, s)
+			return nil
+		})
+	})
+	return statuses, errors.Wrapf(err, "list blueprint statuses for udid %s", udid)
+}
+
 func (db *DB) BlueprintsByApplyAt(ctx context.Context, name string) ([]blueprint.Blueprint, error) {
 	var bps []blueprint.Blueprint
 	err := db.View(func(tx *bolt.Tx) error {
@@ -151,11 +550,8 @@ func (db *DB) BlueprintsByApplyAt(ctx context.Context, name string) ([]blueprint
 				fmt.Println("could not Unmarshal Blueprint")
 				continue
 			}
-			for _, n := range bp.ApplyAt {
-				if strings.ToLower(n) == strings.ToLower(name) {
-					bps = append(bps, bp)
-					break
-				}
+			if bp.HasApplyAt(name) {
+				bps = append(bps, bp)
 			}
 		}
 		return nil
@@ -180,6 +576,10 @@ func (db *DB) Delete(name string) error {
 		if err != nil {
 			return err
 		}
+		err = tx.Bucket([]byte(blueprintStatusBucket)).DeleteBucket([]byte(bp.Name))
+		if err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
 		return nil
 	})
 	return err
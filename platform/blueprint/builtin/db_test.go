@@ -2,12 +2,15 @@ package builtin
 
 import (
 	"context"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"testing"
 
 	"github.com/boltdb/bolt"
+	"github.com/micromdm/micromdm/mdm/mdm"
 	"github.com/micromdm/micromdm/platform/blueprint"
+	"github.com/micromdm/micromdm/platform/device"
 	profile "github.com/micromdm/micromdm/platform/profile/builtin"
 )
 
@@ -67,6 +70,255 @@ func TestSave(t *testing.T) {
 	}
 }
 
+func TestSaveApplyAt(t *testing.T) {
+	db := setupDB(t)
+	bp := &blueprint.Blueprint{
+		UUID:    "a-b-c-d",
+		Name:    "blueprint",
+		ApplyAt: []string{"enroll", "Reboot"},
+	}
+	if err := db.Save(bp); err == nil {
+		t.Fatal("expected unsupported ApplyAt value to be rejected")
+	}
+
+	bp.ApplyAt = []string{"enroll", "depsync", "Checkin", "Manual"}
+	if err := db.Save(bp); err != nil {
+		t.Fatalf("saving blueprint in datastore: %s", err)
+	}
+	byApplyAt, err := db.BlueprintsByApplyAt(context.Background(), blueprint.ApplyAtDEPSync)
+	if err != nil {
+		t.Fatalf("getting blueprint by ApplyAt: %s", err)
+	}
+	if len(byApplyAt) != 1 {
+		t.Fatalf("have %d blueprints, want 1", len(byApplyAt))
+	}
+}
+
+func TestSaveScope(t *testing.T) {
+	db := setupDB(t)
+	bp := &blueprint.Blueprint{
+		UUID:    "a-b-c-d",
+		Name:    "blueprint",
+		ApplyAt: []string{"Enroll"},
+		Scope:   &blueprint.Scope{Models: []string{"iPad["}},
+	}
+	if err := db.Save(bp); err == nil {
+		t.Fatal("expected invalid scope pattern to be rejected")
+	}
+
+	bp.Scope = &blueprint.Scope{
+		Models:     []string{"iPad*"},
+		Attributes: map[string]string{"building": "north"},
+	}
+	if err := db.Save(bp); err != nil {
+		t.Fatalf("saving blueprint in datastore: %s", err)
+	}
+	found, err := db.BlueprintByName("blueprint")
+	if err != nil {
+		t.Fatalf("getting blueprint by name: %s", err)
+	}
+
+	dev := &device.Device{Model: "iPad7,5", Attributes: map[string]string{"building": "north"}}
+	if !found.Scope.Matches(dev) {
+		t.Error("expected device to match scope")
+	}
+	dev.Attributes["building"] = "south"
+	if found.Scope.Matches(dev) {
+		t.Error("expected device with other attributes not to match scope")
+	}
+	dev = &device.Device{Model: "MacBookPro15,1", Attributes: map[string]string{"building": "north"}}
+	if found.Scope.Matches(dev) {
+		t.Error("expected device with other model not to match scope")
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	db := setupDB(t)
+	bp := &blueprint.Blueprint{
+		UUID:    "a-b-c-d",
+		Name:    "blueprint",
+		ApplyAt: []string{"Enroll"},
+	}
+	if _, err := db.SaveRevision(bp, "sha256:0123"); err != nil {
+		t.Fatalf("saving blueprint in datastore: %s", err)
+	}
+	bp.ApplyAt = []string{"Enroll", "Checkin"}
+	rev, err := db.SaveRevision(bp, "")
+	if err != nil {
+		t.Fatalf("saving blueprint in datastore: %s", err)
+	}
+	if rev.Version != 2 {
+		t.Errorf("have version %d, want 2", rev.Version)
+	}
+
+	revisions, err := db.Revisions("blueprint")
+	if err != nil {
+		t.Fatalf("listing revisions: %s", err)
+	}
+	if len(revisions) != 2 || revisions[0].APIKey != "sha256:0123" {
+		t.Fatalf("revisions not saved correctly: %+v", revisions)
+	}
+
+	changes, err := blueprint.Diff(&revisions[0].Blueprint, &revisions[1].Blueprint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Field != "apply_at" {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+
+	if _, err := db.Revision("blueprint", 3); err == nil {
+		t.Error("expected missing revision to return an error")
+	}
+}
+
+func TestDeviceStatus(t *testing.T) {
+	db := setupDB(t)
+	bp := &blueprint.Blueprint{UUID: "a-b-c-d", Name: "blueprint"}
+	if err := db.Save(bp); err != nil {
+		t.Fatalf("saving blueprint in datastore: %s", err)
+	}
+	status := &blueprint.DeviceStatus{
+		BlueprintName: "blueprint",
+		UDID:          "udid",
+		Commands: []blueprint.CommandStatus{
+			{CommandUUID: "cmd-1", RequestType: "InstallProfile", Item: "com.example.wifi", Status: blueprint.CommandQueued},
+		},
+		Errors: []string{"profile com.example.vpn not found"},
+	}
+	if err := db.SaveDeviceStatus(status); err != nil {
+		t.Fatalf("saving device status: %s", err)
+	}
+
+	byDevice, err := db.DeviceStatuses("udid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byDevice) != 1 || byDevice[0].Commands[0].Item != "com.example.wifi" || len(byDevice[0].Errors) != 1 {
+		t.Fatalf("device status not saved correctly: %+v", byDevice)
+	}
+
+	if err := db.Delete("blueprint"); err != nil {
+		t.Fatal(err)
+	}
+	byBlueprint, err := db.BlueprintStatuses("blueprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byBlueprint) != 0 {
+		t.Errorf("have %d statuses after removing the blueprint, want 0", len(byBlueprint))
+	}
+}
+
+func TestUpdateDeviceStatus(t *testing.T) {
+	db := setupDB(t)
+	err := db.UpdateDeviceStatus("blueprint", "udid", func(s *blueprint.DeviceStatus) bool {
+		s.Commands = append(s.Commands, blueprint.CommandStatus{CommandUUID: "cmd-1", Status: blueprint.CommandQueued})
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.UpdateDeviceStatuses("udid", func(s *blueprint.DeviceStatus) bool {
+		s.Commands[0].Status = "Acknowledged"
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := db.DeviceStatuses("udid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].BlueprintName != "blueprint" || statuses[0].Commands[0].Status != "Acknowledged" {
+		t.Errorf("have statuses %+v", statuses)
+	}
+}
+
+func TestPasswordRotation(t *testing.T) {
+	db := setupDB(t)
+	want := &blueprint.PasswordRotation{CommandUUID: "cmd-1", UDID: "udid", UserUUID: "user-1"}
+	if err := db.SavePasswordRotation(want); err != nil {
+		t.Fatal(err)
+	}
+	have, err := db.PasswordRotation("cmd-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have == nil || have.UDID != want.UDID || have.UserUUID != want.UserUUID {
+		t.Fatalf("have rotation %+v, want %+v", have, want)
+	}
+	if err := db.DeletePasswordRotation("cmd-1"); err != nil {
+		t.Fatal(err)
+	}
+	if have, err = db.PasswordRotation("cmd-1"); err != nil || have != nil {
+		t.Errorf("have rotation %+v and err %v after delete", have, err)
+	}
+}
+
+func TestSaveCommands(t *testing.T) {
+	db := setupDB(t)
+	var bp blueprint.Blueprint
+	err := json.Unmarshal([]byte(`{
+		"uuid": "a-b-c-d",
+		"name": "blueprint",
+		"apply_at": ["Enroll"],
+		"commands": [
+			{"request_type": "Settings", "settings": [{"item": "DeviceName", "device_name": "lab-01"}]},
+			{"request_type": "InstallApplication", "itunes_store_id": 497799835},
+			{"request_type": "SecurityInfo"}
+		]
+	}`), &bp)
+	if err != nil {
+		t.Fatalf("decoding blueprint json: %s", err)
+	}
+	if err := db.Save(&bp); err != nil {
+		t.Fatalf("saving blueprint in datastore: %s", err)
+	}
+	found, err := db.BlueprintByName("blueprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Commands) != 3 {
+		t.Fatalf("have %d commands, want 3", len(found.Commands))
+	}
+	if have := *found.Commands[0].Settings.Settings[0].DeviceName; have != "lab-01" {
+		t.Errorf("have device name %q, want lab-01", have)
+	}
+	if have := found.Commands[2].RequestType; have != "SecurityInfo" {
+		t.Errorf("commands not saved in order, have %s last", have)
+	}
+
+	bp.Commands = append(bp.Commands, &mdm.Command{RequestType: "NotACommand"})
+	if err := db.Save(&bp); err == nil {
+		t.Error("expected unknown command to be rejected")
+	}
+}
+
+func TestDEPSerial(t *testing.T) {
+	db := setupDB(t)
+	seen, pending, err := db.DEPSerial("C02ABCDEF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen || pending {
+		t.Fatal("expected new serial to be unseen")
+	}
+
+	for _, want := range []bool{true, false} {
+		if err := db.SaveDEPSerial("C02ABCDEF", want); err != nil {
+			t.Fatal(err)
+		}
+		seen, pending, err = db.DEPSerial("C02ABCDEF")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !seen || pending != want {
+			t.Errorf("have seen=%v pending=%v, want seen=true pending=%v", seen, pending, want)
+		}
+	}
+}
+
 func TestList(t *testing.T) {
 	db := setupDB(t)
 	bp1 := &blueprint.Blueprint{
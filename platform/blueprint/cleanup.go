@@ -0,0 +1,176 @@
+package blueprint
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/platform/device"
+)
+
+// cleanupDevices queues RemoveProfile and RemoveApplication commands for the
+// profiles and applications which were installed on devices by the named
+// blueprint and are not part of keep. keep is nil if the blueprint is
+// removed. The device statuses keep the items of earlier applies, so items
+// dropped from earlier revisions are removed too, and the profiles installed
+// on the user channel are removed from it. Items which another blueprint
+// applicable to the device still installs are left alone. The cleaned up
+// commands are removed from the device status records of the blueprint.
+func (svc *BlueprintService) cleanupDevices(ctx context.Context, name string, keep *Blueprint) error {
+	if svc.cmdsvc == nil {
+		return nil
+	}
+	if keep == nil {
+		keep = &Blueprint{Name: name}
+	}
+	statuses, err := svc.store.BlueprintStatuses(name)
+	if err != nil {
+		return errors.Wrapf(err, "get device statuses of blueprint %s", name)
+	}
+	bps, err := svc.store.List()
+	if err != nil {
+		return errors.Wrap(err, "list blueprints")
+	}
+
+	bundleIDs := make(map[string][]string)
+	for i := range statuses {
+		status := &statuses[i]
+		userChannel := status.DeviceUDID != ""
+		keepProfiles := keep.ProfileIdentifiers
+		if userChannel {
+			keepProfiles = keep.UserProfileIdentifiers
+		}
+		requiredProfiles, requiredApps, err := svc.requiredByOthers(ctx, status, bps)
+		if err != nil {
+			return err
+		}
+
+		var profiles, apps []string
+		for _, c := range status.Commands {
+			var cmds []*mdm.Command
+			switch {
+			case c.RequestType == "InstallProfile" && !containsString(keepProfiles, c.Item):
+				profiles = append(profiles, c.Item)
+				if installed(c.Status) && !requiredProfiles[c.Item] {
+					cmds = append(cmds, &mdm.Command{
+						RequestType:   "RemoveProfile",
+						RemoveProfile: &mdm.RemoveProfile{Identifier: c.Item},
+					})
+				}
+			case c.RequestType == "InstallApplication" && !containsString(keep.ApplicationURLs, c.Item):
+				apps = append(apps, c.Item)
+				if !installed(c.Status) || requiredApps[c.Item] {
+					break
+				}
+				ids, ok := bundleIDs[c.Item]
+				if !ok {
+					ids, err = manifestBundleIDs(ctx, svc.client, c.Item)
+					if err != nil {
+						return err
+					}
+					bundleIDs[c.Item] = ids
+				}
+				for _, id := range ids {
+					cmds = append(cmds, &mdm.Command{
+						RequestType:       "RemoveApplication",
+						RemoveApplication: &mdm.RemoveApplication{Identifier: id},
+					})
+				}
+			default:
+				continue
+			}
+
+			for _, cmd := range cmds {
+				_, err := svc.cmdsvc.NewCommand(ctx, &mdm.CommandRequest{UDID: status.UDID, Command: cmd})
+				if err != nil {
+					return errors.Wrapf(err, "queue %s for udid %s", cmd.RequestType, status.UDID)
+				}
+			}
+		}
+		if len(profiles) == 0 && len(apps) == 0 {
+			continue
+		}
+		err = svc.store.UpdateDeviceStatus(name, status.UDID, func(s *DeviceStatus) bool {
+			s.Commands = withoutItems(s.Commands, profiles, apps)
+			return true
+		})
+		if err != nil {
+			return errors.Wrap(err, "save device status")
+		}
+	}
+	return nil
+}
+
+// requiredByOthers returns the profile identifiers and manifest URLs which
+// the blueprints of bps other than the one of status install on the channel
+// of status. These are the automatic blueprints in scope of the device, and
+// the blueprints which were applied to the channel, like Manual ones. If the
+// device is unknown every automatic blueprint is assumed to be in scope.
+func (svc *BlueprintService) requiredByOthers(ctx context.Context, status *DeviceStatus, bps []Blueprint) (profiles, apps map[string]bool, err error) {
+	statuses, err := svc.store.DeviceStatuses(status.UDID)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "get blueprint statuses of udid %s", status.UDID)
+	}
+	applied := make(map[string]bool)
+	for _, s := range statuses {
+		applied[s.BlueprintName] = true
+	}
+	udid, userChannel := status.UDID, status.DeviceUDID != ""
+	if userChannel {
+		udid = status.DeviceUDID
+	}
+	var dev *device.Device
+	if svc.devices != nil {
+		if dev, err = svc.devices.DeviceByUDID(ctx, udid); err != nil {
+			dev = nil
+		}
+	}
+
+	profiles, apps = make(map[string]bool), make(map[string]bool)
+	for _, bp := range bps {
+		if bp.Name == status.BlueprintName {
+			continue
+		}
+		automatic := bp.HasApplyAt(ApplyAtEnroll) || bp.HasApplyAt(ApplyAtCheckin) || bp.HasApplyAt(ApplyAtDEPSync)
+		inScope := dev == nil || bp.Scope.Matches(dev)
+		if !applied[bp.Name] && !(automatic && inScope) {
+			continue
+		}
+		if userChannel {
+			for _, id := range bp.UserProfileIdentifiers {
+				profiles[id] = true
+			}
+			continue
+		}
+		for _, id := range bp.ProfileIdentifiers {
+			profiles[id] = true
+		}
+		for _, url := range bp.ApplicationURLs {
+			apps[url] = true
+		}
+	}
+	return profiles, apps, nil
+}
+
+// withoutItems returns the commands which do not install one of the profiles
+// or applications.
+func withoutItems(cmds []CommandStatus, profiles, apps []string) []CommandStatus {
+	var kept []CommandStatus
+	for _, c := range cmds {
+		if c.RequestType == "InstallProfile" && containsString(profiles, c.Item) {
+			continue
+		}
+		if c.RequestType == "InstallApplication" && containsString(apps, c.Item) {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// installed reports whether the item of a command may be installed on the
+// device. Commands which are still queued may be installed later.
+func installed(status string) bool {
+	return status != "Error" && status != "CommandFormatError"
+}
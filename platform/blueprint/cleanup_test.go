@@ -0,0 +1,113 @@
+package blueprint
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/platform/command"
+)
+
+type cleanupTestStore struct {
+	Store
+	blueprints map[string]*Blueprint
+	statuses   []DeviceStatus
+	saved      []DeviceStatus
+}
+
+func (s *cleanupTestStore) BlueprintByName(name string) (*Blueprint, error) {
+	if bp, ok := s.blueprints[name]; ok {
+		return bp, nil
+	}
+	return nil, fmt.Errorf("blueprint %s not found", name)
+}
+
+func (s *cleanupTestStore) List() ([]Blueprint, error) {
+	var bps []Blueprint
+	for _, bp := range s.blueprints {
+		bps = append(bps, *bp)
+	}
+	return bps, nil
+}
+
+func (s *cleanupTestStore) BlueprintStatuses(name string) ([]DeviceStatus, error) {
+	var statuses []DeviceStatus
+	for _, st := range s.statuses {
+		if st.BlueprintName == name {
+			statuses = append(statuses, st)
+		}
+	}
+	return statuses, nil
+}
+
+func (s *cleanupTestStore) DeviceStatuses(udid string) ([]DeviceStatus, error) {
+	var statuses []DeviceStatus
+	for _, st := range s.statuses {
+		if st.UDID == udid {
+			statuses = append(statuses, st)
+		}
+	}
+	return statuses, nil
+}
+
+func (s *cleanupTestStore) UpdateDeviceStatus(name, udid string, update func(*DeviceStatus) bool) error {
+	for _, st := range s.statuses {
+		if st.BlueprintName == name && st.UDID == udid && update(&st) {
+			s.saved = append(s.saved, st)
+		}
+	}
+	return nil
+}
+
+type cleanupTestCommands struct {
+	command.Service
+	queued []string
+}
+
+func (c *cleanupTestCommands) NewCommand(ctx context.Context, req *mdm.CommandRequest) (*mdm.CommandPayload, error) {
+	c.queued = append(c.queued, req.UDID+" "+req.RequestType+" "+req.RemoveProfile.Identifier)
+	return &mdm.CommandPayload{Command: req.Command}, nil
+}
+
+func TestCleanupDevices(t *testing.T) {
+	store := &cleanupTestStore{
+		blueprints: map[string]*Blueprint{
+			"base": {Name: "base", ProfileIdentifiers: []string{"com.example.wifi", "com.example.vpn", "com.example.old"},
+				UserProfileIdentifiers: []string{"com.example.mail"}},
+			"other":  {Name: "other", ApplyAt: []string{"Manual"}, ProfileIdentifiers: []string{"com.example.vpn"}},
+			"enroll": {Name: "enroll", ApplyAt: []string{"Enroll"}, ProfileIdentifiers: []string{"com.example.cert"}},
+		},
+		statuses: []DeviceStatus{
+			{BlueprintName: "base", UDID: "udid", Commands: []CommandStatus{
+				{RequestType: "InstallProfile", Item: "com.example.wifi", Status: "Acknowledged"},
+				{RequestType: "InstallProfile", Item: "com.example.vpn", Status: "Acknowledged"},
+				{RequestType: "InstallProfile", Item: "com.example.old", Status: "Error"},
+				// installed by an earlier revision of base.
+				{RequestType: "InstallProfile", Item: "com.example.cert", Status: "Acknowledged"},
+			}},
+			{BlueprintName: "base", UDID: "user", DeviceUDID: "udid", Commands: []CommandStatus{
+				{RequestType: "InstallProfile", Item: "com.example.mail", Status: "Acknowledged"},
+			}},
+			{BlueprintName: "other", UDID: "udid"},
+		},
+	}
+	cmds := &cleanupTestCommands{}
+	svc := New(store, WithCleanup(cmds))
+
+	bp := Blueprint{Name: "base"}
+	if err := svc.cleanupDevices(context.Background(), "base", &bp); err != nil {
+		t.Fatal(err)
+	}
+
+	// vpn is still installed by the other blueprint, cert by the enroll
+	// blueprint in scope of the device, and old never was.
+	want := []string{"udid RemoveProfile com.example.wifi", "user RemoveProfile com.example.mail"}
+	if have := cmds.queued; !reflect.DeepEqual(have, want) {
+		t.Errorf("have queued %v, want %v", have, want)
+	}
+	if len(store.saved) != 2 || len(store.saved[0].Commands) != 0 || len(store.saved[1].Commands) != 0 {
+		t.Errorf("have saved statuses %v, want the base statuses without commands", store.saved)
+	}
+}
@@ -48,9 +48,117 @@ func NewHTTPClient(instance, token string, logger log.Logger, opts ...httptransp
 		).Endpoint()
 	}
 
+	var applyToDevicesEndpoint endpoint.Endpoint
+	{
+		applyToDevicesEndpoint = httptransport.NewClient(
+			"POST",
+			httputil.CopyURL(u, "/v1/blueprints"),
+			httputil.EncodeRequestWithToken(token, encodeApplyToDevicesRequest),
+			decodeApplyToDevicesResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var getReleasesEndpoint endpoint.Endpoint
+	{
+		getReleasesEndpoint = httptransport.NewClient(
+			"POST",
+			httputil.CopyURL(u, "/v1/blueprints/releases"),
+			httputil.EncodeRequestWithToken(token, httptransport.EncodeJSONRequest),
+			decodeGetReleasesResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var reconcileDevicesEndpoint endpoint.Endpoint
+	{
+		reconcileDevicesEndpoint = httptransport.NewClient(
+			"POST",
+			httputil.CopyURL(u, "/v1/blueprints/reconcile"),
+			httputil.EncodeRequestWithToken(token, httptransport.EncodeJSONRequest),
+			decodeReconcileDevicesResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var getDriftReportsEndpoint endpoint.Endpoint
+	{
+		getDriftReportsEndpoint = httptransport.NewClient(
+			"POST",
+			httputil.CopyURL(u, "/v1/blueprints/drift"),
+			httputil.EncodeRequestWithToken(token, httptransport.EncodeJSONRequest),
+			decodeGetDriftReportsResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var getRevisionsEndpoint endpoint.Endpoint
+	{
+		getRevisionsEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/blueprints"),
+			httputil.EncodeRequestWithToken(token, encodeGetRevisionsRequest),
+			decodeGetRevisionsResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var diffRevisionsEndpoint endpoint.Endpoint
+	{
+		diffRevisionsEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/blueprints"),
+			httputil.EncodeRequestWithToken(token, encodeDiffRevisionsRequest),
+			decodeDiffRevisionsResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var rollbackBlueprintEndpoint endpoint.Endpoint
+	{
+		rollbackBlueprintEndpoint = httptransport.NewClient(
+			"POST",
+			httputil.CopyURL(u, "/v1/blueprints"),
+			httputil.EncodeRequestWithToken(token, encodeRollbackBlueprintRequest),
+			decodeRollbackBlueprintResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var getBlueprintStatusEndpoint endpoint.Endpoint
+	{
+		getBlueprintStatusEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/blueprints"),
+			httputil.EncodeRequestWithToken(token, encodeGetBlueprintStatusRequest),
+			decodeGetBlueprintStatusResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var getDeviceStatusEndpoint endpoint.Endpoint
+	{
+		getDeviceStatusEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/blueprints/devices"),
+			httputil.EncodeRequestWithToken(token, encodeGetDeviceStatusRequest),
+			decodeGetDeviceStatusResponse,
+			opts...,
+		).Endpoint()
+	}
+
 	return Endpoints{
-		ApplyBlueprintEndpoint:   applyBlueprintEndpoint,
-		GetBlueprintsEndpoint:    getBlueprintsEndpoint,
-		RemoveBlueprintsEndpoint: removeBlueprintsEndpoint,
+		ApplyBlueprintEndpoint:     applyBlueprintEndpoint,
+		GetBlueprintsEndpoint:      getBlueprintsEndpoint,
+		RemoveBlueprintsEndpoint:   removeBlueprintsEndpoint,
+		ApplyToDevicesEndpoint:     applyToDevicesEndpoint,
+		GetReleasesEndpoint:        getReleasesEndpoint,
+		ReconcileDevicesEndpoint:   reconcileDevicesEndpoint,
+		GetDriftReportsEndpoint:    getDriftReportsEndpoint,
+		GetRevisionsEndpoint:       getRevisionsEndpoint,
+		DiffRevisionsEndpoint:      diffRevisionsEndpoint,
+		RollbackBlueprintEndpoint:  rollbackBlueprintEndpoint,
+		GetBlueprintStatusEndpoint: getBlueprintStatusEndpoint,
+		GetDeviceStatusEndpoint:    getDeviceStatusEndpoint,
 	}, nil
 }
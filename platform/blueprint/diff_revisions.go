@@ -0,0 +1,113 @@
+package blueprint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// DiffRevisions compares two revisions of a blueprint. A to version of 0
+// selects the latest revision, and a from version of 0 the one before to.
+func (svc *BlueprintService) DiffRevisions(ctx context.Context, name string, from, to int) ([]FieldChange, error) {
+	if to == 0 {
+		revisions, err := svc.store.Revisions(name)
+		if err != nil {
+			return nil, err
+		}
+		to = revisions[len(revisions)-1].Version
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	if from < 1 {
+		return nil, fmt.Errorf("blueprint %s has no revision before version %d", name, to)
+	}
+	fromRev, err := svc.store.Revision(name, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := svc.store.Revision(name, to)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(&fromRev.Blueprint, &toRev.Blueprint)
+}
+
+type diffRevisionsRequest struct {
+	Name string
+	From int
+	To   int
+}
+
+type diffRevisionsResponse struct {
+	Changes []FieldChange `json:"changes"`
+	Err     error         `json:"err,omitempty"`
+}
+
+func (r diffRevisionsResponse) Failed() error { return r.Err }
+
+func decodeDiffRevisionsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	name, ok := mux.Vars(r)["name"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	req := diffRevisionsRequest{Name: name}
+	q := r.URL.Query()
+	var err error
+	if v := q.Get("from"); v != "" {
+		if req.From, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid from version %q", v)
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if req.To, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid to version %q", v)
+		}
+	}
+	return req, nil
+}
+
+func encodeDiffRevisionsRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(diffRevisionsRequest)
+	r.Method, r.URL.Path = "GET", "/v1/blueprints/"+url.PathEscape(req.Name)+"/diff"
+	q := url.Values{}
+	if req.From != 0 {
+		q.Set("from", strconv.Itoa(req.From))
+	}
+	if req.To != 0 {
+		q.Set("to", strconv.Itoa(req.To))
+	}
+	r.URL.RawQuery = q.Encode()
+	return nil
+}
+
+func decodeDiffRevisionsResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp diffRevisionsResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeDiffRevisionsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(diffRevisionsRequest)
+		changes, err := svc.DiffRevisions(ctx, req.Name, req.From, req.To)
+		return diffRevisionsResponse{Changes: changes, Err: err}, nil
+	}
+}
+
+func (e Endpoints) DiffRevisions(ctx context.Context, name string, from, to int) ([]FieldChange, error) {
+	resp, err := e.DiffRevisionsEndpoint(ctx, diffRevisionsRequest{Name: name, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(diffRevisionsResponse)
+	return response.Changes, response.Err
+}
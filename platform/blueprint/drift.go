@@ -0,0 +1,95 @@
+package blueprint
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/micromdm/micromdm/platform/blueprint/internal/blueprintproto"
+)
+
+// Drift report states.
+const (
+	// DriftRequested waits for the device to return its ProfileList and
+	// InstalledApplicationList.
+	DriftRequested = "Requested"
+	// DriftReported means the device state was compared with its blueprints.
+	DriftReported = "Reported"
+	// DriftFailed means the device returned an error for an inventory command.
+	DriftFailed = "Failed"
+)
+
+// DriftReport compares the profiles and applications installed on a device
+// with the ones its blueprints install.
+type DriftReport struct {
+	UDID                  string    `json:"udid"`
+	Status                string    `json:"status"`
+	ReportOnly            bool      `json:"report_only"`
+	RequestedAt           time.Time `json:"requested_at"`
+	CompletedAt           time.Time `json:"completed_at,omitempty"`
+	InstalledProfiles     []string  `json:"installed_profiles,omitempty"`
+	InstalledApplications []string  `json:"installed_applications,omitempty"`
+	// MissingProfiles are blueprint profile identifiers which are not
+	// installed on the device.
+	MissingProfiles []string `json:"missing_profiles,omitempty"`
+	// MissingApplications are blueprint manifest URLs whose applications
+	// are not installed on the device.
+	MissingApplications []string `json:"missing_applications,omitempty"`
+	// RemovedProfiles are installed profiles which were dropped from the
+	// blueprints of the device.
+	RemovedProfiles []string `json:"removed_profiles,omitempty"`
+	// Queued are the UUIDs of the commands queued to repair the device.
+	// Nothing is queued in report-only mode.
+	Queued []string `json:"queued,omitempty"`
+	Error  string   `json:"error,omitempty"`
+
+	profileListUUID     string
+	applicationListUUID string
+	// managedProfiles are the profile identifiers the blueprints of the
+	// device installed at the last reconcile, used to find dropped profiles.
+	managedProfiles []string
+}
+
+func MarshalDriftReport(r *DriftReport) ([]byte, error) {
+	pb := blueprintproto.DriftReport{
+		Udid:                  r.UDID,
+		Status:                r.Status,
+		ReportOnly:            r.ReportOnly,
+		RequestedAt:           timeToProto(r.RequestedAt),
+		CompletedAt:           timeToProto(r.CompletedAt),
+		ProfileListUuid:       r.profileListUUID,
+		ApplicationListUuid:   r.applicationListUUID,
+		InstalledProfiles:     r.InstalledProfiles,
+		InstalledApplications: r.InstalledApplications,
+		MissingProfiles:       r.MissingProfiles,
+		MissingApplications:   r.MissingApplications,
+		RemovedProfiles:       r.RemovedProfiles,
+		ManagedProfiles:       r.managedProfiles,
+		Queued:                r.Queued,
+		Error:                 r.Error,
+	}
+	return proto.Marshal(&pb)
+}
+
+func UnmarshalDriftReport(data []byte, r *DriftReport) error {
+	var pb blueprintproto.DriftReport
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+	r.UDID = pb.GetUdid()
+	r.Status = pb.GetStatus()
+	r.ReportOnly = pb.GetReportOnly()
+	r.RequestedAt = timeFromProto(pb.GetRequestedAt())
+	r.CompletedAt = timeFromProto(pb.GetCompletedAt())
+	r.profileListUUID = pb.GetProfileListUuid()
+	r.applicationListUUID = pb.GetApplicationListUuid()
+	r.InstalledProfiles = pb.GetInstalledProfiles()
+	r.InstalledApplications = pb.GetInstalledApplications()
+	r.MissingProfiles = pb.GetMissingProfiles()
+	r.MissingApplications = pb.GetMissingApplications()
+	r.RemovedProfiles = pb.GetRemovedProfiles()
+	r.managedProfiles = pb.GetManagedProfiles()
+	r.Queued = pb.GetQueued()
+	r.Error = pb.GetError()
+	return nil
+}
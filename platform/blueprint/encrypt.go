@@ -0,0 +1,53 @@
+package blueprint
+
+import (
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/profile"
+)
+
+// DeviceCertStore returns the identity certificates saved when devices
+// authenticate.
+type DeviceCertStore interface {
+	GetUDIDCert(udid []byte) ([]byte, error)
+}
+
+// ProfileEncrypter encrypts profiles to the identity certificate of a device.
+type ProfileEncrypter struct {
+	certs DeviceCertStore
+}
+
+func NewProfileEncrypter(certs DeviceCertStore) *ProfileEncrypter {
+	return &ProfileEncrypter{certs: certs}
+}
+
+// EncryptProfile encrypts the payloads of a Mobileconfig to the identity
+// certificate of the device. A nil ProfileEncrypter has no certificates, so
+// encrypting fails.
+func (e *ProfileEncrypter) EncryptProfile(udid string, mc profile.Mobileconfig) (profile.Mobileconfig, error) {
+	if e == nil {
+		return nil, errors.New("profile encryption is not enabled")
+	}
+	der, err := e.certs.GetUDIDCert([]byte(udid))
+	if err != nil {
+		return nil, errors.Wrapf(err, "get identity certificate of device %s, it is saved the next time the device checks in", udid)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse identity certificate of device %s", udid)
+	}
+	return mc.Encrypt(cert)
+}
+
+// blueprintProfile returns the profile as installed by the blueprint, with
+// Encrypt set if the blueprint encrypts its profiles.
+func blueprintProfile(bp *Blueprint, p *profile.Profile) *profile.Profile {
+	if !bp.EncryptProfiles || p.Encrypt {
+		return p
+	}
+	encrypted := *p
+	encrypted.Encrypt = true
+	return &encrypted
+}
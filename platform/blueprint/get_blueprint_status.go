@@ -0,0 +1,70 @@
+package blueprint
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// GetBlueprintStatus returns the status of a blueprint on every device it was
+// applied to.
+func (svc *BlueprintService) GetBlueprintStatus(ctx context.Context, name string) ([]DeviceStatus, error) {
+	if _, err := svc.store.BlueprintByName(name); err != nil {
+		return nil, err
+	}
+	return svc.store.BlueprintStatuses(name)
+}
+
+type getBlueprintStatusRequest struct {
+	Name string
+}
+
+type getBlueprintStatusResponse struct {
+	Statuses []DeviceStatus `json:"statuses"`
+	Err      error          `json:"err,omitempty"`
+}
+
+func (r getBlueprintStatusResponse) Failed() error { return r.Err }
+
+func decodeGetBlueprintStatusRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	name, ok := mux.Vars(r)["name"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	return getBlueprintStatusRequest{Name: name}, nil
+}
+
+func encodeGetBlueprintStatusRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(getBlueprintStatusRequest)
+	r.Method, r.URL.Path = "GET", "/v1/blueprints/"+url.PathEscape(req.Name)+"/status"
+	return nil
+}
+
+func decodeGetBlueprintStatusResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp getBlueprintStatusResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeGetBlueprintStatusEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(getBlueprintStatusRequest)
+		statuses, err := svc.GetBlueprintStatus(ctx, req.Name)
+		return getBlueprintStatusResponse{Statuses: statuses, Err: err}, nil
+	}
+}
+
+func (e Endpoints) GetBlueprintStatus(ctx context.Context, name string) ([]DeviceStatus, error) {
+	resp, err := e.GetBlueprintStatusEndpoint(ctx, getBlueprintStatusRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(getBlueprintStatusResponse)
+	return response.Statuses, response.Err
+}
@@ -0,0 +1,66 @@
+package blueprint
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// GetDeviceStatus returns the status of every blueprint applied to a device.
+func (svc *BlueprintService) GetDeviceStatus(ctx context.Context, udid string) ([]DeviceStatus, error) {
+	return svc.store.DeviceStatuses(udid)
+}
+
+type getDeviceStatusRequest struct {
+	UDID string
+}
+
+type getDeviceStatusResponse struct {
+	Statuses []DeviceStatus `json:"statuses"`
+	Err      error          `json:"err,omitempty"`
+}
+
+func (r getDeviceStatusResponse) Failed() error { return r.Err }
+
+func decodeGetDeviceStatusRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	udid, ok := mux.Vars(r)["udid"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	return getDeviceStatusRequest{UDID: udid}, nil
+}
+
+func encodeGetDeviceStatusRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(getDeviceStatusRequest)
+	r.Method, r.URL.Path = "GET", "/v1/blueprints/devices/"+url.PathEscape(req.UDID)
+	return nil
+}
+
+func decodeGetDeviceStatusResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp getDeviceStatusResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeGetDeviceStatusEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(getDeviceStatusRequest)
+		statuses, err := svc.GetDeviceStatus(ctx, req.UDID)
+		return getDeviceStatusResponse{Statuses: statuses, Err: err}, nil
+	}
+}
+
+func (e Endpoints) GetDeviceStatus(ctx context.Context, udid string) ([]DeviceStatus, error) {
+	resp, err := e.GetDeviceStatusEndpoint(ctx, getDeviceStatusRequest{UDID: udid})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(getDeviceStatusResponse)
+	return response.Statuses, response.Err
+}
@@ -0,0 +1,73 @@
+package blueprint
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// GetDriftReportsOption filters the drift reports by device. No UDIDs
+// returns the reports of all devices.
+type GetDriftReportsOption struct {
+	UDIDs []string `json:"udids,omitempty"`
+}
+
+func (svc *BlueprintService) GetDriftReports(ctx context.Context, opt GetDriftReportsOption) ([]DriftReport, error) {
+	if len(opt.UDIDs) == 0 {
+		return svc.store.DriftReports()
+	}
+	var reports []DriftReport
+	for _, udid := range opt.UDIDs {
+		r, err := svc.store.DriftReport(udid)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			reports = append(reports, *r)
+		}
+	}
+	return reports, nil
+}
+
+type getDriftReportsRequest struct {
+	Opts GetDriftReportsOption `json:"opts"`
+}
+
+type getDriftReportsResponse struct {
+	Reports []DriftReport `json:"reports"`
+	Err     error         `json:"err,omitempty"`
+}
+
+func (r getDriftReportsResponse) Failed() error { return r.Err }
+
+func decodeGetDriftReportsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req getDriftReportsRequest
+	err := httputil.DecodeJSONRequest(r, &req)
+	return req, err
+}
+
+func decodeGetDriftReportsResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp getDriftReportsResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeGetDriftReportsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(getDriftReportsRequest)
+		reports, err := svc.GetDriftReports(ctx, req.Opts)
+		return getDriftReportsResponse{Reports: reports, Err: err}, nil
+	}
+}
+
+func (e Endpoints) GetDriftReports(ctx context.Context, opt GetDriftReportsOption) ([]DriftReport, error) {
+	resp, err := e.GetDriftReportsEndpoint(ctx, getDriftReportsRequest{Opts: opt})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(getDriftReportsResponse)
+	return response.Reports, response.Err
+}
@@ -0,0 +1,73 @@
+package blueprint
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// GetReleasesOption filters the releases by device. No UDIDs returns the
+// releases of all devices.
+type GetReleasesOption struct {
+	UDIDs []string `json:"udids,omitempty"`
+}
+
+func (svc *BlueprintService) GetReleases(ctx context.Context, opt GetReleasesOption) ([]Release, error) {
+	if len(opt.UDIDs) == 0 {
+		return svc.store.Releases()
+	}
+	var releases []Release
+	for _, udid := range opt.UDIDs {
+		r, err := svc.store.Release(udid)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			releases = append(releases, *r)
+		}
+	}
+	return releases, nil
+}
+
+type getReleasesRequest struct {
+	Opts GetReleasesOption `json:"opts"`
+}
+
+type getReleasesResponse struct {
+	Releases []Release `json:"releases"`
+	Err      error     `json:"err,omitempty"`
+}
+
+func (r getReleasesResponse) Failed() error { return r.Err }
+
+func decodeGetReleasesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req getReleasesRequest
+	err := httputil.DecodeJSONRequest(r, &req)
+	return req, err
+}
+
+func decodeGetReleasesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp getReleasesResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeGetReleasesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(getReleasesRequest)
+		releases, err := svc.GetReleases(ctx, req.Opts)
+		return getReleasesResponse{Releases: releases, Err: err}, nil
+	}
+}
+
+func (e Endpoints) GetReleases(ctx context.Context, opt GetReleasesOption) ([]Release, error) {
+	resp, err := e.GetReleasesEndpoint(ctx, getReleasesRequest{Opts: opt})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(getReleasesResponse)
+	return response.Releases, response.Err
+}
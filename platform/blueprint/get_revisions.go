@@ -0,0 +1,65 @@
+package blueprint
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func (svc *BlueprintService) GetRevisions(ctx context.Context, name string) ([]Revision, error) {
+	return svc.store.Revisions(name)
+}
+
+type getRevisionsRequest struct {
+	Name string
+}
+
+type getRevisionsResponse struct {
+	Revisions []Revision `json:"revisions"`
+	Err       error      `json:"err,omitempty"`
+}
+
+func (r getRevisionsResponse) Failed() error { return r.Err }
+
+func decodeGetRevisionsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	name, ok := mux.Vars(r)["name"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	return getRevisionsRequest{Name: name}, nil
+}
+
+func encodeGetRevisionsRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(getRevisionsRequest)
+	r.Method, r.URL.Path = "GET", "/v1/blueprints/"+url.PathEscape(req.Name)+"/revisions"
+	return nil
+}
+
+func decodeGetRevisionsResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp getRevisionsResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeGetRevisionsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(getRevisionsRequest)
+		revisions, err := svc.GetRevisions(ctx, req.Name)
+		return getRevisionsResponse{Revisions: revisions, Err: err}, nil
+	}
+}
+
+func (e Endpoints) GetRevisions(ctx context.Context, name string) ([]Revision, error) {
+	resp, err := e.GetRevisionsEndpoint(ctx, getRevisionsRequest{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(getRevisionsResponse)
+	return response.Revisions, response.Err
+}
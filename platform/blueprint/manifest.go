@@ -0,0 +1,48 @@
+package blueprint
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/micromdm/plist"
+	"github.com/pkg/errors"
+)
+
+// manifestBundleIDs downloads an application manifest and returns the bundle
+// identifiers of its items.
+func manifestBundleIDs(ctx context.Context, client *http.Client, manifestURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download manifest: %s", resp.Status)
+	}
+
+	var manifest struct {
+		Items []struct {
+			Metadata struct {
+				BundleIdentifier string `plist:"bundle-identifier"`
+			} `plist:"metadata"`
+		} `plist:"items"`
+	}
+	if err := plist.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return nil, errors.Wrap(err, "decode manifest")
+	}
+	var ids []string
+	for _, item := range manifest.Items {
+		if item.Metadata.BundleIdentifier != "" {
+			ids = append(ids, item.Metadata.BundleIdentifier)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("manifest has no bundle identifiers")
+	}
+	return ids, nil
+}
@@ -0,0 +1,686 @@
+package blueprint
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/micromdm/plist"
+	"github.com/pkg/errors"
+
+	mdmsvc "github.com/micromdm/micromdm/mdm"
+	"github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/platform/command"
+	"github.com/micromdm/micromdm/platform/device"
+	"github.com/micromdm/micromdm/platform/profile"
+	"github.com/micromdm/micromdm/platform/pubsub"
+)
+
+type ReconcileStore interface {
+	List() ([]Blueprint, error)
+	DEPSerial(serial string) (seen, pending bool, err error)
+	DriftReport(udid string) (*DriftReport, error)
+	SaveDriftReport(r *DriftReport) error
+	DeviceStatuses(udid string) ([]DeviceStatus, error)
+	UpdateDeviceStatus(name, udid string, update func(*DeviceStatus) bool) error
+}
+
+// Reconciler compares the profiles and applications installed on enrolled
+// devices with the ones their blueprints install. Unless it runs in
+// report-only mode it queues InstallProfile and InstallApplication commands
+// for anything missing, and RemoveProfile commands for profiles which were
+// dropped from the blueprints of a device which have Cleanup set. The
+// repairing commands are recorded in the device status of the blueprint they
+// belong to.
+//
+// A reconcile queues ProfileList and InstalledApplicationList commands and
+// completes once the device has responded to both.
+type Reconciler struct {
+	db         ReconcileStore
+	devDB      DeviceStore
+	profileDB  ProfileStore
+	cmdsvc     command.Service
+	sub        pubsub.Subscriber
+	client     *http.Client
+	signer     *profile.Signer
+	vars       *ProfileVariables
+	enc        *ProfileEncrypter
+	logger     log.Logger
+	interval   time.Duration
+	reportOnly bool
+
+	// mu serializes updates of drift reports between API requests and
+	// acknowledge events. It is not held while a report is reconciled, as
+	// that downloads application manifests.
+	mu sync.Mutex
+	// manifests caches the bundle identifiers of application manifests by
+	// URL. The cache is dropped when a reconcile pass starts, so each
+	// manifest is downloaded once per pass rather than once per device.
+	manifests   map[string][]string
+	manifestsMu sync.Mutex
+}
+
+type ReconcilerOption func(*Reconciler)
+
+// WithReconcileInterval reconciles all enrolled devices on a schedule.
+// A value of 0, the default, only reconciles on demand.
+func WithReconcileInterval(d time.Duration) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.interval = d
+	}
+}
+
+// WithReportOnly makes scheduled reconciles only report drift.
+func WithReportOnly(reportOnly bool) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.reportOnly = reportOnly
+	}
+}
+
+// WithManifestClient sets the HTTP client used to download the application
+// manifests of blueprints.
+func WithManifestClient(client *http.Client) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.client = client
+	}
+}
+
+// WithReconcileProfileSigner signs the profiles installed to repair devices.
+func WithReconcileProfileSigner(signer *profile.Signer) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.signer = signer
+	}
+}
+
+// WithReconcileProfileVariables expands the profile variables of the profiles
+// installed to repair devices.
+func WithReconcileProfileVariables(vars *ProfileVariables) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.vars = vars
+	}
+}
+
+// WithReconcileProfileEncrypter encrypts the profiles installed to repair
+// devices which have Encrypt or the EncryptProfiles blueprint option set.
+func WithReconcileProfileEncrypter(enc *ProfileEncrypter) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.enc = enc
+	}
+}
+
+func NewReconciler(
+	db ReconcileStore,
+	devDB DeviceStore,
+	profileDB ProfileStore,
+	cmdsvc command.Service,
+	sub pubsub.Subscriber,
+	logger log.Logger,
+	opts ...ReconcilerOption,
+) *Reconciler {
+	r := &Reconciler{
+		db:        db,
+		devDB:     devDB,
+		profileDB: profileDB,
+		cmdsvc:    cmdsvc,
+		sub:       sub,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		logger:    logger,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *Reconciler) Run(ctx context.Context) error {
+	ackEvents, err := r.sub.Subscribe(ctx, "blueprintReconcile", mdmsvc.ConnectTopic)
+	if err != nil {
+		return errors.Wrapf(err, "subscribing devices to %s topic", mdmsvc.ConnectTopic)
+	}
+	var tick <-chan time.Time
+	if r.interval > 0 {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev := <-ackEvents:
+			err = r.handleAcknowledgeEvent(ctx, ev.Message)
+		case <-tick:
+			_, err = r.Reconcile(ctx, nil, r.reportOnly)
+		}
+
+		if err != nil {
+			level.Info(r.logger).Log(
+				"msg", "reconcile blueprints",
+				"err", err,
+			)
+		}
+	}
+}
+
+// Reconcile requests the inventory of the devices, or of all enrolled devices
+// if no UDIDs are given. It returns the UDIDs of the devices which were sent
+// inventory commands. Unknown and unenrolled devices are skipped.
+func (r *Reconciler) Reconcile(ctx context.Context, udids []string, reportOnly bool) ([]string, error) {
+	r.manifestsMu.Lock()
+	r.manifests = make(map[string][]string)
+	r.manifestsMu.Unlock()
+
+	if len(udids) == 0 {
+		devices, err := r.devDB.List(ctx, device.ListDevicesOption{})
+		if err != nil {
+			return nil, errors.Wrap(err, "list devices")
+		}
+		for _, dev := range devices {
+			if dev.Enrolled && dev.UDID != "" {
+				udids = append(udids, dev.UDID)
+			}
+		}
+	} else {
+		var enrolled []string
+		for _, udid := range udids {
+			dev, err := r.devDB.DeviceByUDID(ctx, udid)
+			if err == nil && dev.Enrolled {
+				enrolled = append(enrolled, udid)
+			}
+		}
+		udids = enrolled
+	}
+
+	var requested []string
+	for _, udid := range udids {
+		if err := r.requestInventory(ctx, udid, reportOnly, time.Now()); err != nil {
+			return requested, errors.Wrapf(err, "request inventory of udid %s", udid)
+		}
+		requested = append(requested, udid)
+	}
+	return requested, nil
+}
+
+func (r *Reconciler) requestInventory(ctx context.Context, udid string, reportOnly bool, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev, err := r.db.DriftReport(udid)
+	if err != nil {
+		return err
+	}
+	report := &DriftReport{
+		UDID:        udid,
+		Status:      DriftRequested,
+		ReportOnly:  reportOnly,
+		RequestedAt: now,
+	}
+	if prev != nil {
+		report.managedProfiles = prev.managedProfiles
+	}
+
+	profileList, err := r.cmdsvc.NewCommand(ctx, &mdm.CommandRequest{
+		UDID:    udid,
+		Command: &mdm.Command{RequestType: "ProfileList"},
+	})
+	if err != nil {
+		return errors.Wrap(err, "queue ProfileList")
+	}
+	appList, err := r.cmdsvc.NewCommand(ctx, &mdm.CommandRequest{
+		UDID: udid,
+		Command: &mdm.Command{
+			RequestType:              "InstalledApplicationList",
+			InstalledApplicationList: &mdm.InstalledApplicationList{},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "queue InstalledApplicationList")
+	}
+	report.profileListUUID = profileList.CommandUUID
+	report.applicationListUUID = appList.CommandUUID
+	return r.db.SaveDriftReport(report)
+}
+
+// inventoryResponse holds the fields of the ProfileList and
+// InstalledApplicationList responses used by the reconciler.
+type inventoryResponse struct {
+	ProfileList []struct {
+		PayloadIdentifier string
+	}
+	InstalledApplicationList []struct {
+		Identifier string
+	}
+}
+
+func (r *Reconciler) handleAcknowledgeEvent(ctx context.Context, message []byte) error {
+	var ev mdmsvc.AcknowledgeEvent
+	if err := mdmsvc.UnmarshalAcknowledgeEvent(message, &ev); err != nil {
+		return errors.Wrap(err, "unmarshal acknowledge event")
+	}
+	resp := ev.Response
+	if resp.UserID != nil || resp.EnrollmentID != nil || resp.CommandUUID == "" || resp.Status == "NotNow" {
+		return nil
+	}
+
+	now := time.Now()
+	report, complete, err := r.recordInventory(&ev, now)
+	if err != nil || !complete {
+		return err
+	}
+	if err := r.reconcile(ctx, report, now); err != nil {
+		report.Status = DriftFailed
+		report.Error = err.Error()
+		report.CompletedAt = now
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, err := r.db.DriftReport(report.UDID)
+	if err != nil {
+		return err
+	}
+	if current == nil || !current.RequestedAt.Equal(report.RequestedAt) {
+		// a new inventory was requested while the report was reconciled.
+		return nil
+	}
+	return r.db.SaveDriftReport(report)
+}
+
+// recordInventory saves the inventory response to the drift report of the
+// device. It returns the report and true once the device responded to both
+// inventory commands and the report is ready to be reconciled.
+func (r *Reconciler) recordInventory(ev *mdmsvc.AcknowledgeEvent, now time.Time) (*DriftReport, bool, error) {
+	resp := ev.Response
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report, err := r.db.DriftReport(resp.UDID)
+	if err != nil {
+		return nil, false, err
+	}
+	if report == nil || report.Status != DriftRequested {
+		return nil, false, nil
+	}
+	isProfileList := resp.CommandUUID == report.profileListUUID
+	isApplicationList := resp.CommandUUID == report.applicationListUUID
+	if !isProfileList && !isApplicationList {
+		return nil, false, nil
+	}
+
+	if resp.Status != "Acknowledged" {
+		report.Status = DriftFailed
+		report.Error = fmt.Sprintf("command %s returned status %s", resp.CommandUUID, resp.Status)
+		report.CompletedAt = now
+		report.profileListUUID, report.applicationListUUID = "", ""
+		return nil, false, r.db.SaveDriftReport(report)
+	}
+
+	var inventory inventoryResponse
+	if err := plist.Unmarshal(ev.Raw, &inventory); err != nil {
+		return nil, false, errors.Wrap(err, "unmarshal inventory response")
+	}
+	if isProfileList {
+		report.InstalledProfiles = nil
+		for _, p := range inventory.ProfileList {
+			report.InstalledProfiles = append(report.InstalledProfiles, p.PayloadIdentifier)
+		}
+		report.profileListUUID = ""
+	} else {
+		report.InstalledApplications = nil
+		for _, app := range inventory.InstalledApplicationList {
+			report.InstalledApplications = append(report.InstalledApplications, app.Identifier)
+		}
+		report.applicationListUUID = ""
+	}
+	complete := report.profileListUUID == "" && report.applicationListUUID == ""
+	return report, complete, r.db.SaveDriftReport(report)
+}
+
+// reconcile compares the inventory of the device with its blueprints and
+// queues the repairing commands unless the report is report-only.
+func (r *Reconciler) reconcile(ctx context.Context, report *DriftReport, now time.Time) error {
+	dev, err := r.devDB.DeviceByUDID(ctx, report.UDID)
+	if err != nil {
+		return errors.Wrap(err, "get device")
+	}
+	bps, err := r.deviceBlueprints(dev)
+	if err != nil {
+		return err
+	}
+
+	var profileIDs, manifestURLs []string
+	encrypted := make(map[string]bool)
+	for _, bp := range bps {
+		profileIDs = appendUnique(profileIDs, bp.ProfileIdentifiers...)
+		manifestURLs = appendUnique(manifestURLs, bp.ApplicationURLs...)
+		if bp.EncryptProfiles {
+			for _, id := range bp.ProfileIdentifiers {
+				encrypted[id] = true
+			}
+		}
+	}
+	installedProfiles := stringSet(report.InstalledProfiles)
+	installedApps := stringSet(report.InstalledApplications)
+	desiredProfiles := stringSet(profileIDs)
+
+	report.MissingProfiles, report.MissingApplications, report.RemovedProfiles = nil, nil, nil
+	for _, id := range profileIDs {
+		if !installedProfiles[id] {
+			report.MissingProfiles = append(report.MissingProfiles, id)
+		}
+	}
+	for _, id := range report.managedProfiles {
+		if !desiredProfiles[id] && installedProfiles[id] {
+			report.RemovedProfiles = append(report.RemovedProfiles, id)
+		}
+	}
+	for _, manifestURL := range manifestURLs {
+		bundleIDs, err := r.manifestBundleIDs(ctx, manifestURL)
+		if err != nil {
+			level.Info(r.logger).Log(
+				"msg", "get bundle identifiers from application manifest",
+				"manifest_url", manifestURL,
+				"err", err,
+			)
+			continue
+		}
+		for _, id := range bundleIDs {
+			if !installedApps[id] {
+				report.MissingApplications = append(report.MissingApplications, manifestURL)
+				break
+			}
+		}
+	}
+
+	report.Status = DriftReported
+	report.CompletedAt = now
+	if report.ReportOnly {
+		// keep tracking the dropped profiles which were not removed.
+		report.managedProfiles = appendUnique(profileIDs, report.managedProfiles...)
+		return nil
+	}
+	report.managedProfiles = profileIDs
+	return r.repair(ctx, report, bps, encrypted)
+}
+
+// manifestBundleIDs returns the bundle identifiers of an application manifest,
+// downloading the manifest once per reconcile pass.
+func (r *Reconciler) manifestBundleIDs(ctx context.Context, manifestURL string) ([]string, error) {
+	r.manifestsMu.Lock()
+	ids, ok := r.manifests[manifestURL]
+	r.manifestsMu.Unlock()
+	if ok {
+		return ids, nil
+	}
+	ids, err := manifestBundleIDs(ctx, r.client, manifestURL)
+	if err != nil {
+		return nil, err
+	}
+	r.manifestsMu.Lock()
+	if r.manifests != nil {
+		r.manifests[manifestURL] = ids
+	}
+	r.manifestsMu.Unlock()
+	return ids, nil
+}
+
+// repairRequest is a command fixing drift and the blueprint item it belongs
+// to.
+type repairRequest struct {
+	*mdm.CommandRequest
+	blueprint string
+	item      string
+}
+
+// repair queues the commands fixing the drift of the report, and records them
+// in the device statuses of the blueprints in bps they belong to. Missing
+// profiles in encrypted are encrypted to the device. Items with an install
+// command which is still queued are not queued again. Dropped profiles are
+// only removed if the blueprint which installed them has Cleanup set.
+func (r *Reconciler) repair(ctx context.Context, report *DriftReport, bps []Blueprint, encrypted map[string]bool) error {
+	cleanup, err := r.cleanupOwners(report.UDID, report.RemovedProfiles)
+	if err != nil {
+		return err
+	}
+	pending, err := r.queuedInstalls(report.UDID)
+	if err != nil {
+		return err
+	}
+	var requests []repairRequest
+	for _, id := range report.MissingProfiles {
+		if pending[id] {
+			continue
+		}
+		p, err := r.profileDB.ProfileById(ctx, id)
+		if err != nil {
+			level.Info(r.logger).Log(
+				"msg", "retrieve missing profile from db",
+				"profile_identifier", id,
+				"device_udid", report.UDID,
+				"err", err,
+			)
+			continue
+		}
+		if encrypted[id] && !p.Encrypt {
+			cp := *p
+			cp.Encrypt = true
+			p = &cp
+		}
+		payload, err := installPayload(ctx, r.vars, r.enc, r.signer, report.UDID, "", p)
+		if err != nil {
+			level.Info(r.logger).Log(
+				"msg", "create missing profile payload",
+				"profile_identifier", id,
+				"device_udid", report.UDID,
+				"err", err,
+			)
+			continue
+		}
+		requests = append(requests, repairRequest{
+			CommandRequest: &mdm.CommandRequest{
+				UDID: report.UDID,
+				Command: &mdm.Command{
+					RequestType:    "InstallProfile",
+					InstallProfile: &mdm.InstallProfile{Payload: payload},
+				},
+			},
+			blueprint: profileOwner(bps, id),
+			item:      id,
+		})
+	}
+	for i := range report.MissingApplications {
+		manifestURL := report.MissingApplications[i]
+		if pending[manifestURL] {
+			continue
+		}
+		requests = append(requests, repairRequest{
+			CommandRequest: &mdm.CommandRequest{
+				UDID: report.UDID,
+				Command: &mdm.Command{
+					RequestType: "InstallApplication",
+					InstallApplication: &mdm.InstallApplication{
+						ManifestURL:     &manifestURL,
+						ManagementFlags: intPtr(1),
+					},
+				},
+			},
+			blueprint: applicationOwner(bps, manifestURL),
+			item:      manifestURL,
+		})
+	}
+	for _, id := range report.RemovedProfiles {
+		owner, ok := cleanup[id]
+		if !ok {
+			continue
+		}
+		requests = append(requests, repairRequest{
+			CommandRequest: &mdm.CommandRequest{
+				UDID: report.UDID,
+				Command: &mdm.Command{
+					RequestType:   "RemoveProfile",
+					RemoveProfile: &mdm.RemoveProfile{Identifier: id},
+				},
+			},
+			blueprint: owner,
+			item:      id,
+		})
+	}
+
+	now := time.Now()
+	queued := make(map[string][]CommandStatus)
+	var order []string
+	for _, req := range requests {
+		payload, err := r.cmdsvc.NewCommand(ctx, req.CommandRequest)
+		if err != nil {
+			return errors.Wrapf(err, "queue %s", req.Command.RequestType)
+		}
+		report.Queued = append(report.Queued, payload.CommandUUID)
+		if req.blueprint == "" {
+			continue
+		}
+		if _, ok := queued[req.blueprint]; !ok {
+			order = append(order, req.blueprint)
+		}
+		queued[req.blueprint] = append(queued[req.blueprint], CommandStatus{
+			CommandUUID: payload.CommandUUID,
+			RequestType: req.Command.RequestType,
+			Item:        req.item,
+			Status:      CommandQueued,
+			UpdatedAt:   now,
+		})
+	}
+	for _, name := range order {
+		err := r.db.UpdateDeviceStatus(name, report.UDID, func(s *DeviceStatus) bool {
+			s.mergeCommands(queued[name])
+			return true
+		})
+		if err != nil {
+			return errors.Wrapf(err, "save status of blueprint %s", name)
+		}
+	}
+	return nil
+}
+
+// cleanupOwners returns the blueprints with Cleanup set which installed the
+// dropped profiles on the device, by profile identifier. Profiles of
+// blueprints without Cleanup are left on the device.
+func (r *Reconciler) cleanupOwners(udid string, dropped []string) (map[string]string, error) {
+	owners := make(map[string]string)
+	if len(dropped) == 0 {
+		return owners, nil
+	}
+	bps, err := r.db.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "list blueprints")
+	}
+	cleanup := make(map[string]bool)
+	for _, bp := range bps {
+		cleanup[bp.Name] = bp.Cleanup
+	}
+	statuses, err := r.db.DeviceStatuses(udid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get blueprint statuses of udid %s", udid)
+	}
+	for _, s := range statuses {
+		if !cleanup[s.BlueprintName] {
+			continue
+		}
+		for _, c := range s.Commands {
+			if c.RequestType == "InstallProfile" && installed(c.Status) && containsString(dropped, c.Item) {
+				owners[c.Item] = s.BlueprintName
+			}
+		}
+	}
+	return owners, nil
+}
+
+// queuedInstalls returns the items of the InstallProfile and
+// InstallApplication commands in the blueprint statuses of the device which
+// the device has not responded to yet.
+func (r *Reconciler) queuedInstalls(udid string) (map[string]bool, error) {
+	statuses, err := r.db.DeviceStatuses(udid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get blueprint statuses of udid %s", udid)
+	}
+	pending := make(map[string]bool)
+	for _, s := range statuses {
+		for _, c := range s.Commands {
+			if c.Status != CommandQueued {
+				continue
+			}
+			if c.RequestType == "InstallProfile" || c.RequestType == "InstallApplication" {
+				pending[c.Item] = true
+			}
+		}
+	}
+	return pending, nil
+}
+
+// profileOwner returns the name of the first blueprint installing the profile.
+func profileOwner(bps []Blueprint, id string) string {
+	for _, bp := range bps {
+		if containsString(bp.ProfileIdentifiers, id) {
+			return bp.Name
+		}
+	}
+	return ""
+}
+
+// applicationOwner returns the name of the first blueprint installing the
+// application manifest.
+func applicationOwner(bps []Blueprint, manifestURL string) string {
+	for _, bp := range bps {
+		if containsString(bp.ApplicationURLs, manifestURL) {
+			return bp.Name
+		}
+	}
+	return ""
+}
+
+// deviceBlueprints returns the blueprints which are applied to the device
+// automatically. Manual blueprints are not reconciled.
+func (r *Reconciler) deviceBlueprints(dev *device.Device) ([]Blueprint, error) {
+	bps, err := r.db.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "list blueprints")
+	}
+	var depSynced bool
+	if dev.SerialNumber != "" {
+		if depSynced, _, err = r.db.DEPSerial(dev.SerialNumber); err != nil {
+			return nil, errors.Wrapf(err, "get DEP serial %s", dev.SerialNumber)
+		}
+	}
+	var applied []Blueprint
+	for _, bp := range bps {
+		automatic := bp.HasApplyAt(ApplyAtEnroll) || bp.HasApplyAt(ApplyAtCheckin) ||
+			(depSynced && bp.HasApplyAt(ApplyAtDEPSync))
+		if automatic && bp.Scope.Matches(dev) {
+			applied = append(applied, bp)
+		}
+	}
+	return applied, nil
+}
+
+func stringSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, s := range list {
+		set[s] = true
+	}
+	return set
+}
+
+// appendUnique appends the values which are not in list yet.
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		if !containsString(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,71 @@
+package blueprint
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// ReconcileOptions selects the devices to reconcile. No UDIDs reconciles all
+// enrolled devices.
+type ReconcileOptions struct {
+	UDIDs      []string `json:"udids,omitempty"`
+	ReportOnly bool     `json:"report_only"`
+}
+
+// DriftReconciler requests the inventory of devices to compare it with their
+// blueprints.
+type DriftReconciler interface {
+	Reconcile(ctx context.Context, udids []string, reportOnly bool) ([]string, error)
+}
+
+func (svc *BlueprintService) ReconcileDevices(ctx context.Context, opt ReconcileOptions) ([]string, error) {
+	if svc.reconciler == nil {
+		return nil, errors.New("reconciling blueprints is not supported")
+	}
+	return svc.reconciler.Reconcile(ctx, opt.UDIDs, opt.ReportOnly)
+}
+
+type reconcileDevicesRequest struct {
+	Opts ReconcileOptions `json:"opts"`
+}
+
+type reconcileDevicesResponse struct {
+	Requested []string `json:"requested"`
+	Err       error    `json:"err,omitempty"`
+}
+
+func (r reconcileDevicesResponse) Failed() error { return r.Err }
+
+func decodeReconcileDevicesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req reconcileDevicesRequest
+	err := httputil.DecodeJSONRequest(r, &req)
+	return req, err
+}
+
+func decodeReconcileDevicesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp reconcileDevicesResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeReconcileDevicesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(reconcileDevicesRequest)
+		requested, err := svc.ReconcileDevices(ctx, req.Opts)
+		return reconcileDevicesResponse{Requested: requested, Err: err}, nil
+	}
+}
+
+func (e Endpoints) ReconcileDevices(ctx context.Context, opt ReconcileOptions) ([]string, error) {
+	resp, err := e.ReconcileDevicesEndpoint(ctx, reconcileDevicesRequest{Opts: opt})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(reconcileDevicesResponse)
+	return response.Requested, response.Err
+}
@@ -0,0 +1,124 @@
+package blueprint
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/micromdm/micromdm/platform/device"
+)
+
+type reconcileTestStore struct {
+	ReconcileStore
+	blueprints []Blueprint
+	statuses   []DeviceStatus
+}
+
+func (s *reconcileTestStore) List() ([]Blueprint, error) { return s.blueprints, nil }
+
+func (s *reconcileTestStore) DeviceStatuses(udid string) ([]DeviceStatus, error) {
+	return s.statuses, nil
+}
+
+func (s *reconcileTestStore) DEPSerial(serial string) (bool, bool, error) { return false, false, nil }
+
+type reconcileTestDevices struct {
+	DeviceStore
+	dev *device.Device
+}
+
+func (s *reconcileTestDevices) DeviceByUDID(ctx context.Context, udid string) (*device.Device, error) {
+	return s.dev, nil
+}
+
+func TestReconcileReportOnly(t *testing.T) {
+	store := &reconcileTestStore{blueprints: []Blueprint{
+		{Name: "enroll", ApplyAt: []string{"Enroll"}, ProfileIdentifiers: []string{"com.example.wifi", "com.example.vpn"}},
+		{Name: "lab", ApplyAt: []string{"Enroll"}, ProfileIdentifiers: []string{"com.example.lab"},
+			Scope: &Scope{Models: []string{"iPad*"}}},
+		{Name: "manual", ApplyAt: []string{"Manual"}, ProfileIdentifiers: []string{"com.example.manual"}},
+	}}
+	devices := &reconcileTestDevices{dev: &device.Device{UDID: "udid", Model: "MacBookPro15,1", Enrolled: true}}
+	r := NewReconciler(store, devices, nil, nil, nil, nil)
+
+	report := &DriftReport{
+		UDID:              "udid",
+		Status:            DriftRequested,
+		ReportOnly:        true,
+		InstalledProfiles: []string{"com.example.wifi", "com.example.old"},
+		managedProfiles:   []string{"com.example.wifi", "com.example.old"},
+	}
+	if err := r.reconcile(context.Background(), report, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := report.MissingProfiles, []string{"com.example.vpn"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have missing profiles %v, want %v", have, want)
+	}
+	if have, want := report.RemovedProfiles, []string{"com.example.old"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have removed profiles %v, want %v", have, want)
+	}
+	if len(report.Queued) != 0 {
+		t.Errorf("report-only reconcile queued %d commands", len(report.Queued))
+	}
+	if !containsString(report.managedProfiles, "com.example.old") {
+		t.Error("report-only reconcile must keep tracking dropped profiles")
+	}
+	if report.Status != DriftReported {
+		t.Errorf("have status %s, want %s", report.Status, DriftReported)
+	}
+}
+
+func TestReconcileCleanupOwners(t *testing.T) {
+	store := &reconcileTestStore{
+		blueprints: []Blueprint{
+			{Name: "cleanup", Cleanup: true},
+			{Name: "keep"},
+		},
+		statuses: []DeviceStatus{
+			{BlueprintName: "cleanup", Commands: []CommandStatus{
+				{RequestType: "InstallProfile", Item: "com.example.old", Status: "Acknowledged"},
+				{RequestType: "InstallProfile", Item: "com.example.failed", Status: "Error"},
+			}},
+			{BlueprintName: "keep", Commands: []CommandStatus{
+				{RequestType: "InstallProfile", Item: "com.example.kept", Status: "Acknowledged"},
+			}},
+			{BlueprintName: "deleted", Commands: []CommandStatus{
+				{RequestType: "InstallProfile", Item: "com.example.orphan", Status: "Acknowledged"},
+			}},
+		},
+	}
+	r := NewReconciler(store, nil, nil, nil, nil, nil)
+
+	dropped := []string{"com.example.old", "com.example.failed", "com.example.kept", "com.example.orphan"}
+	owners, err := r.cleanupOwners("udid", dropped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"com.example.old": "cleanup"}; !reflect.DeepEqual(owners, want) {
+		t.Errorf("have owners %v, want %v", owners, want)
+	}
+}
+
+func TestReconcileQueuedInstalls(t *testing.T) {
+	store := &reconcileTestStore{
+		statuses: []DeviceStatus{
+			{BlueprintName: "enroll", Commands: []CommandStatus{
+				{RequestType: "InstallProfile", Item: "com.example.queued", Status: CommandQueued},
+				{RequestType: "InstallProfile", Item: "com.example.failed", Status: "Error"},
+				{RequestType: "InstallApplication", Item: "https://example.com/app.plist", Status: CommandQueued},
+				{RequestType: "RemoveProfile", Item: "com.example.old", Status: CommandQueued},
+			}},
+		},
+	}
+	r := NewReconciler(store, nil, nil, nil, nil, nil)
+
+	pending, err := r.queuedInstalls("udid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"com.example.queued": true, "https://example.com/app.plist": true}
+	if !reflect.DeepEqual(pending, want) {
+		t.Errorf("have queued installs %v, want %v", pending, want)
+	}
+}
@@ -0,0 +1,116 @@
+package blueprint
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/platform/profile"
+)
+
+// reinstallTarget is a device or user channel to install a profile on again,
+// and the blueprint whose status records the profile, if any.
+type reinstallTarget struct {
+	udid      string
+	blueprint *Blueprint
+	// deviceUDID is the device of a user channel.
+	deviceUDID string
+}
+
+// ReinstallProfile queues an InstallProfile command for the profile on every
+// device which has it installed, and returns the UDIDs of the devices. The
+// devices are taken from the blueprint statuses which install the profile,
+// including commands queued again by an earlier reinstall, and from the
+// ProfileList inventory of the drift reports, which also lists profiles
+// installed without a blueprint. The device status records of the blueprints
+// track the new commands.
+func (w *Worker) ReinstallProfile(ctx context.Context, p *profile.Profile) ([]string, error) {
+	targets, err := w.reinstallTargets(p.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	var udids []string
+	for _, t := range targets {
+		installed := p
+		if t.blueprint != nil {
+			installed = blueprintProfile(t.blueprint, p)
+		}
+		// statuses of user profiles are keyed by the UserID of the user.
+		deviceUDID, userID := t.udid, ""
+		if t.deviceUDID != "" {
+			deviceUDID, userID = t.deviceUDID, t.udid
+		}
+		payload, err := installPayload(ctx, w.vars, w.enc, w.signer, deviceUDID, userID, installed)
+		if err != nil {
+			return udids, errors.Wrapf(err, "create profile payload for udid %s", t.udid)
+		}
+		cmd, err := w.cmdsvc.NewCommand(ctx, &mdm.CommandRequest{
+			UDID: t.udid,
+			Command: &mdm.Command{
+				RequestType:    "InstallProfile",
+				InstallProfile: &mdm.InstallProfile{Payload: payload},
+			},
+		})
+		if err != nil {
+			return udids, errors.Wrapf(err, "queue InstallProfile for udid %s", t.udid)
+		}
+		if t.blueprint != nil {
+			err = w.db.UpdateDeviceStatus(t.blueprint.Name, t.udid, func(s *DeviceStatus) bool {
+				return s.requeue(p.Identifier, "InstallProfile", cmd.CommandUUID, time.Now())
+			})
+			if err != nil {
+				return udids, errors.Wrap(err, "save device status")
+			}
+		}
+		udids = append(udids, t.udid)
+	}
+	return udids, nil
+}
+
+// reinstallTargets returns the devices and user channels which have the
+// profile installed, once each.
+func (w *Worker) reinstallTargets(identifier string) ([]reinstallTarget, error) {
+	bps, err := w.db.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "list blueprints")
+	}
+	var targets []reinstallTarget
+	seen := make(map[string]bool)
+	for i := range bps {
+		bp := &bps[i]
+		statuses, err := w.db.BlueprintStatuses(bp.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get device statuses of blueprint %s", bp.Name)
+		}
+		for _, status := range statuses {
+			if seen[status.UDID] {
+				continue
+			}
+			for _, c := range status.Commands {
+				if c.RequestType == "InstallProfile" && c.Item == identifier && installed(c.Status) {
+					seen[status.UDID] = true
+					targets = append(targets, reinstallTarget{
+						udid:       status.UDID,
+						blueprint:  bp,
+						deviceUDID: status.DeviceUDID,
+					})
+					break
+				}
+			}
+		}
+	}
+
+	reports, err := w.db.DriftReports()
+	if err != nil {
+		return nil, errors.Wrap(err, "get drift reports")
+	}
+	for _, r := range reports {
+		if !seen[r.UDID] && containsString(r.InstalledProfiles, identifier) {
+			seen[r.UDID] = true
+			targets = append(targets, reinstallTarget{udid: r.UDID})
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,179 @@
+package blueprint
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/micromdm/micromdm/platform/blueprint/internal/blueprintproto"
+)
+
+// Release states of a device which is awaiting configuration.
+const (
+	// ReleasePending waits for the queued commands to be acknowledged.
+	ReleasePending = "Pending"
+	// ReleaseReleased means DeviceConfigured was queued for the device.
+	ReleaseReleased = "Released"
+	// ReleaseFailed means more commands failed than allowed. The device is
+	// released when the deadline passes.
+	ReleaseFailed = "Failed"
+)
+
+// Release tracks the commands queued by the Enroll blueprints of a device
+// which is awaiting configuration. DeviceConfigured is sent once every
+// command was acknowledged, or when the deadline passes.
+type Release struct {
+	UDID            string    `json:"udid"`
+	Blueprints      []string  `json:"blueprints"`
+	Pending         []string  `json:"pending,omitempty"`
+	Acknowledged    []string  `json:"acknowledged,omitempty"`
+	Failed          []string  `json:"failed,omitempty"`
+	AllowedFailures int       `json:"allowed_failures"`
+	Deadline        time.Time `json:"deadline,omitempty"`
+	Status          string    `json:"status"`
+	Reason          string    `json:"reason,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	ReleasedAt      time.Time `json:"released_at,omitempty"`
+}
+
+// defaultReleaseTimeout is the deadline of releases whose blueprints were
+// saved before release_timeout was required with release_on_completion.
+const defaultReleaseTimeout = time.Hour
+
+// newRelease creates a Release for the commands queued by the blueprints, or
+// returns nil if none of the blueprints has ReleaseOnCompletion set. The
+// longest timeout and the highest number of allowed failures of the
+// blueprints apply. Every release has a deadline, so a Failed release is
+// still released eventually.
+func newRelease(udid string, bps []Blueprint, commandUUIDs []string, now time.Time) *Release {
+	var (
+		release bool
+		names   []string
+		timeout time.Duration
+		allowed int
+	)
+	for i := range bps {
+		names = append(names, bps[i].Name)
+		if !bps[i].ReleaseOnCompletion {
+			continue
+		}
+		release = true
+		if d := bps[i].releaseTimeout(); d > timeout {
+			timeout = d
+		}
+		if bps[i].ReleaseAllowedFailures > allowed {
+			allowed = bps[i].ReleaseAllowedFailures
+		}
+	}
+	if !release {
+		return nil
+	}
+	r := &Release{
+		UDID:            udid,
+		Blueprints:      names,
+		Pending:         commandUUIDs,
+		AllowedFailures: allowed,
+		Status:          ReleasePending,
+		CreatedAt:       now,
+	}
+	if timeout <= 0 {
+		timeout = defaultReleaseTimeout
+	}
+	r.Deadline = now.Add(timeout)
+	return r
+}
+
+// acknowledge records the response to a command. It reports whether the
+// command is tracked by the release and was completed.
+func (r *Release) acknowledge(commandUUID, status string) bool {
+	for i, uuid := range r.Pending {
+		if uuid != commandUUID {
+			continue
+		}
+		switch status {
+		case "Acknowledged":
+			r.Acknowledged = append(r.Acknowledged, uuid)
+		case "Error", "CommandFormatError":
+			r.Failed = append(r.Failed, uuid)
+		default:
+			// NotNow and Idle leave the command outstanding.
+			return false
+		}
+		r.Pending = append(r.Pending[:i], r.Pending[i+1:]...)
+		return true
+	}
+	return false
+}
+
+// ready updates the status of the release and reports whether the device
+// should be released now.
+func (r *Release) ready(now time.Time) bool {
+	if r.Status == ReleaseReleased {
+		return false
+	}
+	if !r.Deadline.IsZero() && !now.Before(r.Deadline) {
+		r.Reason = "timeout"
+		return true
+	}
+	if len(r.Failed) > r.AllowedFailures {
+		r.Status = ReleaseFailed
+		r.Reason = fmt.Sprintf("%d commands failed, %d allowed", len(r.Failed), r.AllowedFailures)
+		return false
+	}
+	if len(r.Pending) == 0 {
+		r.Reason = "completed"
+		return true
+	}
+	return false
+}
+
+func MarshalRelease(r *Release) ([]byte, error) {
+	pb := blueprintproto.Release{
+		Udid:            r.UDID,
+		Blueprints:      r.Blueprints,
+		Pending:         r.Pending,
+		Acknowledged:    r.Acknowledged,
+		Failed:          r.Failed,
+		AllowedFailures: int32(r.AllowedFailures),
+		Deadline:        timeToProto(r.Deadline),
+		Status:          r.Status,
+		Reason:          r.Reason,
+		CreatedAt:       timeToProto(r.CreatedAt),
+		ReleasedAt:      timeToProto(r.ReleasedAt),
+	}
+	return proto.Marshal(&pb)
+}
+
+func UnmarshalRelease(data []byte, r *Release) error {
+	var pb blueprintproto.Release
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+	r.UDID = pb.GetUdid()
+	r.Blueprints = pb.GetBlueprints()
+	r.Pending = pb.GetPending()
+	r.Acknowledged = pb.GetAcknowledged()
+	r.Failed = pb.GetFailed()
+	r.AllowedFailures = int(pb.GetAllowedFailures())
+	r.Deadline = timeFromProto(pb.GetDeadline())
+	r.Status = pb.GetStatus()
+	r.Reason = pb.GetReason()
+	r.CreatedAt = timeFromProto(pb.GetCreatedAt())
+	r.ReleasedAt = timeFromProto(pb.GetReleasedAt())
+	return nil
+}
+
+func timeToProto(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func timeFromProto(nsec int64) time.Time {
+	if nsec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nsec).UTC()
+}
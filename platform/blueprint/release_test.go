@@ -0,0 +1,63 @@
+package blueprint
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRelease(t *testing.T) {
+	now := time.Now()
+	bps := []Blueprint{
+		{Name: "apps"},
+		{Name: "setup", ReleaseOnCompletion: true, ReleaseTimeout: "1h", ReleaseAllowedFailures: 1},
+	}
+	if r := newRelease("udid", bps[:1], []string{"a"}, now); r != nil {
+		t.Fatal("expected no release without ReleaseOnCompletion")
+	}
+
+	r := newRelease("udid", bps, []string{"a", "b", "c"}, now)
+	if r == nil {
+		t.Fatal("expected release")
+	}
+	if have, want := r.Deadline, now.Add(time.Hour); !have.Equal(want) {
+		t.Errorf("have deadline %s, want %s", have, want)
+	}
+
+	if r.acknowledge("a", "NotNow") {
+		t.Error("NotNow must leave the command pending")
+	}
+	r.acknowledge("a", "Acknowledged")
+	r.acknowledge("b", "Error")
+	if r.ready(now) {
+		t.Fatal("release must wait for pending commands")
+	}
+	r.acknowledge("c", "Error")
+	if r.ready(now) || r.Status != ReleaseFailed {
+		t.Fatalf("have status %s, want %s", r.Status, ReleaseFailed)
+	}
+	if !r.ready(r.Deadline) || r.Reason != "timeout" {
+		t.Fatalf("expected release at deadline, have reason %q", r.Reason)
+	}
+
+	// Blueprints saved before release_timeout was required still get a
+	// deadline, so a failed release is not held forever.
+	legacy := newRelease("udid", []Blueprint{{Name: "old", ReleaseOnCompletion: true}}, []string{"a"}, now)
+	if have, want := legacy.Deadline, now.Add(defaultReleaseTimeout); !have.Equal(want) {
+		t.Errorf("have legacy deadline %s, want %s", have, want)
+	}
+	if err := (&Blueprint{Name: "old", UUID: "uuid", ReleaseOnCompletion: true}).Verify(); err == nil {
+		t.Error("expected release_on_completion without release_timeout to be rejected")
+	}
+
+	data, err := MarshalRelease(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Release
+	if err := UnmarshalRelease(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Deadline.Equal(r.Deadline) || len(loaded.Failed) != 2 || !loaded.ReleasedAt.IsZero() {
+		t.Errorf("release not unmarshaled correctly: %+v", loaded)
+	}
+}
@@ -10,7 +10,17 @@ import (
 
 func (svc *BlueprintService) RemoveBlueprints(ctx context.Context, names []string) error {
 	for _, name := range names {
-		err := svc.store.Delete(name)
+		bp, err := svc.store.BlueprintByName(name)
+		if err != nil {
+			return err
+		}
+		if bp.Cleanup {
+			err := svc.cleanupDevices(ctx, name, nil)
+			if err != nil {
+				return err
+			}
+		}
+		err = svc.store.Delete(name)
 		if err != nil {
 			return err
 		}
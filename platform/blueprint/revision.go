@@ -0,0 +1,99 @@
+package blueprint
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/micromdm/micromdm/platform/blueprint/internal/blueprintproto"
+)
+
+// Revision is an immutable copy of a Blueprint, recorded every time the
+// blueprint is applied. Versions start at 1 and increase with every apply.
+type Revision struct {
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	// APIKey is a fingerprint of the API key which applied the revision.
+	APIKey    string    `json:"api_key,omitempty"`
+	Blueprint Blueprint `json:"blueprint"`
+}
+
+// FieldChange is a changed JSON field of a Blueprint. Old or New is empty if
+// the field was added or removed.
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+// Diff returns the JSON fields which differ between two blueprints, sorted by
+// field name.
+func Diff(from, to *Blueprint) ([]FieldChange, error) {
+	oldFields, err := jsonFields(from)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := jsonFields(to)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for name := range oldFields {
+		names[name] = true
+	}
+	for name := range newFields {
+		names[name] = true
+	}
+
+	var changes []FieldChange
+	for name := range names {
+		o, n := oldFields[name], newFields[name]
+		if bytes.Equal(o, n) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, Old: o, New: n})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+func jsonFields(bp *Blueprint) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(bp)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+func MarshalRevision(r *Revision) ([]byte, error) {
+	bp, err := blueprintToProto(&r.Blueprint)
+	if err != nil {
+		return nil, err
+	}
+	pb := blueprintproto.Revision{
+		Version:   int64(r.Version),
+		Time:      timeToProto(r.Time),
+		ApiKey:    r.APIKey,
+		Blueprint: bp,
+	}
+	return proto.Marshal(&pb)
+}
+
+func UnmarshalRevision(data []byte, r *Revision) error {
+	var pb blueprintproto.Revision
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+	r.Version = int(pb.GetVersion())
+	r.Time = timeFromProto(pb.GetTime())
+	r.APIKey = pb.GetApiKey()
+	if pb.GetBlueprint() == nil {
+		return nil
+	}
+	return blueprintFromProto(pb.GetBlueprint(), &r.Blueprint)
+}
@@ -0,0 +1,84 @@
+package blueprint
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// RollbackBlueprint applies the blueprint of an earlier revision again, which
+// records it as a new revision.
+func (svc *BlueprintService) RollbackBlueprint(ctx context.Context, name string, version int) (*Revision, error) {
+	rev, err := svc.store.Revision(name, version)
+	if err != nil {
+		return nil, err
+	}
+	bp := rev.Blueprint
+	// keep the UUID of the current blueprint in case it was removed and
+	// applied again since the revision.
+	if current, err := svc.store.BlueprintByName(name); err == nil {
+		bp.UUID = current.UUID
+	}
+	return svc.saveRevision(ctx, &bp)
+}
+
+type rollbackBlueprintRequest struct {
+	Name    string `json:"-"`
+	Version int    `json:"version"`
+}
+
+type rollbackBlueprintResponse struct {
+	Revision *Revision `json:"revision,omitempty"`
+	Err      error     `json:"err,omitempty"`
+}
+
+func (r rollbackBlueprintResponse) Failed() error { return r.Err }
+
+func decodeRollbackBlueprintRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req rollbackBlueprintRequest
+	if err := httputil.DecodeJSONRequest(r, &req); err != nil {
+		return nil, err
+	}
+	name, ok := mux.Vars(r)["name"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	req.Name = name
+	return req, nil
+}
+
+func encodeRollbackBlueprintRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(rollbackBlueprintRequest)
+	r.Method, r.URL.Path = "POST", "/v1/blueprints/"+url.PathEscape(req.Name)+"/rollback"
+	return httptransport.EncodeJSONRequest(ctx, r, request)
+}
+
+func decodeRollbackBlueprintResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp rollbackBlueprintResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeRollbackBlueprintEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(rollbackBlueprintRequest)
+		rev, err := svc.RollbackBlueprint(ctx, req.Name, req.Version)
+		return rollbackBlueprintResponse{Revision: rev, Err: err}, nil
+	}
+}
+
+func (e Endpoints) RollbackBlueprint(ctx context.Context, name string, version int) (*Revision, error) {
+	resp, err := e.RollbackBlueprintEndpoint(ctx, rollbackBlueprintRequest{Name: name, Version: version})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(rollbackBlueprintResponse)
+	return response.Revision, response.Err
+}
@@ -0,0 +1,204 @@
+package blueprint
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/micromdm/plist"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	mdmsvc "github.com/micromdm/micromdm/mdm"
+	"github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/platform/blueprint/internal/blueprintproto"
+	"github.com/micromdm/micromdm/platform/user"
+)
+
+// PasswordRotation is a password rotation waiting for the response to the
+// UserList command queued to look up the GUID of the account on the device.
+type PasswordRotation struct {
+	CommandUUID string    `json:"command_uuid"`
+	UDID        string    `json:"udid"`
+	UserUUID    string    `json:"user_uuid"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RotateAdminPassword queues a SetAutoAdminPassword command with the password
+// hash of the user for every device which acknowledged creating the user as
+// an admin account through a blueprint. The command needs the GUID of the
+// account on the device. It is known once the user has checked in on the user
+// channel of the device, which hidden admin accounts may never do. Other
+// devices are sent a UserList command, and are returned as pending until the
+// response has the GUID and the password is sent.
+func (w *Worker) RotateAdminPassword(ctx context.Context, u *user.User) (queued, pending []string, err error) {
+	bps, err := w.db.List()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "list blueprints")
+	}
+	for _, bp := range bps {
+		if !containsString(bp.UserUUID, u.UUID) {
+			continue
+		}
+		statuses, err := w.db.BlueprintStatuses(bp.Name)
+		if err != nil {
+			return queued, pending, errors.Wrapf(err, "get device statuses of blueprint %s", bp.Name)
+		}
+		for _, status := range statuses {
+			udid := status.UDID
+			if !accountConfigured(status, u.UUID) || containsString(queued, udid) || containsString(pending, udid) {
+				continue
+			}
+			guid, err := w.accountGUID(udid, u.UserShortname)
+			if err != nil {
+				return queued, pending, err
+			}
+			if guid == "" {
+				if err := w.requestUserList(ctx, udid, u.UUID); err != nil {
+					return queued, pending, err
+				}
+				pending = append(pending, udid)
+				continue
+			}
+			if err := w.setAutoAdminPassword(ctx, udid, guid, u.PasswordHash); err != nil {
+				return queued, pending, err
+			}
+			queued = append(queued, udid)
+		}
+	}
+	return queued, pending, nil
+}
+
+// accountConfigured reports whether the device acknowledged the
+// AccountConfiguration command creating the user.
+func accountConfigured(status DeviceStatus, userUUID string) bool {
+	for _, c := range status.Commands {
+		if c.RequestType == "AccountConfiguration" && c.Item == userUUID && c.Status == "Acknowledged" {
+			return true
+		}
+	}
+	return false
+}
+
+// accountGUID returns the UserID of the user with the short name on the
+// device, or an empty string if the user never checked in.
+func (w *Worker) accountGUID(udid, shortname string) (string, error) {
+	users, err := w.userDB.DeviceUsers(udid)
+	if err != nil {
+		return "", errors.Wrapf(err, "get users of device %s", udid)
+	}
+	for _, u := range users {
+		if u.UserShortname == shortname && u.UserID != "" {
+			return u.UserID, nil
+		}
+	}
+	return "", nil
+}
+
+func (w *Worker) setAutoAdminPassword(ctx context.Context, udid, guid string, passwordHash []byte) error {
+	_, err := w.cmdsvc.NewCommand(ctx, &mdm.CommandRequest{
+		UDID: udid,
+		Command: &mdm.Command{
+			RequestType: "SetAutoAdminPassword",
+			SetAutoAdminPassword: &mdm.SetAutoAdminPassword{
+				GUID:         guid,
+				PasswordHash: passwordHash,
+			},
+		},
+	})
+	return errors.Wrapf(err, "queue SetAutoAdminPassword for udid %s", udid)
+}
+
+// requestUserList queues a UserList command for the device and saves the
+// rotation which finishes when the device responds.
+func (w *Worker) requestUserList(ctx context.Context, udid, userUUID string) error {
+	cmd, err := w.cmdsvc.NewCommand(ctx, &mdm.CommandRequest{
+		UDID:    udid,
+		Command: &mdm.Command{RequestType: "UserList"},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "queue UserList for udid %s", udid)
+	}
+	return w.db.SavePasswordRotation(&PasswordRotation{
+		CommandUUID: cmd.CommandUUID,
+		UDID:        udid,
+		UserUUID:    userUUID,
+		CreatedAt:   time.Now(),
+	})
+}
+
+// userListResponse holds the fields of the UserList response used to find
+// the GUID of an account.
+type userListResponse struct {
+	Users []struct {
+		UserName string
+		UserGUID string
+	}
+}
+
+// finishPasswordRotation sends the password of a rotation waiting for the
+// response to a UserList command, with the GUID of the account from the
+// response. The current password hash of the user is sent, so rotations
+// made while the device was offline send the latest password.
+func (w *Worker) finishPasswordRotation(ctx context.Context, ev *mdmsvc.AcknowledgeEvent) error {
+	if ev.Response.Status == "NotNow" {
+		return nil
+	}
+	rotation, err := w.db.PasswordRotation(ev.Response.CommandUUID)
+	if err != nil || rotation == nil {
+		return err
+	}
+	if err := w.db.DeletePasswordRotation(rotation.CommandUUID); err != nil {
+		return err
+	}
+	if ev.Response.Status != "Acknowledged" {
+		level.Info(w.logger).Log(
+			"msg", "UserList for password rotation failed",
+			"device_udid", rotation.UDID,
+			"user_uuid", rotation.UserUUID,
+			"status", ev.Response.Status,
+		)
+		return nil
+	}
+	var resp userListResponse
+	if err := plist.Unmarshal(ev.Raw, &resp); err != nil {
+		return errors.Wrap(err, "unmarshal UserList response")
+	}
+	u, err := w.userDB.User(ctx, rotation.UserUUID)
+	if err != nil {
+		return errors.Wrapf(err, "get user %s", rotation.UserUUID)
+	}
+	for _, account := range resp.Users {
+		if account.UserName == u.UserShortname && account.UserGUID != "" {
+			return w.setAutoAdminPassword(ctx, rotation.UDID, account.UserGUID, u.PasswordHash)
+		}
+	}
+	level.Info(w.logger).Log(
+		"msg", "account for password rotation not found in UserList",
+		"device_udid", rotation.UDID,
+		"user_shortname", u.UserShortname,
+	)
+	return nil
+}
+
+func MarshalPasswordRotation(r *PasswordRotation) ([]byte, error) {
+	pb := blueprintproto.PasswordRotation{
+		CommandUuid: r.CommandUUID,
+		Udid:        r.UDID,
+		UserUuid:    r.UserUUID,
+		CreatedAt:   timeToProto(r.CreatedAt),
+	}
+	return proto.Marshal(&pb)
+}
+
+func UnmarshalPasswordRotation(data []byte, r *PasswordRotation) error {
+	var pb blueprintproto.PasswordRotation
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+	r.CommandUUID = pb.GetCommandUuid()
+	r.UDID = pb.GetUdid()
+	r.UserUUID = pb.GetUserUuid()
+	r.CreatedAt = timeFromProto(pb.GetCreatedAt())
+	return nil
+}
@@ -0,0 +1,138 @@
+package blueprint
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/micromdm/micromdm/platform/blueprint/internal/blueprintproto"
+	"github.com/micromdm/micromdm/platform/device"
+)
+
+// Scope limits the devices a Blueprint is applied to. Every non-empty field
+// must match the device, and a list matches if any of its values match.
+// Models and ProductNames are shell patterns as accepted by path.Match, for
+// example "iPad*". A nil or empty Scope matches all devices.
+type Scope struct {
+	Serials         []string          `json:"serials,omitempty"`
+	Models          []string          `json:"models,omitempty"`
+	ProductNames    []string          `json:"product_names,omitempty"`
+	DEPProfileUUIDs []string          `json:"dep_profile_uuids,omitempty"`
+	Attributes      map[string]string `json:"attributes,omitempty"`
+}
+
+// Empty reports whether the scope matches all devices.
+func (s *Scope) Empty() bool {
+	return s == nil || len(s.Serials) == 0 && len(s.Models) == 0 && len(s.ProductNames) == 0 &&
+		len(s.DEPProfileUUIDs) == 0 && len(s.Attributes) == 0
+}
+
+// Matches reports whether the device is in scope. The model patterns are
+// matched against both the model and the model name of the device.
+func (s *Scope) Matches(dev *device.Device) bool {
+	if s.Empty() {
+		return true
+	}
+	if len(s.Serials) > 0 && !containsString(s.Serials, dev.SerialNumber) {
+		return false
+	}
+	if len(s.Models) > 0 && !matchAny(s.Models, dev.Model, dev.ModelName) {
+		return false
+	}
+	if len(s.ProductNames) > 0 && !matchAny(s.ProductNames, dev.ProductName) {
+		return false
+	}
+	if len(s.DEPProfileUUIDs) > 0 && !containsString(s.DEPProfileUUIDs, dev.DEPProfileUUID) {
+		return false
+	}
+	return dev.MatchAttributes(s.Attributes)
+}
+
+// Verify checks that every pattern in the scope is valid.
+func (s *Scope) Verify() error {
+	if s == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, s.Models...), s.ProductNames...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid scope pattern %q: %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+// String summarizes the scope for display.
+func (s *Scope) String() string {
+	if s.Empty() {
+		return "(All)"
+	}
+	var parts []string
+	if len(s.Serials) > 0 {
+		parts = append(parts, fmt.Sprintf("serials=%d", len(s.Serials)))
+	}
+	if len(s.Models) > 0 {
+		parts = append(parts, "models="+strings.Join(s.Models, ","))
+	}
+	if len(s.ProductNames) > 0 {
+		parts = append(parts, "products="+strings.Join(s.ProductNames, ","))
+	}
+	if len(s.DEPProfileUUIDs) > 0 {
+		parts = append(parts, "dep_profiles="+strings.Join(s.DEPProfileUUIDs, ","))
+	}
+	if len(s.Attributes) > 0 {
+		var attrs []string
+		for k, v := range s.Attributes {
+			attrs = append(attrs, k+"="+v)
+		}
+		sort.Strings(attrs)
+		parts = append(parts, "attributes="+strings.Join(attrs, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, values ...string) bool {
+	for _, pattern := range patterns {
+		for _, v := range values {
+			if ok, _ := path.Match(pattern, v); ok && v != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func scopeToProto(s *Scope) *blueprintproto.Scope {
+	if s.Empty() {
+		return nil
+	}
+	return &blueprintproto.Scope{
+		Serials:         s.Serials,
+		Models:          s.Models,
+		ProductNames:    s.ProductNames,
+		DepProfileUuids: s.DEPProfileUUIDs,
+		Attributes:      s.Attributes,
+	}
+}
+
+func scopeFromProto(pb *blueprintproto.Scope) *Scope {
+	if pb == nil {
+		return nil
+	}
+	return &Scope{
+		Serials:         pb.GetSerials(),
+		Models:          pb.GetModels(),
+		ProductNames:    pb.GetProductNames(),
+		DEPProfileUUIDs: pb.GetDepProfileUuids(),
+		Attributes:      pb.GetAttributes(),
+	}
+}
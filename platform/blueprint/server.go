@@ -8,16 +8,34 @@ import (
 )
 
 type Endpoints struct {
-	ApplyBlueprintEndpoint   endpoint.Endpoint
-	GetBlueprintsEndpoint    endpoint.Endpoint
-	RemoveBlueprintsEndpoint endpoint.Endpoint
+	ApplyBlueprintEndpoint     endpoint.Endpoint
+	GetBlueprintsEndpoint      endpoint.Endpoint
+	RemoveBlueprintsEndpoint   endpoint.Endpoint
+	ApplyToDevicesEndpoint     endpoint.Endpoint
+	GetReleasesEndpoint        endpoint.Endpoint
+	ReconcileDevicesEndpoint   endpoint.Endpoint
+	GetDriftReportsEndpoint    endpoint.Endpoint
+	GetRevisionsEndpoint       endpoint.Endpoint
+	DiffRevisionsEndpoint      endpoint.Endpoint
+	RollbackBlueprintEndpoint  endpoint.Endpoint
+	GetBlueprintStatusEndpoint endpoint.Endpoint
+	GetDeviceStatusEndpoint    endpoint.Endpoint
 }
 
 func MakeServerEndpoints(s Service, outer endpoint.Middleware, others ...endpoint.Middleware) Endpoints {
 	return Endpoints{
-		GetBlueprintsEndpoint:    endpoint.Chain(outer, others...)(MakeGetBlueprintsEndpoint(s)),
-		ApplyBlueprintEndpoint:   endpoint.Chain(outer, others...)(MakeApplyBlueprintEndpoint(s)),
-		RemoveBlueprintsEndpoint: endpoint.Chain(outer, others...)(MakeRemoveBlueprintsEndpoint(s)),
+		GetBlueprintsEndpoint:      endpoint.Chain(outer, others...)(MakeGetBlueprintsEndpoint(s)),
+		ApplyBlueprintEndpoint:     endpoint.Chain(outer, others...)(MakeApplyBlueprintEndpoint(s)),
+		RemoveBlueprintsEndpoint:   endpoint.Chain(outer, others...)(MakeRemoveBlueprintsEndpoint(s)),
+		ApplyToDevicesEndpoint:     endpoint.Chain(outer, others...)(MakeApplyToDevicesEndpoint(s)),
+		GetReleasesEndpoint:        endpoint.Chain(outer, others...)(MakeGetReleasesEndpoint(s)),
+		ReconcileDevicesEndpoint:   endpoint.Chain(outer, others...)(MakeReconcileDevicesEndpoint(s)),
+		GetDriftReportsEndpoint:    endpoint.Chain(outer, others...)(MakeGetDriftReportsEndpoint(s)),
+		GetRevisionsEndpoint:       endpoint.Chain(outer, others...)(MakeGetRevisionsEndpoint(s)),
+		DiffRevisionsEndpoint:      endpoint.Chain(outer, others...)(MakeDiffRevisionsEndpoint(s)),
+		RollbackBlueprintEndpoint:  endpoint.Chain(outer, others...)(MakeRollbackBlueprintEndpoint(s)),
+		GetBlueprintStatusEndpoint: endpoint.Chain(outer, others...)(MakeGetBlueprintStatusEndpoint(s)),
+		GetDeviceStatusEndpoint:    endpoint.Chain(outer, others...)(MakeGetDeviceStatusEndpoint(s)),
 	}
 }
 
@@ -25,6 +43,15 @@ func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.S
 	// PUT     /v1/blueprints			create or replace a blueprint on the server
 	// POST    /v1/blueprints			get a list of blueprints managed by the server
 	// DELETE  /v1/blueprints			remove one or more blueprints from the server
+	// POST    /v1/blueprints/{name}/apply	apply a Manual blueprint to devices
+	// POST    /v1/blueprints/releases		get the release progress of devices awaiting configuration
+	// POST    /v1/blueprints/reconcile		compare devices with their blueprints and repair drift
+	// POST    /v1/blueprints/drift		get the latest drift report of devices
+	// GET     /v1/blueprints/{name}/revisions	list the revisions of a blueprint
+	// GET     /v1/blueprints/{name}/diff	compare two revisions of a blueprint
+	// POST    /v1/blueprints/{name}/rollback	apply an earlier revision of a blueprint
+	// GET     /v1/blueprints/devices/{udid}	get the status of every blueprint applied to a device
+	// GET     /v1/blueprints/{name}/status	get the status of a blueprint on every device
 
 	r.Methods("PUT").Path("/v1/blueprints").Handler(httptransport.NewServer(
 		e.ApplyBlueprintEndpoint,
@@ -46,4 +73,69 @@ func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.S
 		httputil.EncodeJSONResponse,
 		options...,
 	))
+
+	r.Methods("POST").Path("/v1/blueprints/{name}/apply").Handler(httptransport.NewServer(
+		e.ApplyToDevicesEndpoint,
+		decodeApplyToDevicesRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/v1/blueprints/releases").Handler(httptransport.NewServer(
+		e.GetReleasesEndpoint,
+		decodeGetReleasesRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/v1/blueprints/reconcile").Handler(httptransport.NewServer(
+		e.ReconcileDevicesEndpoint,
+		decodeReconcileDevicesRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/v1/blueprints/drift").Handler(httptransport.NewServer(
+		e.GetDriftReportsEndpoint,
+		decodeGetDriftReportsRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/blueprints/{name}/revisions").Handler(httptransport.NewServer(
+		e.GetRevisionsEndpoint,
+		decodeGetRevisionsRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/blueprints/{name}/diff").Handler(httptransport.NewServer(
+		e.DiffRevisionsEndpoint,
+		decodeDiffRevisionsRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/v1/blueprints/{name}/rollback").Handler(httptransport.NewServer(
+		e.RollbackBlueprintEndpoint,
+		decodeRollbackBlueprintRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	// registered before the {name}/status route, which would match a UDID
+	// of "status".
+	r.Methods("GET").Path("/v1/blueprints/devices/{udid}").Handler(httptransport.NewServer(
+		e.GetDeviceStatusEndpoint,
+		decodeGetDeviceStatusRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/blueprints/{name}/status").Handler(httptransport.NewServer(
+		e.GetBlueprintStatusEndpoint,
+		decodeGetBlueprintStatusRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
 }
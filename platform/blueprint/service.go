@@ -2,6 +2,10 @@ package blueprint
 
 import (
 	"context"
+	"net/http"
+	"time"
+
+	"github.com/micromdm/micromdm/platform/command"
 )
 
 type GetBlueprintsOption struct {
@@ -12,19 +16,76 @@ type Service interface {
 	ApplyBlueprint(ctx context.Context, bp *Blueprint) error
 	GetBlueprints(ctx context.Context, opt GetBlueprintsOption) ([]Blueprint, error)
 	RemoveBlueprints(ctx context.Context, names []string) error
+	ApplyBlueprintToDevices(ctx context.Context, name string, opt ApplyToDevicesOptions) (*ApplyToDevicesResult, error)
+	GetReleases(ctx context.Context, opt GetReleasesOption) ([]Release, error)
+	ReconcileDevices(ctx context.Context, opt ReconcileOptions) ([]string, error)
+	GetDriftReports(ctx context.Context, opt GetDriftReportsOption) ([]DriftReport, error)
+	GetRevisions(ctx context.Context, name string) ([]Revision, error)
+	DiffRevisions(ctx context.Context, name string, from, to int) ([]FieldChange, error)
+	RollbackBlueprint(ctx context.Context, name string, version int) (*Revision, error)
+	GetBlueprintStatus(ctx context.Context, name string) ([]DeviceStatus, error)
+	GetDeviceStatus(ctx context.Context, udid string) ([]DeviceStatus, error)
 }
 
 type Store interface {
 	Save(*Blueprint) error
+	SaveRevision(bp *Blueprint, apiKey string) (*Revision, error)
+	Revisions(name string) ([]Revision, error)
+	Revision(name string, version int) (*Revision, error)
+	BlueprintStatuses(name string) ([]DeviceStatus, error)
+	DeviceStatuses(udid string) ([]DeviceStatus, error)
+	UpdateDeviceStatus(name, udid string, update func(*DeviceStatus) bool) error
 	BlueprintByName(name string) (*Blueprint, error)
 	List() ([]Blueprint, error)
 	Delete(string) error
+	Release(udid string) (*Release, error)
+	Releases() ([]Release, error)
+	DriftReport(udid string) (*DriftReport, error)
+	DriftReports() ([]DriftReport, error)
 }
 
 type BlueprintService struct {
-	store Store
+	store      Store
+	applier    Applier
+	devices    DeviceStore
+	reconciler DriftReconciler
+	cmdsvc     command.Service
+	client     *http.Client
+}
+
+type Option func(*BlueprintService)
+
+// WithApplier enables applying Manual blueprints through the API.
+func WithApplier(applier Applier, devices DeviceStore) Option {
+	return func(svc *BlueprintService) {
+		svc.applier = applier
+		svc.devices = devices
+	}
+}
+
+// WithReconciler enables reconciling devices with their blueprints through
+// the API.
+func WithReconciler(reconciler DriftReconciler) Option {
+	return func(svc *BlueprintService) {
+		svc.reconciler = reconciler
+	}
+}
+
+// WithCleanup enables removing the profiles and applications of blueprints
+// with Cleanup set from devices when they are dropped from the blueprint.
+func WithCleanup(cmdsvc command.Service) Option {
+	return func(svc *BlueprintService) {
+		svc.cmdsvc = cmdsvc
+	}
 }
 
-func New(store Store) *BlueprintService {
-	return &BlueprintService{store: store}
+func New(store Store, opts ...Option) *BlueprintService {
+	svc := &BlueprintService{
+		store:  store,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
 }
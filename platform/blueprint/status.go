@@ -0,0 +1,139 @@
+package blueprint
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/micromdm/micromdm/platform/blueprint/internal/blueprintproto"
+)
+
+// CommandQueued is the status of a blueprint command which the device has
+// not responded to yet.
+const CommandQueued = "Queued"
+
+// DeviceStatus records the commands a blueprint queued for a device. The
+// commands of every apply are merged by item, so the status keeps the items
+// installed by earlier applies.
+type DeviceStatus struct {
+	BlueprintName string          `json:"blueprint_name"`
+	UDID          string          `json:"udid"`
+	AppliedAt     time.Time       `json:"applied_at"`
+	Commands      []CommandStatus `json:"commands"`
+	// Errors are the blueprint items which could not be turned into
+	// commands, like profiles which do not exist.
+	Errors []string `json:"errors,omitempty"`
+	// DeviceUDID is set for the statuses of the user channel. Their UDID is
+	// the UserID of the user and DeviceUDID the device the user is on.
+	DeviceUDID string `json:"device_udid,omitempty"`
+}
+
+// CommandStatus is a command queued by a blueprint. Status is CommandQueued
+// or the status of the last response of the device.
+type CommandStatus struct {
+	CommandUUID string `json:"command_uuid"`
+	RequestType string `json:"request_type"`
+	// Item is the blueprint item the command was created for: a user UUID,
+	// a manifest URL, a profile identifier, or commands[N] for the Commands
+	// of the blueprint.
+	Item      string    `json:"item,omitempty"`
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// acknowledge updates the status of a command from a device response. It
+// reports whether the command belongs to the blueprint.
+func (s *DeviceStatus) acknowledge(commandUUID, status string, now time.Time) bool {
+	for i := range s.Commands {
+		if s.Commands[i].CommandUUID == commandUUID {
+			s.Commands[i].Status = status
+			s.Commands[i].UpdatedAt = now
+			return true
+		}
+	}
+	return false
+}
+
+// merge records another apply of the blueprint. Its commands replace the
+// commands of the same item, and the commands of the other items, like those
+// installed by earlier applies, are kept.
+func (s *DeviceStatus) merge(applied *DeviceStatus) {
+	s.AppliedAt = applied.AppliedAt
+	s.Errors = applied.Errors
+	s.DeviceUDID = applied.DeviceUDID
+	s.mergeCommands(applied.Commands)
+}
+
+// mergeCommands replaces the commands of the same items with cmds.
+func (s *DeviceStatus) mergeCommands(cmds []CommandStatus) {
+	for _, c := range cmds {
+		replaced := false
+		for i := range s.Commands {
+			if s.Commands[i].Item == c.Item {
+				s.Commands[i] = c
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.Commands = append(s.Commands, c)
+		}
+	}
+}
+
+// requeue records a new command queued for the item of a blueprint command.
+func (s *DeviceStatus) requeue(item, requestType, commandUUID string, now time.Time) bool {
+	for i := range s.Commands {
+		c := &s.Commands[i]
+		if c.Item == item && c.RequestType == requestType {
+			c.CommandUUID = commandUUID
+			c.Status = CommandQueued
+			c.UpdatedAt = now
+			return true
+		}
+	}
+	return false
+}
+
+func MarshalDeviceStatus(s *DeviceStatus) ([]byte, error) {
+	pb := blueprintproto.DeviceStatus{
+		BlueprintName: s.BlueprintName,
+		Udid:          s.UDID,
+		AppliedAt:     timeToProto(s.AppliedAt),
+		Errors:        s.Errors,
+		DeviceUdid:    s.DeviceUDID,
+	}
+	for _, c := range s.Commands {
+		pb.Commands = append(pb.Commands, &blueprintproto.CommandStatus{
+			CommandUuid: c.CommandUUID,
+			RequestType: c.RequestType,
+			Item:        c.Item,
+			Status:      c.Status,
+			UpdatedAt:   timeToProto(c.UpdatedAt),
+		})
+	}
+	return proto.Marshal(&pb)
+}
+
+func UnmarshalDeviceStatus(data []byte, s *DeviceStatus) error {
+	var pb blueprintproto.DeviceStatus
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+	s.BlueprintName = pb.GetBlueprintName()
+	s.UDID = pb.GetUdid()
+	s.AppliedAt = timeFromProto(pb.GetAppliedAt())
+	s.Errors = pb.GetErrors()
+	s.DeviceUDID = pb.GetDeviceUdid()
+	s.Commands = nil
+	for _, c := range pb.GetCommands() {
+		s.Commands = append(s.Commands, CommandStatus{
+			CommandUUID: c.GetCommandUuid(),
+			RequestType: c.GetRequestType(),
+			Item:        c.GetItem(),
+			Status:      c.GetStatus(),
+			UpdatedAt:   timeFromProto(c.GetUpdatedAt()),
+		})
+	}
+	return nil
+}
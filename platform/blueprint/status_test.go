@@ -0,0 +1,29 @@
+package blueprint
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeviceStatusMerge(t *testing.T) {
+	now := time.Now()
+	s := &DeviceStatus{BlueprintName: "base", UDID: "udid", Commands: []CommandStatus{
+		{CommandUUID: "1", Item: "com.example.wifi", Status: "Acknowledged"},
+		{CommandUUID: "2", Item: "com.example.vpn", Status: "Acknowledged"},
+	}}
+	s.merge(&DeviceStatus{AppliedAt: now, Commands: []CommandStatus{
+		{CommandUUID: "3", Item: "com.example.vpn", Status: CommandQueued},
+		{CommandUUID: "4", Item: "com.example.mail", Status: CommandQueued},
+	}})
+	var uuids []string
+	for _, c := range s.Commands {
+		uuids = append(uuids, c.CommandUUID)
+	}
+	if have, want := strings.Join(uuids, ","), "1,3,4"; have != want {
+		t.Errorf("have commands %s, want %s", have, want)
+	}
+	if !s.AppliedAt.Equal(now) {
+		t.Error("expected AppliedAt of the last apply")
+	}
+}
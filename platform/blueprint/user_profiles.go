@@ -0,0 +1,96 @@
+package blueprint
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/platform/profile"
+)
+
+// applyUserProfiles installs the UserProfileIdentifiers of the blueprints of
+// a device on the user channel of a managed user, the first time the user
+// checks in. The device status of the user is recorded under its UserID,
+// which is also the queue of the user channel.
+func (w *Worker) applyUserProfiles(ctx context.Context, udid, userID string) error {
+	bps, err := w.db.BlueprintsByApplyAt(ctx, ApplyAtEnroll)
+	if err != nil {
+		return errors.Wrap(err, "get blueprints by ApplyAtEnroll")
+	}
+	dev, err := w.devDB.DeviceByUDID(ctx, udid)
+	if err != nil {
+		dev = nil
+	}
+	statuses, err := w.db.DeviceStatuses(userID)
+	if err != nil {
+		return errors.Wrapf(err, "get blueprint statuses of user %s", userID)
+	}
+	applied := make(map[string]bool)
+	for _, s := range statuses {
+		applied[s.BlueprintName] = true
+	}
+
+	for _, bp := range inScope(bps, dev) {
+		if len(bp.UserProfileIdentifiers) == 0 || applied[bp.Name] {
+			continue
+		}
+		status := &DeviceStatus{BlueprintName: bp.Name, UDID: userID, DeviceUDID: udid, AppliedAt: time.Now()}
+		var (
+			requests []*mdm.CommandRequest
+			items    []string
+		)
+		for _, pid := range bp.UserProfileIdentifiers {
+			payload, err := w.userProfilePayload(ctx, &bp, udid, userID, pid)
+			if err != nil {
+				level.Info(w.logger).Log(
+					"msg", "create user profile payload",
+					"blueprint_name", bp.Name,
+					"profile_identifier", pid,
+					"device_udid", udid,
+					"user_id", userID,
+					"err", err,
+				)
+				status.Errors = append(status.Errors, fmt.Sprintf("profile %s: %s", pid, err))
+				continue
+			}
+			items = append(items, pid)
+			requests = append(requests, &mdm.CommandRequest{
+				UDID: userID,
+				Command: &mdm.Command{
+					RequestType:    "InstallProfile",
+					InstallProfile: &mdm.InstallProfile{Payload: payload},
+				},
+			})
+		}
+		if err := w.saveDeviceStatus(status); err != nil {
+			return err
+		}
+		for i, r := range requests {
+			if err := w.queueCommand(ctx, bp.Name, r, items[i], status.AppliedAt); err != nil {
+				// the error is recorded in the status.
+				level.Info(w.logger).Log(
+					"msg", "queue user profile",
+					"blueprint_name", bp.Name,
+					"profile_identifier", items[i],
+					"user_id", userID,
+					"err", err,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Worker) userProfilePayload(ctx context.Context, bp *Blueprint, udid, userID, pid string) ([]byte, error) {
+	p, err := w.profileDB.ProfileById(ctx, pid)
+	if profile.IsNotFound(err) {
+		return nil, errors.New("not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return installPayload(ctx, w.vars, w.enc, w.signer, udid, userID, blueprintProfile(bp, p))
+}
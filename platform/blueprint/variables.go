@@ -0,0 +1,118 @@
+package blueprint
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/profile"
+	"github.com/micromdm/micromdm/platform/user"
+)
+
+type DeviceUserStore interface {
+	DeviceUsers(udid string) ([]user.User, error)
+}
+
+// ProfileVariables resolves the profile variables of a device from the
+// device record and the users of the device.
+type ProfileVariables struct {
+	devices DeviceStore
+	users   DeviceUserStore
+}
+
+func NewProfileVariables(devices DeviceStore, users DeviceUserStore) *ProfileVariables {
+	return &ProfileVariables{devices: devices, users: users}
+}
+
+// Values returns the values of the profile variables for a device. The user
+// variables are taken from the managed user with the UserID, for profiles
+// installed on the user channel. Without a UserID they are taken from the
+// first user of the device which is not hidden, or the first user if all of
+// them are hidden.
+func (v *ProfileVariables) Values(ctx context.Context, udid, userID string) (map[string]string, error) {
+	dev, err := v.devices.DeviceByUDID(ctx, udid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get device %s", udid)
+	}
+	values := map[string]string{
+		profile.VarSerialNumber: dev.SerialNumber,
+		profile.VarUDID:         dev.UDID,
+		profile.VarDeviceName:   dev.DeviceName,
+		profile.VarModel:        dev.Model,
+	}
+	for k, val := range dev.Attributes {
+		values[profile.AttributePrefix+k] = val
+	}
+
+	users, err := v.users.DeviceUsers(udid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get users of device %s", udid)
+	}
+	if userID != "" {
+		u, ok := userByUserID(users, userID)
+		if !ok {
+			return nil, errors.Errorf("managed user %s is not a user of device %s", userID, udid)
+		}
+		values[profile.VarUserShortname] = u.UserShortname
+		values[profile.VarUserLongname] = u.UserLongname
+	} else if len(users) > 0 {
+		u := users[0]
+		for _, candidate := range users {
+			if !candidate.Hidden {
+				u = candidate
+				break
+			}
+		}
+		values[profile.VarUserShortname] = u.UserShortname
+		values[profile.VarUserLongname] = u.UserLongname
+	}
+	return values, nil
+}
+
+func userByUserID(users []user.User, userID string) (user.User, bool) {
+	for _, u := range users {
+		if u.UserID == userID {
+			return u, true
+		}
+	}
+	return user.User{}, false
+}
+
+// ExpandProfile expands the profile variables of a profile payload for a
+// device, and for the managed user with the UserID if it is not empty.
+// Signed payloads and payloads without variables are returned unchanged.
+// A nil ProfileVariables resolves no variables, so payloads with variables
+// fail.
+func (v *ProfileVariables) ExpandProfile(ctx context.Context, udid, userID string, payload []byte) ([]byte, error) {
+	mc := profile.Mobileconfig(payload)
+	if mc.IsSigned() || len(mc.Variables()) == 0 {
+		return payload, nil
+	}
+	var values map[string]string
+	if v != nil {
+		var err error
+		if values, err = v.Values(ctx, udid, userID); err != nil {
+			return nil, err
+		}
+	}
+	return mc.Expand(values)
+}
+
+// installPayload returns the payload of the InstallProfile command of a
+// profile for a device, or for the managed user with the UserID on the
+// device, with its variables expanded, encrypted to the device if the profile
+// has Encrypt set, and signed by the server.
+func installPayload(ctx context.Context, vars *ProfileVariables, enc *ProfileEncrypter, signer *profile.Signer, udid, userID string, p *profile.Profile) ([]byte, error) {
+	mc, err := vars.ExpandProfile(ctx, udid, userID, p.Mobileconfig)
+	if err != nil {
+		return nil, err
+	}
+	if p.Encrypt {
+		if mc, err = enc.EncryptProfile(udid, mc); err != nil {
+			return nil, err
+		}
+	}
+	expanded := *p
+	expanded.Mobileconfig = mc
+	return signer.Mobileconfig(&expanded)
+}
@@ -0,0 +1,44 @@
+package blueprint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micromdm/micromdm/platform/device"
+	"github.com/micromdm/micromdm/platform/profile"
+	"github.com/micromdm/micromdm/platform/user"
+)
+
+type variablesTestUsers []user.User
+
+func (s variablesTestUsers) DeviceUsers(udid string) ([]user.User, error) { return s, nil }
+
+func TestProfileVariablesValuesUser(t *testing.T) {
+	devices := &reconcileTestDevices{dev: &device.Device{UDID: "udid"}}
+	users := variablesTestUsers{
+		{UDID: "udid", UserID: "guid-admin", UserShortname: "admin", Hidden: true},
+		{UDID: "udid", UserID: "guid-alice", UserShortname: "alice"},
+		{UDID: "udid", UserID: "guid-bob", UserShortname: "bob"},
+	}
+	vars := NewProfileVariables(devices, users)
+
+	values, err := vars.Values(context.Background(), "udid", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := values[profile.VarUserShortname]; have != "alice" {
+		t.Errorf("have device user %q, want alice", have)
+	}
+
+	values, err = vars.Values(context.Background(), "udid", "guid-bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := values[profile.VarUserShortname]; have != "bob" {
+		t.Errorf("have target user %q, want bob", have)
+	}
+
+	if _, err := vars.Values(context.Background(), "udid", "guid-unknown"); err == nil {
+		t.Error("expected an error for a user of another device")
+	}
+}
@@ -2,15 +2,21 @@ package blueprint
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
 	mdmsvc "github.com/micromdm/micromdm/mdm"
 	"github.com/micromdm/micromdm/mdm/mdm"
 	"github.com/micromdm/micromdm/platform/command"
+	"github.com/micromdm/micromdm/platform/dep/sync"
 	"github.com/micromdm/micromdm/platform/device"
+	"github.com/micromdm/micromdm/platform/invite"
 	"github.com/micromdm/micromdm/platform/profile"
 	"github.com/micromdm/micromdm/platform/pubsub"
 	"github.com/micromdm/micromdm/platform/user"
@@ -18,12 +24,46 @@ import (
 
 type BlueprintWorkerStore interface {
 	BlueprintsByApplyAt(ctx context.Context, action string) ([]Blueprint, error)
+	DEPSerial(serial string) (seen, pending bool, err error)
+	SaveDEPSerial(serial string, pending bool) error
+	Release(udid string) (*Release, error)
+	Releases() ([]Release, error)
+	SaveRelease(r *Release) error
+	// UpdateDeviceStatus and UpdateDeviceStatuses read and save statuses in
+	// a single transaction, as statuses are updated both by the worker and
+	// by API requests.
+	UpdateDeviceStatus(name, udid string, update func(*DeviceStatus) bool) error
+	UpdateDeviceStatuses(udid string, update func(*DeviceStatus) bool) error
+	DeviceStatuses(udid string) ([]DeviceStatus, error)
+	BlueprintStatuses(name string) ([]DeviceStatus, error)
+	DriftReports() ([]DriftReport, error)
+	PasswordRotation(commandUUID string) (*PasswordRotation, error)
+	SavePasswordRotation(r *PasswordRotation) error
+	DeletePasswordRotation(commandUUID string) error
+	List() ([]Blueprint, error)
+	BlueprintByName(name string) (*Blueprint, error)
+}
+
+// InviteStore returns the enrollment invite for a token.
+type InviteStore interface {
+	Invite(ctx context.Context, token string) (*invite.Invite, error)
 }
 
 type UserStore interface {
 	User(ctx context.Context, uuid string) (*user.User, error)
+	DeviceUsers(udid string) ([]user.User, error)
+}
+
+type DeviceStore interface {
+	DeviceByUDID(ctx context.Context, udid string) (*device.Device, error)
+	DeviceBySerial(ctx context.Context, serial string) (*device.Device, error)
+	List(ctx context.Context, opt device.ListDevicesOption) ([]device.Device, error)
 }
 
+// releaseCheckInterval is how often the deadlines of pending releases are
+// checked.
+const releaseCheckInterval = time.Minute
+
 type ProfileStore interface {
 	ProfileById(ctx context.Context, id string) (*profile.Profile, error)
 }
@@ -31,27 +71,73 @@ type ProfileStore interface {
 func NewWorker(
 	db BlueprintWorkerStore,
 	userDB UserStore,
+	devDB DeviceStore,
 	profileDB ProfileStore,
 	cmdsvc command.Service,
-	sub pubsub.Subscriber,
+	ps pubsub.PublishSubscriber,
 	logger log.Logger,
+	opts ...WorkerOption,
 ) *Worker {
-	return &Worker{
+	w := &Worker{
 		db:        db,
 		userDB:    userDB,
+		devDB:     devDB,
 		profileDB: profileDB,
-		ps:        sub,
+		ps:        ps,
 		cmdsvc:    cmdsvc,
 		logger:    logger,
 	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+type WorkerOption func(*Worker)
+
+// WithProfileVariables expands the profile variables of the profiles of
+// blueprints for each device.
+func WithProfileVariables(vars *ProfileVariables) WorkerOption {
+	return func(w *Worker) {
+		w.vars = vars
+	}
+}
+
+// WithProfileEncrypter encrypts the profiles of blueprints which have
+// Encrypt or the EncryptProfiles blueprint option set.
+func WithProfileEncrypter(enc *ProfileEncrypter) WorkerOption {
+	return func(w *Worker) {
+		w.enc = enc
+	}
+}
+
+// WithProfileSigner signs the profiles of blueprints before they are sent to
+// devices.
+func WithProfileSigner(signer *profile.Signer) WorkerOption {
+	return func(w *Worker) {
+		w.signer = signer
+	}
+}
+
+// WithInvites applies the blueprint of the enrollment invite a device
+// enrolled with.
+func WithInvites(invites InviteStore) WorkerOption {
+	return func(w *Worker) {
+		w.invites = invites
+	}
 }
 
 type Worker struct {
 	db        BlueprintWorkerStore
 	userDB    UserStore
+	devDB     DeviceStore
 	profileDB ProfileStore
-	ps        pubsub.Subscriber
+	ps        pubsub.PublishSubscriber
 	cmdsvc    command.Service
+	signer    *profile.Signer
+	vars      *ProfileVariables
+	enc       *ProfileEncrypter
+	invites   InviteStore
 	logger    log.Logger
 }
 
@@ -60,14 +146,36 @@ func (w *Worker) Run(ctx context.Context) error {
 	if err != nil {
 		return errors.Wrapf(err, "subscribing devices to %s topic", device.DeviceEnrolledTopic)
 	}
+	checkinEvents, err := w.ps.Subscribe(ctx, "applyAtCheckin", mdmsvc.TokenUpdateTopic)
+	if err != nil {
+		return errors.Wrapf(err, "subscribing devices to %s topic", mdmsvc.TokenUpdateTopic)
+	}
+	depSyncEvents, err := w.ps.Subscribe(ctx, "applyAtDEPSync", sync.SyncTopic)
+	if err != nil {
+		return errors.Wrapf(err, "subscribing devices to %s topic", sync.SyncTopic)
+	}
+	ackEvents, err := w.ps.Subscribe(ctx, "blueprintAcknowledge", mdmsvc.ConnectTopic)
+	if err != nil {
+		return errors.Wrapf(err, "subscribing devices to %s topic", mdmsvc.ConnectTopic)
+	}
+	ticker := time.NewTicker(releaseCheckInterval)
+	defer ticker.Stop()
 
 	for {
 		var err error
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case ev := <-ackEvents:
+			err = w.handleAcknowledgeEvent(ctx, ev.Message)
+		case now := <-ticker.C:
+			err = w.checkReleaseDeadlines(ctx, now)
 		case ev := <-tokenUpdateEvents:
 			err = w.handleTokenUpdateEvent(ctx, ev.Message)
+		case ev := <-checkinEvents:
+			err = w.handleCheckinEvent(ctx, ev.Message)
+		case ev := <-depSyncEvents:
+			err = w.handleDEPSyncEvent(ctx, ev.Message)
 		}
 
 		if err != nil {
@@ -93,13 +201,7 @@ func (w *Worker) handleTokenUpdateEvent(ctx context.Context, message []byte) err
 		return errors.Wrap(err, "unmarshal checkin event")
 	}
 	if ev.Command.UserID != "" {
-		level.Debug(w.logger).Log(
-			"msg", "skipping user token update in blueprint worker.",
-			"device_udid", ev.Command.UDID,
-			"user_id", ev.Command.UserID,
-		)
-		// skip UserID token updates
-		return nil
+		return w.applyUserProfiles(ctx, ev.Command.UDID, ev.Command.UserID)
 	}
 
 	bps, err := w.db.BlueprintsByApplyAt(ctx, ApplyAtEnroll)
@@ -107,6 +209,30 @@ func (w *Worker) handleTokenUpdateEvent(ctx context.Context, message []byte) err
 		return errors.Wrap(err, "get blueprints by ApplyAtEnroll")
 	}
 
+	// the device may not be known to the device store yet, in which case
+	// only blueprints without a scope are applied.
+	dev, err := w.devDB.DeviceByUDID(ctx, ev.Command.UDID)
+	if err != nil {
+		dev = nil
+	}
+
+	depBps, err := w.pendingDEPSyncBlueprints(ctx, dev)
+	if err != nil {
+		return err
+	}
+	bps = inScope(append(bps, depBps...), dev)
+
+	inviteBp, err := w.inviteBlueprint(ctx, ev)
+	if err != nil {
+		level.Info(w.logger).Log(
+			"msg", "get blueprint of enrollment invite",
+			"device_udid", ev.Command.UDID,
+			"err", err,
+		)
+	} else if inviteBp != nil && !hasBlueprint(bps, inviteBp.Name) {
+		bps = append(bps, *inviteBp)
+	}
+
 	// if there are no blueprints exit early. This will ensure that DeviceConfigured is not sent.
 	if len(bps) == 0 {
 		level.Debug(w.logger).Log(
@@ -116,6 +242,7 @@ func (w *Worker) handleTokenUpdateEvent(ctx context.Context, message []byte) err
 		return nil
 	}
 
+	var commandUUIDs []string
 	for _, bp := range bps {
 		level.Debug(w.logger).Log(
 			"msg", "applying blueprint",
@@ -123,31 +250,284 @@ func (w *Worker) handleTokenUpdateEvent(ctx context.Context, message []byte) err
 			"blueprint_name", bp.Name,
 		)
 
-		if err := w.applyToDevice(ctx, bp, ev.Command.UDID); err != nil {
+		uuids, err := w.applyToDevice(ctx, bp, ev.Command.UDID)
+		if err != nil {
 			return errors.Wrapf(err, "apply blueprint to udid name=%s, udid=%s", bp.Name, ev.Command.UDID)
 		}
+		commandUUIDs = append(commandUUIDs, uuids...)
 	}
 
-	if ev.Command.AwaitingConfiguration {
+	if !ev.Command.AwaitingConfiguration {
+		return nil
+	}
+
+	// hold the device in Setup Assistant until the commands complete if
+	// any of the blueprints asks for it.
+	if release := newRelease(ev.Command.UDID, bps, commandUUIDs, time.Now()); release != nil {
 		level.Debug(w.logger).Log(
-			"msg", "sending DeviceConfigured at the end of blueprint",
+			"msg", "waiting for blueprint commands before sending DeviceConfigured",
 			"device_udid", ev.Command.UDID,
+			"commands", len(commandUUIDs),
 		)
-		_, err := w.cmdsvc.NewCommand(ctx, &mdm.CommandRequest{
-			Command: &mdm.Command{RequestType: "DeviceConfigured"},
-			UDID:    ev.Command.UDID,
-		})
-		if err != nil {
-			return errors.Wrap(err, "send DeviceConfigured")
+		return w.updateRelease(ctx, release, time.Now())
+	}
+
+	level.Debug(w.logger).Log(
+		"msg", "sending DeviceConfigured at the end of blueprint",
+		"device_udid", ev.Command.UDID,
+	)
+	return w.sendDeviceConfigured(ctx, ev.Command.UDID)
+}
+
+// inviteBlueprint returns the blueprint of the invite whose token is in the
+// check-in URL, if the device enrolled with the invite.
+func (w *Worker) inviteBlueprint(ctx context.Context, ev mdmsvc.CheckinEvent) (*Blueprint, error) {
+	token := ev.Params[invite.TokenParam]
+	if w.invites == nil || token == "" {
+		return nil, nil
+	}
+	inv, err := w.invites.Invite(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if inv.BlueprintName == "" || !inv.Redeemed(ev.Command.UDID) {
+		return nil, nil
+	}
+	bp, err := w.db.BlueprintByName(inv.BlueprintName)
+	return bp, errors.Wrapf(err, "get blueprint %s", inv.BlueprintName)
+}
+
+func hasBlueprint(bps []Blueprint, name string) bool {
+	for _, bp := range bps {
+		if bp.Name == name {
+			return true
 		}
 	}
+	return false
+}
+
+func (w *Worker) sendDeviceConfigured(ctx context.Context, udid string) error {
+	_, err := w.cmdsvc.NewCommand(ctx, &mdm.CommandRequest{
+		Command: &mdm.Command{RequestType: "DeviceConfigured"},
+		UDID:    udid,
+	})
+	return errors.Wrap(err, "send DeviceConfigured")
+}
 
+// handleAcknowledgeEvent records the responses to blueprint commands, finishes
+// password rotations waiting for a UserList response, and updates the release
+// of a device awaiting configuration.
+func (w *Worker) handleAcknowledgeEvent(ctx context.Context, message []byte) error {
+	var ev mdmsvc.AcknowledgeEvent
+	if err := mdmsvc.UnmarshalAcknowledgeEvent(message, &ev); err != nil {
+		return errors.Wrap(err, "unmarshal acknowledge event")
+	}
+	if ev.Response.EnrollmentID != nil || ev.Response.CommandUUID == "" {
+		return nil
+	}
+	if ev.Response.UserID != nil {
+		// the user channel only has the statuses of user profiles.
+		return w.updateDeviceStatus(*ev.Response.UserID, ev.Response.CommandUUID, ev.Response.Status)
+	}
+	if err := w.updateDeviceStatus(ev.Response.UDID, ev.Response.CommandUUID, ev.Response.Status); err != nil {
+		return err
+	}
+	if err := w.finishPasswordRotation(ctx, &ev); err != nil {
+		return err
+	}
+	release, err := w.db.Release(ev.Response.UDID)
+	if err != nil {
+		return err
+	}
+	if release == nil || release.Status == ReleaseReleased {
+		return nil
+	}
+	if !release.acknowledge(ev.Response.CommandUUID, ev.Response.Status) {
+		return nil
+	}
+	return w.updateRelease(ctx, release, time.Now())
+}
+
+// updateDeviceStatus records the response to a blueprint command. Responses
+// to commands which are not in the statuses of the device, like most commands
+// sent with the command API, are skipped without a write transaction.
+func (w *Worker) updateDeviceStatus(udid, commandUUID, status string) error {
+	statuses, err := w.db.DeviceStatuses(udid)
+	if err != nil {
+		return errors.Wrapf(err, "get blueprint statuses of udid %s", udid)
+	}
+	if !tracksCommand(statuses, commandUUID) {
+		return nil
+	}
+	now := time.Now()
+	return w.db.UpdateDeviceStatuses(udid, func(s *DeviceStatus) bool {
+		return s.acknowledge(commandUUID, status, now)
+	})
+}
+
+// checkReleaseDeadlines releases the devices whose release deadline passed.
+func (w *Worker) checkReleaseDeadlines(ctx context.Context, now time.Time) error {
+	releases, err := w.db.Releases()
+	if err != nil {
+		return err
+	}
+	for i := range releases {
+		r := &releases[i]
+		if r.Status == ReleaseReleased || r.Deadline.IsZero() || now.Before(r.Deadline) {
+			continue
+		}
+		if err := w.updateRelease(ctx, r, now); err != nil {
+			return err
+		}
+	}
 	return nil
+}
 
+// updateRelease sends DeviceConfigured if the release is ready and saves it.
+func (w *Worker) updateRelease(ctx context.Context, r *Release, now time.Time) error {
+	if r.ready(now) {
+		level.Debug(w.logger).Log(
+			"msg", "sending DeviceConfigured after blueprint commands",
+			"device_udid", r.UDID,
+			"reason", r.Reason,
+		)
+		if err := w.sendDeviceConfigured(ctx, r.UDID); err != nil {
+			return err
+		}
+		r.Status = ReleaseReleased
+		r.ReleasedAt = now
+	}
+	return w.db.SaveRelease(r)
 }
 
-func (w *Worker) applyToDevice(ctx context.Context, bp Blueprint, udid string) error {
-	var requests []*mdm.CommandRequest
+// handleCheckinEvent applies the Checkin blueprints on every device TokenUpdate.
+func (w *Worker) handleCheckinEvent(ctx context.Context, message []byte) error {
+	var ev mdmsvc.CheckinEvent
+	if err := mdmsvc.UnmarshalCheckinEvent(message, &ev); err != nil {
+		return errors.Wrap(err, "unmarshal checkin event")
+	}
+	if ev.Command.UserID != "" || ev.Command.EnrollmentID != "" {
+		return nil
+	}
+
+	bps, err := w.db.BlueprintsByApplyAt(ctx, ApplyAtCheckin)
+	if err != nil {
+		return errors.Wrap(err, "get blueprints by ApplyAtCheckin")
+	}
+	dev, err := w.devDB.DeviceByUDID(ctx, ev.Command.UDID)
+	if err != nil {
+		dev = nil
+	}
+	for _, bp := range inScope(bps, dev) {
+		if _, err := w.applyToDevice(ctx, bp, ev.Command.UDID); err != nil {
+			return errors.Wrapf(err, "apply blueprint to udid name=%s, udid=%s", bp.Name, ev.Command.UDID)
+		}
+	}
+	return nil
+}
+
+// handleDEPSyncEvent records the serials which first appear in a DEP sync.
+// The DEPSync blueprints are applied right away to devices which are already
+// enrolled, and when the device enrolls otherwise.
+func (w *Worker) handleDEPSyncEvent(ctx context.Context, message []byte) error {
+	var ev sync.Event
+	if err := sync.UnmarshalEvent(message, &ev); err != nil {
+		return errors.Wrap(err, "unmarshal depsync event")
+	}
+
+	for _, dd := range ev.Devices {
+		if dd.OpType != "" && dd.OpType != "added" {
+			continue
+		}
+		seen, _, err := w.db.DEPSerial(dd.SerialNumber)
+		if err != nil {
+			return errors.Wrapf(err, "get DEP serial %s", dd.SerialNumber)
+		}
+		if seen {
+			continue
+		}
+
+		dev, err := w.devDB.DeviceBySerial(ctx, dd.SerialNumber)
+		if err != nil || dev.UDID == "" || !dev.Enrolled {
+			if err := w.db.SaveDEPSerial(dd.SerialNumber, true); err != nil {
+				return err
+			}
+			continue
+		}
+
+		bps, err := w.db.BlueprintsByApplyAt(ctx, ApplyAtDEPSync)
+		if err != nil {
+			return errors.Wrap(err, "get blueprints by ApplyAtDEPSync")
+		}
+		for _, bp := range inScope(bps, dev) {
+			if _, err := w.applyToDevice(ctx, bp, dev.UDID); err != nil {
+				return errors.Wrapf(err, "apply blueprint to udid name=%s, udid=%s", bp.Name, dev.UDID)
+			}
+		}
+		if err := w.db.SaveDEPSerial(dd.SerialNumber, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pendingDEPSyncBlueprints returns the DEPSync blueprints if the enrolling
+// device first appeared in a DEP sync before it enrolled.
+func (w *Worker) pendingDEPSyncBlueprints(ctx context.Context, dev *device.Device) ([]Blueprint, error) {
+	if dev == nil {
+		// the device is not known to the device store yet, so it could
+		// not have been seen in a DEP sync.
+		return nil, nil
+	}
+	_, pending, err := w.db.DEPSerial(dev.SerialNumber)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get DEP serial %s", dev.SerialNumber)
+	}
+	if !pending {
+		return nil, nil
+	}
+	bps, err := w.db.BlueprintsByApplyAt(ctx, ApplyAtDEPSync)
+	if err != nil {
+		return nil, errors.Wrap(err, "get blueprints by ApplyAtDEPSync")
+	}
+	if err := w.db.SaveDEPSerial(dev.SerialNumber, false); err != nil {
+		return nil, err
+	}
+	return bps, nil
+}
+
+// inScope returns the blueprints whose scope matches the device. If the device
+// is nil only the blueprints without a scope are returned.
+func inScope(bps []Blueprint, dev *device.Device) []Blueprint {
+	var matched []Blueprint
+	for _, bp := range bps {
+		if bp.Scope.Empty() || (dev != nil && bp.Scope.Matches(dev)) {
+			matched = append(matched, bp)
+		}
+	}
+	return matched
+}
+
+// ApplyToDevices queues the commands of a blueprint for each device. It is
+// called by API requests while the worker runs; each update of a device status
+// is a single store transaction and releases are only changed by Run.
+func (w *Worker) ApplyToDevices(ctx context.Context, bp Blueprint, udids []string) error {
+	for _, udid := range udids {
+		if _, err := w.applyToDevice(ctx, bp, udid); err != nil {
+			return errors.Wrapf(err, "apply blueprint to udid name=%s, udid=%s", bp.Name, udid)
+		}
+	}
+	return nil
+}
+
+// applyToDevice queues the commands of a blueprint and returns their UUIDs.
+func (w *Worker) applyToDevice(ctx context.Context, bp Blueprint, udid string) ([]string, error) {
+	var (
+		requests []*mdm.CommandRequest
+		// items are the blueprint items of the requests.
+		items  []string
+		status = &DeviceStatus{BlueprintName: bp.Name, UDID: udid, AppliedAt: time.Now()}
+	)
 	for _, uuid := range bp.UserUUID {
 		level.Debug(w.logger).Log(
 			"msg", "creating mdm command request from blueprint",
@@ -166,9 +546,28 @@ func (w *Worker) applyToDevice(ctx context.Context, bp Blueprint, udid string) e
 				"device_udid", udid,
 				"err", err,
 			)
+			status.Errors = append(status.Errors, fmt.Sprintf("user %s: %s", uuid, err))
+			continue
+		}
+		// users synced from a directory have no password until one is set,
+		// and are disabled when they leave the directory group.
+		if usr.Disabled || len(usr.PasswordHash) == 0 {
+			reason := "user is disabled"
+			if !usr.Disabled {
+				reason = "user has no password"
+			}
+			level.Info(w.logger).Log(
+				"msg", "skip AccountConfiguration request",
+				"blueprint_name", bp.Name,
+				"user_uuid", uuid,
+				"device_udid", udid,
+				"reason", reason,
+			)
+			status.Errors = append(status.Errors, fmt.Sprintf("user %s: %s", uuid, reason))
 			continue
 		}
 
+		items = append(items, uuid)
 		requests = append(requests, &mdm.CommandRequest{
 			UDID: udid,
 			Command: &mdm.Command{
@@ -199,6 +598,7 @@ func (w *Worker) applyToDevice(ctx context.Context, bp Blueprint, udid string) e
 			"device_udid", udid,
 		)
 
+		items = append(items, *appURL)
 		requests = append(requests, &mdm.CommandRequest{
 			UDID: udid,
 			Command: &mdm.Command{
@@ -228,28 +628,125 @@ func (w *Worker) applyToDevice(ctx context.Context, bp Blueprint, udid string) e
 				"is_not_found_err", profile.IsNotFound(err),
 				"err", err,
 			)
+			if profile.IsNotFound(err) {
+				status.Errors = append(status.Errors, fmt.Sprintf("profile %s not found", pid))
+			} else {
+				status.Errors = append(status.Errors, fmt.Sprintf("profile %s: %s", pid, err))
+			}
+			continue
+		}
+		payload, err := installPayload(ctx, w.vars, w.enc, w.signer, udid, "", blueprintProfile(&bp, foundProfile))
+		if err != nil {
+			level.Info(w.logger).Log(
+				"msg", "create profile payload",
+				"blueprint_name", bp.Name,
+				"profile_identifier", pid,
+				"device_udid", udid,
+				"err", err,
+			)
+			status.Errors = append(status.Errors, fmt.Sprintf("profile %s: %s", pid, err))
 			continue
 		}
 
+		items = append(items, pid)
 		requests = append(requests, &mdm.CommandRequest{
 			UDID: udid,
 			Command: &mdm.Command{
 				RequestType: "InstallProfile",
 				InstallProfile: &mdm.InstallProfile{
-					Payload: foundProfile.Mobileconfig,
+					Payload: payload,
 				},
 			},
 		})
 	}
 
-	for _, r := range requests {
-		if _, err := w.cmdsvc.NewCommand(ctx, r); err != nil {
-			return errors.Wrap(err, "create new command from blueprint")
+	for i, cmd := range bp.Commands {
+		level.Debug(w.logger).Log(
+			"msg", "creating mdm command request from blueprint",
+			"request_type", cmd.RequestType,
+			"blueprint_name", bp.Name,
+			"device_udid", udid,
+		)
+		items = append(items, fmt.Sprintf("commands[%d]", i))
+		requests = append(requests, &mdm.CommandRequest{UDID: udid, Command: cmd})
+	}
+
+	// the status is saved before the commands are queued, so the response to
+	// a command which is acknowledged right away finds it.
+	if err := w.saveDeviceStatus(status); err != nil {
+		return nil, err
+	}
+	var commandUUIDs []string
+	for i, r := range requests {
+		if err := w.queueCommand(ctx, bp.Name, r, items[i], status.AppliedAt); err != nil {
+			return commandUUIDs, errors.Wrap(err, "create new command from blueprint")
 		}
+		commandUUIDs = append(commandUUIDs, r.CommandUUID)
+	}
+
+	msg, err := json.Marshal(AppliedEvent{UDID: udid, BlueprintName: bp.Name})
+	if err != nil {
+		return commandUUIDs, errors.Wrap(err, "marshal blueprint applied event")
+	}
+	err = w.ps.Publish(ctx, AppliedTopic, msg)
+	return commandUUIDs, errors.Wrapf(err, "publish on topic %s", AppliedTopic)
+}
+
+// queueCommand records the command for the item in the status of the named
+// blueprint for the UDID of the request, and then queues it. The command gets
+// its UUID before it is queued, so the response to a command which is
+// acknowledged right away finds it. A command which fails to queue is
+// recorded with status Error.
+func (w *Worker) queueCommand(ctx context.Context, name string, r *mdm.CommandRequest, item string, now time.Time) error {
+	r.CommandUUID = uuid.New().String()
+	cmd := CommandStatus{
+		CommandUUID: r.CommandUUID,
+		RequestType: r.Command.RequestType,
+		Item:        item,
+		Status:      CommandQueued,
+		UpdatedAt:   now,
+	}
+	err := w.db.UpdateDeviceStatus(name, r.UDID, func(s *DeviceStatus) bool {
+		s.mergeCommands([]CommandStatus{cmd})
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := w.cmdsvc.NewCommand(ctx, r); err != nil {
+		msg := fmt.Sprintf("queue %s for %s: %s", r.Command.RequestType, item, err)
+		if err := w.db.UpdateDeviceStatus(name, r.UDID, func(s *DeviceStatus) bool {
+			s.Errors = append(s.Errors, msg)
+			return s.acknowledge(cmd.CommandUUID, "Error", time.Now())
+		}); err != nil {
+			level.Info(w.logger).Log("msg", "save blueprint device status", "err", err)
+		}
+		return err
 	}
 	return nil
 }
 
+// tracksCommand reports whether one of the statuses has the command.
+func tracksCommand(statuses []DeviceStatus, commandUUID string) bool {
+	for _, s := range statuses {
+		for _, c := range s.Commands {
+			if c.CommandUUID == commandUUID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// saveDeviceStatus merges the commands of an apply into the saved status of
+// the blueprint on the device.
+func (w *Worker) saveDeviceStatus(status *DeviceStatus) error {
+	return w.db.UpdateDeviceStatus(status.BlueprintName, status.UDID, func(s *DeviceStatus) bool {
+		s.merge(status)
+		return true
+	})
+}
+
 func intPtr(i int) *int {
 	return &i
 }
@@ -0,0 +1,76 @@
+package blueprint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/platform/command"
+	"github.com/micromdm/micromdm/platform/pubsub/inmem"
+)
+
+type workerTestStore struct {
+	BlueprintWorkerStore
+	statuses map[string]*DeviceStatus
+}
+
+func (s *workerTestStore) UpdateDeviceStatus(name, udid string, update func(*DeviceStatus) bool) error {
+	status, ok := s.statuses[name]
+	if !ok {
+		status = &DeviceStatus{BlueprintName: name, UDID: udid}
+	}
+	if update(status) {
+		s.statuses[name] = status
+	}
+	return nil
+}
+
+func (s *workerTestStore) UpdateDeviceStatuses(udid string, update func(*DeviceStatus) bool) error {
+	for _, status := range s.statuses {
+		update(status)
+	}
+	return nil
+}
+
+func (s *workerTestStore) DeviceStatuses(udid string) ([]DeviceStatus, error) {
+	var statuses []DeviceStatus
+	for _, status := range s.statuses {
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// ackingCommandService acknowledges every command as soon as it is queued.
+type ackingCommandService struct {
+	command.Service
+	w *Worker
+}
+
+func (s *ackingCommandService) NewCommand(ctx context.Context, req *mdm.CommandRequest) (*mdm.CommandPayload, error) {
+	if err := s.w.updateDeviceStatus(req.UDID, req.CommandUUID, "Acknowledged"); err != nil {
+		return nil, err
+	}
+	return mdm.NewCommandPayload(req)
+}
+
+func TestApplyToDeviceRecordsCommandsBeforeQueuing(t *testing.T) {
+	store := &workerTestStore{statuses: make(map[string]*DeviceStatus)}
+	cmdsvc := &ackingCommandService{}
+	w := NewWorker(store, nil, nil, nil, cmdsvc, inmem.NewPubSub(), log.NewNopLogger())
+	cmdsvc.w = w
+
+	bp := Blueprint{Name: "apps", ApplicationURLs: []string{"https://example.com/app.plist"}}
+	uuids, err := w.applyToDevice(context.Background(), bp, "udid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := store.statuses["apps"]
+	if status == nil || len(status.Commands) != 1 {
+		t.Fatalf("have status %+v, want one command", status)
+	}
+	if have := status.Commands[0]; have.CommandUUID != uuids[0] || have.Status != "Acknowledged" {
+		t.Errorf("have command %+v, want %s acknowledged", have, uuids[0])
+	}
+}
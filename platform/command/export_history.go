@@ -0,0 +1,101 @@
+package command
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/export"
+)
+
+// HistoryRecord is a single command in the queue or history of a device.
+type HistoryRecord struct {
+	UDID           string    `json:"udid"`
+	CommandUUID    string    `json:"command_uuid"`
+	RequestType    string    `json:"request_type"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastSentAt     time.Time `json:"last_sent_at"`
+	Acknowledged   time.Time `json:"acknowledged"`
+	TimesSent      int       `json:"times_sent"`
+	FailureMessage string    `json:"failure_message,omitempty"`
+}
+
+var historyRecordHeader = []string{
+	"udid", "command_uuid", "request_type", "status", "created_at",
+	"last_sent_at", "acknowledged", "times_sent", "failure_message",
+}
+
+// CSVRecord implements export.Record.
+func (r HistoryRecord) CSVRecord() []string {
+	return []string{
+		r.UDID, r.CommandUUID, r.RequestType, r.Status, formatTime(r.CreatedAt),
+		formatTime(r.LastSentAt), formatTime(r.Acknowledged), strconv.Itoa(r.TimesSent),
+		r.FailureMessage,
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// History provides the command history of every device.
+type History interface {
+	CommandHistory(ctx context.Context, fn func(HistoryRecord) error) error
+}
+
+// ExportCommandHistory calls fn for every command in the queue or history of
+// every device.
+func (svc *CommandService) ExportCommandHistory(ctx context.Context, fn func(HistoryRecord) error) error {
+	if svc.history == nil {
+		return errors.New("command history is not available with this command queue")
+	}
+	err := svc.history.CommandHistory(ctx, fn)
+	return errors.Wrap(err, "get command history")
+}
+
+type exportCommandHistoryRequest struct {
+	Format string
+}
+
+type exportCommandHistoryResponse struct {
+	records func(fn func(HistoryRecord) error) error
+	format  string
+	Err     error
+}
+
+func (r exportCommandHistoryResponse) Failed() error    { return r.Err }
+func (r exportCommandHistoryResponse) Format() string   { return r.format }
+func (r exportCommandHistoryResponse) Header() []string { return historyRecordHeader }
+
+func (r exportCommandHistoryResponse) WriteRecords(w *export.Writer) error {
+	return r.records(func(c HistoryRecord) error {
+		return w.Write(c)
+	})
+}
+
+func decodeExportCommandHistoryRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	format, err := export.ParseFormat(r.URL.Query().Get("format"))
+	return exportCommandHistoryRequest{Format: format}, err
+}
+
+// MakeExportCommandHistoryEndpoint creates an endpoint which exports the
+// command history of all devices.
+func MakeExportCommandHistoryEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(exportCommandHistoryRequest)
+		return exportCommandHistoryResponse{
+			records: func(fn func(HistoryRecord) error) error {
+				return svc.ExportCommandHistory(ctx, fn)
+			},
+			format: req.Format,
+		}, nil
+	}
+}
@@ -76,13 +76,13 @@ func decodeNewCommandRequest(ctx context.Context, r *http.Request) (interface{},
 	return req, err
 }
 
-var errEmptyRequest = errors.New("request must contain UDID of the device")
+var errEmptyRequest = errors.New("request must contain UDID of the device or UserID of the user")
 
 // MakeNewCommandEndpoint creates an endpoint which creates new MDM Commands.
 func MakeNewCommandEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(newCommandRequest)
-		if req.UDID == "" || req.RequestType == "" {
+		if (req.UDID == "" && req.UserID == "") || req.RequestType == "" {
 			return newCommandResponse{Err: errEmptyRequest}, nil
 		}
 		payload, err := svc.NewCommand(ctx, &req.CommandRequest)
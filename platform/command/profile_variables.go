@@ -0,0 +1,58 @@
+package command
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/platform/profile"
+)
+
+// ProfileExpander expands the profile variables of an InstallProfile payload
+// for a device, and for the managed user with the UserID if it is not empty.
+type ProfileExpander interface {
+	ExpandProfile(ctx context.Context, udid, userID string, payload []byte) ([]byte, error)
+}
+
+type Middleware func(next Service) Service
+
+// ProfileVariablesMiddleware expands the profile variables of InstallProfile
+// commands before they are queued, and signs the expanded payloads with
+// signer unless they are already signed. Commands with unresolved variables
+// are rejected. A nil signer leaves the payloads unsigned. Commands for the
+// user channel are expanded for the user which UserTargetMiddleware resolved,
+// so it has to run before this middleware.
+func ProfileVariablesMiddleware(expander ProfileExpander, signer *profile.Signer) Middleware {
+	return func(next Service) Service {
+		return profileVariablesMiddleware{Service: next, expander: expander, signer: signer}
+	}
+}
+
+type profileVariablesMiddleware struct {
+	Service
+	expander ProfileExpander
+	signer   *profile.Signer
+}
+
+func (mw profileVariablesMiddleware) NewCommand(ctx context.Context, request *mdm.CommandRequest) (*mdm.CommandPayload, error) {
+	if request == nil || request.Command == nil || request.InstallProfile == nil {
+		return mw.Service.NewCommand(ctx, request)
+	}
+	udid, userID := request.UDID, ""
+	if u, ok := TargetUser(ctx); ok {
+		udid, userID = u.UDID, u.UserID
+	}
+	payload, err := mw.expander.ExpandProfile(ctx, udid, userID, request.InstallProfile.Payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "expand profile variables")
+	}
+	signed, err := mw.signer.Sign(profile.Mobileconfig(payload))
+	if err != nil {
+		return nil, errors.Wrap(err, "sign profile")
+	}
+	cmd := *request.Command
+	cmd.InstallProfile = &mdm.InstallProfile{Payload: signed}
+	expanded := *request
+	expanded.Command = &cmd
+	return mw.Service.NewCommand(ctx, &expanded)
+}
@@ -0,0 +1,103 @@
+package command_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/platform/command"
+	"github.com/micromdm/micromdm/platform/profile"
+)
+
+type identityExpander struct{}
+
+func (identityExpander) ExpandProfile(ctx context.Context, udid, userID string, payload []byte) ([]byte, error) {
+	return payload, nil
+}
+
+type queuedPayloads struct {
+	command.Service
+	payloads [][]byte
+}
+
+func (q *queuedPayloads) NewCommand(ctx context.Context, request *mdm.CommandRequest) (*mdm.CommandPayload, error) {
+	q.payloads = append(q.payloads, request.InstallProfile.Payload)
+	return mdm.NewCommandPayload(request)
+}
+
+func TestProfileVariablesMiddlewareSigns(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "profile signing"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queue := &queuedPayloads{}
+	svc := command.ProfileVariablesMiddleware(identityExpander{}, profile.NewSigner(key, cert))(queue)
+	mc := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>PayloadIdentifier</key><string>com.example.test</string></dict></plist>`)
+	_, err = svc.NewCommand(context.Background(), &mdm.CommandRequest{
+		UDID: "udid",
+		Command: &mdm.Command{
+			RequestType:    "InstallProfile",
+			InstallProfile: &mdm.InstallProfile{Payload: mc},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue.payloads) != 1 || !profile.Mobileconfig(queue.payloads[0]).IsSigned() {
+		t.Fatal("expected the queued profile to be signed")
+	}
+}
+
+type recordingExpander struct {
+	udid, userID string
+}
+
+func (e *recordingExpander) ExpandProfile(ctx context.Context, udid, userID string, payload []byte) ([]byte, error) {
+	e.udid, e.userID = udid, userID
+	return payload, nil
+}
+
+func TestProfileVariablesMiddlewareTargetUser(t *testing.T) {
+	users := userTargetStore{
+		{UDID: "device-1", UserID: "user-guid-1", UserShortname: "alice"},
+	}
+	expander := &recordingExpander{}
+	queue := &queuedPayloads{}
+	svc := command.UserTargetMiddleware(users)(command.ProfileVariablesMiddleware(expander, nil)(queue))
+	_, err := svc.NewCommand(context.Background(), &mdm.CommandRequest{
+		UserID: "user-guid-1",
+		Command: &mdm.Command{
+			RequestType:    "InstallProfile",
+			InstallProfile: &mdm.InstallProfile{Payload: []byte("payload")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expander.udid != "device-1" || expander.userID != "user-guid-1" {
+		t.Errorf("have expanded for udid %q user %q, want device-1 user-guid-1", expander.udid, expander.userID)
+	}
+}
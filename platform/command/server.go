@@ -4,6 +4,7 @@ import (
 	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
+	"github.com/micromdm/micromdm/pkg/export"
 	"github.com/micromdm/micromdm/pkg/httputil"
 )
 
@@ -12,6 +13,8 @@ type Endpoints struct {
 	NewRawCommandEndpoint endpoint.Endpoint
 	ClearQueueEndpoint    endpoint.Endpoint
 	ViewQueueEndpoint     endpoint.Endpoint
+
+	ExportCommandHistoryEndpoint endpoint.Endpoint
 }
 
 func MakeServerEndpoints(s Service, outer endpoint.Middleware, others ...endpoint.Middleware) Endpoints {
@@ -20,10 +23,21 @@ func MakeServerEndpoints(s Service, outer endpoint.Middleware, others ...endpoin
 		NewRawCommandEndpoint: endpoint.Chain(outer, others...)(MakeNewRawCommandEndpoint(s)),
 		ClearQueueEndpoint:    endpoint.Chain(outer, others...)(MakeClearQueueEndpoint(s)),
 		ViewQueueEndpoint:     endpoint.Chain(outer, others...)(MakeViewQueueEndpoint(s)),
+
+		ExportCommandHistoryEndpoint: endpoint.Chain(outer, others...)(MakeExportCommandHistoryEndpoint(s)),
 	}
 }
 
 func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.ServerOption) {
+	// GET /v1/commands/export		Export the command history of all devices as CSV or NDJSON.
+	// Registered before the udid routes so that "export" is not matched as a UDID.
+	r.Methods("GET").Path("/v1/commands/export").Handler(httptransport.NewServer(
+		e.ExportCommandHistoryEndpoint,
+		decodeExportCommandHistoryRequest,
+		export.EncodeResponse,
+		options...,
+	))
+
 	// GET /v1/commands/udid		View device queue.
 	r.Methods("GET").Path("/v1/commands/{udid}").Handler(httptransport.NewServer(
 		e.ViewQueueEndpoint,
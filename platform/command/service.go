@@ -13,6 +13,7 @@ type Service interface {
 	NewRawCommand(context.Context, *RawCommand) error
 	ClearQueue(ctx context.Context, udid string) error
 	ViewQueue(ctx context.Context, udid string) ([]*mdmsvc.Command, error)
+	ExportCommandHistory(ctx context.Context, fn func(HistoryRecord) error) error
 }
 
 // Queue is an MDM Command Queue.
@@ -24,12 +25,25 @@ type Queue interface {
 type CommandService struct {
 	publisher pubsub.Publisher
 	queue     Queue
+	history   History
 }
 
-func New(pub pubsub.Publisher, queue Queue) (*CommandService, error) {
+type Option func(*CommandService)
+
+// WithHistory enables the command history export.
+func WithHistory(h History) Option {
+	return func(svc *CommandService) {
+		svc.history = h
+	}
+}
+
+func New(pub pubsub.Publisher, queue Queue, opts ...Option) (*CommandService, error) {
 	svc := CommandService{
 		publisher: pub,
 		queue:     queue,
 	}
+	for _, opt := range opts {
+		opt(&svc)
+	}
 	return &svc, nil
 }
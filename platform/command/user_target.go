@@ -0,0 +1,82 @@
+package command
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/platform/user"
+)
+
+// UserStore looks up the managed users which checked in on the user channel
+// of a device.
+type UserStore interface {
+	UserByUserID(userID string) (*user.User, error)
+	DeviceUsers(udid string) ([]user.User, error)
+}
+
+// UserTargetMiddleware queues commands with a UserID or UserShortname for the
+// user channel of the managed user. The queue and push notifications of the
+// user channel are keyed by the UserID, which replaces the UDID of the
+// request. The resolved user is passed to the next service in the context,
+// see TargetUser.
+func UserTargetMiddleware(users UserStore) Middleware {
+	return func(next Service) Service {
+		return userTargetMiddleware{Service: next, users: users}
+	}
+}
+
+type userTargetMiddleware struct {
+	Service
+	users UserStore
+}
+
+func (mw userTargetMiddleware) NewCommand(ctx context.Context, request *mdm.CommandRequest) (*mdm.CommandPayload, error) {
+	if request == nil || (request.UserID == "" && request.UserShortname == "") {
+		return mw.Service.NewCommand(ctx, request)
+	}
+	u, err := mw.user(request)
+	if err != nil {
+		return nil, err
+	}
+	targeted := *request
+	targeted.UDID = u.UserID
+	targeted.UserID, targeted.UserShortname = "", ""
+	ctx = context.WithValue(ctx, targetUserKey{}, u)
+	return mw.Service.NewCommand(ctx, &targeted)
+}
+
+func (mw userTargetMiddleware) user(request *mdm.CommandRequest) (*user.User, error) {
+	if request.UserID != "" {
+		u, err := mw.users.UserByUserID(request.UserID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get managed user %s", request.UserID)
+		}
+		if request.UDID != "" && u.UDID != request.UDID {
+			return nil, errors.Errorf("managed user %s is not a user of device %s", request.UserID, request.UDID)
+		}
+		return u, nil
+	}
+	if request.UDID == "" {
+		return nil, errors.New("user_shortname requires the udid of the device")
+	}
+	users, err := mw.users.DeviceUsers(request.UDID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get users of device %s", request.UDID)
+	}
+	for _, u := range users {
+		if u.UserShortname == request.UserShortname && u.UserID != "" {
+			return &u, nil
+		}
+	}
+	return nil, errors.Errorf("no managed user %s on device %s", request.UserShortname, request.UDID)
+}
+
+type targetUserKey struct{}
+
+// TargetUser returns the managed user which UserTargetMiddleware resolved for
+// the command, or false if the command is queued for a device.
+func TargetUser(ctx context.Context) (*user.User, bool) {
+	u, ok := ctx.Value(targetUserKey{}).(*user.User)
+	return u, ok
+}
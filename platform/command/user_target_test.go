@@ -0,0 +1,83 @@
+package command_test
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/platform/command"
+	"github.com/micromdm/micromdm/platform/user"
+)
+
+type userTargetStore []user.User
+
+func (s userTargetStore) UserByUserID(userID string) (*user.User, error) {
+	for _, u := range s {
+		if u.UserID == userID {
+			return &u, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (s userTargetStore) DeviceUsers(udid string) ([]user.User, error) {
+	var users []user.User
+	for _, u := range s {
+		if u.UDID == udid {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+type queuedCommands struct {
+	command.Service
+	udids []string
+}
+
+func (q *queuedCommands) NewCommand(ctx context.Context, request *mdm.CommandRequest) (*mdm.CommandPayload, error) {
+	q.udids = append(q.udids, request.UDID)
+	return mdm.NewCommandPayload(request)
+}
+
+func TestUserTargetMiddleware(t *testing.T) {
+	users := userTargetStore{
+		{UDID: "device-1", UserID: "user-guid-1", UserShortname: "alice"},
+		{UDID: "device-2", UserID: "user-guid-2", UserShortname: "alice"},
+	}
+	tests := []struct {
+		name    string
+		request mdm.CommandRequest
+		want    string
+		wantErr bool
+	}{
+		{name: "device", request: mdm.CommandRequest{UDID: "device-1"}, want: "device-1"},
+		{name: "shortname", request: mdm.CommandRequest{UDID: "device-2", UserShortname: "alice"}, want: "user-guid-2"},
+		{name: "user id", request: mdm.CommandRequest{UserID: "user-guid-1"}, want: "user-guid-1"},
+		{name: "user id of other device", request: mdm.CommandRequest{UDID: "device-2", UserID: "user-guid-1"}, wantErr: true},
+		{name: "unknown shortname", request: mdm.CommandRequest{UDID: "device-1", UserShortname: "bob"}, wantErr: true},
+		{name: "shortname without device", request: mdm.CommandRequest{UserShortname: "alice"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &queuedCommands{}
+			svc := command.UserTargetMiddleware(users)(queue)
+			tt.request.Command = &mdm.Command{RequestType: "ProfileList"}
+			_, err := svc.NewCommand(context.Background(), &tt.request)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, queued for %v", queue.udids)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(queue.udids) != 1 || queue.udids[0] != tt.want {
+				t.Errorf("have queued for %v, want %s", queue.udids, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AdmissionPolicy restricts which devices can enroll. It is evaluated when a
+// device sends the Authenticate check-in. A zero policy admits every device.
+type AdmissionPolicy struct {
+	// KnownSerialsOnly only admits devices whose serial number was seen in
+	// a DEP sync or is listed in Serials.
+	KnownSerialsOnly bool     `json:"known_serials_only,omitempty"`
+	Serials          []string `json:"serials,omitempty"`
+
+	// MinOSVersions maps a ProductName prefix, like "iPhone" or "Mac", to
+	// the minimum OSVersion of the devices with a matching ProductName.
+	MinOSVersions map[string]string `json:"min_os_versions,omitempty"`
+
+	// ProductNamePrefixes only admits devices whose ProductName starts
+	// with one of the prefixes, like "iPad" or "MacBookPro".
+	ProductNamePrefixes []string `json:"product_name_prefixes,omitempty"`
+}
+
+// AdmissionRequest describes a device asking to enroll.
+type AdmissionRequest struct {
+	SerialNumber string
+	ProductName  string
+	OSVersion    string
+	// KnownSerial is set if the serial number was seen in a DEP sync.
+	KnownSerial bool
+}
+
+// Validate checks the values of the policy.
+func (p *AdmissionPolicy) Validate() error {
+	for prefix, version := range p.MinOSVersions {
+		if prefix == "" {
+			return fmt.Errorf("min_os_versions has an empty ProductName prefix")
+		}
+		if _, err := parseVersion(version); err != nil {
+			return fmt.Errorf("min_os_versions %s: %s", prefix, err)
+		}
+	}
+	for _, prefix := range p.ProductNamePrefixes {
+		if prefix == "" {
+			return fmt.Errorf("product_name_prefixes has an empty prefix")
+		}
+	}
+	return nil
+}
+
+// Evaluate returns the reasons the device is rejected by the policy. The
+// device is admitted if there are none.
+func (p *AdmissionPolicy) Evaluate(req AdmissionRequest) []string {
+	var reasons []string
+	if p.KnownSerialsOnly && !req.KnownSerial && !p.listsSerial(req.SerialNumber) {
+		reasons = append(reasons, fmt.Sprintf("serial number %q is not known", req.SerialNumber))
+	}
+	if len(p.ProductNamePrefixes) > 0 && !hasAnyPrefix(req.ProductName, p.ProductNamePrefixes) {
+		reasons = append(reasons, fmt.Sprintf("product %q is not allowed", req.ProductName))
+	}
+	// the longest matching prefix decides the minimum version, so that
+	// "MacBookAir" can be set apart from "Mac".
+	var prefix string
+	for pfx := range p.MinOSVersions {
+		if strings.HasPrefix(req.ProductName, pfx) && len(pfx) > len(prefix) {
+			prefix = pfx
+		}
+	}
+	if prefix != "" {
+		min := p.MinOSVersions[prefix]
+		if !versionAtLeast(req.OSVersion, min) {
+			reasons = append(reasons, fmt.Sprintf("OS version %q of %s is older than %s", req.OSVersion, req.ProductName, min))
+		}
+	}
+	return reasons
+}
+
+func (p *AdmissionPolicy) listsSerial(serial string) bool {
+	for _, s := range p.Serials {
+		if strings.EqualFold(s, serial) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// versionAtLeast reports whether version is min or newer. A version which
+// can't be parsed is never new enough.
+func versionAtLeast(version, min string) bool {
+	have, err := parseVersion(version)
+	if err != nil {
+		return false
+	}
+	want, _ := parseVersion(min)
+	for i := 0; i < len(have) || i < len(want); i++ {
+		var h, w int
+		if i < len(have) {
+			h = have[i]
+		}
+		if i < len(want) {
+			w = want[i]
+		}
+		if h != w {
+			return h > w
+		}
+	}
+	return true
+}
+
+func parseVersion(version string) ([]int, error) {
+	var parts []int
+	for _, s := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestAdmissionPolicyEvaluate(t *testing.T) {
+	policy := AdmissionPolicy{
+		KnownSerialsOnly:    true,
+		Serials:             []string{"C02IMPORTED"},
+		MinOSVersions:       map[string]string{"Mac": "13.0", "MacBookAir": "14.2"},
+		ProductNamePrefixes: []string{"Mac", "iPad"},
+	}
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		req     AdmissionRequest
+		reasons int
+	}{
+		{"dep", AdmissionRequest{SerialNumber: "C02DEP", ProductName: "MacBookPro18,1", OSVersion: "13.4.1", KnownSerial: true}, 0},
+		{"imported", AdmissionRequest{SerialNumber: "c02imported", ProductName: "iPad13,1", OSVersion: "15.0"}, 0},
+		{"unknown", AdmissionRequest{SerialNumber: "C02OTHER", ProductName: "Mac14,2", OSVersion: "14.0"}, 1},
+		{"old", AdmissionRequest{SerialNumber: "C02DEP", ProductName: "MacBookPro18,1", OSVersion: "12.6", KnownSerial: true}, 1},
+		{"longest prefix", AdmissionRequest{SerialNumber: "C02DEP", ProductName: "MacBookAir10,1", OSVersion: "14.1", KnownSerial: true}, 1},
+		{"product", AdmissionRequest{SerialNumber: "C02OTHER", ProductName: "iPhone15,2", OSVersion: "17.0"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons := policy.Evaluate(tt.req)
+			if have, want := len(reasons), tt.reasons; have != want {
+				t.Errorf("have %d reasons %v, want %d", have, reasons, want)
+			}
+		})
+	}
+
+	var empty AdmissionPolicy
+	if reasons := empty.Evaluate(AdmissionRequest{}); reasons != nil {
+		t.Errorf("empty policy rejected device: %v", reasons)
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version, min string
+		want         bool
+	}{
+		{"13.0", "13", true},
+		{"13.0.1", "13.0", true},
+		{"12.6.8", "13.0", false},
+		{"16.10", "16.9", true},
+		{"", "13.0", false},
+	}
+	for _, tt := range tests {
+		if have := versionAtLeast(tt.version, tt.min); have != tt.want {
+			t.Errorf("versionAtLeast(%q, %q): have %v, want %v", tt.version, tt.min, have, tt.want)
+		}
+	}
+	if _, err := parseVersion("13.x"); err == nil {
+		t.Error("expected invalid version error")
+	}
+}
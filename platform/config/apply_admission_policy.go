@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func (svc *ConfigService) ApplyAdmissionPolicy(ctx context.Context, policy AdmissionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return errors.Wrap(err, "validate admission policy")
+	}
+	err := svc.store.SaveAdmissionPolicy(&policy)
+	return errors.Wrap(err, "save admission policy")
+}
+
+type applyAdmissionPolicyRequest struct {
+	Policy AdmissionPolicy `json:"policy"`
+}
+
+type applyAdmissionPolicyResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+func (r applyAdmissionPolicyResponse) Failed() error { return r.Err }
+
+func decodeApplyAdmissionPolicyRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req applyAdmissionPolicyRequest
+	err := httputil.DecodeJSONRequest(r, &req)
+	return req, err
+}
+
+func decodeApplyAdmissionPolicyResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp applyAdmissionPolicyResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeApplyAdmissionPolicyEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(applyAdmissionPolicyRequest)
+		err = svc.ApplyAdmissionPolicy(ctx, req.Policy)
+		return applyAdmissionPolicyResponse{Err: err}, nil
+	}
+}
+
+func (e Endpoints) ApplyAdmissionPolicy(ctx context.Context, policy AdmissionPolicy) error {
+	response, err := e.ApplyAdmissionPolicyEndpoint(ctx, applyAdmissionPolicyRequest{Policy: policy})
+	if err != nil {
+		return err
+	}
+	return response.(applyAdmissionPolicyResponse).Err
+}
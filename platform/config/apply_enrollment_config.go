@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func (svc *ConfigService) ApplyEnrollmentConfig(ctx context.Context, conf EnrollmentConfig) error {
+	if err := conf.Validate(); err != nil {
+		return errors.Wrap(err, "validate enrollment config")
+	}
+	err := svc.store.SaveEnrollmentConfig(&conf)
+	return errors.Wrap(err, "save enrollment config")
+}
+
+type applyEnrollmentConfigRequest struct {
+	Config EnrollmentConfig `json:"config"`
+}
+
+type applyEnrollmentConfigResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+func (r applyEnrollmentConfigResponse) Failed() error { return r.Err }
+
+func decodeApplyEnrollmentConfigRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req applyEnrollmentConfigRequest
+	err := httputil.DecodeJSONRequest(r, &req)
+	return req, err
+}
+
+func decodeApplyEnrollmentConfigResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp applyEnrollmentConfigResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeApplyEnrollmentConfigEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(applyEnrollmentConfigRequest)
+		err = svc.ApplyEnrollmentConfig(ctx, req.Config)
+		return applyEnrollmentConfigResponse{Err: err}, nil
+	}
+}
+
+func (e Endpoints) ApplyEnrollmentConfig(ctx context.Context, conf EnrollmentConfig) error {
+	response, err := e.ApplyEnrollmentConfigEndpoint(ctx, applyEnrollmentConfigRequest{Config: conf})
+	if err != nil {
+		return err
+	}
+	return response.(applyEnrollmentConfigResponse).Err
+}
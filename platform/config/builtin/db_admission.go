@@ -0,0 +1,39 @@
+package builtin
+
+import (
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/config"
+)
+
+const admissionPolicyKey = "admission"
+
+// SaveAdmissionPolicy saves the enrollment admission policy.
+func (db *DB) SaveAdmissionPolicy(policy *config.AdmissionPolicy) error {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return errors.Wrap(err, "marshal admission policy")
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(ConfigBucket))
+		return bkt.Put([]byte(admissionPolicyKey), data)
+	})
+	return errors.Wrap(err, "save admission policy in bolt")
+}
+
+// AdmissionPolicy returns the saved admission policy, or an empty policy
+// which admits every device if none was saved.
+func (db *DB) AdmissionPolicy() (*config.AdmissionPolicy, error) {
+	var policy config.AdmissionPolicy
+	err := db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(ConfigBucket)).Get([]byte(admissionPolicyKey))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &policy)
+	})
+	return &policy, errors.Wrap(err, "get admission policy from bolt")
+}
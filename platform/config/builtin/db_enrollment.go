@@ -0,0 +1,44 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/config"
+)
+
+const enrollmentConfigKey = "enrollment"
+
+// SaveEnrollmentConfig saves the enrollment config and publishes it to the
+// EnrollmentConfigTopic.
+func (db *DB) SaveEnrollmentConfig(conf *config.EnrollmentConfig) error {
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return errors.Wrap(err, "marshal enrollment config")
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(ConfigBucket))
+		return bkt.Put([]byte(enrollmentConfigKey), data)
+	})
+	if err != nil {
+		return errors.Wrap(err, "save enrollment config in bolt")
+	}
+	return db.Publisher.Publish(context.TODO(), config.EnrollmentConfigTopic, data)
+}
+
+// EnrollmentConfig returns the saved enrollment config, or an empty config
+// if none was saved.
+func (db *DB) EnrollmentConfig() (*config.EnrollmentConfig, error) {
+	var conf config.EnrollmentConfig
+	err := db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(ConfigBucket)).Get([]byte(enrollmentConfigKey))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &conf)
+	})
+	return &conf, errors.Wrap(err, "get enrollment config from bolt")
+}
@@ -48,9 +48,59 @@ func NewHTTPClient(instance, token string, logger log.Logger, opts ...httptransp
 		).Endpoint()
 	}
 
+	var applyEnrollmentConfigEndpoint endpoint.Endpoint
+	{
+		applyEnrollmentConfigEndpoint = httptransport.NewClient(
+			"PUT",
+			httputil.CopyURL(u, "/v1/config/enrollment"),
+			httputil.EncodeRequestWithToken(token, httptransport.EncodeJSONRequest),
+			decodeApplyEnrollmentConfigResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var getEnrollmentConfigEndpoint endpoint.Endpoint
+	{
+		getEnrollmentConfigEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/config/enrollment"),
+			httputil.EncodeRequestWithToken(token, httputil.EncodeEmptyRequest),
+			decodeGetEnrollmentConfigResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var applyAdmissionPolicyEndpoint endpoint.Endpoint
+	{
+		applyAdmissionPolicyEndpoint = httptransport.NewClient(
+			"PUT",
+			httputil.CopyURL(u, "/v1/config/admission"),
+			httputil.EncodeRequestWithToken(token, httptransport.EncodeJSONRequest),
+			decodeApplyAdmissionPolicyResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var getAdmissionPolicyEndpoint endpoint.Endpoint
+	{
+		getAdmissionPolicyEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/config/admission"),
+			httputil.EncodeRequestWithToken(token, httputil.EncodeEmptyRequest),
+			decodeGetAdmissionPolicyResponse,
+			opts...,
+		).Endpoint()
+	}
+
 	return Endpoints{
 		SavePushCertificateEndpoint: saveEndpoint,
 		ApplyDEPTokensEndpoint:      applyDEPTokensEndpoint,
 		GetDEPTokensEndpoint:        getDEPTokensEndpoint,
+
+		ApplyEnrollmentConfigEndpoint: applyEnrollmentConfigEndpoint,
+		GetEnrollmentConfigEndpoint:   getEnrollmentConfigEndpoint,
+
+		ApplyAdmissionPolicyEndpoint: applyAdmissionPolicyEndpoint,
+		GetAdmissionPolicyEndpoint:   getAdmissionPolicyEndpoint,
 	}, nil
 }
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/micromdm/plist"
+	"github.com/pkg/errors"
+)
+
+const EnrollmentConfigTopic = "mdm.EnrollmentConfigUpdated"
+
+// EnrollmentConfig changes the contents of the enrollment profile served by
+// the server. Zero values keep the defaults of the server.
+type EnrollmentConfig struct {
+	Organization string `json:"organization,omitempty"`
+	DisplayName  string `json:"display_name,omitempty"`
+	Description  string `json:"description,omitempty"`
+
+	// AccessRights of the MDM payload, a bitmask of the rights from 1 to
+	// 4096. The default of 8191 grants all rights.
+	AccessRights int `json:"access_rights,omitempty"`
+	// CheckOutWhenRemoved defaults to true.
+	CheckOutWhenRemoved *bool `json:"check_out_when_removed,omitempty"`
+
+	// KeySize of the SCEP identity, 1024, 2048 or 4096. The default is
+	// 2048.
+	KeySize int `json:"key_size,omitempty"`
+	// KeyUsage of the SCEP identity, 1 for signing, 4 for encryption or 5
+	// for both. The default is 5.
+	KeyUsage int `json:"key_usage,omitempty"`
+
+	// Certificates are PEM encoded certificates, like a root CA, added to
+	// the profile as certificate payloads.
+	Certificates []string `json:"certificates,omitempty"`
+	// Payloads are plist encoded payload dictionaries added to the profile
+	// as they are.
+	Payloads [][]byte `json:"payloads,omitempty"`
+}
+
+// Validate checks the values of the configuration.
+func (c *EnrollmentConfig) Validate() error {
+	if c.AccessRights < 0 || c.AccessRights > 8191 {
+		return fmt.Errorf("access_rights %d is not a bitmask of the rights from 1 to 4096", c.AccessRights)
+	}
+	switch c.KeySize {
+	case 0, 1024, 2048, 4096:
+	default:
+		return fmt.Errorf("key_size %d must be 1024, 2048 or 4096", c.KeySize)
+	}
+	switch c.KeyUsage {
+	case 0, 1, 4, 5:
+	default:
+		return fmt.Errorf("key_usage %d must be 1, 4 or 5", c.KeyUsage)
+	}
+	if _, err := c.ParseCertificates(); err != nil {
+		return err
+	}
+	for i, data := range c.Payloads {
+		var payload struct {
+			PayloadType       string
+			PayloadIdentifier string
+			PayloadUUID       string
+		}
+		if err := plist.Unmarshal(data, &payload); err != nil {
+			return errors.Wrapf(err, "payload %d", i)
+		}
+		if payload.PayloadType == "" || payload.PayloadIdentifier == "" || payload.PayloadUUID == "" {
+			return fmt.Errorf("payload %d must have a PayloadType, PayloadIdentifier and PayloadUUID", i)
+		}
+	}
+	return nil
+}
+
+// ParseCertificates parses the PEM encoded Certificates.
+func (c *EnrollmentConfig) ParseCertificates() ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for i, data := range c.Certificates {
+		block, _ := pem.Decode([]byte(data))
+		if block == nil || block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("certificate %d is not a PEM encoded certificate", i)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse certificate %d", i)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func (svc *ConfigService) GetAdmissionPolicy(ctx context.Context) (*AdmissionPolicy, error) {
+	return svc.store.AdmissionPolicy()
+}
+
+type getAdmissionPolicyResponse struct {
+	Policy *AdmissionPolicy `json:"policy,omitempty"`
+	Err    error            `json:"err,omitempty"`
+}
+
+func (r getAdmissionPolicyResponse) Failed() error { return r.Err }
+
+func decodeGetAdmissionPolicyRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeGetAdmissionPolicyResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp getAdmissionPolicyResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeGetAdmissionPolicyEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		policy, err := svc.GetAdmissionPolicy(ctx)
+		return getAdmissionPolicyResponse{Policy: policy, Err: err}, nil
+	}
+}
+
+func (e Endpoints) GetAdmissionPolicy(ctx context.Context) (*AdmissionPolicy, error) {
+	resp, err := e.GetAdmissionPolicyEndpoint(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(getAdmissionPolicyResponse)
+	return response.Policy, response.Err
+}
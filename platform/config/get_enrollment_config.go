@@ -0,0 +1,47 @@
+package config
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func (svc *ConfigService) GetEnrollmentConfig(ctx context.Context) (*EnrollmentConfig, error) {
+	return svc.store.EnrollmentConfig()
+}
+
+type getEnrollmentConfigResponse struct {
+	Config *EnrollmentConfig `json:"config,omitempty"`
+	Err    error             `json:"err,omitempty"`
+}
+
+func (r getEnrollmentConfigResponse) Failed() error { return r.Err }
+
+func decodeGetEnrollmentConfigRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeGetEnrollmentConfigResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp getEnrollmentConfigResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeGetEnrollmentConfigEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		conf, err := svc.GetEnrollmentConfig(ctx)
+		return getEnrollmentConfigResponse{Config: conf, Err: err}, nil
+	}
+}
+
+func (e Endpoints) GetEnrollmentConfig(ctx context.Context) (*EnrollmentConfig, error) {
+	resp, err := e.GetEnrollmentConfigEndpoint(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(getEnrollmentConfigResponse)
+	return response.Config, response.Err
+}
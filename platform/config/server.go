@@ -12,6 +12,12 @@ type Endpoints struct {
 	GetPushCertificateEndpoint  endpoint.Endpoint
 	ApplyDEPTokensEndpoint      endpoint.Endpoint
 	GetDEPTokensEndpoint        endpoint.Endpoint
+
+	ApplyEnrollmentConfigEndpoint endpoint.Endpoint
+	GetEnrollmentConfigEndpoint   endpoint.Endpoint
+
+	ApplyAdmissionPolicyEndpoint endpoint.Endpoint
+	GetAdmissionPolicyEndpoint   endpoint.Endpoint
 }
 
 func MakeServerEndpoints(s Service, outer endpoint.Middleware, others ...endpoint.Middleware) Endpoints {
@@ -20,6 +26,12 @@ func MakeServerEndpoints(s Service, outer endpoint.Middleware, others ...endpoin
 		GetPushCertificateEndpoint:  endpoint.Chain(outer, others...)(MakeGetPushCertificateEndpoint(s)),
 		ApplyDEPTokensEndpoint:      endpoint.Chain(outer, others...)(MakeApplyDEPTokensEndpoint(s)),
 		GetDEPTokensEndpoint:        endpoint.Chain(outer, others...)(MakeGetDEPTokensEndpoint(s)),
+
+		ApplyEnrollmentConfigEndpoint: endpoint.Chain(outer, others...)(MakeApplyEnrollmentConfigEndpoint(s)),
+		GetEnrollmentConfigEndpoint:   endpoint.Chain(outer, others...)(MakeGetEnrollmentConfigEndpoint(s)),
+
+		ApplyAdmissionPolicyEndpoint: endpoint.Chain(outer, others...)(MakeApplyAdmissionPolicyEndpoint(s)),
+		GetAdmissionPolicyEndpoint:   endpoint.Chain(outer, others...)(MakeGetAdmissionPolicyEndpoint(s)),
 	}
 }
 
@@ -28,6 +40,10 @@ func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.S
 	// GET     /v1/config/certificate		retrieve the MDM Push Certificate
 	// PUT     /v1/dep-tokens				create or replace a DEP OAuth token
 	// GET     /v1/dep-tokens				get the OAuth Token used for the DEP client
+	// PUT     /v1/config/enrollment		create or replace the enrollment profile configuration
+	// GET     /v1/config/enrollment		get the enrollment profile configuration
+	// PUT     /v1/config/admission		create or replace the enrollment admission policy
+	// GET     /v1/config/admission		get the enrollment admission policy
 
 	r.Methods("PUT").Path("/v1/config/certificate").Handler(httptransport.NewServer(
 		e.SavePushCertificateEndpoint,
@@ -56,4 +72,32 @@ func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.S
 		httputil.EncodeJSONResponse,
 		options...,
 	))
+
+	r.Methods("PUT").Path("/v1/config/enrollment").Handler(httptransport.NewServer(
+		e.ApplyEnrollmentConfigEndpoint,
+		decodeApplyEnrollmentConfigRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/config/enrollment").Handler(httptransport.NewServer(
+		e.GetEnrollmentConfigEndpoint,
+		decodeGetEnrollmentConfigRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("PUT").Path("/v1/config/admission").Handler(httptransport.NewServer(
+		e.ApplyAdmissionPolicyEndpoint,
+		decodeApplyAdmissionPolicyRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/config/admission").Handler(httptransport.NewServer(
+		e.GetAdmissionPolicyEndpoint,
+		decodeGetAdmissionPolicyRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
 }
@@ -12,6 +12,10 @@ type Service interface {
 	GetPushCertificate(ctx context.Context) ([]byte, error)
 	ApplyDEPToken(ctx context.Context, P7MContent []byte) error
 	GetDEPTokens(ctx context.Context) ([]DEPToken, []byte, error)
+	ApplyEnrollmentConfig(ctx context.Context, conf EnrollmentConfig) error
+	GetEnrollmentConfig(ctx context.Context) (*EnrollmentConfig, error)
+	ApplyAdmissionPolicy(ctx context.Context, policy AdmissionPolicy) error
+	GetAdmissionPolicy(ctx context.Context) (*AdmissionPolicy, error)
 }
 
 type Store interface {
@@ -22,6 +26,10 @@ type Store interface {
 	DEPKeypair() (key *rsa.PrivateKey, cert *x509.Certificate, err error)
 	AddToken(consumerKey string, json []byte) error
 	DEPTokens() ([]DEPToken, error)
+	SaveEnrollmentConfig(conf *EnrollmentConfig) error
+	EnrollmentConfig() (*EnrollmentConfig, error)
+	SaveAdmissionPolicy(policy *AdmissionPolicy) error
+	AdmissionPolicy() (*AdmissionPolicy, error)
 }
 
 type ConfigService struct {
@@ -1,6 +1,7 @@
 package builtin
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 
@@ -20,6 +21,10 @@ const (
 	// The udidCertAuthBucket stores a simple mapping from UDID to
 	// sha256 hash of the device identity certificate for future validation
 	udidCertAuthBucket = "mdm.UDIDCertAuth"
+
+	// The udidCertBucket stores the DER encoded device identity certificate
+	// by UDID, used to encrypt profiles to the device.
+	udidCertBucket = "mdm.UDIDCert"
 )
 
 type DB struct {
@@ -37,6 +42,10 @@ func NewDB(db *bolt.DB) (*DB, error) {
 			return err
 		}
 		_, err = tx.CreateBucketIfNotExists([]byte(udidCertAuthBucket))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte(udidCertBucket))
 		return err
 	})
 	if err != nil {
@@ -57,28 +66,78 @@ func (db *DB) GetBootstrapToken(ctx context.Context, udid string) ([]byte, error
 
 func (db *DB) List(ctx context.Context, opt device.ListDevicesOption) ([]device.Device, error) {
 	var devices []device.Device
-	err := db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(DeviceBucket))
-		// TODO optimize by implemting Seek() and bytes.HasPrefix() so we don't
-		// hit all keys in the database if we dont have to.
-		return b.ForEach(func(k, v []byte) error {
-			var dev device.Device
-			if err := device.UnmarshalDevice(v, &dev); err != nil {
-				return err
-			}
-			if len(opt.FilterSerial) == 0 {
-				devices = append(devices, dev)
-				return nil
+	err := db.ForEach(ctx, opt, func(dev *device.Device) error {
+		devices = append(devices, *dev)
+		return nil
+	})
+	return devices, err
+}
+
+// forEachBatchSize is the number of devices ForEach reads in one
+// transaction.
+const forEachBatchSize = 100
+
+// ForEach calls fn for every device matching the filters of opt, without
+// loading all devices first. Devices are read in batches, and fn is called
+// outside of the read transaction, so a slow fn, like a write to a slow HTTP
+// client, does not hold the transaction open.
+func (db *DB) ForEach(ctx context.Context, opt device.ListDevicesOption, fn func(*device.Device) error) error {
+	var (
+		after []byte
+		done  bool
+	)
+	for !done {
+		var batch []device.Device
+		err := db.View(func(tx *bolt.Tx) error {
+			c := tx.Bucket([]byte(DeviceBucket)).Cursor()
+			k, v := c.First()
+			if after != nil {
+				if k, v = c.Seek(after); k != nil && bytes.Equal(k, after) {
+					k, v = c.Next()
+				}
 			}
-			for _, fs := range opt.FilterSerial {
-				if fs == dev.SerialNumber {
-					devices = append(devices, dev)
+			for ; k != nil; k, v = c.Next() {
+				if len(batch) == forEachBatchSize {
+					return nil
+				}
+				after = append(after[:0], k...)
+				var dev device.Device
+				if err := device.UnmarshalDevice(v, &dev); err != nil {
+					return err
+				}
+				if matchDevice(&dev, opt) {
+					batch = append(batch, dev)
 				}
 			}
+			done = true
 			return nil
 		})
-	})
-	return devices, err
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchDevice reports whether the device matches the filters of opt.
+func matchDevice(dev *device.Device, opt device.ListDevicesOption) bool {
+	if !dev.MatchAttributes(opt.FilterAttributes) {
+		return false
+	}
+	if len(opt.FilterSerial) == 0 {
+		return true
+	}
+	for _, fs := range opt.FilterSerial {
+		if fs == dev.SerialNumber {
+			return true
+		}
+	}
+	return false
 }
 
 func (db *DB) Save(ctx context.Context, dev *device.Device) error {
@@ -196,6 +255,39 @@ func (db *DB) deviceByIndex(key string) (*device.Device, error) {
 	return &dev, nil
 }
 
+// UpdateDevice loads the device by UDID and, if update returns true, saves
+// the changed device in the same transaction, so that concurrent updates of
+// other fields are not lost. It returns the device as updated.
+func (db *DB) UpdateDevice(ctx context.Context, udid string, update func(*device.Device) bool) (*device.Device, error) {
+	var dev device.Device
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(DeviceBucket))
+		idx := tx.Bucket([]byte(deviceIndexBucket)).Get([]byte(udid))
+		if idx == nil {
+			return &notFound{"Device", fmt.Sprintf("udid %s", udid)}
+		}
+		v := b.Get(idx)
+		if v == nil {
+			return &notFound{"Device", fmt.Sprintf("uuid %s", string(idx))}
+		}
+		if err := device.UnmarshalDevice(v, &dev); err != nil {
+			return err
+		}
+		if !update(&dev) {
+			return nil
+		}
+		data, err := device.MarshalDevice(&dev)
+		if err != nil {
+			return errors.Wrap(err, "marshalling device")
+		}
+		return b.Put(idx, data)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update device")
+	}
+	return &dev, nil
+}
+
 func (db *DB) SaveUDIDCertHash(udid, certHash []byte) error {
 	tx, err := db.DB.Begin(true)
 	if err != nil {
@@ -211,6 +303,24 @@ func (db *DB) SaveUDIDCertHash(udid, certHash []byte) error {
 	return tx.Commit()
 }
 
+// DeleteUDIDCertHash deletes the cert hash and the identity certificate of
+// the device.
+func (db *DB) DeleteUDIDCertHash(udid []byte) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{udidCertAuthBucket, udidCertBucket} {
+			b := tx.Bucket([]byte(name))
+			if b == nil {
+				return fmt.Errorf("bucket %q not found!", name)
+			}
+			if err := b.Delete(udid); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrapf(err, "delete udid cert hash for %s", string(udid))
+}
+
 func (db *DB) GetUDIDCertHash(udid []byte) ([]byte, error) {
 	var certHash []byte
 	err := db.View(func(tx *bolt.Tx) error {
@@ -226,3 +336,33 @@ func (db *DB) GetUDIDCertHash(udid []byte) ([]byte, error) {
 	})
 	return certHash, err
 }
+
+func (db *DB) SaveUDIDCert(udid, cert []byte) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(udidCertBucket))
+		if b == nil {
+			return fmt.Errorf("bucket %q not found!", udidCertBucket)
+		}
+		return b.Put(udid, cert)
+	})
+	return errors.Wrapf(err, "save udid cert for %s", string(udid))
+}
+
+// GetUDIDCert returns the DER encoded identity certificate of the device.
+func (db *DB) GetUDIDCert(udid []byte) ([]byte, error) {
+	var cert []byte
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(udidCertBucket))
+		if b == nil {
+			return fmt.Errorf("bucket %q not found!", udidCertBucket)
+		}
+		v := b.Get(udid)
+		if v == nil {
+			return &notFound{"UDID", fmt.Sprintf("udid %s", string(udid))}
+		}
+		cert = make([]byte, len(v))
+		copy(cert, v)
+		return nil
+	})
+	return cert, err
+}
@@ -3,12 +3,14 @@ package builtin
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
 
 	"github.com/boltdb/bolt"
 
+	"github.com/micromdm/micromdm/pkg/export"
 	"github.com/micromdm/micromdm/platform/device"
 )
 
@@ -139,6 +141,242 @@ func TestDeleteBySerial(t *testing.T) {
 	}
 }
 
+func TestListFilterAttributes(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	devices := []*device.Device{
+		{UUID: "1", UDID: "UDID-1", Attributes: map[string]string{"department": "sales", "owner": "a@example.com"}},
+		{UUID: "2", UDID: "UDID-2", Attributes: map[string]string{"department": "engineering"}},
+		{UUID: "3", UDID: "UDID-3"},
+	}
+	for _, dev := range devices {
+		if err := db.Save(ctx, dev); err != nil {
+			t.Fatalf("saving device in datastore: %s", err)
+		}
+	}
+
+	tests := []struct {
+		filter map[string]string
+		want   int
+	}{
+		{filter: nil, want: 3},
+		{filter: map[string]string{"department": "sales"}, want: 1},
+		{filter: map[string]string{"department": "sales", "owner": "b@example.com"}, want: 0},
+		{filter: map[string]string{"cost_center": ""}, want: 0},
+	}
+	for _, tt := range tests {
+		list, err := db.List(ctx, device.ListDevicesOption{FilterAttributes: tt.filter})
+		if err != nil {
+			t.Fatalf("listing devices: %s", err)
+		}
+		if have, want := len(list), tt.want; have != want {
+			t.Errorf("filter %v: have %d devices, want %d", tt.filter, have, want)
+		}
+	}
+}
+
+func TestImportDeviceAttributes(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	dev := &device.Device{
+		UUID:         "a-b-c-d",
+		UDID:         "UDID-FOO-BAR-BAZ",
+		SerialNumber: "foobarbaz",
+		Attributes:   map[string]string{"department": "sales"},
+	}
+	if err := db.Save(ctx, dev); err != nil {
+		t.Fatalf("saving device in datastore: %s", err)
+	}
+
+	svc := device.New(db)
+	csv := "serial_number,owner,department\nfoobarbaz,a@example.com,\nmissing,b@example.com,sales\n"
+	result, err := svc.ImportDeviceAttributes(ctx, []byte(csv))
+	if err != nil {
+		t.Fatalf("importing attributes: %s", err)
+	}
+	if have, want := result.Updated, 1; have != want {
+		t.Errorf("have %d updated, want %d", have, want)
+	}
+	if have, want := len(result.NotFound), 1; have != want {
+		t.Fatalf("have %d not found, want %d", have, want)
+	}
+
+	haveDev, err := db.DeviceByUDID(ctx, dev.UDID)
+	if err != nil {
+		t.Fatalf("getting device by UDID: %s", err)
+	}
+	if have, want := haveDev.Attributes["owner"], "a@example.com"; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+	if _, ok := haveDev.Attributes["department"]; ok {
+		t.Errorf("expected empty cell to remove the department attribute")
+	}
+}
+
+func TestForEachBatches(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	for i := 0; i < 2*forEachBatchSize+1; i++ {
+		dev := &device.Device{UUID: fmt.Sprintf("uuid-%d", i), UDID: fmt.Sprintf("udid-%d", i)}
+		if err := db.Save(ctx, dev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seen := make(map[string]bool)
+	err := db.ForEach(ctx, device.ListDevicesOption{}, func(dev *device.Device) error {
+		// a write while iterating must not block on the read transaction.
+		dev.Notes = "seen"
+		if err := db.Save(ctx, dev); err != nil {
+			return err
+		}
+		seen[dev.UDID] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(seen), 2*forEachBatchSize+1; have != want {
+		t.Errorf("have %d devices, want %d", have, want)
+	}
+}
+
+func TestSetDeviceAttributesNotes(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	dev := &device.Device{UUID: "a-b-c-d", UDID: "UDID-FOO", SerialNumber: "foo"}
+	if err := db.Save(ctx, dev); err != nil {
+		t.Fatalf("saving device in datastore: %s", err)
+	}
+
+	svc := device.New(db)
+	notes := "returned by the owner"
+	err := svc.SetDeviceAttributes(ctx, device.SetDeviceAttributesOptions{
+		Serials:    []string{"foo"},
+		Attributes: map[string]string{"owner": "a@example.com"},
+		Notes:      &notes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	haveDev, err := db.DeviceByUDID(ctx, dev.UDID)
+	if err != nil {
+		t.Fatalf("getting device by UDID: %s", err)
+	}
+	if haveDev.Notes != notes || haveDev.Attributes["owner"] != "a@example.com" {
+		t.Errorf("have notes %q and attributes %v", haveDev.Notes, haveDev.Attributes)
+	}
+}
+
+func TestPurgeDevices(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	dev := &device.Device{
+		UUID:         "a-b-c-d",
+		UDID:         "UDID-FOO-BAR-BAZ",
+		SerialNumber: "foobarbaz",
+		Enrolled:     true,
+	}
+	if err := db.Save(ctx, dev); err != nil {
+		t.Fatalf("saving device in datastore: %s", err)
+	}
+	if err := db.SaveUDIDCertHash([]byte(dev.UDID), []byte("hash")); err != nil {
+		t.Fatalf("saving cert hash: %s", err)
+	}
+	if err := db.SaveUDIDCert([]byte(dev.UDID), []byte("cert")); err != nil {
+		t.Fatalf("saving cert: %s", err)
+	}
+
+	var purged []string
+	svc := device.New(db, device.WithPurgeFuncs(func(ctx context.Context, udid string) error {
+		purged = append(purged, udid)
+		return nil
+	}))
+	if err := svc.PurgeDevices(ctx, device.PurgeDevicesOptions{Serials: []string{dev.SerialNumber}}); err != nil {
+		t.Fatalf("purging device: %s", err)
+	}
+
+	if len(purged) != 1 || purged[0] != dev.UDID {
+		t.Errorf("have purged %v, want %s", purged, dev.UDID)
+	}
+	if _, err := db.DeviceByUDID(ctx, dev.UDID); err == nil {
+		t.Errorf("expected device to be deleted")
+	}
+	if _, err := db.GetUDIDCertHash([]byte(dev.UDID)); err == nil {
+		t.Errorf("expected cert hash to be deleted")
+	}
+	if _, err := db.GetUDIDCert([]byte(dev.UDID)); err == nil {
+		t.Errorf("expected cert to be deleted")
+	}
+
+	err := svc.PurgeDevices(ctx, device.PurgeDevicesOptions{
+		UDIDs:                   []string{dev.UDID},
+		RemoveEnrollmentProfile: true,
+	})
+	if err == nil {
+		t.Error("expected error removing the enrollment profile without a command sender")
+	}
+}
+
+func TestExportImportDevices(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	dev := &device.Device{
+		UUID:         "a-b-c-d",
+		UDID:         "UDID-FOO-BAR-BAZ",
+		SerialNumber: "foobarbaz",
+		AssetTag:     "old",
+		Description:  "MacBook Pro",
+	}
+	if err := db.Save(ctx, dev); err != nil {
+		t.Fatalf("saving device in datastore: %s", err)
+	}
+
+	svc := device.New(db)
+	var records []device.DeviceRecord
+	err := svc.ExportDevices(ctx, device.ListDevicesOption{}, func(r device.DeviceRecord) error {
+		records = append(records, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("exporting devices: %s", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("have %d exported devices, want 1", len(records))
+	}
+
+	// edit the exported CSV and import it again.
+	records[0].AssetTag = "A-1234"
+	var buf bytes.Buffer
+	w, err := export.NewWriter(&buf, export.CSV, device.DeviceRecordHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(records[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := svc.ImportDevices(ctx, buf.Bytes())
+	if err != nil {
+		t.Fatalf("importing devices: %s", err)
+	}
+	if have, want := result.Updated, 1; have != want {
+		t.Errorf("have %d updated, want %d", have, want)
+	}
+	haveDev, err := db.DeviceBySerial(ctx, dev.SerialNumber)
+	if err != nil {
+		t.Fatalf("getting device by serial: %s", err)
+	}
+	if have, want := haveDev.AssetTag, "A-1234"; have != want {
+		t.Errorf("have asset tag %s, want %s", have, want)
+	}
+	if have, want := haveDev.Description, dev.Description; have != want {
+		t.Errorf("have description %s, want %s", have, want)
+	}
+}
+
 func setupDB(t *testing.T) *DB {
 	f, _ := ioutil.TempFile("", "bolt-")
 	f.Close()
@@ -154,3 +392,34 @@ func setupDB(t *testing.T) *DB {
 	}
 	return devDB
 }
+
+func TestUpdateDevice(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	dev := &device.Device{UUID: "a-b-c-d", UDID: "UDID-FOO", SerialNumber: "foobarbaz", Stale: true}
+	if err := db.Save(ctx, dev); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := db.UpdateDevice(ctx, dev.UDID, func(d *device.Device) bool {
+		d.Stale = false
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := db.DeviceByUDID(ctx, dev.UDID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Stale || saved.Stale {
+		t.Error("expected device to be updated")
+	}
+	if have, want := saved.SerialNumber, dev.SerialNumber; have != want {
+		t.Errorf("have serial %s, want %s", have, want)
+	}
+
+	if _, err := db.UpdateDevice(ctx, "UDID-UNKNOWN", func(*device.Device) bool { return true }); err == nil {
+		t.Errorf("expected error for unknown device")
+	}
+}
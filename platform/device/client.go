@@ -38,9 +38,93 @@ func NewHTTPClient(instance, token string, logger log.Logger, opts ...httptransp
 		).Endpoint()
 	}
 
+	var setDeviceAttributesEndpoint endpoint.Endpoint
+	{
+		setDeviceAttributesEndpoint = httptransport.NewClient(
+			"PUT",
+			httputil.CopyURL(u, "/v1/devices/attributes"),
+			httputil.EncodeRequestWithToken(token, httptransport.EncodeJSONRequest),
+			decodeSetDeviceAttributesResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var removeDeviceAttributesEndpoint endpoint.Endpoint
+	{
+		removeDeviceAttributesEndpoint = httptransport.NewClient(
+			"DELETE",
+			httputil.CopyURL(u, "/v1/devices/attributes"),
+			httputil.EncodeRequestWithToken(token, httptransport.EncodeJSONRequest),
+			decodeRemoveDeviceAttributesResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var importDeviceAttributesEndpoint endpoint.Endpoint
+	{
+		importDeviceAttributesEndpoint = httptransport.NewClient(
+			"PUT",
+			httputil.CopyURL(u, "/v1/devices/attributes/csv"),
+			httputil.EncodeRequestWithToken(token, encodeImportDeviceAttributesRequest),
+			decodeImportDeviceAttributesResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var staleDevicesEndpoint endpoint.Endpoint
+	{
+		staleDevicesEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/devices/stale"),
+			httputil.EncodeRequestWithToken(token, httputil.EncodeEmptyRequest),
+			decodeStaleDevicesResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var purgeDevicesEndpoint endpoint.Endpoint
+	{
+		purgeDevicesEndpoint = httptransport.NewClient(
+			"DELETE",
+			httputil.CopyURL(u, "/v1/devices/purge"),
+			httputil.EncodeRequestWithToken(token, httptransport.EncodeJSONRequest),
+			decodePurgeDevicesResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var exportDevicesEndpoint endpoint.Endpoint
+	{
+		exportDevicesEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/devices/export"),
+			httputil.EncodeRequestWithToken(token, encodeExportDevicesRequest),
+			decodeExportDevicesResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var importDevicesEndpoint endpoint.Endpoint
+	{
+		importDevicesEndpoint = httptransport.NewClient(
+			"PUT",
+			httputil.CopyURL(u, "/v1/devices/import"),
+			httputil.EncodeRequestWithToken(token, encodeImportDevicesRequest),
+			decodeImportDevicesResponse,
+			opts...,
+		).Endpoint()
+	}
+
 	return Endpoints{
-		ListDevicesEndpoint:   listDevicesEndpoint,
-		RemoveDevicesEndpoint: removeDevicesEndpoint,
+		ListDevicesEndpoint:            listDevicesEndpoint,
+		RemoveDevicesEndpoint:          removeDevicesEndpoint,
+		SetDeviceAttributesEndpoint:    setDeviceAttributesEndpoint,
+		RemoveDeviceAttributesEndpoint: removeDeviceAttributesEndpoint,
+		ImportDeviceAttributesEndpoint: importDeviceAttributesEndpoint,
+		StaleDevicesEndpoint:           staleDevicesEndpoint,
+		PurgeDevicesEndpoint:           purgeDevicesEndpoint,
+		ExportDevicesEndpoint:          exportDevicesEndpoint,
+		ImportDevicesEndpoint:          importDevicesEndpoint,
 	}, nil
 
 }
@@ -39,6 +39,27 @@ type Device struct {
 	DEPProfileAssignedBy   string           `db:"dep_profile_assigned_by"`
 	LastSeen               time.Time        `db:"last_seen"`
 	BootstrapToken         []byte           `db:"bootstrap_token"`
+
+	// Attributes are arbitrary key/value pairs assigned to the device
+	// through the API, for example an owner email or a cost center.
+	Attributes map[string]string `db:"attributes"`
+	// Notes is free-form text about the device set through the API.
+	Notes string `db:"notes"`
+
+	// Stale is set by the StaleWorker once the device has not been seen
+	// for longer than the configured threshold.
+	Stale bool `db:"stale"`
+	// RePushTime is the last time the StaleWorker sent a push notification
+	// to the device.
+	RePushTime time.Time `db:"repush_time"`
+	// PurgeCommandUUID is the RemoveProfile command which was sent before
+	// purging the device. The purge completes once the device responds
+	// to the command or checks out.
+	PurgeCommandUUID string `db:"purge_command_uuid"`
+	// PurgeRequestedAt is the time the RemoveProfile command was queued.
+	// The StaleWorker purges the device anyway once the purge timeout
+	// has passed.
+	PurgeRequestedAt time.Time `db:"purge_requested_at"`
 }
 
 // DEPProfileStatus is the status of the DEP Profile
@@ -53,6 +74,17 @@ const (
 	REMOVED                   = "removed"
 )
 
+// MatchAttributes reports whether every key/value pair in want is set on
+// the device. An empty want matches all devices.
+func (dev *Device) MatchAttributes(want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := dev.Attributes[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
 func MarshalDevice(dev *Device) ([]byte, error) {
 	protodev := deviceproto.Device{
 		Uuid:                   dev.UUID,
@@ -82,6 +114,12 @@ func MarshalDevice(dev *Device) ([]byte, error) {
 		DepProfileAssignedBy:   dev.DEPProfileAssignedBy,
 		LastSeen:               timeToNano(dev.LastSeen),
 		BootstrapToken:         dev.BootstrapToken,
+		Attributes:             dev.Attributes,
+		Stale:                  dev.Stale,
+		RepushTime:             timeToNano(dev.RePushTime),
+		PurgeCommandUuid:       dev.PurgeCommandUUID,
+		PurgeRequestedAt:       timeToNano(dev.PurgeRequestedAt),
+		Notes:                  dev.Notes,
 	}
 	return proto.Marshal(&protodev)
 }
@@ -118,6 +156,12 @@ func UnmarshalDevice(data []byte, dev *Device) error {
 	dev.DEPProfileAssignedBy = pb.GetDepProfileAssignedBy()
 	dev.LastSeen = timeFromNano(pb.GetLastSeen())
 	dev.BootstrapToken = pb.GetBootstrapToken()
+	dev.Attributes = pb.GetAttributes()
+	dev.Stale = pb.GetStale()
+	dev.RePushTime = timeFromNano(pb.GetRepushTime())
+	dev.PurgeCommandUUID = pb.GetPurgeCommandUuid()
+	dev.PurgeRequestedAt = timeFromNano(pb.GetPurgeRequestedAt())
+	dev.Notes = pb.GetNotes()
 	return nil
 }
 
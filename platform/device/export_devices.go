@@ -0,0 +1,228 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/micromdm/micromdm/pkg/export"
+)
+
+// DeviceRecord is a device as written by the export endpoint.
+// Push tokens, the unlock token and the bootstrap token are not exported.
+type DeviceRecord struct {
+	UUID                   string            `json:"uuid"`
+	UDID                   string            `json:"udid"`
+	SerialNumber           string            `json:"serial_number"`
+	OSVersion              string            `json:"os_version"`
+	BuildVersion           string            `json:"build_version"`
+	ProductName            string            `json:"product_name"`
+	IMEI                   string            `json:"imei"`
+	MEID                   string            `json:"meid"`
+	Enrolled               bool              `json:"enrolled"`
+	AwaitingConfiguration  bool              `json:"awaiting_configuration"`
+	Description            string            `json:"description"`
+	Model                  string            `json:"model"`
+	ModelName              string            `json:"model_name"`
+	DeviceName             string            `json:"device_name"`
+	Color                  string            `json:"color"`
+	AssetTag               string            `json:"asset_tag"`
+	DEPProfileStatus       DEPProfileStatus  `json:"dep_profile_status"`
+	DEPProfileUUID         string            `json:"dep_profile_uuid"`
+	DEPProfileAssignTime   time.Time         `json:"dep_profile_assign_time"`
+	DEPProfilePushTime     time.Time         `json:"dep_profile_push_time"`
+	DEPProfileAssignedDate time.Time         `json:"dep_profile_assigned_date"`
+	DEPProfileAssignedBy   string            `json:"dep_profile_assigned_by"`
+	LastSeen               time.Time         `json:"last_seen"`
+	Stale                  bool              `json:"stale"`
+	Attributes             map[string]string `json:"attributes,omitempty"`
+	Notes                  string            `json:"notes,omitempty"`
+}
+
+// DeviceRecordHeader is the CSV header of an exported device.
+var DeviceRecordHeader = []string{
+	"uuid", "udid", "serial_number", "os_version", "build_version", "product_name",
+	"imei", "meid", "enrolled", "awaiting_configuration", "description", "model",
+	"model_name", "device_name", "color", "asset_tag", "dep_profile_status",
+	"dep_profile_uuid", "dep_profile_assign_time", "dep_profile_push_time",
+	"dep_profile_assigned_date", "dep_profile_assigned_by", "last_seen", "stale",
+	"attributes",
+}
+
+func newDeviceRecord(d *Device) DeviceRecord {
+	return DeviceRecord{
+		UUID:                   d.UUID,
+		UDID:                   d.UDID,
+		SerialNumber:           d.SerialNumber,
+		OSVersion:              d.OSVersion,
+		BuildVersion:           d.BuildVersion,
+		ProductName:            d.ProductName,
+		IMEI:                   d.IMEI,
+		MEID:                   d.MEID,
+		Enrolled:               d.Enrolled,
+		AwaitingConfiguration:  d.AwaitingConfiguration,
+		Description:            d.Description,
+		Model:                  d.Model,
+		ModelName:              d.ModelName,
+		DeviceName:             d.DeviceName,
+		Color:                  d.Color,
+		AssetTag:               d.AssetTag,
+		DEPProfileStatus:       d.DEPProfileStatus,
+		DEPProfileUUID:         d.DEPProfileUUID,
+		DEPProfileAssignTime:   d.DEPProfileAssignTime,
+		DEPProfilePushTime:     d.DEPProfilePushTime,
+		DEPProfileAssignedDate: d.DEPProfileAssignedDate,
+		DEPProfileAssignedBy:   d.DEPProfileAssignedBy,
+		LastSeen:               d.LastSeen,
+		Stale:                  d.Stale,
+		Attributes:             d.Attributes,
+		Notes:                  d.Notes,
+	}
+}
+
+// CSVRecord implements export.Record. Attributes are written as a single
+// column of comma separated key=value pairs.
+func (r DeviceRecord) CSVRecord() []string {
+	return []string{
+		r.UUID, r.UDID, r.SerialNumber, r.OSVersion, r.BuildVersion, r.ProductName,
+		r.IMEI, r.MEID, strconv.FormatBool(r.Enrolled), strconv.FormatBool(r.AwaitingConfiguration),
+		r.Description, r.Model, r.ModelName, r.DeviceName, r.Color, r.AssetTag,
+		string(r.DEPProfileStatus), r.DEPProfileUUID, formatTime(r.DEPProfileAssignTime),
+		formatTime(r.DEPProfilePushTime), formatTime(r.DEPProfileAssignedDate),
+		r.DEPProfileAssignedBy, formatTime(r.LastSeen), strconv.FormatBool(r.Stale),
+		formatAttributes(r.Attributes),
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatAttributes(attrs map[string]string) string {
+	pairs := make([]string, 0, len(attrs))
+	for k, v := range attrs {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// ExportDevices calls fn for every device matching opt. The devices are read
+// from the store one at a time, so large fleets are not held in memory.
+func (svc *DeviceService) ExportDevices(ctx context.Context, opt ListDevicesOption, fn func(DeviceRecord) error) error {
+	return svc.store.ForEach(ctx, opt, func(d *Device) error {
+		return fn(newDeviceRecord(d))
+	})
+}
+
+type exportDevicesRequest struct {
+	Opts   ListDevicesOption
+	Format string
+}
+
+type exportDevicesResponse struct {
+	// Devices is set when the response is decoded by the client.
+	Devices []DeviceRecord
+	// records streams the devices on the server.
+	records func(fn func(DeviceRecord) error) error
+	format  string
+	Err     error
+}
+
+func (r exportDevicesResponse) Failed() error    { return r.Err }
+func (r exportDevicesResponse) Format() string   { return r.format }
+func (r exportDevicesResponse) Header() []string { return DeviceRecordHeader }
+
+func (r exportDevicesResponse) WriteRecords(w *export.Writer) error {
+	return r.records(func(d DeviceRecord) error {
+		return w.Write(d)
+	})
+}
+
+func decodeExportDevicesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	format, err := export.ParseFormat(q.Get("format"))
+	if err != nil {
+		return nil, err
+	}
+	req := exportDevicesRequest{Format: format}
+	req.Opts.FilterSerial = q["filter_serial"]
+	for _, attr := range q["filter_attribute"] {
+		kv := strings.SplitN(attr, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid attribute filter %q, want key=value", attr)
+		}
+		if req.Opts.FilterAttributes == nil {
+			req.Opts.FilterAttributes = make(map[string]string)
+		}
+		req.Opts.FilterAttributes[kv[0]] = kv[1]
+	}
+	return req, nil
+}
+
+func encodeExportDevicesRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(exportDevicesRequest)
+	// the client always reads NDJSON.
+	q := url.Values{"format": []string{export.NDJSON}}
+	for _, serial := range req.Opts.FilterSerial {
+		q.Add("filter_serial", serial)
+	}
+	for k, v := range req.Opts.FilterAttributes {
+		q.Add("filter_attribute", k+"="+v)
+	}
+	r.URL.RawQuery = q.Encode()
+	return nil
+}
+
+func decodeExportDevicesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp exportDevicesResponse
+	err := export.DecodeNDJSON(r, func(dec *json.Decoder) error {
+		var d DeviceRecord
+		if err := dec.Decode(&d); err != nil {
+			return err
+		}
+		resp.Devices = append(resp.Devices, d)
+		return nil
+	})
+	return resp, err
+}
+
+func MakeExportDevicesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(exportDevicesRequest)
+		return exportDevicesResponse{
+			records: func(fn func(DeviceRecord) error) error {
+				return svc.ExportDevices(ctx, req.Opts, fn)
+			},
+			format: req.Format,
+		}, nil
+	}
+}
+
+func (e Endpoints) ExportDevices(ctx context.Context, opt ListDevicesOption, fn func(DeviceRecord) error) error {
+	resp, err := e.ExportDevicesEndpoint(ctx, exportDevicesRequest{Opts: opt})
+	if err != nil {
+		return err
+	}
+	response := resp.(exportDevicesResponse)
+	if response.Err != nil {
+		return response.Err
+	}
+	for _, d := range response.Devices {
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -16,14 +16,20 @@ type ListDevicesOption struct {
 
 	FilterSerial []string `json:"filter_serial"`
 	FilterUDID   []string `json:"filter_udid"`
+
+	// FilterAttributes limits the result to devices which have all of the
+	// given attribute values set.
+	FilterAttributes map[string]string `json:"filter_attributes"`
 }
 
 type DeviceDTO struct {
-	SerialNumber     string           `json:"serial_number"`
-	UDID             string           `json:"udid"`
-	EnrollmentStatus bool             `json:"enrollment_status"`
-	LastSeen         time.Time        `json:"last_seen"`
-	DEPProfileStatus DEPProfileStatus `json:"dep_profile_status"`
+	SerialNumber     string            `json:"serial_number"`
+	UDID             string            `json:"udid"`
+	EnrollmentStatus bool              `json:"enrollment_status"`
+	LastSeen         time.Time         `json:"last_seen"`
+	DEPProfileStatus DEPProfileStatus  `json:"dep_profile_status"`
+	Attributes       map[string]string `json:"attributes,omitempty"`
+	Notes            string            `json:"notes,omitempty"`
 }
 
 func (svc *DeviceService) ListDevices(ctx context.Context, opt ListDevicesOption) ([]DeviceDTO, error) {
@@ -36,6 +42,8 @@ func (svc *DeviceService) ListDevices(ctx context.Context, opt ListDevicesOption
 			EnrollmentStatus: d.Enrolled,
 			LastSeen:         d.LastSeen,
 			DEPProfileStatus: d.DEPProfileStatus,
+			Attributes:       d.Attributes,
+			Notes:            d.Notes,
 		})
 	}
 	return dto, err
@@ -0,0 +1,109 @@
+package device
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/micromdm/plist"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/mdm"
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+type StaleDeviceDTO struct {
+	SerialNumber    string           `json:"serial_number"`
+	UDID            string           `json:"udid"`
+	LastSeen        time.Time        `json:"last_seen"`
+	RePushTime      time.Time        `json:"repush_time,omitempty"`
+	PendingCommands []PendingCommand `json:"pending_commands,omitempty"`
+}
+
+type PendingCommand struct {
+	UUID        string `json:"uuid"`
+	RequestType string `json:"request_type,omitempty"`
+}
+
+func (svc *DeviceService) StaleDevices(ctx context.Context) ([]StaleDeviceDTO, error) {
+	devices, err := svc.store.List(ctx, ListDevicesOption{})
+	if err != nil {
+		return nil, err
+	}
+	var dto []StaleDeviceDTO
+	for _, d := range devices {
+		if !d.Stale {
+			continue
+		}
+		stale := StaleDeviceDTO{
+			SerialNumber: d.SerialNumber,
+			UDID:         d.UDID,
+			LastSeen:     d.LastSeen,
+			RePushTime:   d.RePushTime,
+		}
+		if svc.queue != nil {
+			stale.PendingCommands, err = svc.pendingCommands(ctx, d.UDID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		dto = append(dto, stale)
+	}
+	return dto, nil
+}
+
+func (svc *DeviceService) pendingCommands(ctx context.Context, udid string) ([]PendingCommand, error) {
+	cmds, err := svc.queue.ViewQueue(ctx, mdm.CheckinEvent{Command: mdm.CheckinCommand{UDID: udid}})
+	if err != nil {
+		return nil, errors.Wrapf(err, "view command queue for udid %s", udid)
+	}
+	var pending []PendingCommand
+	for _, cmd := range cmds {
+		var payload struct {
+			Command struct {
+				RequestType string
+			}
+		}
+		// the request type is informational, ignore payloads which can't be parsed.
+		_ = plist.Unmarshal(cmd.Payload, &payload)
+		pending = append(pending, PendingCommand{
+			UUID:        cmd.UUID,
+			RequestType: payload.Command.RequestType,
+		})
+	}
+	return pending, nil
+}
+
+type staleDevicesResponse struct {
+	Devices []StaleDeviceDTO `json:"devices"`
+	Err     error            `json:"err,omitempty"`
+}
+
+func (r staleDevicesResponse) Failed() error { return r.Err }
+
+func decodeStaleDevicesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeStaleDevicesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp staleDevicesResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeStaleDevicesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		devices, err := svc.StaleDevices(ctx)
+		return staleDevicesResponse{Devices: devices, Err: err}, nil
+	}
+}
+
+func (e Endpoints) StaleDevices(ctx context.Context) ([]StaleDeviceDTO, error) {
+	resp, err := e.StaleDevicesEndpoint(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(staleDevicesResponse)
+	return response.Devices, response.Err
+}
@@ -0,0 +1,171 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// ImportDeviceAttributesResult summarizes a bulk attribute import.
+type ImportDeviceAttributesResult struct {
+	Updated  int      `json:"updated"`
+	NotFound []string `json:"not_found,omitempty"`
+}
+
+// attributeRow is a single parsed row of an attribute CSV.
+type attributeRow struct {
+	UDID       string
+	Serial     string
+	Attributes map[string]string
+}
+
+// parseAttributesCSV reads device attributes from CSV. The header row must
+// have a "udid" or "serial_number" column which identifies the device; every
+// other column is an attribute key. An empty cell removes the attribute.
+func parseAttributesCSV(r io.Reader) ([]attributeRow, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "read csv header")
+	}
+	udidCol, serialCol := -1, -1
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+		switch header[i] {
+		case "udid":
+			udidCol = i
+		case "serial_number":
+			serialCol = i
+		}
+	}
+	if udidCol == -1 && serialCol == -1 {
+		return nil, errors.New("csv header must contain a udid or serial_number column")
+	}
+
+	var rows []attributeRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "read csv record")
+		}
+		row := attributeRow{Attributes: make(map[string]string)}
+		for i, value := range record {
+			switch i {
+			case udidCol:
+				row.UDID = value
+			case serialCol:
+				row.Serial = value
+			default:
+				row.Attributes[header[i]] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (svc *DeviceService) ImportDeviceAttributes(ctx context.Context, data []byte) (*ImportDeviceAttributesResult, error) {
+	rows, err := parseAttributesCSV(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var result ImportDeviceAttributesResult
+	for _, row := range rows {
+		if row.UDID == "" && row.Serial == "" {
+			continue
+		}
+		var (
+			dev *Device
+			id  string
+		)
+		if row.UDID != "" {
+			id = row.UDID
+			dev, err = svc.store.DeviceByUDID(ctx, row.UDID)
+		} else {
+			id = row.Serial
+			dev, err = svc.store.DeviceBySerial(ctx, row.Serial)
+		}
+		if err != nil && isNotFound(err) {
+			result.NotFound = append(result.NotFound, id)
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "get device %s", id)
+		}
+		_, err = svc.store.UpdateDevice(ctx, dev.UDID, func(dev *Device) bool {
+			if dev.Attributes == nil {
+				dev.Attributes = make(map[string]string, len(row.Attributes))
+			}
+			for k, v := range row.Attributes {
+				if v == "" {
+					delete(dev.Attributes, k)
+					continue
+				}
+				dev.Attributes[k] = v
+			}
+			return true
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "save attributes for device %s", id)
+		}
+		result.Updated++
+	}
+	return &result, nil
+}
+
+type importDeviceAttributesRequest struct{ CSV []byte }
+
+type importDeviceAttributesResponse struct {
+	*ImportDeviceAttributesResult
+	Err error `json:"err,omitempty"`
+}
+
+func (r importDeviceAttributesResponse) Failed() error { return r.Err }
+
+func decodeImportDeviceAttributesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	return importDeviceAttributesRequest{CSV: data}, err
+}
+
+func encodeImportDeviceAttributesRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(importDeviceAttributesRequest)
+	r.Header.Set("Content-Type", "text/csv")
+	r.ContentLength = int64(len(req.CSV))
+	r.Body = ioutil.NopCloser(bytes.NewReader(req.CSV))
+	return nil
+}
+
+func decodeImportDeviceAttributesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp importDeviceAttributesResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeImportDeviceAttributesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(importDeviceAttributesRequest)
+		result, err := svc.ImportDeviceAttributes(ctx, req.CSV)
+		return importDeviceAttributesResponse{ImportDeviceAttributesResult: result, Err: err}, nil
+	}
+}
+
+func (e Endpoints) ImportDeviceAttributes(ctx context.Context, data []byte) (*ImportDeviceAttributesResult, error) {
+	resp, err := e.ImportDeviceAttributesEndpoint(ctx, importDeviceAttributesRequest{CSV: data})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(importDeviceAttributesResponse)
+	return response.ImportDeviceAttributesResult, response.Err
+}
@@ -0,0 +1,156 @@
+package device
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// ImportDevicesResult summarizes a device metadata import.
+type ImportDevicesResult struct {
+	Updated  int      `json:"updated"`
+	NotFound []string `json:"not_found,omitempty"`
+}
+
+// metadataRow is a single parsed row of a device metadata CSV.
+// A nil field was not present in the CSV and is left unchanged.
+type metadataRow struct {
+	Serial      string
+	AssetTag    *string
+	Description *string
+}
+
+// parseMetadataCSV reads asset tags and descriptions from CSV. The header row
+// must have a "serial_number" column and at least one of the "asset_tag" and
+// "description" columns. Other columns are ignored, so the CSV written by the
+// device export can be edited and imported again.
+func parseMetadataCSV(r io.Reader) ([]metadataRow, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "read csv header")
+	}
+	serialCol, assetTagCol, descriptionCol := -1, -1, -1
+	for i, name := range header {
+		switch strings.TrimSpace(name) {
+		case "serial_number":
+			serialCol = i
+		case "asset_tag":
+			assetTagCol = i
+		case "description":
+			descriptionCol = i
+		}
+	}
+	if serialCol == -1 {
+		return nil, errors.New("csv header must contain a serial_number column")
+	}
+	if assetTagCol == -1 && descriptionCol == -1 {
+		return nil, errors.New("csv header must contain an asset_tag or description column")
+	}
+
+	var rows []metadataRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "read csv record")
+		}
+		row := metadataRow{Serial: record[serialCol]}
+		if assetTagCol != -1 {
+			row.AssetTag = &record[assetTagCol]
+		}
+		if descriptionCol != -1 {
+			row.Description = &record[descriptionCol]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (svc *DeviceService) ImportDevices(ctx context.Context, data []byte) (*ImportDevicesResult, error) {
+	rows, err := parseMetadataCSV(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var result ImportDevicesResult
+	for _, row := range rows {
+		if row.Serial == "" {
+			continue
+		}
+		dev, err := svc.store.DeviceBySerial(ctx, row.Serial)
+		if err != nil && isNotFound(err) {
+			result.NotFound = append(result.NotFound, row.Serial)
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "get device %s", row.Serial)
+		}
+		if row.AssetTag != nil {
+			dev.AssetTag = *row.AssetTag
+		}
+		if row.Description != nil {
+			dev.Description = *row.Description
+		}
+		if err := svc.store.Save(ctx, dev); err != nil {
+			return nil, errors.Wrapf(err, "save device %s", row.Serial)
+		}
+		result.Updated++
+	}
+	return &result, nil
+}
+
+type importDevicesRequest struct{ CSV []byte }
+
+type importDevicesResponse struct {
+	*ImportDevicesResult
+	Err error `json:"err,omitempty"`
+}
+
+func (r importDevicesResponse) Failed() error { return r.Err }
+
+func decodeImportDevicesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	return importDevicesRequest{CSV: data}, err
+}
+
+func encodeImportDevicesRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(importDevicesRequest)
+	r.Header.Set("Content-Type", "text/csv")
+	r.ContentLength = int64(len(req.CSV))
+	r.Body = ioutil.NopCloser(bytes.NewReader(req.CSV))
+	return nil
+}
+
+func decodeImportDevicesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp importDevicesResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeImportDevicesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(importDevicesRequest)
+		result, err := svc.ImportDevices(ctx, req.CSV)
+		return importDevicesResponse{ImportDevicesResult: result, Err: err}, nil
+	}
+}
+
+func (e Endpoints) ImportDevices(ctx context.Context, data []byte) (*ImportDevicesResult, error) {
+	resp, err := e.ImportDevicesEndpoint(ctx, importDevicesRequest{CSV: data})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(importDevicesResponse)
+	return response.ImportDevicesResult, response.Err
+}
@@ -25,36 +25,42 @@ type Device struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Uuid                   string `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
-	Udid                   string `protobuf:"bytes,2,opt,name=udid,proto3" json:"udid,omitempty"`
-	SerialNumber           string `protobuf:"bytes,3,opt,name=serial_number,json=serialNumber,proto3" json:"serial_number,omitempty"`
-	OsVersion              string `protobuf:"bytes,4,opt,name=os_version,json=osVersion,proto3" json:"os_version,omitempty"`
-	BuildVersion           string `protobuf:"bytes,5,opt,name=build_version,json=buildVersion,proto3" json:"build_version,omitempty"`
-	ProductName            string `protobuf:"bytes,6,opt,name=product_name,json=productName,proto3" json:"product_name,omitempty"`
-	Imei                   string `protobuf:"bytes,7,opt,name=imei,proto3" json:"imei,omitempty"`
-	Meid                   string `protobuf:"bytes,8,opt,name=meid,proto3" json:"meid,omitempty"`
-	Token                  string `protobuf:"bytes,9,opt,name=token,proto3" json:"token,omitempty"`
-	PushMagic              string `protobuf:"bytes,10,opt,name=push_magic,json=pushMagic,proto3" json:"push_magic,omitempty"`
-	MdmTopic               string `protobuf:"bytes,11,opt,name=mdm_topic,json=mdmTopic,proto3" json:"mdm_topic,omitempty"`
-	UnlockToken            string `protobuf:"bytes,12,opt,name=unlock_token,json=unlockToken,proto3" json:"unlock_token,omitempty"`
-	Enrolled               bool   `protobuf:"varint,13,opt,name=enrolled,proto3" json:"enrolled,omitempty"`
-	AwaitingConfiguration  bool   `protobuf:"varint,14,opt,name=awaiting_configuration,json=awaitingConfiguration,proto3" json:"awaiting_configuration,omitempty"`
-	DeviceName             string `protobuf:"bytes,15,opt,name=device_name,json=deviceName,proto3" json:"device_name,omitempty"`
-	Model                  string `protobuf:"bytes,16,opt,name=model,proto3" json:"model,omitempty"`
-	ModelName              string `protobuf:"bytes,17,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
-	Description            string `protobuf:"bytes,18,opt,name=description,proto3" json:"description,omitempty"`
-	Color                  string `protobuf:"bytes,19,opt,name=color,proto3" json:"color,omitempty"`
-	AssetTag               string `protobuf:"bytes,20,opt,name=asset_tag,json=assetTag,proto3" json:"asset_tag,omitempty"`
-	DepDevice              bool   `protobuf:"varint,21,opt,name=dep_device,json=depDevice,proto3" json:"dep_device,omitempty"`
-	DepProfileStatus       string `protobuf:"bytes,22,opt,name=dep_profile_status,json=depProfileStatus,proto3" json:"dep_profile_status,omitempty"`
-	DepProfileUuid         string `protobuf:"bytes,23,opt,name=dep_profile_uuid,json=depProfileUuid,proto3" json:"dep_profile_uuid,omitempty"`
-	DepProfileAssignTime   int64  `protobuf:"varint,24,opt,name=dep_profile_assign_time,json=depProfileAssignTime,proto3" json:"dep_profile_assign_time,omitempty"`
-	DepProfilePushTime     int64  `protobuf:"varint,25,opt,name=dep_profile_push_time,json=depProfilePushTime,proto3" json:"dep_profile_push_time,omitempty"`
-	DepProfileAssignedDate int64  `protobuf:"varint,26,opt,name=dep_profile_assigned_date,json=depProfileAssignedDate,proto3" json:"dep_profile_assigned_date,omitempty"`
-	DepProfileAssignedBy   string `protobuf:"bytes,27,opt,name=dep_profile_assigned_by,json=depProfileAssignedBy,proto3" json:"dep_profile_assigned_by,omitempty"`
-	LastSeen               int64  `protobuf:"varint,28,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
-	LastQueryResponse      []byte `protobuf:"bytes,29,opt,name=last_query_response,json=lastQueryResponse,proto3" json:"last_query_response,omitempty"`
-	BootstrapToken         []byte `protobuf:"bytes,30,opt,name=bootstrap_token,json=bootstrapToken,proto3" json:"bootstrap_token,omitempty"`
+	Uuid                   string            `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Udid                   string            `protobuf:"bytes,2,opt,name=udid,proto3" json:"udid,omitempty"`
+	SerialNumber           string            `protobuf:"bytes,3,opt,name=serial_number,json=serialNumber,proto3" json:"serial_number,omitempty"`
+	OsVersion              string            `protobuf:"bytes,4,opt,name=os_version,json=osVersion,proto3" json:"os_version,omitempty"`
+	BuildVersion           string            `protobuf:"bytes,5,opt,name=build_version,json=buildVersion,proto3" json:"build_version,omitempty"`
+	ProductName            string            `protobuf:"bytes,6,opt,name=product_name,json=productName,proto3" json:"product_name,omitempty"`
+	Imei                   string            `protobuf:"bytes,7,opt,name=imei,proto3" json:"imei,omitempty"`
+	Meid                   string            `protobuf:"bytes,8,opt,name=meid,proto3" json:"meid,omitempty"`
+	Token                  string            `protobuf:"bytes,9,opt,name=token,proto3" json:"token,omitempty"`
+	PushMagic              string            `protobuf:"bytes,10,opt,name=push_magic,json=pushMagic,proto3" json:"push_magic,omitempty"`
+	MdmTopic               string            `protobuf:"bytes,11,opt,name=mdm_topic,json=mdmTopic,proto3" json:"mdm_topic,omitempty"`
+	UnlockToken            string            `protobuf:"bytes,12,opt,name=unlock_token,json=unlockToken,proto3" json:"unlock_token,omitempty"`
+	Enrolled               bool              `protobuf:"varint,13,opt,name=enrolled,proto3" json:"enrolled,omitempty"`
+	AwaitingConfiguration  bool              `protobuf:"varint,14,opt,name=awaiting_configuration,json=awaitingConfiguration,proto3" json:"awaiting_configuration,omitempty"`
+	DeviceName             string            `protobuf:"bytes,15,opt,name=device_name,json=deviceName,proto3" json:"device_name,omitempty"`
+	Model                  string            `protobuf:"bytes,16,opt,name=model,proto3" json:"model,omitempty"`
+	ModelName              string            `protobuf:"bytes,17,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
+	Description            string            `protobuf:"bytes,18,opt,name=description,proto3" json:"description,omitempty"`
+	Color                  string            `protobuf:"bytes,19,opt,name=color,proto3" json:"color,omitempty"`
+	AssetTag               string            `protobuf:"bytes,20,opt,name=asset_tag,json=assetTag,proto3" json:"asset_tag,omitempty"`
+	DepDevice              bool              `protobuf:"varint,21,opt,name=dep_device,json=depDevice,proto3" json:"dep_device,omitempty"`
+	DepProfileStatus       string            `protobuf:"bytes,22,opt,name=dep_profile_status,json=depProfileStatus,proto3" json:"dep_profile_status,omitempty"`
+	DepProfileUuid         string            `protobuf:"bytes,23,opt,name=dep_profile_uuid,json=depProfileUuid,proto3" json:"dep_profile_uuid,omitempty"`
+	DepProfileAssignTime   int64             `protobuf:"varint,24,opt,name=dep_profile_assign_time,json=depProfileAssignTime,proto3" json:"dep_profile_assign_time,omitempty"`
+	DepProfilePushTime     int64             `protobuf:"varint,25,opt,name=dep_profile_push_time,json=depProfilePushTime,proto3" json:"dep_profile_push_time,omitempty"`
+	DepProfileAssignedDate int64             `protobuf:"varint,26,opt,name=dep_profile_assigned_date,json=depProfileAssignedDate,proto3" json:"dep_profile_assigned_date,omitempty"`
+	DepProfileAssignedBy   string            `protobuf:"bytes,27,opt,name=dep_profile_assigned_by,json=depProfileAssignedBy,proto3" json:"dep_profile_assigned_by,omitempty"`
+	LastSeen               int64             `protobuf:"varint,28,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	LastQueryResponse      []byte            `protobuf:"bytes,29,opt,name=last_query_response,json=lastQueryResponse,proto3" json:"last_query_response,omitempty"`
+	BootstrapToken         []byte            `protobuf:"bytes,30,opt,name=bootstrap_token,json=bootstrapToken,proto3" json:"bootstrap_token,omitempty"`
+	Attributes             map[string]string `protobuf:"bytes,31,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Stale                  bool              `protobuf:"varint,32,opt,name=stale,proto3" json:"stale,omitempty"`
+	RepushTime             int64             `protobuf:"varint,33,opt,name=repush_time,json=repushTime,proto3" json:"repush_time,omitempty"`
+	PurgeCommandUuid       string            `protobuf:"bytes,34,opt,name=purge_command_uuid,json=purgeCommandUuid,proto3" json:"purge_command_uuid,omitempty"`
+	PurgeRequestedAt       int64             `protobuf:"varint,35,opt,name=purge_requested_at,json=purgeRequestedAt,proto3" json:"purge_requested_at,omitempty"`
+	Notes                  string            `protobuf:"bytes,36,opt,name=notes,proto3" json:"notes,omitempty"`
 }
 
 func (x *Device) Reset() {
@@ -299,11 +305,53 @@ func (x *Device) GetBootstrapToken() []byte {
 	return nil
 }
 
+func (x *Device) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *Device) GetStale() bool {
+	if x != nil {
+		return x.Stale
+	}
+	return false
+}
+
+func (x *Device) GetRepushTime() int64 {
+	if x != nil {
+		return x.RepushTime
+	}
+	return 0
+}
+
+func (x *Device) GetPurgeCommandUuid() string {
+	if x != nil {
+		return x.PurgeCommandUuid
+	}
+	return ""
+}
+
+func (x *Device) GetPurgeRequestedAt() int64 {
+	if x != nil {
+		return x.PurgeRequestedAt
+	}
+	return 0
+}
+
+func (x *Device) GetNotes() string {
+	if x != nil {
+		return x.Notes
+	}
+	return ""
+}
+
 var File_device_proto protoreflect.FileDescriptor
 
 var file_device_proto_rawDesc = []byte{
 	0x0a, 0x0c, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0b,
-	0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xa0, 0x08, 0x0a, 0x06,
+	0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xcd, 0x0a, 0x0a, 0x06,
 	0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x75, 0x69, 0x64, 0x18, 0x01,
 	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x75, 0x75, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x64,
 	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x75, 0x64, 0x69, 0x64, 0x12, 0x23,
@@ -369,12 +417,31 @@ var file_device_proto_rawDesc = []byte{
 	0x28, 0x0c, 0x52, 0x11, 0x6c, 0x61, 0x73, 0x74, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73,
 	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x62, 0x6f, 0x6f, 0x74, 0x73, 0x74, 0x72,
 	0x61, 0x70, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x1e, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0e,
-	0x62, 0x6f, 0x6f, 0x74, 0x73, 0x74, 0x72, 0x61, 0x70, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x42, 0x43,
-	0x5a, 0x41, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x69, 0x63,
-	0x72, 0x6f, 0x6d, 0x64, 0x6d, 0x2f, 0x6d, 0x69, 0x63, 0x72, 0x6f, 0x6d, 0x64, 0x6d, 0x2f, 0x70,
-	0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x2f, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x2f, 0x69,
-	0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x62, 0x6f, 0x6f, 0x74, 0x73, 0x74, 0x72, 0x61, 0x70, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x43,
+	0x0a, 0x0a, 0x61, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x65, 0x73, 0x18, 0x1f, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x23, 0x2e, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x41, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74,
+	0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x61, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75,
+	0x74, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x6c, 0x65, 0x18, 0x20, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x05, 0x73, 0x74, 0x61, 0x6c, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x65, 0x70,
+	0x75, 0x73, 0x68, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x21, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a,
+	0x72, 0x65, 0x70, 0x75, 0x73, 0x68, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x2c, 0x0a, 0x12, 0x70, 0x75,
+	0x72, 0x67, 0x65, 0x5f, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x5f, 0x75, 0x75, 0x69, 0x64,
+	0x18, 0x22, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x70, 0x75, 0x72, 0x67, 0x65, 0x43, 0x6f, 0x6d,
+	0x6d, 0x61, 0x6e, 0x64, 0x55, 0x75, 0x69, 0x64, 0x12, 0x2c, 0x0a, 0x12, 0x70, 0x75, 0x72, 0x67,
+	0x65, 0x5f, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x23,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x10, 0x70, 0x75, 0x72, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x74, 0x65, 0x73, 0x18,
+	0x24, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6e, 0x6f, 0x74, 0x65, 0x73, 0x1a, 0x3d, 0x0a, 0x0f,
+	0x41, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x42, 0x43, 0x5a, 0x41, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x69, 0x63, 0x72, 0x6f, 0x6d,
+	0x64, 0x6d, 0x2f, 0x6d, 0x69, 0x63, 0x72, 0x6f, 0x6d, 0x64, 0x6d, 0x2f, 0x70, 0x6c, 0x61, 0x74,
+	0x66, 0x6f, 0x72, 0x6d, 0x2f, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x2f, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -389,16 +456,18 @@ func file_device_proto_rawDescGZIP() []byte {
 	return file_device_proto_rawDescData
 }
 
-var file_device_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_device_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
 var file_device_proto_goTypes = []interface{}{
 	(*Device)(nil), // 0: deviceproto.Device
+	nil,            // 1: deviceproto.Device.AttributesEntry
 }
 var file_device_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1, // 0: deviceproto.Device.attributes:type_name -> deviceproto.Device.AttributesEntry
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_device_proto_init() }
@@ -426,7 +495,7 @@ func file_device_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_device_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   1,
+			NumMessages:   2,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
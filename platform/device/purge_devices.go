@@ -0,0 +1,173 @@
+package device
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/mdm/enroll"
+	mdmsvc "github.com/micromdm/micromdm/mdm/mdm"
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// DevicePurgedTopic is published with the marshaled device record after a
+// device was purged.
+const DevicePurgedTopic = "mdm.DevicePurged"
+
+type PurgeDevicesOptions struct {
+	UDIDs   []string `json:"udids"`
+	Serials []string `json:"serials"`
+
+	// RemoveEnrollmentProfile sends a RemoveProfile command for the
+	// enrollment profile first. The purge completes once the device
+	// acknowledges the command or checks out, or once the purge timeout
+	// of the StaleWorker has passed.
+	RemoveEnrollmentProfile bool `json:"remove_enrollment_profile"`
+}
+
+// PurgeFunc removes the data another store keeps for a device.
+// A PurgeFunc must not return an error if no data exists for the UDID, so
+// that a purge which failed part way can be retried.
+type PurgeFunc func(ctx context.Context, udid string) error
+
+// CommandSender queues MDM commands.
+type CommandSender interface {
+	NewCommand(ctx context.Context, request *mdmsvc.CommandRequest) (*mdmsvc.CommandPayload, error)
+}
+
+// WithPurgeFuncs registers the functions called for every purged device.
+func WithPurgeFuncs(fns ...PurgeFunc) Option {
+	return func(svc *DeviceService) {
+		svc.purgeFuncs = append(svc.purgeFuncs, fns...)
+	}
+}
+
+// WithCommandSender allows the purge to remove the enrollment profile.
+func WithCommandSender(cmd CommandSender) Option {
+	return func(svc *DeviceService) {
+		svc.cmd = cmd
+	}
+}
+
+func (svc *DeviceService) PurgeDevices(ctx context.Context, opt PurgeDevicesOptions) error {
+	if opt.RemoveEnrollmentProfile && svc.cmd == nil {
+		return errors.New("removing the enrollment profile is not supported")
+	}
+	devices, err := svc.devicesFor(ctx, opt.UDIDs, opt.Serials)
+	if err != nil {
+		return err
+	}
+	for _, dev := range devices {
+		// DEP devices which never enrolled have no UDID and nothing to remove
+		// from the device itself.
+		if !opt.RemoveEnrollmentProfile || dev.UDID == "" || !dev.Enrolled {
+			if err := svc.PurgeDevice(ctx, dev); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := svc.removeEnrollmentProfile(ctx, dev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *DeviceService) removeEnrollmentProfile(ctx context.Context, dev *Device) error {
+	payload, err := svc.cmd.NewCommand(ctx, &mdmsvc.CommandRequest{
+		UDID:        dev.UDID,
+		CommandUUID: uuid.New().String(),
+		Command: &mdmsvc.Command{
+			RequestType: "RemoveProfile",
+			RemoveProfile: &mdmsvc.RemoveProfile{
+				Identifier: enroll.EnrollmentProfileId,
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "queue RemoveProfile for device %s", dev.UDID)
+	}
+	dev.PurgeCommandUUID = payload.CommandUUID
+	dev.PurgeRequestedAt = time.Now()
+	err = svc.store.Save(ctx, dev)
+	return errors.Wrapf(err, "save purge command for device %s", dev.UDID)
+}
+
+// PurgeDevice removes the device record and calls every registered PurgeFunc
+// for the device UDID. The device record is deleted last, so if any step
+// fails the device is kept and purging it again resumes the purge.
+func (svc *DeviceService) PurgeDevice(ctx context.Context, dev *Device) error {
+	if dev.UDID != "" {
+		for _, purge := range svc.purgeFuncs {
+			if err := purge(ctx, dev.UDID); err != nil {
+				return errors.Wrapf(err, "purge device %s", dev.UDID)
+			}
+		}
+		if err := svc.store.DeleteUDIDCertHash([]byte(dev.UDID)); err != nil {
+			return errors.Wrapf(err, "delete cert hash for device %s", dev.UDID)
+		}
+	}
+
+	var err error
+	if dev.UDID != "" {
+		err = svc.store.DeleteByUDID(ctx, dev.UDID)
+	} else {
+		err = svc.store.DeleteBySerial(ctx, dev.SerialNumber)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "delete device %s", dev.SerialNumber)
+	}
+
+	if svc.pub == nil {
+		return nil
+	}
+	msg, err := MarshalDevice(dev)
+	if err != nil {
+		return errors.Wrap(err, "marshal purged device")
+	}
+	err = svc.pub.Publish(ctx, DevicePurgedTopic, msg)
+	return errors.Wrap(err, "publish device purged event")
+}
+
+type purgeDevicesRequest struct{ Opts PurgeDevicesOptions }
+
+type purgeDevicesResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+func (r purgeDevicesResponse) Failed() error { return r.Err }
+
+func decodePurgeDevicesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req purgeDevicesRequest
+	err := httputil.DecodeJSONRequest(r, &req)
+	return req, err
+}
+
+func decodePurgeDevicesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp purgeDevicesResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakePurgeDevicesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(purgeDevicesRequest)
+		err = svc.PurgeDevices(ctx, req.Opts)
+		return purgeDevicesResponse{
+			Err: err,
+		}, nil
+	}
+}
+
+func (e Endpoints) PurgeDevices(ctx context.Context, opts PurgeDevicesOptions) error {
+	request := purgeDevicesRequest{Opts: opts}
+	resp, err := e.PurgeDevicesEndpoint(ctx, request)
+	if err != nil {
+		return err
+	}
+	return resp.(purgeDevicesResponse).Err
+}
@@ -0,0 +1,69 @@
+package device
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func (svc *DeviceService) RemoveDeviceAttributes(ctx context.Context, opt RemoveDeviceAttributesOptions) error {
+	if len(opt.Keys) == 0 {
+		return errors.New("no attribute keys to remove")
+	}
+	devices, err := svc.devicesFor(ctx, opt.UDIDs, opt.Serials)
+	if err != nil {
+		return err
+	}
+	for _, dev := range devices {
+		_, err := svc.store.UpdateDevice(ctx, dev.UDID, func(dev *Device) bool {
+			for _, k := range opt.Keys {
+				delete(dev.Attributes, k)
+			}
+			return true
+		})
+		if err != nil {
+			return errors.Wrapf(err, "save attributes for device %s", dev.UDID)
+		}
+	}
+	return nil
+}
+
+type removeDeviceAttributesRequest struct{ Opts RemoveDeviceAttributesOptions }
+
+type removeDeviceAttributesResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+func (r removeDeviceAttributesResponse) Failed() error { return r.Err }
+
+func decodeRemoveDeviceAttributesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var opts RemoveDeviceAttributesOptions
+	err := httputil.DecodeJSONRequest(r, &opts)
+	return removeDeviceAttributesRequest{Opts: opts}, err
+}
+
+func decodeRemoveDeviceAttributesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp removeDeviceAttributesResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeRemoveDeviceAttributesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(removeDeviceAttributesRequest)
+		err = svc.RemoveDeviceAttributes(ctx, req.Opts)
+		return removeDeviceAttributesResponse{Err: err}, nil
+	}
+}
+
+func (e Endpoints) RemoveDeviceAttributes(ctx context.Context, opts RemoveDeviceAttributesOptions) error {
+	resp, err := e.RemoveDeviceAttributesEndpoint(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return resp.(removeDeviceAttributesResponse).Err
+}
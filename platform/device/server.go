@@ -5,24 +5,46 @@ import (
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 
+	"github.com/micromdm/micromdm/pkg/export"
 	"github.com/micromdm/micromdm/pkg/httputil"
 )
 
 type Endpoints struct {
-	ListDevicesEndpoint   endpoint.Endpoint
-	RemoveDevicesEndpoint endpoint.Endpoint
+	ListDevicesEndpoint            endpoint.Endpoint
+	RemoveDevicesEndpoint          endpoint.Endpoint
+	SetDeviceAttributesEndpoint    endpoint.Endpoint
+	RemoveDeviceAttributesEndpoint endpoint.Endpoint
+	ImportDeviceAttributesEndpoint endpoint.Endpoint
+	StaleDevicesEndpoint           endpoint.Endpoint
+	PurgeDevicesEndpoint           endpoint.Endpoint
+	ExportDevicesEndpoint          endpoint.Endpoint
+	ImportDevicesEndpoint          endpoint.Endpoint
 }
 
 func MakeServerEndpoints(s Service, outer endpoint.Middleware, others ...endpoint.Middleware) Endpoints {
 	return Endpoints{
-		ListDevicesEndpoint:   endpoint.Chain(outer, others...)(MakeListDevicesEndpoint(s)),
-		RemoveDevicesEndpoint: endpoint.Chain(outer, others...)(MakeRemoveDevicesEndpoint(s)),
+		ListDevicesEndpoint:            endpoint.Chain(outer, others...)(MakeListDevicesEndpoint(s)),
+		RemoveDevicesEndpoint:          endpoint.Chain(outer, others...)(MakeRemoveDevicesEndpoint(s)),
+		SetDeviceAttributesEndpoint:    endpoint.Chain(outer, others...)(MakeSetDeviceAttributesEndpoint(s)),
+		RemoveDeviceAttributesEndpoint: endpoint.Chain(outer, others...)(MakeRemoveDeviceAttributesEndpoint(s)),
+		ImportDeviceAttributesEndpoint: endpoint.Chain(outer, others...)(MakeImportDeviceAttributesEndpoint(s)),
+		StaleDevicesEndpoint:           endpoint.Chain(outer, others...)(MakeStaleDevicesEndpoint(s)),
+		PurgeDevicesEndpoint:           endpoint.Chain(outer, others...)(MakePurgeDevicesEndpoint(s)),
+		ExportDevicesEndpoint:          endpoint.Chain(outer, others...)(MakeExportDevicesEndpoint(s)),
+		ImportDevicesEndpoint:          endpoint.Chain(outer, others...)(MakeImportDevicesEndpoint(s)),
 	}
 }
 
 func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.ServerOption) {
 	// POST     /v1/devices		get a list of devices managed by the server
 	// DELETE  /v1/devices		remove one or more devices from the server
+	// PUT     /v1/devices/attributes		set attributes on one or more devices
+	// DELETE  /v1/devices/attributes		remove attributes from one or more devices
+	// PUT     /v1/devices/attributes/csv	bulk import device attributes from CSV
+	// GET     /v1/devices/stale		get a report of stale devices and their pending commands
+	// DELETE  /v1/devices/purge		remove one or more devices and all of their data from the server
+	// GET     /v1/devices/export		export all devices as CSV or NDJSON
+	// PUT     /v1/devices/import		update asset tags and descriptions from CSV
 
 	r.Methods("POST").Path("/v1/devices").Handler(httptransport.NewServer(
 		e.ListDevicesEndpoint,
@@ -37,4 +59,53 @@ func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.S
 		httputil.EncodeJSONResponse,
 		options...,
 	))
+
+	r.Methods("PUT").Path("/v1/devices/attributes").Handler(httptransport.NewServer(
+		e.SetDeviceAttributesEndpoint,
+		decodeSetDeviceAttributesRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("DELETE").Path("/v1/devices/attributes").Handler(httptransport.NewServer(
+		e.RemoveDeviceAttributesEndpoint,
+		decodeRemoveDeviceAttributesRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("PUT").Path("/v1/devices/attributes/csv").Handler(httptransport.NewServer(
+		e.ImportDeviceAttributesEndpoint,
+		decodeImportDeviceAttributesRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/devices/stale").Handler(httptransport.NewServer(
+		e.StaleDevicesEndpoint,
+		decodeStaleDevicesRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("DELETE").Path("/v1/devices/purge").Handler(httptransport.NewServer(
+		e.PurgeDevicesEndpoint,
+		decodePurgeDevicesRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/devices/export").Handler(httptransport.NewServer(
+		e.ExportDevicesEndpoint,
+		decodeExportDevicesRequest,
+		export.EncodeResponse,
+		options...,
+	))
+
+	r.Methods("PUT").Path("/v1/devices/import").Handler(httptransport.NewServer(
+		e.ImportDevicesEndpoint,
+		decodeImportDevicesRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
 }
@@ -2,6 +2,9 @@ package device
 
 import (
 	"context"
+
+	"github.com/micromdm/micromdm/mdm"
+	"github.com/micromdm/micromdm/platform/pubsub"
 )
 
 type RemoveDevicesOptions struct {
@@ -9,21 +12,81 @@ type RemoveDevicesOptions struct {
 	Serials []string `json:"serials"`
 }
 
+type SetDeviceAttributesOptions struct {
+	UDIDs      []string          `json:"udids"`
+	Serials    []string          `json:"serials"`
+	Attributes map[string]string `json:"attributes"`
+	// Notes replaces the notes of the devices if set. An empty string
+	// clears them.
+	Notes *string `json:"notes,omitempty"`
+}
+
+type RemoveDeviceAttributesOptions struct {
+	UDIDs   []string `json:"udids"`
+	Serials []string `json:"serials"`
+	Keys    []string `json:"keys"`
+}
+
 type Service interface {
 	ListDevices(ctx context.Context, opt ListDevicesOption) ([]DeviceDTO, error)
 	RemoveDevices(ctx context.Context, opt RemoveDevicesOptions) error
+	SetDeviceAttributes(ctx context.Context, opt SetDeviceAttributesOptions) error
+	RemoveDeviceAttributes(ctx context.Context, opt RemoveDeviceAttributesOptions) error
+	ImportDeviceAttributes(ctx context.Context, csv []byte) (*ImportDeviceAttributesResult, error)
+	StaleDevices(ctx context.Context) ([]StaleDeviceDTO, error)
+	PurgeDevices(ctx context.Context, opt PurgeDevicesOptions) error
+	ExportDevices(ctx context.Context, opt ListDevicesOption, fn func(DeviceRecord) error) error
+	ImportDevices(ctx context.Context, csv []byte) (*ImportDevicesResult, error)
 }
 
 type Store interface {
 	List(ctx context.Context, opt ListDevicesOption) ([]Device, error)
+	// ForEach calls fn for every device matching opt.
+	ForEach(ctx context.Context, opt ListDevicesOption, fn func(*Device) error) error
+	Save(ctx context.Context, d *Device) error
+	// UpdateDevice saves the changes of update to the device in a single
+	// transaction.
+	UpdateDevice(ctx context.Context, udid string, update func(*Device) bool) (*Device, error)
+	DeviceByUDID(ctx context.Context, udid string) (*Device, error)
+	DeviceBySerial(ctx context.Context, serial string) (*Device, error)
 	DeleteByUDID(ctx context.Context, udid string) error
 	DeleteBySerial(ctx context.Context, serial string) error
+	DeleteUDIDCertHash(udid []byte) error
+}
+
+// CommandQueue is used to look up the pending commands of a device.
+type CommandQueue interface {
+	ViewQueue(ctx context.Context, event mdm.CheckinEvent) ([]*mdm.Command, error)
 }
 
 type DeviceService struct {
-	store Store
+	store      Store
+	queue      CommandQueue
+	cmd        CommandSender
+	pub        pubsub.Publisher
+	purgeFuncs []PurgeFunc
+}
+
+type Option func(*DeviceService)
+
+// WithCommandQueue includes the pending commands in the stale device report.
+func WithCommandQueue(q CommandQueue) Option {
+	return func(svc *DeviceService) {
+		svc.queue = q
+	}
+}
+
+// WithPublisher publishes an event for every purged device.
+func WithPublisher(pub pubsub.Publisher) Option {
+	return func(svc *DeviceService) {
+		svc.pub = pub
+	}
 }
 
-func New(store Store) *DeviceService {
-	return &DeviceService{store: store}
+func New(store Store, opts ...Option) *DeviceService {
+	svc := &DeviceService{store: store}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
 }
@@ -0,0 +1,101 @@
+package device
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// SetDeviceAttributes sets the attributes, and the notes if given, of the
+// devices. Each device is updated in a single store transaction, so changes
+// made by check-ins at the same time are kept.
+func (svc *DeviceService) SetDeviceAttributes(ctx context.Context, opt SetDeviceAttributesOptions) error {
+	if len(opt.Attributes) == 0 && opt.Notes == nil {
+		return errors.New("no attributes or notes to set")
+	}
+	devices, err := svc.devicesFor(ctx, opt.UDIDs, opt.Serials)
+	if err != nil {
+		return err
+	}
+	for _, dev := range devices {
+		_, err := svc.store.UpdateDevice(ctx, dev.UDID, func(dev *Device) bool {
+			if dev.Attributes == nil && len(opt.Attributes) > 0 {
+				dev.Attributes = make(map[string]string, len(opt.Attributes))
+			}
+			for k, v := range opt.Attributes {
+				dev.Attributes[k] = v
+			}
+			if opt.Notes != nil {
+				dev.Notes = *opt.Notes
+			}
+			return true
+		})
+		if err != nil {
+			return errors.Wrapf(err, "save attributes for device %s", dev.UDID)
+		}
+	}
+	return nil
+}
+
+// devicesFor looks up every device referenced by UDID or serial number.
+func (svc *DeviceService) devicesFor(ctx context.Context, udids, serials []string) ([]*Device, error) {
+	var devices []*Device
+	for _, udid := range udids {
+		dev, err := svc.store.DeviceByUDID(ctx, udid)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get device by udid %s", udid)
+		}
+		devices = append(devices, dev)
+	}
+	for _, serial := range serials {
+		dev, err := svc.store.DeviceBySerial(ctx, serial)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get device by serial %s", serial)
+		}
+		devices = append(devices, dev)
+	}
+	if len(devices) == 0 {
+		return nil, errors.New("no udids or serials specified")
+	}
+	return devices, nil
+}
+
+type setDeviceAttributesRequest struct{ Opts SetDeviceAttributesOptions }
+
+type setDeviceAttributesResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+func (r setDeviceAttributesResponse) Failed() error { return r.Err }
+
+func decodeSetDeviceAttributesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var opts SetDeviceAttributesOptions
+	err := httputil.DecodeJSONRequest(r, &opts)
+	return setDeviceAttributesRequest{Opts: opts}, err
+}
+
+func decodeSetDeviceAttributesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp setDeviceAttributesResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeSetDeviceAttributesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(setDeviceAttributesRequest)
+		err = svc.SetDeviceAttributes(ctx, req.Opts)
+		return setDeviceAttributesResponse{Err: err}, nil
+	}
+}
+
+func (e Endpoints) SetDeviceAttributes(ctx context.Context, opts SetDeviceAttributesOptions) error {
+	resp, err := e.SetDeviceAttributesEndpoint(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return resp.(setDeviceAttributesResponse).Err
+}
@@ -0,0 +1,208 @@
+package device
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/apns"
+	"github.com/micromdm/micromdm/platform/pubsub"
+)
+
+// DeviceStaleTopic is published with the marshaled device when a device
+// is marked as stale.
+const DeviceStaleTopic = "mdm.DeviceStale"
+
+type StaleWorkerStore interface {
+	List(ctx context.Context, opt ListDevicesOption) ([]Device, error)
+	DeviceByUDID(ctx context.Context, udid string) (*Device, error)
+	// UpdateDevice saves the changes of update to the device in a single
+	// transaction if update returns true.
+	UpdateDevice(ctx context.Context, udid string, update func(*Device) bool) (*Device, error)
+}
+
+// StaleWorker periodically checks the LastSeen time of enrolled devices.
+// Devices which were not seen for longer than the push threshold are sent
+// a push notification, and devices which were not seen for longer than the
+// stale threshold are marked as stale. Pending purges which exceeded the
+// purge timeout are completed.
+type StaleWorker struct {
+	db        StaleWorkerStore
+	push      apns.Service
+	pub       pubsub.Publisher
+	logger    log.Logger
+	pushAfter time.Duration
+	markAfter time.Duration
+	interval  time.Duration
+
+	purger       Purger
+	purgeTimeout time.Duration
+}
+
+type StaleWorkerOption func(*StaleWorker)
+
+// WithPushAfter sets the time after which an unresponsive device is sent a
+// push notification. A value of 0 disables the push.
+func WithPushAfter(d time.Duration) StaleWorkerOption {
+	return func(w *StaleWorker) {
+		w.pushAfter = d
+	}
+}
+
+// WithStaleAfter sets the time after which an unresponsive device is marked
+// as stale. A value of 0 disables marking devices as stale.
+func WithStaleAfter(d time.Duration) StaleWorkerOption {
+	return func(w *StaleWorker) {
+		w.markAfter = d
+	}
+}
+
+// WithCheckInterval sets how often devices are checked.
+func WithCheckInterval(d time.Duration) StaleWorkerOption {
+	return func(w *StaleWorker) {
+		w.interval = d
+	}
+}
+
+// WithPurgeTimeout purges devices which did not acknowledge the RemoveProfile
+// command of a pending purge within d.
+func WithPurgeTimeout(p Purger, d time.Duration) StaleWorkerOption {
+	return func(w *StaleWorker) {
+		w.purger = p
+		w.purgeTimeout = d
+	}
+}
+
+func NewStaleWorker(db StaleWorkerStore, push apns.Service, pub pubsub.Publisher, logger log.Logger, opts ...StaleWorkerOption) *StaleWorker {
+	w := &StaleWorker{
+		db:        db,
+		push:      push,
+		pub:       pub,
+		logger:    logger,
+		pushAfter: 24 * time.Hour,
+		markAfter: 7 * 24 * time.Hour,
+		interval:  30 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+func (w *StaleWorker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := w.check(ctx, time.Now()); err != nil {
+				level.Info(w.logger).Log(
+					"msg", "check for stale devices",
+					"err", err,
+				)
+			}
+		}
+	}
+}
+
+func (w *StaleWorker) check(ctx context.Context, now time.Time) error {
+	devices, err := w.db.List(ctx, ListDevicesOption{})
+	if err != nil {
+		return errors.Wrap(err, "list devices")
+	}
+	for _, dev := range devices {
+		if w.purgeOverdue(dev, now) {
+			if err := w.purger.PurgeDevice(ctx, &dev); err != nil {
+				level.Info(w.logger).Log(
+					"msg", "purge device after timeout",
+					"udid", dev.UDID,
+					"err", err,
+				)
+			}
+			continue
+		}
+		if !dev.Enrolled || dev.UDID == "" || dev.LastSeen.IsZero() {
+			continue
+		}
+		if err := w.checkDevice(ctx, dev.UDID, now); err != nil {
+			level.Info(w.logger).Log(
+				"msg", "check stale device",
+				"udid", dev.UDID,
+				"err", err,
+			)
+		}
+	}
+	return nil
+}
+
+func (w *StaleWorker) purgeOverdue(dev Device, now time.Time) bool {
+	if w.purger == nil || w.purgeTimeout <= 0 || dev.PurgeCommandUUID == "" {
+		return false
+	}
+	return !dev.PurgeRequestedAt.IsZero() && now.Sub(dev.PurgeRequestedAt) >= w.purgeTimeout
+}
+
+func (w *StaleWorker) checkDevice(ctx context.Context, udid string, now time.Time) error {
+	// the device is loaded again to pick up check-ins since the list was created.
+	dev, err := w.db.DeviceByUDID(ctx, udid)
+	if err != nil {
+		return errors.Wrap(err, "get device")
+	}
+	since := now.Sub(dev.LastSeen)
+	lastSeen := dev.LastSeen
+
+	switch {
+	case w.markAfter > 0 && since >= w.markAfter:
+		if dev.Stale {
+			return nil
+		}
+		// the device may check in while it is marked as stale.
+		var marked bool
+		dev, err := w.db.UpdateDevice(ctx, udid, func(dev *Device) bool {
+			marked = !dev.Stale && dev.LastSeen.Equal(lastSeen)
+			dev.Stale = dev.Stale || marked
+			return marked
+		})
+		if err != nil || !marked {
+			return errors.Wrap(err, "save stale device")
+		}
+		msg, err := MarshalDevice(dev)
+		if err != nil {
+			return errors.Wrap(err, "marshal stale device")
+		}
+		level.Debug(w.logger).Log("msg", "marking device stale", "udid", udid, "last_seen", dev.LastSeen)
+		err = w.pub.Publish(ctx, DeviceStaleTopic, msg)
+		return errors.Wrapf(err, "publish on topic %s", DeviceStaleTopic)
+
+	case w.pushAfter > 0 && since >= w.pushAfter:
+		// only push once until the device is seen again.
+		if dev.RePushTime.After(dev.LastSeen) {
+			return nil
+		}
+		level.Debug(w.logger).Log("msg", "pushing unresponsive device", "udid", udid, "last_seen", dev.LastSeen)
+		if _, err := w.push.Push(ctx, udid); err != nil {
+			return errors.Wrap(err, "push unresponsive device")
+		}
+		_, err := w.db.UpdateDevice(ctx, udid, func(dev *Device) bool {
+			dev.RePushTime = now
+			return true
+		})
+		return errors.Wrap(err, "save pushed device")
+
+	case dev.Stale:
+		// the device checked in again before the device worker cleared
+		// the flag.
+		_, err := w.db.UpdateDevice(ctx, udid, func(dev *Device) bool {
+			cleared := dev.Stale
+			dev.Stale = false
+			return cleared
+		})
+		return errors.Wrap(err, "save device no longer stale")
+	}
+	return nil
+}
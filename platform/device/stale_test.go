@@ -0,0 +1,120 @@
+package device
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/micromdm/micromdm/platform/apns"
+)
+
+func TestStaleWorkerCheck(t *testing.T) {
+	now := time.Now()
+	store := &memStore{devices: map[string]*Device{
+		"fresh":  {UDID: "fresh", Enrolled: true, LastSeen: now.Add(-time.Hour)},
+		"silent": {UDID: "silent", Enrolled: true, LastSeen: now.Add(-2 * 24 * time.Hour)},
+		"stale":  {UDID: "stale", Enrolled: true, LastSeen: now.Add(-8 * 24 * time.Hour)},
+		"back":   {UDID: "back", Enrolled: true, Stale: true, LastSeen: now.Add(-time.Minute)},
+	}}
+	pusher := &memPusher{}
+	pub := &memPublisher{}
+	w := NewStaleWorker(store, pusher, pub, log.NewNopLogger(),
+		WithPushAfter(24*time.Hour),
+		WithStaleAfter(7*24*time.Hour),
+	)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := w.check(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if have, want := pusher.pushed, []string{"silent"}; len(have) != 1 || have[0] != want[0] {
+		t.Errorf("pushed: have %v, want %v", have, want)
+	}
+	if have, want := pub.topics, []string{DeviceStaleTopic}; len(have) != 1 || have[0] != want[0] {
+		t.Errorf("published: have %v, want %v", have, want)
+	}
+	if !store.devices["stale"].Stale {
+		t.Error("expected device to be marked stale")
+	}
+	if store.devices["back"].Stale {
+		t.Error("expected device which checked in to no longer be stale")
+	}
+	if store.devices["fresh"].Stale || !store.devices["fresh"].RePushTime.IsZero() {
+		t.Error("expected fresh device to be left alone")
+	}
+}
+
+func TestStaleWorkerPurgeTimeout(t *testing.T) {
+	now := time.Now()
+	store := &memStore{devices: map[string]*Device{
+		"waiting": {UDID: "waiting", Enrolled: true, LastSeen: now, PurgeCommandUUID: "a", PurgeRequestedAt: now.Add(-time.Hour)},
+		"overdue": {UDID: "overdue", Enrolled: true, LastSeen: now, PurgeCommandUUID: "b", PurgeRequestedAt: now.Add(-73 * time.Hour)},
+	}}
+	purger := &memPurger{}
+	w := NewStaleWorker(store, &memPusher{}, &memPublisher{}, log.NewNopLogger(),
+		WithPurgeTimeout(purger, 72*time.Hour),
+	)
+	if err := w.check(context.Background(), now); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := purger.purged, []string{"overdue"}; len(have) != 1 || have[0] != want[0] {
+		t.Errorf("purged: have %v, want %v", have, want)
+	}
+}
+
+type memPurger struct {
+	purged []string
+}
+
+func (p *memPurger) PurgeDevice(ctx context.Context, dev *Device) error {
+	p.purged = append(p.purged, dev.UDID)
+	return nil
+}
+
+type memStore struct {
+	devices map[string]*Device
+}
+
+func (s *memStore) List(ctx context.Context, opt ListDevicesOption) ([]Device, error) {
+	var devices []Device
+	for _, d := range s.devices {
+		devices = append(devices, *d)
+	}
+	return devices, nil
+}
+
+func (s *memStore) UpdateDevice(ctx context.Context, udid string, update func(*Device) bool) (*Device, error) {
+	d := *s.devices[udid]
+	if update(&d) {
+		s.devices[udid] = &d
+	}
+	return &d, nil
+}
+
+func (s *memStore) DeviceByUDID(ctx context.Context, udid string) (*Device, error) {
+	d := *s.devices[udid]
+	return &d, nil
+}
+
+type memPusher struct {
+	pushed []string
+}
+
+func (p *memPusher) Push(ctx context.Context, udid string, opts ...apns.PushOption) (string, error) {
+	p.pushed = append(p.pushed, udid)
+	return "", nil
+}
+
+type memPublisher struct {
+	topics []string
+}
+
+func (p *memPublisher) Publish(ctx context.Context, topic string, msg []byte) error {
+	p.topics = append(p.topics, topic)
+	return nil
+}
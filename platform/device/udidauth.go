@@ -15,6 +15,8 @@ import (
 type UDIDCertAuthStore interface {
 	SaveUDIDCertHash(udid, certHash []byte) error
 	GetUDIDCertHash(udid []byte) ([]byte, error)
+	SaveUDIDCert(udid, cert []byte) error
+	GetUDIDCert(udid []byte) ([]byte, error)
 }
 
 func UDIDCertAuthMiddleware(store UDIDCertAuthStore, logger log.Logger, warnOnly bool) mdm.Middleware {
@@ -42,6 +44,17 @@ func hashCertRaw(c []byte) []byte {
 	return retBytes
 }
 
+// saveUDIDCert saves the identity certificate of a device which was enrolled
+// before certificates were saved. Certificates are saved on Authenticate
+// otherwise.
+func (mw *udidCertAuthMiddleware) saveUDIDCert(udid, cert []byte) error {
+	_, err := mw.store.GetUDIDCert(udid)
+	if err == nil || !isNotFound(err) {
+		return err
+	}
+	return mw.store.SaveUDIDCert(udid, cert)
+}
+
 func (mw *udidCertAuthMiddleware) validateUDIDCertAuth(udid, certHash []byte) (bool, error) {
 	dbCertHash, err := mw.store.GetUDIDCertHash(udid)
 	if err != nil && !isNotFound(err) {
@@ -82,6 +95,11 @@ func (mw *udidCertAuthMiddleware) Acknowledge(ctx context.Context, req mdm.Ackno
 	if !matched && !mw.warnOnly {
 		return nil, errors.New("device certifcate UDID mismatch")
 	}
+	if matched {
+		if err := mw.saveUDIDCert([]byte(req.Response.UDID), devcert.Raw); err != nil {
+			return nil, err
+		}
+	}
 	return mw.next.Acknowledge(ctx, req)
 }
 
@@ -95,11 +113,20 @@ func (mw *udidCertAuthMiddleware) Checkin(ctx context.Context, req mdm.CheckinEv
 		return nil, errors.Wrap(err, "error retrieving device certificate")
 	}
 	if req.Command.MessageType == "Authenticate" {
-		// unconditionally save the cert hash on Authenticate message
+		// the cert hash is saved unconditionally on Authenticate, but only
+		// once the enrollment was admitted, so that a rejected device
+		// leaves nothing behind.
+		resp, err := mw.next.Checkin(ctx, req)
+		if err != nil {
+			return resp, err
+		}
 		if err := mw.store.SaveUDIDCertHash([]byte(req.Command.UDID), hashCertRaw(devcert.Raw)); err != nil {
 			return nil, err
 		}
-		return mw.next.Checkin(ctx, req)
+		if err := mw.store.SaveUDIDCert([]byte(req.Command.UDID), devcert.Raw); err != nil {
+			return nil, err
+		}
+		return resp, nil
 	}
 	matched, err := mw.validateUDIDCertAuth([]byte(req.Command.UDID), hashCertRaw(devcert.Raw))
 	if err != nil {
@@ -108,5 +135,10 @@ func (mw *udidCertAuthMiddleware) Checkin(ctx context.Context, req mdm.CheckinEv
 	if !matched && !mw.warnOnly {
 		return nil, errors.New("device certifcate UDID mismatch")
 	}
+	if matched {
+		if err := mw.saveUDIDCert([]byte(req.Command.UDID), devcert.Raw); err != nil {
+			return nil, err
+		}
+	}
 	return mw.next.Checkin(ctx, req)
 }
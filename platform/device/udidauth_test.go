@@ -0,0 +1,54 @@
+package device
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/micromdm/micromdm/mdm"
+)
+
+func TestUDIDCertAuthRejectedAuthenticate(t *testing.T) {
+	store := &memCertStore{hashes: make(map[string][]byte)}
+	next := rejectingService{errors.New("enrollment rejected")}
+	svc := UDIDCertAuthMiddleware(store, log.NewNopLogger(), false)(next)
+
+	ctx := context.WithValue(context.Background(), mdm.ContextKeyDeviceCertificate, &x509.Certificate{Raw: []byte("cert")})
+	event := mdm.CheckinEvent{Command: mdm.CheckinCommand{MessageType: "Authenticate", UDID: "UDID-1"}}
+	if _, err := svc.Checkin(ctx, event); err == nil {
+		t.Fatal("expected the enrollment to be rejected")
+	}
+	if len(store.hashes) != 0 {
+		t.Error("expected no cert hash to be saved for a rejected device")
+	}
+}
+
+type rejectingService struct{ err error }
+
+func (s rejectingService) Checkin(ctx context.Context, event mdm.CheckinEvent) ([]byte, error) {
+	return nil, s.err
+}
+
+func (s rejectingService) Acknowledge(ctx context.Context, req mdm.AcknowledgeEvent) ([]byte, error) {
+	return nil, s.err
+}
+
+type memCertStore struct {
+	hashes map[string][]byte
+}
+
+func (s *memCertStore) SaveUDIDCertHash(udid, certHash []byte) error {
+	s.hashes[string(udid)] = certHash
+	return nil
+}
+
+func (s *memCertStore) GetUDIDCertHash(udid []byte) ([]byte, error) {
+	return s.hashes[string(udid)], nil
+}
+
+func (s *memCertStore) SaveUDIDCert(udid, cert []byte) error { return nil }
+
+func (s *memCertStore) GetUDIDCert(udid []byte) ([]byte, error) { return nil, nil }
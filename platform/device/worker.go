@@ -11,6 +11,7 @@ import (
 
 	"github.com/micromdm/micromdm/mdm"
 	"github.com/micromdm/micromdm/platform/dep/sync"
+	"github.com/micromdm/micromdm/platform/invite"
 	"github.com/micromdm/micromdm/platform/pubsub"
 )
 
@@ -20,18 +21,54 @@ type DeviceWorkerStore interface {
 	DeviceBySerial(ctx context.Context, serial string) (*Device, error)
 }
 
+// Purger completes the purge of a device which was waiting for the removal
+// of its enrollment profile.
+type Purger interface {
+	PurgeDevice(ctx context.Context, dev *Device) error
+}
+
+// InviteStore returns the enrollment invite for a token.
+type InviteStore interface {
+	Invite(ctx context.Context, token string) (*invite.Invite, error)
+}
+
 type Worker struct {
-	db     DeviceWorkerStore
-	ps     pubsub.PublishSubscriber
-	logger log.Logger
+	db      DeviceWorkerStore
+	ps      pubsub.PublishSubscriber
+	purger  Purger
+	invites InviteStore
+	logger  log.Logger
+}
+
+type WorkerOption func(*Worker)
+
+// WithPurger completes pending device purges once the device responds to the
+// RemoveProfile command or checks out.
+func WithPurger(p Purger) WorkerOption {
+	return func(w *Worker) {
+		w.purger = p
+	}
+}
+
+// WithInvites sets the owner and attributes of the invite whose token is in
+// the check-in URL on a device which authenticates. The invite is redeemed
+// by the MDM service before the Authenticate event is published.
+func WithInvites(invites InviteStore) WorkerOption {
+	return func(w *Worker) {
+		w.invites = invites
+	}
 }
 
-func NewWorker(db DeviceWorkerStore, ps pubsub.PublishSubscriber, logger log.Logger) *Worker {
-	return &Worker{
+func NewWorker(db DeviceWorkerStore, ps pubsub.PublishSubscriber, logger log.Logger, opts ...WorkerOption) *Worker {
+	w := &Worker{
 		db:     db,
 		ps:     ps,
 		logger: logger,
 	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
 }
 
 func (w *Worker) Run(ctx context.Context) error {
@@ -161,7 +198,15 @@ func (w *Worker) updateFromAcknowledge(ctx context.Context, message []byte) erro
 	if err != nil {
 		return errors.Wrapf(err, "retrieve device with udid %s", ev.Response.UDID)
 	}
+
+	if w.purger != nil && dev.PurgeCommandUUID != "" &&
+		dev.PurgeCommandUUID == ev.Response.CommandUUID && ev.Response.Status != "NotNow" {
+		err := w.purger.PurgeDevice(ctx, dev)
+		return errors.Wrapf(err, "purge device %s after RemoveProfile", dev.UDID)
+	}
+
 	dev.LastSeen = time.Now()
+	dev.Stale = false
 
 	err = w.db.Save(ctx, dev)
 	return errors.Wrapf(err, "saving updated device for acknowledge event")
@@ -183,8 +228,14 @@ func (w *Worker) updateFromCheckout(ctx context.Context, message []byte) error {
 		return errors.Wrapf(err, "retrieve device with udid %s", ev.Command.UDID)
 	}
 
+	if w.purger != nil && dev.PurgeCommandUUID != "" {
+		err := w.purger.PurgeDevice(ctx, dev)
+		return errors.Wrapf(err, "purge device %s after checkout", dev.UDID)
+	}
+
 	dev.Enrolled = false
 	dev.LastSeen = time.Now()
+	dev.Stale = false
 
 	err = w.db.Save(ctx, dev)
 	return errors.Wrapf(err, "saving updated device for checkout event")
@@ -204,6 +255,7 @@ func (w *Worker) updateFromGetBootstrapToken(ctx context.Context, message []byte
 
 	dev.AwaitingConfiguration = ev.Command.AwaitingConfiguration
 	dev.LastSeen = time.Now()
+	dev.Stale = false
 
 	err = w.db.Save(ctx, dev)
 	return errors.Wrapf(err, "saving updated device for GetBootstrapToken event")
@@ -224,6 +276,7 @@ func (w *Worker) updateFromSetBootstrapToken(ctx context.Context, message []byte
 	dev.BootstrapToken = ev.Command.BootstrapToken
 	dev.AwaitingConfiguration = ev.Command.AwaitingConfiguration
 	dev.LastSeen = time.Now()
+	dev.Stale = false
 
 	err = w.db.Save(ctx, dev)
 	return errors.Wrapf(err, "saving updated device for SetBootstrapToken event")
@@ -250,6 +303,7 @@ func (w *Worker) updateFromTokenUpdate(ctx context.Context, message []byte) erro
 	dev.UnlockToken = ev.Command.UnlockToken.String()
 	dev.AwaitingConfiguration = ev.Command.AwaitingConfiguration
 	dev.LastSeen = time.Now()
+	dev.Stale = false
 	// first TokenUpdate event will have the enrollment status set to false.
 	newlyEnrolled := !dev.Enrolled
 	dev.Enrolled = true
@@ -307,10 +361,40 @@ func (w *Worker) updateFromAuthenticate(ctx context.Context, message []byte) err
 	device.Model = ev.Command.Model
 	device.ModelName = ev.Command.ModelName
 	device.LastSeen = time.Now()
+	device.Stale = false
+	if token := ev.Params[invite.TokenParam]; token != "" && w.invites != nil {
+		w.inviteMetadata(ctx, device, token)
+	}
 	err = w.db.Save(ctx, device)
 	return errors.Wrapf(err, "saving updated device for authenticate event")
 }
 
+// inviteMetadata adds the metadata of the invite to the device, if the device
+// redeemed the invite when it authenticated.
+func (w *Worker) inviteMetadata(ctx context.Context, dev *Device, token string) {
+	inv, err := w.invites.Invite(ctx, token)
+	if err != nil {
+		level.Info(w.logger).Log(
+			"msg", "get enrollment invite",
+			"serial", dev.SerialNumber,
+			"err", err,
+		)
+		return
+	}
+	if !inv.Redeemed(dev.UDID) {
+		return
+	}
+	if dev.Attributes == nil {
+		dev.Attributes = make(map[string]string)
+	}
+	for k, v := range inv.Attributes {
+		dev.Attributes[k] = v
+	}
+	if inv.OwnerEmail != "" {
+		dev.Attributes[invite.OwnerAttribute] = inv.OwnerEmail
+	}
+}
+
 func getOrCreateDevice(ctx context.Context, db DeviceWorkerStore, serial, udid string) (dev *Device, reenrolling bool, err error) {
 	if udid != "" {
 		// first try to fetch a device by UDID.
@@ -0,0 +1,127 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/invite"
+)
+
+const InviteBucket = "mdm.Invites"
+
+type DB struct {
+	*bolt.DB
+}
+
+func NewDB(db *bolt.DB) (*DB, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(InviteBucket))
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating %s bucket", InviteBucket)
+	}
+	return &DB{DB: db}, nil
+}
+
+func (db *DB) Save(ctx context.Context, inv *invite.Invite) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return put(tx, inv)
+	})
+}
+
+func put(tx *bolt.Tx, inv *invite.Invite) error {
+	data, err := invite.MarshalInvite(inv)
+	if err != nil {
+		return errors.Wrap(err, "marshal invite")
+	}
+	err = tx.Bucket([]byte(InviteBucket)).Put([]byte(inv.Token), data)
+	return errors.Wrap(err, "store invite in boltdb")
+}
+
+func get(tx *bolt.Tx, token string) (*invite.Invite, error) {
+	data := tx.Bucket([]byte(InviteBucket)).Get([]byte(token))
+	if data == nil {
+		return nil, &notFound{"Invite", "token"}
+	}
+	var inv invite.Invite
+	return &inv, invite.UnmarshalInvite(data, &inv)
+}
+
+func (db *DB) Invite(ctx context.Context, token string) (*invite.Invite, error) {
+	var inv *invite.Invite
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		inv, err = get(tx, token)
+		return err
+	})
+	return inv, errors.Wrap(err, "get invite from bolt")
+}
+
+func (db *DB) List(ctx context.Context) ([]invite.Invite, error) {
+	var invites []invite.Invite
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(InviteBucket)).ForEach(func(k, v []byte) error {
+			var inv invite.Invite
+			if err := invite.UnmarshalInvite(v, &inv); err != nil {
+				return err
+			}
+			invites = append(invites, inv)
+			return nil
+		})
+	})
+	return invites, errors.Wrap(err, "list invites")
+}
+
+func (db *DB) Delete(ctx context.Context, token string) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(InviteBucket))
+		if bkt.Get([]byte(token)) == nil {
+			return &notFound{"Invite", "token"}
+		}
+		return bkt.Delete([]byte(token))
+	})
+	return errors.Wrap(err, "delete invite")
+}
+
+// Redeem records the enrollment of the device with the invite and returns
+// the invite. It fails if the invite is expired or used up, unless the
+// device already enrolled with it.
+func (db *DB) Redeem(ctx context.Context, token, udid string) (*invite.Invite, error) {
+	var inv *invite.Invite
+	err := db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if inv, err = get(tx, token); err != nil {
+			return err
+		}
+		if inv.Redeemed(udid) {
+			return nil
+		}
+		if err := inv.Valid(time.Now()); err != nil {
+			return err
+		}
+		inv.UDIDs = append(inv.UDIDs, udid)
+		return put(tx, inv)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "redeem invite")
+	}
+	return inv, nil
+}
+
+type notFound struct {
+	ResourceType string
+	Message      string
+}
+
+func (e *notFound) Error() string {
+	return fmt.Sprintf("not found: %s %s", e.ResourceType, e.Message)
+}
+
+func (e *notFound) NotFound() bool {
+	return true
+}
@@ -0,0 +1,96 @@
+package builtin
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/micromdm/micromdm/platform/invite"
+)
+
+func TestRedeem(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	inv := &invite.Invite{
+		Token:      "token",
+		CreatedAt:  time.Now().UTC(),
+		ExpiresAt:  time.Now().Add(time.Hour).UTC(),
+		MaxUses:    2,
+		OwnerEmail: "jane@example.com",
+		Attributes: map[string]string{"department": "sales"},
+	}
+	if err := db.Save(ctx, inv); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, udid := range []string{"UDID-1", "UDID-1", "UDID-2"} {
+		redeemed, err := db.Redeem(ctx, inv.Token, udid)
+		if err != nil {
+			t.Fatalf("redeem invite for %s: %s", udid, err)
+		}
+		if have, want := redeemed.Attributes["department"], "sales"; have != want {
+			t.Errorf("have department %q, want %q", have, want)
+		}
+	}
+
+	// the invite is used up, but devices which enrolled with it may
+	// enroll again.
+	if _, err := db.Redeem(ctx, inv.Token, "UDID-3"); err == nil {
+		t.Error("expected used up invite to fail")
+	}
+	if _, err := db.Redeem(ctx, inv.Token, "UDID-2"); err != nil {
+		t.Errorf("redeem invite again: %s", err)
+	}
+
+	saved, err := db.Invite(ctx, inv.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(saved.UDIDs), 2; have != want {
+		t.Errorf("have %d devices, want %d", have, want)
+	}
+
+	if err := db.Delete(ctx, inv.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Redeem(ctx, inv.Token, "UDID-1"); !invite.IsNotFound(err) {
+		t.Errorf("have %v, want not found error", err)
+	}
+}
+
+func TestRedeemExpired(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	inv := &invite.Invite{
+		Token:     "expired",
+		CreatedAt: time.Now().Add(-2 * time.Hour).UTC(),
+		ExpiresAt: time.Now().Add(-time.Hour).UTC(),
+		MaxUses:   1,
+	}
+	if err := db.Save(ctx, inv); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Redeem(ctx, inv.Token, "UDID-1"); err == nil {
+		t.Error("expected expired invite to fail")
+	}
+}
+
+func setupDB(t *testing.T) *DB {
+	f, _ := ioutil.TempFile("", "bolt-")
+	f.Close()
+	os.Remove(f.Name())
+
+	db, err := bolt.Open(f.Name(), 0777, nil)
+	if err != nil {
+		t.Fatalf("couldn't open bolt, err %s\n", err)
+	}
+	inviteDB, err := NewDB(db)
+	if err != nil {
+		t.Fatalf("couldn't create invite DB, err %s\n", err)
+	}
+	return inviteDB
+}
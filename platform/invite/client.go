@@ -0,0 +1,57 @@
+package invite
+
+import (
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func NewHTTPClient(instance, token string, logger log.Logger, opts ...httptransport.ClientOption) (Service, error) {
+	u, err := url.Parse(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	var createInviteEndpoint endpoint.Endpoint
+	{
+		createInviteEndpoint = httptransport.NewClient(
+			"POST",
+			httputil.CopyURL(u, "/v1/invites"),
+			httputil.EncodeRequestWithToken(token, httptransport.EncodeJSONRequest),
+			decodeCreateInviteResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var listInvitesEndpoint endpoint.Endpoint
+	{
+		listInvitesEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/invites"),
+			httputil.EncodeRequestWithToken(token, httputil.EncodeEmptyRequest),
+			decodeListInvitesResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var removeInviteEndpoint endpoint.Endpoint
+	{
+		removeInviteEndpoint = httptransport.NewClient(
+			"DELETE",
+			httputil.CopyURL(u, "/v1/invites"),
+			httputil.EncodeRequestWithToken(token, encodeRemoveInviteRequest),
+			decodeRemoveInviteResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	return Endpoints{
+		CreateInviteEndpoint: createInviteEndpoint,
+		ListInvitesEndpoint:  listInvitesEndpoint,
+		RemoveInviteEndpoint: removeInviteEndpoint,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package invite
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// CreateInvite saves a new invite with a random token. An invite without
+// MaxUses can be used by a single device.
+func (svc *InviteService) CreateInvite(ctx context.Context, inv Invite) (*Invite, error) {
+	if inv.MaxUses < 0 {
+		return nil, errors.New("max_uses must not be negative")
+	}
+	if inv.MaxUses == 0 {
+		inv.MaxUses = 1
+	}
+	if !inv.ExpiresAt.IsZero() && inv.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("expires_at is in the past")
+	}
+	token, err := NewToken()
+	if err != nil {
+		return nil, err
+	}
+	inv.Token = token
+	inv.CreatedAt = time.Now().UTC()
+	inv.UDIDs = nil
+	if err := svc.store.Save(ctx, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+type createInviteRequest struct {
+	Invite Invite `json:"invite"`
+}
+
+type createInviteResponse struct {
+	Invite *Invite `json:"invite,omitempty"`
+	Err    error   `json:"err,omitempty"`
+}
+
+func (r createInviteResponse) Failed() error { return r.Err }
+
+func decodeCreateInviteRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req createInviteRequest
+	err := httputil.DecodeJSONRequest(r, &req)
+	return req, err
+}
+
+func decodeCreateInviteResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp createInviteResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeCreateInviteEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(createInviteRequest)
+		inv, err := svc.CreateInvite(ctx, req.Invite)
+		return createInviteResponse{Invite: inv, Err: err}, nil
+	}
+}
+
+func (e Endpoints) CreateInvite(ctx context.Context, inv Invite) (*Invite, error) {
+	resp, err := e.CreateInviteEndpoint(ctx, createInviteRequest{Invite: inv})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(createInviteResponse)
+	return response.Invite, response.Err
+}
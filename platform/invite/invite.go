@@ -0,0 +1,117 @@
+// Package invite manages enrollment invites, tokens which allow a limited
+// number of devices to download the enrollment profile and carry metadata
+// into their enrollment.
+package invite
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/micromdm/micromdm/platform/invite/internal/inviteproto"
+)
+
+// TokenParam is the query parameter of the enrollment and check-in URLs
+// which carries the invite token.
+const TokenParam = "token"
+
+// OwnerAttribute is the device attribute set to the OwnerEmail of the invite
+// a device enrolled with.
+const OwnerAttribute = "owner"
+
+type Invite struct {
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when the invite stops being valid. A zero value never
+	// expires.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	// MaxUses is the number of devices which can enroll with the invite.
+	MaxUses int `json:"max_uses"`
+	// UDIDs are the devices which enrolled with the invite.
+	UDIDs []string `json:"udids,omitempty"`
+
+	// OwnerEmail is set as the owner attribute of enrolled devices.
+	OwnerEmail string `json:"owner_email,omitempty"`
+	// Attributes are set on enrolled devices.
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// BlueprintName is applied to enrolled devices after enrollment.
+	BlueprintName string `json:"blueprint_name,omitempty"`
+}
+
+// Valid returns an error if the invite is expired or used up.
+func (inv *Invite) Valid(now time.Time) error {
+	if !inv.ExpiresAt.IsZero() && now.After(inv.ExpiresAt) {
+		return fmt.Errorf("invite expired at %s", inv.ExpiresAt.Format(time.RFC3339))
+	}
+	if len(inv.UDIDs) >= inv.MaxUses {
+		return fmt.Errorf("invite was used by %d of %d devices", len(inv.UDIDs), inv.MaxUses)
+	}
+	return nil
+}
+
+// Redeemed reports whether the device enrolled with the invite.
+func (inv *Invite) Redeemed(udid string) bool {
+	for _, u := range inv.UDIDs {
+		if u == udid {
+			return true
+		}
+	}
+	return false
+}
+
+// NewToken returns a random URL safe token.
+func NewToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generate invite token")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func MarshalInvite(inv *Invite) ([]byte, error) {
+	pb := inviteproto.Invite{
+		Token:         inv.Token,
+		CreatedAt:     timeToNano(inv.CreatedAt),
+		ExpiresAt:     timeToNano(inv.ExpiresAt),
+		MaxUses:       int64(inv.MaxUses),
+		Udids:         inv.UDIDs,
+		OwnerEmail:    inv.OwnerEmail,
+		Attributes:    inv.Attributes,
+		BlueprintName: inv.BlueprintName,
+	}
+	return proto.Marshal(&pb)
+}
+
+func UnmarshalInvite(data []byte, inv *Invite) error {
+	var pb inviteproto.Invite
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return errors.Wrap(err, "unmarshal proto to invite")
+	}
+	inv.Token = pb.GetToken()
+	inv.CreatedAt = timeFromNano(pb.GetCreatedAt())
+	inv.ExpiresAt = timeFromNano(pb.GetExpiresAt())
+	inv.MaxUses = int(pb.GetMaxUses())
+	inv.UDIDs = pb.GetUdids()
+	inv.OwnerEmail = pb.GetOwnerEmail()
+	inv.Attributes = pb.GetAttributes()
+	inv.BlueprintName = pb.GetBlueprintName()
+	return nil
+}
+
+func timeToNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func timeFromNano(nano int64) time.Time {
+	if nano == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nano).UTC()
+}
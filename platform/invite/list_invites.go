@@ -0,0 +1,47 @@
+package invite
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func (svc *InviteService) ListInvites(ctx context.Context) ([]Invite, error) {
+	return svc.store.List(ctx)
+}
+
+type listInvitesResponse struct {
+	Invites []Invite `json:"invites"`
+	Err     error    `json:"err,omitempty"`
+}
+
+func (r listInvitesResponse) Failed() error { return r.Err }
+
+func decodeListInvitesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeListInvitesResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp listInvitesResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeListInvitesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		invites, err := svc.ListInvites(ctx)
+		return listInvitesResponse{Invites: invites, Err: err}, nil
+	}
+}
+
+func (e Endpoints) ListInvites(ctx context.Context) ([]Invite, error) {
+	resp, err := e.ListInvitesEndpoint(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(listInvitesResponse)
+	return response.Invites, response.Err
+}
@@ -0,0 +1,65 @@
+package invite
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// RemoveInvite deletes the invite. Devices which already enrolled with it
+// are not affected.
+func (svc *InviteService) RemoveInvite(ctx context.Context, token string) error {
+	return svc.store.Delete(ctx, token)
+}
+
+type removeInviteRequest struct {
+	Token string
+}
+
+type removeInviteResponse struct {
+	Err error `json:"err,omitempty"`
+}
+
+func (r removeInviteResponse) Failed() error { return r.Err }
+
+func decodeRemoveInviteRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	token, ok := mux.Vars(r)["token"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	return removeInviteRequest{Token: token}, nil
+}
+
+func encodeRemoveInviteRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(removeInviteRequest)
+	r.Method, r.URL.Path = "DELETE", "/v1/invites/"+url.PathEscape(req.Token)
+	return nil
+}
+
+func decodeRemoveInviteResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp removeInviteResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeRemoveInviteEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(removeInviteRequest)
+		err = svc.RemoveInvite(ctx, req.Token)
+		return removeInviteResponse{Err: err}, nil
+	}
+}
+
+func (e Endpoints) RemoveInvite(ctx context.Context, token string) error {
+	resp, err := e.RemoveInviteEndpoint(ctx, removeInviteRequest{Token: token})
+	if err != nil {
+		return err
+	}
+	return resp.(removeInviteResponse).Err
+}
@@ -0,0 +1,50 @@
+package invite
+
+import (
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+type Endpoints struct {
+	CreateInviteEndpoint endpoint.Endpoint
+	ListInvitesEndpoint  endpoint.Endpoint
+	RemoveInviteEndpoint endpoint.Endpoint
+}
+
+func MakeServerEndpoints(s Service, outer endpoint.Middleware, others ...endpoint.Middleware) Endpoints {
+	return Endpoints{
+		CreateInviteEndpoint: endpoint.Chain(outer, others...)(MakeCreateInviteEndpoint(s)),
+		ListInvitesEndpoint:  endpoint.Chain(outer, others...)(MakeListInvitesEndpoint(s)),
+		RemoveInviteEndpoint: endpoint.Chain(outer, others...)(MakeRemoveInviteEndpoint(s)),
+	}
+}
+
+func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.ServerOption) {
+	// POST    /v1/invites		create an enrollment invite
+	// GET     /v1/invites		list enrollment invites
+	// DELETE  /v1/invites/{token}	remove an enrollment invite
+
+	r.Methods("POST").Path("/v1/invites").Handler(httptransport.NewServer(
+		e.CreateInviteEndpoint,
+		decodeCreateInviteRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/invites").Handler(httptransport.NewServer(
+		e.ListInvitesEndpoint,
+		decodeListInvitesRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("DELETE").Path("/v1/invites/{token}").Handler(httptransport.NewServer(
+		e.RemoveInviteEndpoint,
+		decodeRemoveInviteRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+}
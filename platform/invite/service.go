@@ -0,0 +1,38 @@
+package invite
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+type Service interface {
+	CreateInvite(ctx context.Context, inv Invite) (*Invite, error)
+	ListInvites(ctx context.Context) ([]Invite, error)
+	RemoveInvite(ctx context.Context, token string) error
+}
+
+type Store interface {
+	Save(ctx context.Context, inv *Invite) error
+	Invite(ctx context.Context, token string) (*Invite, error)
+	List(ctx context.Context) ([]Invite, error)
+	Delete(ctx context.Context, token string) error
+}
+
+type InviteService struct {
+	store Store
+}
+
+func New(store Store) *InviteService {
+	return &InviteService{store: store}
+}
+
+func IsNotFound(err error) bool {
+	type notFoundError interface {
+		error
+		NotFound() bool
+	}
+
+	_, ok := errors.Cause(err).(notFoundError)
+	return ok
+}
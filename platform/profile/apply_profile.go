@@ -9,7 +9,8 @@ import (
 )
 
 func (svc *ProfileService) ApplyProfile(ctx context.Context, p *Profile) error {
-	return svc.store.Save(p)
+	_, err := svc.store.SaveRevision(p, httputil.APIKeyID(ctx))
+	return err
 }
 
 type applyProfileRequest struct {
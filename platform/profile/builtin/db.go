@@ -2,7 +2,9 @@ package builtin
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/micromdm/micromdm/platform/profile"
@@ -11,6 +13,10 @@ import (
 
 const (
 	ProfileBucket = "mdm.Profile"
+
+	// profileRevisionBucket holds a nested bucket of revisions for each
+	// profile identifier, keyed by big endian version.
+	profileRevisionBucket = "mdm.ProfileRevisions"
 )
 
 type DB struct {
@@ -19,7 +25,11 @@ type DB struct {
 
 func NewDB(db *bolt.DB) (*DB, error) {
 	err := db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(ProfileBucket))
+		_, err := tx.CreateBucketIfNotExists([]byte(profileRevisionBucket))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucketIfNotExists([]byte(ProfileBucket))
 		return err
 	})
 	if err != nil {
@@ -50,26 +60,95 @@ func (db *DB) List() ([]profile.Profile, error) {
 }
 
 func (db *DB) Save(p *profile.Profile) error {
+	_, err := db.SaveRevision(p, "")
+	return err
+}
+
+// SaveRevision saves the profile and records it as a new revision uploaded
+// with the API key.
+func (db *DB) SaveRevision(p *profile.Profile, apiKey string) (*profile.Revision, error) {
 	err := p.Validate()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	tx, err := db.DB.Begin(true)
 	if err != nil {
-		return errors.Wrap(err, "begin transaction")
+		return nil, errors.Wrap(err, "begin transaction")
 	}
+	defer tx.Rollback()
 	bkt := tx.Bucket([]byte(ProfileBucket))
 	if bkt == nil {
-		return fmt.Errorf("bucket %q not found!", ProfileBucket)
+		return nil, fmt.Errorf("bucket %q not found!", ProfileBucket)
 	}
 	pproto, err := profile.MarshalProfile(p)
 	if err != nil {
-		return errors.Wrap(err, "marshalling profile")
+		return nil, errors.Wrap(err, "marshalling profile")
 	}
 	if err := bkt.Put([]byte(p.Identifier), pproto); err != nil {
-		return errors.Wrap(err, "put profile to boltdb")
+		return nil, errors.Wrap(err, "put profile to boltdb")
+	}
+
+	revBkt, err := tx.Bucket([]byte(profileRevisionBucket)).CreateBucketIfNotExists([]byte(p.Identifier))
+	if err != nil {
+		return nil, errors.Wrap(err, "create profile revision bucket")
+	}
+	version, err := revBkt.NextSequence()
+	if err != nil {
+		return nil, errors.Wrap(err, "get next profile revision")
+	}
+	rev := profile.NewRevision(p, int(version), apiKey, time.Now().UTC())
+	revproto, err := profile.MarshalRevision(rev)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling profile revision")
+	}
+	if err := revBkt.Put(revisionKey(rev.Version), revproto); err != nil {
+		return nil, errors.Wrap(err, "put profile revision to boltdb")
+	}
+	return rev, tx.Commit()
+}
+
+func revisionKey(version int) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(version))
+	return key
+}
+
+// Revisions returns every revision of a profile, oldest first.
+func (db *DB) Revisions(id string) ([]profile.Revision, error) {
+	var revisions []profile.Revision
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(profileRevisionBucket)).Bucket([]byte(id))
+		if b == nil {
+			return &notFound{"Profile revisions", fmt.Sprintf("id %s", id)}
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var rev profile.Revision
+			if err := profile.UnmarshalRevision(v, &rev); err != nil {
+				return err
+			}
+			revisions = append(revisions, rev)
+			return nil
+		})
+	})
+	return revisions, err
+}
+
+func (db *DB) Revision(id string, version int) (*profile.Revision, error) {
+	var rev profile.Revision
+	err := db.View(func(tx *bolt.Tx) error {
+		var v []byte
+		if b := tx.Bucket([]byte(profileRevisionBucket)).Bucket([]byte(id)); b != nil {
+			v = b.Get(revisionKey(version))
+		}
+		if v == nil {
+			return &notFound{"Profile revision", fmt.Sprintf("id %s version %d", id, version)}
+		}
+		return profile.UnmarshalRevision(v, &rev)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return tx.Commit()
+	return &rev, nil
 }
 
 func (db *DB) ProfileById(ctx context.Context, id string) (*profile.Profile, error) {
@@ -48,9 +48,69 @@ func NewHTTPClient(instance, token string, logger log.Logger, opts ...httptransp
 		).Endpoint()
 	}
 
+	var lintProfileEndpoint endpoint.Endpoint
+	{
+		lintProfileEndpoint = httptransport.NewClient(
+			"POST",
+			httputil.CopyURL(u, "/v1/profiles/lint"),
+			httputil.EncodeRequestWithToken(token, httptransport.EncodeJSONRequest),
+			decodeLintProfileResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var getRevisionsEndpoint endpoint.Endpoint
+	{
+		getRevisionsEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/profiles"),
+			httputil.EncodeRequestWithToken(token, encodeGetRevisionsRequest),
+			decodeGetRevisionsResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var getRevisionEndpoint endpoint.Endpoint
+	{
+		getRevisionEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/profiles"),
+			httputil.EncodeRequestWithToken(token, encodeGetRevisionRequest),
+			decodeGetRevisionResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var diffRevisionsEndpoint endpoint.Endpoint
+	{
+		diffRevisionsEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/profiles"),
+			httputil.EncodeRequestWithToken(token, encodeDiffRevisionsRequest),
+			decodeDiffRevisionsResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var promoteRevisionEndpoint endpoint.Endpoint
+	{
+		promoteRevisionEndpoint = httptransport.NewClient(
+			"POST",
+			httputil.CopyURL(u, "/v1/profiles"),
+			httputil.EncodeRequestWithToken(token, encodePromoteRevisionRequest),
+			decodePromoteRevisionResponse,
+			opts...,
+		).Endpoint()
+	}
+
 	return Endpoints{
-		ApplyProfileEndpoint:   applyProfileEndpoint,
-		GetProfilesEndpoint:    getProfilesEndpoint,
-		RemoveProfilesEndpoint: removeProfilesEndpoint,
+		ApplyProfileEndpoint:    applyProfileEndpoint,
+		GetProfilesEndpoint:     getProfilesEndpoint,
+		RemoveProfilesEndpoint:  removeProfilesEndpoint,
+		LintProfileEndpoint:     lintProfileEndpoint,
+		GetRevisionsEndpoint:    getRevisionsEndpoint,
+		GetRevisionEndpoint:     getRevisionEndpoint,
+		DiffRevisionsEndpoint:   diffRevisionsEndpoint,
+		PromoteRevisionEndpoint: promoteRevisionEndpoint,
 	}, nil
 }
@@ -0,0 +1,113 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// DiffRevisions compares two revisions of a profile. A to version of 0
+// selects the latest revision, and a from version of 0 the one before to.
+func (svc *ProfileService) DiffRevisions(ctx context.Context, id string, from, to int) ([]KeyChange, error) {
+	if to == 0 {
+		revisions, err := svc.store.Revisions(id)
+		if err != nil {
+			return nil, err
+		}
+		to = revisions[len(revisions)-1].Version
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	if from < 1 {
+		return nil, fmt.Errorf("profile %s has no revision before version %d", id, to)
+	}
+	fromRev, err := svc.store.Revision(id, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := svc.store.Revision(id, to)
+	if err != nil {
+		return nil, err
+	}
+	return DiffMobileconfig(fromRev.Profile.Mobileconfig, toRev.Profile.Mobileconfig)
+}
+
+type diffRevisionsRequest struct {
+	Identifier string
+	From       int
+	To         int
+}
+
+type diffRevisionsResponse struct {
+	Changes []KeyChange `json:"changes"`
+	Err     error       `json:"err,omitempty"`
+}
+
+func (r diffRevisionsResponse) Failed() error { return r.Err }
+
+func decodeDiffRevisionsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	req := diffRevisionsRequest{Identifier: id}
+	q := r.URL.Query()
+	var err error
+	if v := q.Get("from"); v != "" {
+		if req.From, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid from version %q", v)
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if req.To, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid to version %q", v)
+		}
+	}
+	return req, nil
+}
+
+func encodeDiffRevisionsRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(diffRevisionsRequest)
+	r.Method, r.URL.Path = "GET", "/v1/profiles/"+url.PathEscape(req.Identifier)+"/diff"
+	q := url.Values{}
+	if req.From != 0 {
+		q.Set("from", strconv.Itoa(req.From))
+	}
+	if req.To != 0 {
+		q.Set("to", strconv.Itoa(req.To))
+	}
+	r.URL.RawQuery = q.Encode()
+	return nil
+}
+
+func decodeDiffRevisionsResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp diffRevisionsResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeDiffRevisionsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(diffRevisionsRequest)
+		changes, err := svc.DiffRevisions(ctx, req.Identifier, req.From, req.To)
+		return diffRevisionsResponse{Changes: changes, Err: err}, nil
+	}
+}
+
+func (e Endpoints) DiffRevisions(ctx context.Context, id string, from, to int) ([]KeyChange, error) {
+	resp, err := e.DiffRevisionsEndpoint(ctx, diffRevisionsRequest{Identifier: id, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(diffRevisionsResponse)
+	return response.Changes, response.Err
+}
@@ -0,0 +1,38 @@
+package profile
+
+import (
+	"crypto/x509"
+
+	"github.com/micromdm/plist"
+	"github.com/pkg/errors"
+	"github.com/smallstep/pkcs7"
+)
+
+// Encrypt returns the Mobileconfig with its PayloadContent encrypted to the
+// identity certificate of a device. The payloads are replaced by the CMS
+// enveloped EncryptedPayloadContent, which only the device can decrypt.
+// Signed profiles can not be encrypted, they are signed after encryption.
+func (mc Mobileconfig) Encrypt(cert *x509.Certificate) (Mobileconfig, error) {
+	if mc.IsSigned() {
+		return nil, errors.New("signed profile can not be encrypted")
+	}
+	var p map[string]interface{}
+	if err := plist.Unmarshal(mc, &p); err != nil {
+		return nil, errors.Wrap(err, "parse profile property list")
+	}
+	content, ok := p["PayloadContent"]
+	if !ok {
+		return nil, errors.New("profile has no PayloadContent to encrypt")
+	}
+	data, err := plist.Marshal(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal PayloadContent")
+	}
+	encrypted, err := pkcs7.Encrypt(data, []*x509.Certificate{cert})
+	if err != nil {
+		return nil, errors.Wrap(err, "encrypt PayloadContent")
+	}
+	delete(p, "PayloadContent")
+	p["EncryptedPayloadContent"] = encrypted
+	return plist.MarshalIndent(p, "\t")
+}
@@ -0,0 +1,58 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/jessepeterson/cfgprofiles"
+	"github.com/micromdm/plist"
+	"github.com/smallstep/pkcs7"
+)
+
+func TestEncryptMobileconfig(t *testing.T) {
+	device := testSigner(t)
+	mc := Mobileconfig(fmt.Sprintf(lintTestProfile, "com.example.test"))
+
+	encrypted, err := mc.Encrypt(device.cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p cfgprofiles.Profile
+	if err := plist.Unmarshal(encrypted, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.PayloadIdentifier != "com.example.test" {
+		t.Errorf("have PayloadIdentifier %q, want com.example.test", p.PayloadIdentifier)
+	}
+	if len(p.PayloadContent) != 0 {
+		t.Error("expected PayloadContent to be removed")
+	}
+	if bytes.Contains(encrypted, []byte("com.example.wifi")) {
+		t.Error("encrypted profile contains the payloads in plaintext")
+	}
+
+	p7, err := pkcs7.Parse(p.EncryptedPayloadContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := p7.Decrypt(device.cert, device.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payloads []map[string]interface{}
+	if err := plist.Unmarshal(content, &payloads); err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 2 || payloads[0]["PayloadIdentifier"] != "com.example.wifi" {
+		t.Errorf("have decrypted payloads %v", payloads)
+	}
+
+	signed, err := device.Mobileconfig(&Profile{Mobileconfig: mc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signed.Encrypt(device.cert); err == nil {
+		t.Error("expected an error encrypting a signed profile")
+	}
+}
@@ -0,0 +1,74 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func (svc *ProfileService) GetRevision(ctx context.Context, id string, version int) (*Revision, error) {
+	return svc.store.Revision(id, version)
+}
+
+type getRevisionRequest struct {
+	Identifier string
+	Version    int
+}
+
+type getRevisionResponse struct {
+	Revision *Revision `json:"revision,omitempty"`
+	Err      error     `json:"err,omitempty"`
+}
+
+func (r getRevisionResponse) Failed() error { return r.Err }
+
+func decodeGetRevisionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q", vars["version"])
+	}
+	return getRevisionRequest{Identifier: id, Version: version}, nil
+}
+
+func encodeGetRevisionRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(getRevisionRequest)
+	r.Method = "GET"
+	r.URL.Path = "/v1/profiles/" + url.PathEscape(req.Identifier) + "/revisions/" + strconv.Itoa(req.Version)
+	return nil
+}
+
+func decodeGetRevisionResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp getRevisionResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeGetRevisionEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(getRevisionRequest)
+		rev, err := svc.GetRevision(ctx, req.Identifier, req.Version)
+		return getRevisionResponse{Revision: rev, Err: err}, nil
+	}
+}
+
+func (e Endpoints) GetRevision(ctx context.Context, id string, version int) (*Revision, error) {
+	resp, err := e.GetRevisionEndpoint(ctx, getRevisionRequest{Identifier: id, Version: version})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(getRevisionResponse)
+	return response.Revision, response.Err
+}
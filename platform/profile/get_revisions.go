@@ -0,0 +1,65 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func (svc *ProfileService) GetRevisions(ctx context.Context, id string) ([]Revision, error) {
+	return svc.store.Revisions(id)
+}
+
+type getRevisionsRequest struct {
+	Identifier string
+}
+
+type getRevisionsResponse struct {
+	Revisions []Revision `json:"revisions"`
+	Err       error      `json:"err,omitempty"`
+}
+
+func (r getRevisionsResponse) Failed() error { return r.Err }
+
+func decodeGetRevisionsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	return getRevisionsRequest{Identifier: id}, nil
+}
+
+func encodeGetRevisionsRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(getRevisionsRequest)
+	r.Method, r.URL.Path = "GET", "/v1/profiles/"+url.PathEscape(req.Identifier)+"/revisions"
+	return nil
+}
+
+func decodeGetRevisionsResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp getRevisionsResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeGetRevisionsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(getRevisionsRequest)
+		revisions, err := svc.GetRevisions(ctx, req.Identifier)
+		return getRevisionsResponse{Revisions: revisions, Err: err}, nil
+	}
+}
+
+func (e Endpoints) GetRevisions(ctx context.Context, id string) ([]Revision, error) {
+	resp, err := e.GetRevisionsEndpoint(ctx, getRevisionsRequest{Identifier: id})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(getRevisionsResponse)
+	return response.Revisions, response.Err
+}
@@ -0,0 +1,237 @@
+package profile
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jessepeterson/cfgprofiles"
+	"github.com/micromdm/plist"
+	"github.com/smallstep/pkcs7"
+)
+
+// Severities of lint issues.
+const (
+	// LintError is an issue which makes devices reject the profile.
+	LintError = "error"
+	// LintWarning is an issue which may cause the profile to behave
+	// differently than intended.
+	LintWarning = "warning"
+)
+
+// enrollmentProfileIdentifier is the PayloadIdentifier of the enrollment
+// profile served by the server, see enroll.EnrollmentProfileId.
+const enrollmentProfileIdentifier = "com.github.micromdm.micromdm.enroll"
+
+// certExpiryWarning is how long before the signing certificate expires a
+// warning is returned.
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// knownPayloadTypes are the payload types devices are known to accept.
+var knownPayloadTypes = map[string]bool{
+	"com.apple.ADCertificate.managed":               true,
+	"com.apple.airplay":                             true,
+	"com.apple.airprint":                            true,
+	"com.apple.app.lock":                            true,
+	"com.apple.applicationaccess":                   true,
+	"com.apple.applicationaccess.new":               true,
+	"com.apple.associated-domains":                  true,
+	"com.apple.caldav.account":                      true,
+	"com.apple.carddav.account":                     true,
+	"com.apple.configurationprofile.identification": true,
+	"com.apple.dnsSettings.managed":                 true,
+	"com.apple.dock":                                true,
+	"com.apple.eas.account":                         true,
+	"com.apple.extensiblesso":                       true,
+	"com.apple.familycontrols.contentfilter":        true,
+	"com.apple.finder":                              true,
+	"com.apple.font":                                true,
+	"com.apple.homescreenlayout":                    true,
+	"com.apple.ldap.account":                        true,
+	"com.apple.loginitems.managed":                  true,
+	"com.apple.loginwindow":                         true,
+	"com.apple.mail.managed":                        true,
+	"com.apple.management.lightsout":                true,
+	"com.apple.ManagedClient.preferences":           true,
+	"com.apple.mdm":                                 true,
+	"com.apple.MCX":                                 true,
+	"com.apple.MCX.FileVault2":                      true,
+	"com.apple.mobiledevice.passwordpolicy":         true,
+	"com.apple.notificationsettings":                true,
+	"com.apple.preference.security":                 true,
+	"com.apple.security.acme":                       true,
+	"com.apple.security.firewall":                   true,
+	"com.apple.security.pem":                        true,
+	"com.apple.security.pkcs1":                      true,
+	"com.apple.security.pkcs12":                     true,
+	"com.apple.security.root":                       true,
+	"com.apple.security.scep":                       true,
+	"com.apple.servicemanagement":                   true,
+	"com.apple.SoftwareUpdate":                      true,
+	"com.apple.syspolicy.kernel-extension-policy":   true,
+	"com.apple.system-extension-policy":             true,
+	"com.apple.systempolicy.control":                true,
+	"com.apple.TCC.configuration-profile-policy":    true,
+	"com.apple.vpn.managed":                         true,
+	"com.apple.webClip.managed":                     true,
+	"com.apple.webcontent-filter":                   true,
+	"com.apple.wifi.managed":                        true,
+}
+
+// LintIssue is a problem found in a profile. Payload is the
+// PayloadIdentifier of the payload with the issue, or empty for the profile
+// itself.
+type LintIssue struct {
+	Severity string `json:"severity"`
+	Payload  string `json:"payload,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	if i.Payload == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Payload, i.Message)
+}
+
+// LintResult holds the issues found in a profile.
+type LintResult struct {
+	Issues []LintIssue `json:"issues,omitempty"`
+}
+
+// HasErrors reports whether any issue has LintError severity.
+func (r *LintResult) HasErrors() bool {
+	for _, i := range r.Issues {
+		if i.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *LintResult) add(severity, payload, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, LintIssue{
+		Severity: severity,
+		Payload:  payload,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Lint checks the structure, payload types and signature of a Mobileconfig.
+func Lint(mc Mobileconfig, now time.Time) *LintResult {
+	r := &LintResult{}
+	content := []byte(mc)
+	if mc.IsSigned() {
+		content = lintSignature(r, mc, now)
+		if content == nil {
+			return r
+		}
+	}
+
+	var p cfgprofiles.Profile
+	if err := plist.Unmarshal(content, &p); err != nil {
+		r.add(LintError, "", "profile is not a valid property list: %s", err)
+		return r
+	}
+
+	lintPayload(r, &p.Payload, "")
+	if p.PayloadType != "" && p.PayloadType != "Configuration" {
+		r.add(LintError, "", "PayloadType is %q, must be Configuration", p.PayloadType)
+	}
+	if p.PayloadExpirationDate != nil && p.PayloadExpirationDate.Before(now) {
+		r.add(LintError, "", "profile expired on %s", p.PayloadExpirationDate.Format(time.RFC3339))
+	}
+	if len(p.PayloadContent) == 0 && !p.IsEncrypted {
+		r.add(LintWarning, "", "profile has no payloads")
+	}
+
+	uuids := map[string]bool{p.PayloadUUID: true}
+	identifiers := map[string]bool{}
+	var hasMDM bool
+	for _, pc := range p.PayloadContent {
+		pld := cfgprofiles.CommonPayload(pc.Payload)
+		if pld == nil {
+			continue
+		}
+		name := pld.PayloadIdentifier
+		lintPayload(r, pld, name)
+		if pld.PayloadUUID != "" {
+			if uuids[pld.PayloadUUID] {
+				r.add(LintError, name, "duplicate PayloadUUID %s", pld.PayloadUUID)
+			}
+			uuids[pld.PayloadUUID] = true
+		}
+		if name != "" {
+			if identifiers[name] {
+				r.add(LintError, name, "duplicate PayloadIdentifier")
+			}
+			identifiers[name] = true
+		}
+		if pld.PayloadType != "" && !knownPayloadTypes[pld.PayloadType] {
+			r.add(LintWarning, name, "unknown PayloadType %s", pld.PayloadType)
+		}
+		if pld.PayloadType == "com.apple.mdm" {
+			hasMDM = true
+		}
+	}
+
+	if p.PayloadIdentifier == enrollmentProfileIdentifier {
+		if hasMDM {
+			r.add(LintWarning, "", "profile replaces the enrollment profile of the server")
+		} else {
+			r.add(LintError, "", "PayloadIdentifier %s is reserved for the enrollment profile, installing it removes MDM enrollment", enrollmentProfileIdentifier)
+		}
+	}
+	return r
+}
+
+// lintPayload checks the keys common to all payloads.
+func lintPayload(r *LintResult, pld *cfgprofiles.Payload, name string) {
+	if pld.PayloadIdentifier == "" {
+		r.add(LintError, name, "missing PayloadIdentifier")
+	}
+	if pld.PayloadUUID == "" {
+		r.add(LintError, name, "missing PayloadUUID")
+	}
+	if pld.PayloadType == "" {
+		r.add(LintError, name, "missing PayloadType")
+	}
+	if pld.PayloadVersion == 0 {
+		r.add(LintError, name, "missing PayloadVersion")
+	} else if pld.PayloadVersion != 1 {
+		r.add(LintWarning, name, "PayloadVersion is %d, expected 1", pld.PayloadVersion)
+	}
+}
+
+// lintSignature verifies the signature of a signed Mobileconfig and checks
+// the validity of the signing certificate. It returns the signed content, or
+// nil if the signature can not be parsed.
+func lintSignature(r *LintResult, mc Mobileconfig, now time.Time) []byte {
+	p7, err := pkcs7.Parse(mc)
+	if err != nil {
+		r.add(LintError, "", "parse signature: %s", err)
+		return nil
+	}
+	if err := p7.Verify(); err != nil {
+		r.add(LintError, "", "invalid signature: %s", err)
+	}
+	cert := p7.GetOnlySigner()
+	if cert == nil {
+		r.add(LintWarning, "", "profile does not have exactly one signer")
+		return p7.Content
+	}
+	subject := cert.Subject.CommonName
+	switch {
+	case now.After(cert.NotAfter):
+		r.add(LintError, "", "signing certificate %q expired on %s", subject, cert.NotAfter.Format(time.RFC3339))
+	case now.Before(cert.NotBefore):
+		r.add(LintError, "", "signing certificate %q is not valid before %s", subject, cert.NotBefore.Format(time.RFC3339))
+	case cert.NotAfter.Sub(now) < certExpiryWarning:
+		r.add(LintWarning, "", "signing certificate %q expires on %s", subject, cert.NotAfter.Format(time.RFC3339))
+	}
+	if strings.TrimSpace(string(p7.Content)) == "" {
+		r.add(LintError, "", "signed profile has no content")
+		return nil
+	}
+	return p7.Content
+}
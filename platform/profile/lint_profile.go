@@ -0,0 +1,62 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func (svc *ProfileService) LintProfile(ctx context.Context, mc Mobileconfig) (*LintResult, error) {
+	if len(mc) == 0 {
+		return nil, errors.New("no Mobileconfig data")
+	}
+	return Lint(mc, time.Now()), nil
+}
+
+type lintProfileRequest struct {
+	Mobileconfig Mobileconfig `json:"mobileconfig"`
+}
+
+type lintProfileResponse struct {
+	Result *LintResult `json:"result,omitempty"`
+	Err    error       `json:"err,omitempty"`
+}
+
+func (r lintProfileResponse) Failed() error { return r.Err }
+
+func decodeLintProfileRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req lintProfileRequest
+	err := httputil.DecodeJSONRequest(r, &req)
+	return req, err
+}
+
+func decodeLintProfileResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp lintProfileResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeLintProfileEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(lintProfileRequest)
+		result, err := svc.LintProfile(ctx, req.Mobileconfig)
+		return lintProfileResponse{
+			Result: result,
+			Err:    err,
+		}, nil
+	}
+}
+
+func (e Endpoints) LintProfile(ctx context.Context, mc Mobileconfig) (*LintResult, error) {
+	request := lintProfileRequest{Mobileconfig: mc}
+	resp, err := e.LintProfileEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(lintProfileResponse)
+	return response.Result, response.Err
+}
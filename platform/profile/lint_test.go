@@ -0,0 +1,101 @@
+package profile
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+const lintTestProfile = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>PayloadIdentifier</key>
+	<string>%s</string>
+	<key>PayloadType</key>
+	<string>Configuration</string>
+	<key>PayloadUUID</key>
+	<string>4F5E1C1A-0D2B-4E0B-9E6A-2C3F0A1B2C3D</string>
+	<key>PayloadVersion</key>
+	<integer>1</integer>
+	<key>PayloadContent</key>
+	<array>
+		<dict>
+			<key>PayloadIdentifier</key>
+			<string>com.example.wifi</string>
+			<key>PayloadType</key>
+			<string>com.apple.wifi.managed</string>
+			<key>PayloadUUID</key>
+			<string>4F5E1C1A-0D2B-4E0B-9E6A-2C3F0A1B2C3D</string>
+		</dict>
+		<dict>
+			<key>PayloadIdentifier</key>
+			<string>com.example.custom</string>
+			<key>PayloadType</key>
+			<string>com.example.custom</string>
+			<key>PayloadUUID</key>
+			<string>9A8B7C6D-0000-4000-8000-000000000000</string>
+			<key>PayloadVersion</key>
+			<integer>1</integer>
+		</dict>
+	</array>
+</dict>
+</plist>`
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		identifier string
+		want       []LintIssue
+	}{
+		{
+			identifier: "com.example.test",
+			want: []LintIssue{
+				{Severity: LintError, Payload: "com.example.wifi", Message: "missing PayloadVersion"},
+				{Severity: LintError, Payload: "com.example.wifi", Message: "duplicate PayloadUUID 4F5E1C1A-0D2B-4E0B-9E6A-2C3F0A1B2C3D"},
+				{Severity: LintWarning, Payload: "com.example.custom", Message: "unknown PayloadType com.example.custom"},
+			},
+		},
+		{
+			identifier: enrollmentProfileIdentifier,
+			want: []LintIssue{
+				{Severity: LintError, Payload: "com.example.wifi", Message: "missing PayloadVersion"},
+				{Severity: LintError, Payload: "com.example.wifi", Message: "duplicate PayloadUUID 4F5E1C1A-0D2B-4E0B-9E6A-2C3F0A1B2C3D"},
+				{Severity: LintWarning, Payload: "com.example.custom", Message: "unknown PayloadType com.example.custom"},
+				{Severity: LintError, Message: "PayloadIdentifier com.github.micromdm.micromdm.enroll is reserved for the enrollment profile, installing it removes MDM enrollment"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.identifier, func(t *testing.T) {
+			mc := Mobileconfig(fmt.Sprintf(lintTestProfile, tt.identifier))
+			result := Lint(mc, time.Now())
+			if len(result.Issues) != len(tt.want) {
+				t.Fatalf("have issues %v, want %v", result.Issues, tt.want)
+			}
+			for i := range tt.want {
+				if result.Issues[i] != tt.want[i] {
+					t.Errorf("issue %d: have %v, want %v", i, result.Issues[i], tt.want[i])
+				}
+			}
+			if !result.HasErrors() {
+				t.Error("expected lint errors")
+			}
+		})
+	}
+}
+
+func TestLintExpiredSignature(t *testing.T) {
+	signer := testSigner(t)
+	p := &Profile{Mobileconfig: Mobileconfig(fmt.Sprintf(lintTestProfile, "com.example.test"))}
+	signed, err := signer.Mobileconfig(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := Lint(signed, time.Now().Add(2*time.Hour))
+	for _, issue := range result.Issues {
+		if issue.Severity == LintError && issue.Payload == "" {
+			return
+		}
+	}
+	t.Errorf("expected an error for the expired signing certificate, have %v", result.Issues)
+}
@@ -40,9 +40,21 @@ func (mc *Mobileconfig) GetPayloadIdentifier() (string, error) {
 	return pId.PayloadIdentifier, err
 }
 
+// IsSigned reports whether the Mobileconfig is a PKCS7 signed profile.
+func (mc Mobileconfig) IsSigned() bool {
+	_, err := pkcs7.Parse(mc)
+	return err == nil
+}
+
 type Profile struct {
 	Identifier   string
 	Mobileconfig Mobileconfig
+	// SkipSigning sends the Mobileconfig to devices as uploaded, even if the
+	// server has a profile signing identity.
+	SkipSigning bool `json:",omitempty"`
+	// Encrypt encrypts the payloads of the Mobileconfig to the identity
+	// certificate of each device it is sent to.
+	Encrypt bool `json:",omitempty"`
 }
 
 // Validate checks the internal consistency and validity of a Profile structure
@@ -60,6 +72,12 @@ func (p *Profile) Validate() error {
 	if payloadId != p.Identifier {
 		return errors.New("payload Identifier does not match Profile")
 	}
+	if p.Mobileconfig.IsSigned() && len(p.Mobileconfig.Variables()) > 0 {
+		return errors.New("signed profile can not contain variables")
+	}
+	if p.Encrypt && p.Mobileconfig.IsSigned() {
+		return errors.New("signed profile can not be encrypted")
+	}
 	return nil
 }
 
@@ -67,6 +85,8 @@ func MarshalProfile(p *Profile) ([]byte, error) {
 	protobp := profileproto.Profile{
 		Id:           p.Identifier,
 		Mobileconfig: p.Mobileconfig,
+		SkipSigning:  p.SkipSigning,
+		Encrypt:      p.Encrypt,
 	}
 	return proto.Marshal(&protobp)
 }
@@ -78,5 +98,7 @@ func UnmarshalProfile(data []byte, p *Profile) error {
 	}
 	p.Identifier = pb.GetId()
 	p.Mobileconfig = pb.GetMobileconfig()
+	p.SkipSigning = pb.GetSkipSigning()
+	p.Encrypt = pb.GetEncrypt()
 	return nil
 }
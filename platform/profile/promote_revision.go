@@ -0,0 +1,100 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+type PromoteRevisionResult struct {
+	Revision *Revision `json:"revision"`
+	// Pushed are the UDIDs of the devices the profile was installed on
+	// again.
+	Pushed []string `json:"pushed,omitempty"`
+}
+
+// PromoteRevision saves the profile of an earlier revision again, which
+// records it as a new revision. With push the profile is installed again on
+// the devices which have it installed.
+func (svc *ProfileService) PromoteRevision(ctx context.Context, id string, version int, push bool) (*PromoteRevisionResult, error) {
+	rev, err := svc.store.Revision(id, version)
+	if err != nil {
+		return nil, err
+	}
+	promoted, err := svc.store.SaveRevision(&rev.Profile, httputil.APIKeyID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	result := &PromoteRevisionResult{Revision: promoted}
+	if !push {
+		return result, nil
+	}
+	if svc.reinstaller == nil {
+		return result, errors.New("server does not support installing profiles again")
+	}
+	result.Pushed, err = svc.reinstaller.ReinstallProfile(ctx, &promoted.Profile)
+	return result, err
+}
+
+type promoteRevisionRequest struct {
+	Identifier string `json:"-"`
+	Version    int    `json:"version"`
+	Push       bool   `json:"push,omitempty"`
+}
+
+type promoteRevisionResponse struct {
+	*PromoteRevisionResult
+	Err error `json:"err,omitempty"`
+}
+
+func (r promoteRevisionResponse) Failed() error { return r.Err }
+
+func decodePromoteRevisionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req promoteRevisionRequest
+	if err := httputil.DecodeJSONRequest(r, &req); err != nil {
+		return nil, err
+	}
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	req.Identifier = id
+	return req, nil
+}
+
+func encodePromoteRevisionRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(promoteRevisionRequest)
+	r.Method, r.URL.Path = "POST", "/v1/profiles/"+url.PathEscape(req.Identifier)+"/promote"
+	return httptransport.EncodeJSONRequest(ctx, r, request)
+}
+
+func decodePromoteRevisionResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp promoteRevisionResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakePromoteRevisionEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(promoteRevisionRequest)
+		result, err := svc.PromoteRevision(ctx, req.Identifier, req.Version, req.Push)
+		return promoteRevisionResponse{PromoteRevisionResult: result, Err: err}, nil
+	}
+}
+
+func (e Endpoints) PromoteRevision(ctx context.Context, id string, version int, push bool) (*PromoteRevisionResult, error) {
+	request := promoteRevisionRequest{Identifier: id, Version: version, Push: push}
+	resp, err := e.PromoteRevisionEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(promoteRevisionResponse)
+	return response.PromoteRevisionResult, response.Err
+}
@@ -0,0 +1,180 @@
+package profile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/micromdm/plist"
+	"github.com/smallstep/pkcs7"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/micromdm/micromdm/platform/profile/internal/profileproto"
+)
+
+// Revision is an immutable copy of a Profile, recorded every time the
+// profile is saved. Versions start at 1 and increase with every save.
+type Revision struct {
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	// APIKey is a fingerprint of the API key which uploaded the revision.
+	APIKey string `json:"api_key,omitempty"`
+	// SHA256 is the hex encoded hash of the Mobileconfig.
+	SHA256      string  `json:"sha256"`
+	PayloadUUID string  `json:"payload_uuid,omitempty"`
+	Profile     Profile `json:"profile"`
+}
+
+// NewRevision creates a revision of the profile.
+func NewRevision(p *Profile, version int, apiKey string, now time.Time) *Revision {
+	sum := sha256.Sum256(p.Mobileconfig)
+	return &Revision{
+		Version:     version,
+		Time:        now,
+		APIKey:      apiKey,
+		SHA256:      hex.EncodeToString(sum[:]),
+		PayloadUUID: p.Mobileconfig.payloadUUID(),
+		Profile:     *p,
+	}
+}
+
+// content returns the property list of the Mobileconfig, without the
+// signature of signed profiles.
+func (mc Mobileconfig) content() []byte {
+	if !mc.IsSigned() {
+		return mc
+	}
+	p7, err := pkcs7.Parse(mc)
+	if err != nil {
+		return nil
+	}
+	return p7.Content
+}
+
+func (mc Mobileconfig) payloadUUID() string {
+	var p struct{ PayloadUUID string }
+	plist.Unmarshal(mc.content(), &p)
+	return p.PayloadUUID
+}
+
+// KeyChange is a changed key of a profile property list. Path is the key path
+// like PayloadContent[com.example.wifi].SSID_STR, where payloads are named by
+// their PayloadIdentifier and other array elements by their index. Old or New
+// is empty if the key was added or removed.
+type KeyChange struct {
+	Path string          `json:"path"`
+	Old  json.RawMessage `json:"old,omitempty"`
+	New  json.RawMessage `json:"new,omitempty"`
+}
+
+// DiffMobileconfig returns the keys which differ between the property lists
+// of two profiles, sorted by path.
+func DiffMobileconfig(from, to Mobileconfig) ([]KeyChange, error) {
+	oldKeys, err := flattenMobileconfig(from)
+	if err != nil {
+		return nil, err
+	}
+	newKeys, err := flattenMobileconfig(to)
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]bool)
+	for path := range oldKeys {
+		paths[path] = true
+	}
+	for path := range newKeys {
+		paths[path] = true
+	}
+
+	var changes []KeyChange
+	for path := range paths {
+		o, n := oldKeys[path], newKeys[path]
+		if bytes.Equal(o, n) {
+			continue
+		}
+		changes = append(changes, KeyChange{Path: path, Old: o, New: n})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func flattenMobileconfig(mc Mobileconfig) (map[string]json.RawMessage, error) {
+	var v interface{}
+	if err := plist.Unmarshal(mc.content(), &v); err != nil {
+		return nil, fmt.Errorf("parse profile property list: %s", err)
+	}
+	keys := make(map[string]json.RawMessage)
+	return keys, flatten(keys, "", v)
+}
+
+func flatten(keys map[string]json.RawMessage, path string, v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			if err := flatten(keys, p, val); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			name := fmt.Sprint(i)
+			if dict, ok := val.(map[string]interface{}); ok {
+				if id, ok := dict["PayloadIdentifier"].(string); ok && id != "" {
+					name = id
+				}
+			}
+			if err := flatten(keys, fmt.Sprintf("%s[%s]", path, name), val); err != nil {
+				return err
+			}
+		}
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		keys[path] = data
+	}
+	return nil
+}
+
+func MarshalRevision(r *Revision) ([]byte, error) {
+	pb := profileproto.Revision{
+		Version:     int64(r.Version),
+		Time:        r.Time.UnixNano(),
+		ApiKey:      r.APIKey,
+		Sha256:      r.SHA256,
+		PayloadUuid: r.PayloadUUID,
+		Profile: &profileproto.Profile{
+			Id:           r.Profile.Identifier,
+			Mobileconfig: r.Profile.Mobileconfig,
+			SkipSigning:  r.Profile.SkipSigning,
+			Encrypt:      r.Profile.Encrypt,
+		},
+	}
+	return proto.Marshal(&pb)
+}
+
+func UnmarshalRevision(data []byte, r *Revision) error {
+	var pb profileproto.Revision
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+	r.Version = int(pb.GetVersion())
+	r.Time = time.Unix(0, pb.GetTime()).UTC()
+	r.APIKey = pb.GetApiKey()
+	r.SHA256 = pb.GetSha256()
+	r.PayloadUUID = pb.GetPayloadUuid()
+	r.Profile.Identifier = pb.GetProfile().GetId()
+	r.Profile.Mobileconfig = pb.GetProfile().GetMobileconfig()
+	r.Profile.SkipSigning = pb.GetProfile().GetSkipSigning()
+	r.Profile.Encrypt = pb.GetProfile().GetEncrypt()
+	return nil
+}
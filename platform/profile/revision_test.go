@@ -0,0 +1,59 @@
+package profile
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffMobileconfig(t *testing.T) {
+	from := Mobileconfig(fmt.Sprintf(lintTestProfile, "com.example.test"))
+	to := Mobileconfig(strings.Replace(
+		fmt.Sprintf(lintTestProfile, "com.example.renamed"),
+		"<string>com.example.custom</string>\n\t\t\t<key>PayloadUUID</key>",
+		"<string>com.example.custom</string>\n\t\t\t<key>PayloadDisplayName</key>\n\t\t\t<string>Custom</string>\n\t\t\t<key>PayloadUUID</key>",
+		1,
+	))
+
+	changes, err := DiffMobileconfig(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []KeyChange{
+		{Path: "PayloadContent[com.example.custom].PayloadDisplayName", New: []byte(`"Custom"`)},
+		{Path: "PayloadIdentifier", Old: []byte(`"com.example.test"`), New: []byte(`"com.example.renamed"`)},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("have changes %v, want %v", changes, want)
+	}
+	for i := range want {
+		have := changes[i]
+		if have.Path != want[i].Path || string(have.Old) != string(want[i].Old) || string(have.New) != string(want[i].New) {
+			t.Errorf("change %d: have %s %s -> %s, want %s %s -> %s",
+				i, have.Path, have.Old, have.New, want[i].Path, want[i].Old, want[i].New)
+		}
+	}
+}
+
+func TestRevisionMarshal(t *testing.T) {
+	p := &Profile{
+		Identifier:   "com.example.test",
+		Mobileconfig: Mobileconfig(fmt.Sprintf(lintTestProfile, "com.example.test")),
+	}
+	rev := NewRevision(p, 2, "key", time.Now())
+	if rev.PayloadUUID != "4F5E1C1A-0D2B-4E0B-9E6A-2C3F0A1B2C3D" {
+		t.Errorf("have PayloadUUID %q", rev.PayloadUUID)
+	}
+	data, err := MarshalRevision(rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have Revision
+	if err := UnmarshalRevision(data, &have); err != nil {
+		t.Fatal(err)
+	}
+	if have.Version != 2 || have.SHA256 != rev.SHA256 || !have.Time.Equal(rev.Time) || have.Profile.Identifier != p.Identifier {
+		t.Errorf("have revision %+v, want %+v", have, rev)
+	}
+}
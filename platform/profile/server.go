@@ -8,16 +8,26 @@ import (
 )
 
 type Endpoints struct {
-	ApplyProfileEndpoint   endpoint.Endpoint
-	GetProfilesEndpoint    endpoint.Endpoint
-	RemoveProfilesEndpoint endpoint.Endpoint
+	ApplyProfileEndpoint    endpoint.Endpoint
+	GetProfilesEndpoint     endpoint.Endpoint
+	RemoveProfilesEndpoint  endpoint.Endpoint
+	LintProfileEndpoint     endpoint.Endpoint
+	GetRevisionsEndpoint    endpoint.Endpoint
+	GetRevisionEndpoint     endpoint.Endpoint
+	DiffRevisionsEndpoint   endpoint.Endpoint
+	PromoteRevisionEndpoint endpoint.Endpoint
 }
 
 func MakeServerEndpoints(s Service, outer endpoint.Middleware, others ...endpoint.Middleware) Endpoints {
 	return Endpoints{
-		ApplyProfileEndpoint:   endpoint.Chain(outer, others...)(MakeApplyProfileEndpoint(s)),
-		GetProfilesEndpoint:    endpoint.Chain(outer, others...)(MakeGetProfilesEndpoint(s)),
-		RemoveProfilesEndpoint: endpoint.Chain(outer, others...)(MakeRemoveProfilesEndpoint(s)),
+		ApplyProfileEndpoint:    endpoint.Chain(outer, others...)(MakeApplyProfileEndpoint(s)),
+		GetProfilesEndpoint:     endpoint.Chain(outer, others...)(MakeGetProfilesEndpoint(s)),
+		RemoveProfilesEndpoint:  endpoint.Chain(outer, others...)(MakeRemoveProfilesEndpoint(s)),
+		LintProfileEndpoint:     endpoint.Chain(outer, others...)(MakeLintProfileEndpoint(s)),
+		GetRevisionsEndpoint:    endpoint.Chain(outer, others...)(MakeGetRevisionsEndpoint(s)),
+		GetRevisionEndpoint:     endpoint.Chain(outer, others...)(MakeGetRevisionEndpoint(s)),
+		DiffRevisionsEndpoint:   endpoint.Chain(outer, others...)(MakeDiffRevisionsEndpoint(s)),
+		PromoteRevisionEndpoint: endpoint.Chain(outer, others...)(MakePromoteRevisionEndpoint(s)),
 	}
 }
 
@@ -25,6 +35,11 @@ func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.S
 	// POST    /v1/profiles		get a list of profiles managed by the server
 	// PUT     /v1/profiles		create or replace a profile on the server
 	// DELETE  /v1/profiles		remove one or more profiles from the server
+	// POST    /v1/profiles/lint	check a profile for problems without saving it
+	// GET     /v1/profiles/:id/revisions		list the revisions of a profile
+	// GET     /v1/profiles/:id/revisions/:version	get a revision of a profile
+	// GET     /v1/profiles/:id/diff?from=&to=	compare two revisions of a profile
+	// POST    /v1/profiles/:id/promote		save an earlier revision as the current profile
 
 	r.Methods("POST").Path("/v1/profiles").Handler(httptransport.NewServer(
 		e.GetProfilesEndpoint,
@@ -46,4 +61,39 @@ func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.S
 		httputil.EncodeJSONResponse,
 		options...,
 	))
+
+	r.Methods("POST").Path("/v1/profiles/lint").Handler(httptransport.NewServer(
+		e.LintProfileEndpoint,
+		decodeLintProfileRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/profiles/{id}/revisions").Handler(httptransport.NewServer(
+		e.GetRevisionsEndpoint,
+		decodeGetRevisionsRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/profiles/{id}/revisions/{version}").Handler(httptransport.NewServer(
+		e.GetRevisionEndpoint,
+		decodeGetRevisionRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/profiles/{id}/diff").Handler(httptransport.NewServer(
+		e.DiffRevisionsEndpoint,
+		decodeDiffRevisionsRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/v1/profiles/{id}/promote").Handler(httptransport.NewServer(
+		e.PromoteRevisionEndpoint,
+		decodePromoteRevisionRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
 }
@@ -8,6 +8,11 @@ type Service interface {
 	ApplyProfile(ctx context.Context, p *Profile) error
 	GetProfiles(ctx context.Context, opt GetProfilesOption) ([]Profile, error)
 	RemoveProfiles(ctx context.Context, ids []string) error
+	LintProfile(ctx context.Context, mc Mobileconfig) (*LintResult, error)
+	GetRevisions(ctx context.Context, id string) ([]Revision, error)
+	GetRevision(ctx context.Context, id string, version int) (*Revision, error)
+	DiffRevisions(ctx context.Context, id string, from, to int) ([]KeyChange, error)
+	PromoteRevision(ctx context.Context, id string, version int, push bool) (*PromoteRevisionResult, error)
 }
 
 type GetProfilesOption struct {
@@ -17,16 +22,39 @@ type GetProfilesOption struct {
 type Store interface {
 	ProfileById(ctx context.Context, id string) (*Profile, error)
 	Save(p *Profile) error
+	SaveRevision(p *Profile, apiKey string) (*Revision, error)
+	Revisions(id string) ([]Revision, error)
+	Revision(id string, version int) (*Revision, error)
 	List() ([]Profile, error)
 	Delete(id string) error
 }
 
-func New(store Store) *ProfileService {
-	return &ProfileService{store: store}
+// Reinstaller installs a profile again on the devices which have it
+// installed, and returns their UDIDs.
+type Reinstaller interface {
+	ReinstallProfile(ctx context.Context, p *Profile) ([]string, error)
+}
+
+type Option func(*ProfileService)
+
+// WithReinstaller enables installing promoted profile revisions on devices.
+func WithReinstaller(r Reinstaller) Option {
+	return func(svc *ProfileService) {
+		svc.reinstaller = r
+	}
+}
+
+func New(store Store, opts ...Option) *ProfileService {
+	svc := &ProfileService{store: store}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
 }
 
 type ProfileService struct {
-	store Store
+	store       Store
+	reinstaller Reinstaller
 }
 
 func IsNotFound(err error) bool {
@@ -0,0 +1,44 @@
+package profile
+
+import (
+	"crypto"
+	"crypto/x509"
+
+	"github.com/micromdm/micromdm/pkg/crypto/profileutil"
+)
+
+// Signer signs profiles with the server signing identity before they are
+// sent to devices, so that they show as verified on the device.
+type Signer struct {
+	key  crypto.PrivateKey
+	cert *x509.Certificate
+	// chain are the intermediate certificates included in signatures.
+	chain []*x509.Certificate
+}
+
+func NewSigner(key crypto.PrivateKey, cert *x509.Certificate, chain ...*x509.Certificate) *Signer {
+	return &Signer{key: key, cert: cert, chain: chain}
+}
+
+// Mobileconfig returns the Mobileconfig of the profile to install on a device.
+// It is signed unless the profile is already signed or has SkipSigning set.
+// A nil Signer returns the Mobileconfig unchanged.
+func (s *Signer) Mobileconfig(p *Profile) (Mobileconfig, error) {
+	if p.SkipSigning {
+		return p.Mobileconfig, nil
+	}
+	return s.Sign(p.Mobileconfig)
+}
+
+// Sign signs the Mobileconfig unless it is already signed. A nil Signer
+// returns the Mobileconfig unchanged.
+func (s *Signer) Sign(mc Mobileconfig) (Mobileconfig, error) {
+	if s == nil || mc.IsSigned() {
+		return mc, nil
+	}
+	signed, err := profileutil.SignWithChain(s.key, s.cert, s.chain, mc)
+	if err != nil {
+		return nil, err
+	}
+	return Mobileconfig(signed), nil
+}
@@ -0,0 +1,144 @@
+package profile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+const testMobileconfig = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>PayloadIdentifier</key>
+	<string>com.example.test</string>
+</dict>
+</plist>`
+
+func testSigner(t *testing.T) *Signer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "profile signing"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSigner(key, cert)
+}
+
+func TestSignerMobileconfig(t *testing.T) {
+	signer := testSigner(t)
+	p := &Profile{Identifier: "com.example.test", Mobileconfig: Mobileconfig(testMobileconfig)}
+
+	signed, err := signer.Mobileconfig(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !signed.IsSigned() {
+		t.Fatal("expected a signed mobileconfig")
+	}
+	id, err := signed.GetPayloadIdentifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != p.Identifier {
+		t.Errorf("have payload identifier %s, want %s", id, p.Identifier)
+	}
+
+	// already signed profiles are not signed again.
+	again, err := signer.Mobileconfig(&Profile{Identifier: id, Mobileconfig: signed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, signed) {
+		t.Error("signed profile was signed again")
+	}
+
+	p.SkipSigning = true
+	unsigned, err := signer.Mobileconfig(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unsigned.IsSigned() {
+		t.Error("profile with SkipSigning was signed")
+	}
+
+	var none *Signer
+	if mc, err := none.Mobileconfig(p); err != nil || !bytes.Equal(mc, p.Mobileconfig) {
+		t.Error("nil signer must return the profile unchanged")
+	}
+}
+
+func TestSignerChain(t *testing.T) {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "intermediate"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "profile signing"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := NewSigner(key, cert, ca).Sign(Mobileconfig(testMobileconfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p7, err := pkcs7.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := len(p7.Certificates), 2; have != want {
+		t.Errorf("have %d certificates in the signature, want %d", have, want)
+	}
+	if err := p7.Verify(); err != nil {
+		t.Errorf("verify signature: %s", err)
+	}
+}
@@ -0,0 +1,85 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Profile variables which are expanded per device. Device attributes are
+// available as $ATTRIBUTE_<key>.
+const (
+	VarSerialNumber  = "SERIAL_NUMBER"
+	VarUDID          = "UDID"
+	VarDeviceName    = "DEVICE_NAME"
+	VarModel         = "MODEL"
+	VarUserShortname = "USER_SHORTNAME"
+	VarUserLongname  = "USER_LONGNAME"
+
+	// AttributePrefix is the prefix of the variables of device attributes.
+	AttributePrefix = "ATTRIBUTE_"
+)
+
+var (
+	variableRegexp = regexp.MustCompile(`\$(` + AttributePrefix + `[A-Za-z0-9_-]+|[A-Z][A-Z0-9_]*)`)
+
+	knownVariables = map[string]bool{
+		VarSerialNumber:  true,
+		VarUDID:          true,
+		VarDeviceName:    true,
+		VarModel:         true,
+		VarUserShortname: true,
+		VarUserLongname:  true,
+	}
+)
+
+func isVariable(name string) bool {
+	return knownVariables[name] || strings.HasPrefix(name, AttributePrefix)
+}
+
+// Variables returns the names of the profile variables in the Mobileconfig,
+// sorted and without duplicates. Other words starting with $ are not
+// variables and are left alone.
+func (mc Mobileconfig) Variables() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range variableRegexp.FindAllSubmatch(mc, -1) {
+		name := string(m[1])
+		if isVariable(name) && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Expand replaces the profile variables in the Mobileconfig with their
+// values, escaped for XML. It returns an error naming the variables which
+// have no value, so that a profile is never installed with a variable left
+// in it.
+func (mc Mobileconfig) Expand(values map[string]string) (Mobileconfig, error) {
+	var missing []string
+	expanded := variableRegexp.ReplaceAllFunc(mc, func(match []byte) []byte {
+		name := string(match[1:])
+		if !isVariable(name) {
+			return match
+		}
+		v, ok := values[name]
+		if !ok || v == "" {
+			missing = append(missing, name)
+			return match
+		}
+		var buf bytes.Buffer
+		xml.EscapeText(&buf, []byte(v))
+		return buf.Bytes()
+	})
+	if len(missing) > 0 {
+		return nil, errors.Errorf("unresolved profile variables: %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
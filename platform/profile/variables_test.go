@@ -0,0 +1,40 @@
+package profile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandVariables(t *testing.T) {
+	mc := Mobileconfig(`<string>$DEVICE_NAME ($SERIAL_NUMBER)</string>
+<string>$ATTRIBUTE_cost_center</string>
+<string>cd $HOME; echo $SERIAL_NUMBER</string>`)
+
+	if have, want := mc.Variables(), []string{"ATTRIBUTE_cost_center", "DEVICE_NAME", "SERIAL_NUMBER"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have variables %v, want %v", have, want)
+	}
+
+	expanded, err := mc.Expand(map[string]string{
+		VarDeviceName:                   "Lab & Co",
+		VarSerialNumber:                 "C02XYZ",
+		AttributePrefix + "cost_center": "4711",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<string>Lab &amp; Co (C02XYZ)</string>
+<string>4711</string>
+<string>cd $HOME; echo C02XYZ</string>`
+	if string(expanded) != want {
+		t.Errorf("have expanded\n%s\nwant\n%s", expanded, want)
+	}
+
+	_, err = mc.Expand(map[string]string{VarDeviceName: "lab"})
+	if err == nil {
+		t.Fatal("expected an error for unresolved variables")
+	}
+	if !strings.Contains(err.Error(), "SERIAL_NUMBER") {
+		t.Errorf("error %q does not name the unresolved variable", err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 type QueueInMem struct {
 	logger log.Logger
 	queue  map[string]*list.List
+	pub    pubsub.Publisher
 }
 
 type queuedCommand struct {
@@ -31,6 +32,7 @@ func New(pubsub pubsub.PublishSubscriber, logger log.Logger) *QueueInMem {
 	q := &QueueInMem{
 		logger: logger,
 		queue:  make(map[string]*list.List),
+		pub:    pubsub,
 	}
 	q.startPolling(pubsub)
 	q.startRawPolling(pubsub)
@@ -66,11 +68,11 @@ func (q *QueueInMem) findCommandByUUID(l *list.List, uuid string) (*queuedComman
 	return nil, nil
 }
 
-func (q *QueueInMem) nextCommandPayload(l *list.List, skipNotNow bool) []byte {
+func (q *QueueInMem) nextCommand(l *list.List, skipNotNow bool) *queuedCommand {
 	for e := l.Front(); e != nil; e = e.Next() {
 		qCmd := e.Value.(*queuedCommand)
 		if !(skipNotNow && qCmd.notNow) {
-			return qCmd.payload
+			return qCmd
 		}
 	}
 	return nil
@@ -104,9 +106,15 @@ func (q *QueueInMem) Next(_ context.Context, resp mdm.Response) ([]byte, error)
 		}
 	}
 
-	cmdBytes := q.nextCommandPayload(l, resp.Status == "NotNow")
+	qCmd := q.nextCommand(l, resp.Status == "NotNow")
+	if qCmd == nil {
+		return nil, nil
+	}
+	if err := boltqueue.PublishCommandSent(q.pub, resp.UDID, qCmd.uuid); err != nil {
+		level.Info(q.logger).Log("msg", "publish command sent", "udid", resp.UDID, "err", err)
+	}
 
-	return cmdBytes, nil
+	return qCmd.payload, nil
 }
 
 // Clear clears a command queue for the enrollment in event
@@ -123,6 +131,12 @@ func (q *QueueInMem) Clear(_ context.Context, event mdm.CheckinEvent) error {
 	return nil
 }
 
+// DeleteDeviceCommand removes the command queue of a device.
+func (q *QueueInMem) DeleteDeviceCommand(_ context.Context, udid string) error {
+	q.clearList(udid)
+	return nil
+}
+
 // View returns the command queue for the device in event
 func (q *QueueInMem) ViewQueue(_ context.Context, event mdm.CheckinEvent) ([]*mdm.Command, error) {
 	udid := event.Command.UDID
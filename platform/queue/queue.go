@@ -2,6 +2,7 @@
 package queue
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"time"
@@ -21,12 +22,16 @@ const (
 	DeviceCommandBucket = "mdm.DeviceCommands"
 
 	CommandQueuedTopic = "mdm.CommandQueued"
+
+	// CommandSentTopic is published when a queued command is delivered to a device.
+	CommandSentTopic = "mdm.CommandSent"
 )
 
 type Store struct {
 	*bolt.DB
 	logger         log.Logger
 	withoutHistory bool
+	pub            pubsub.Publisher
 }
 
 type Option func(*Store)
@@ -51,6 +56,11 @@ func (db *Store) Next(ctx context.Context, resp mdm.Response) ([]byte, error) {
 	if cmd == nil {
 		return nil, nil
 	}
+	if db.pub != nil {
+		if err := PublishCommandSent(db.pub, resp.UDID, cmd.UUID); err != nil {
+			level.Info(db.logger).Log("msg", "publish command sent", "udid", resp.UDID, "err", err)
+		}
+	}
 	return cmd.Payload, nil
 }
 
@@ -230,7 +240,7 @@ func NewQueue(db *bolt.DB, pubsub pubsub.PublishSubscriber, opts ...Option) (*St
 		return nil, errors.Wrapf(err, "creating %s bucket", DeviceCommandBucket)
 	}
 
-	datastore := &Store{DB: db, logger: log.NewNopLogger()}
+	datastore := &Store{DB: db, logger: log.NewNopLogger(), pub: pubsub}
 	for _, fn := range opts {
 		fn(datastore)
 	}
@@ -282,6 +292,114 @@ func (db *Store) DeviceCommand(udid string) (*DeviceCommand, error) {
 	return &dev, nil
 }
 
+// DeleteDeviceCommand removes the queue and the command history of a device.
+func (db *Store) DeleteDeviceCommand(ctx context.Context, udid string) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(DeviceCommandBucket))
+		return b.Delete([]byte(udid))
+	})
+	return errors.Wrapf(err, "delete DeviceCommand for udid %s", udid)
+}
+
+// CommandHistory calls fn with the queued, completed, failed and NotNow
+// commands of every device while iterating over the device command bucket.
+func (db *Store) CommandHistory(ctx context.Context, fn func(command.HistoryRecord) error) error {
+	err := db.forEachDeviceCommand(func(dc *DeviceCommand) error {
+		for _, h := range []struct {
+			status string
+			cmds   []Command
+		}{
+			{"Queued", dc.Commands},
+			{"Acknowledged", dc.Completed},
+			{"Error", dc.Failed},
+			{"NotNow", dc.NotNow},
+		} {
+			if err := writeHistory(fn, dc.DeviceUDID, h.status, h.cmds); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrap(err, "list command history")
+}
+
+// historyBatchSize is the number of device queues forEachDeviceCommand reads
+// in one transaction.
+const historyBatchSize = 100
+
+// forEachDeviceCommand calls fn with the queue of every device. The queues
+// are read in batches, and fn is called outside of the read transaction, so
+// a slow fn does not hold the transaction open.
+func (db *Store) forEachDeviceCommand(fn func(*DeviceCommand) error) error {
+	var (
+		after []byte
+		done  bool
+	)
+	for !done {
+		var batch []DeviceCommand
+		err := db.View(func(tx *bolt.Tx) error {
+			c := tx.Bucket([]byte(DeviceCommandBucket)).Cursor()
+			k, v := c.First()
+			if after != nil {
+				if k, v = c.Seek(after); k != nil && bytes.Equal(k, after) {
+					k, v = c.Next()
+				}
+			}
+			for ; k != nil; k, v = c.Next() {
+				if len(batch) == historyBatchSize {
+					return nil
+				}
+				after = append(after[:0], k...)
+				var dc DeviceCommand
+				if err := UnmarshalDeviceCommand(v, &dc); err != nil {
+					return errors.Wrapf(err, "unmarshal DeviceCommand for udid %s", string(k))
+				}
+				batch = append(batch, dc)
+			}
+			done = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeHistory(fn func(command.HistoryRecord) error, udid, status string, cmds []Command) error {
+	for _, cmd := range cmds {
+		var payload struct {
+			Command struct{ RequestType string }
+		}
+		// the request type is informational, ignore payloads which fail to parse.
+		_ = plist.Unmarshal(cmd.Payload, &payload)
+		cmdStatus := status
+		if cmd.LastStatus != "" {
+			cmdStatus = cmd.LastStatus
+		}
+		err := fn(command.HistoryRecord{
+			UDID:           udid,
+			CommandUUID:    cmd.UUID,
+			RequestType:    payload.Command.RequestType,
+			Status:         cmdStatus,
+			CreatedAt:      cmd.CreatedAt,
+			LastSentAt:     cmd.LastSentAt,
+			Acknowledged:   cmd.Acknowledged,
+			TimesSent:      cmd.TimesSent,
+			FailureMessage: string(cmd.FailureMessage),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type notFound struct {
 	ResourceType string
 	Message      string
@@ -409,6 +527,15 @@ func isNotFound(err error) bool {
 }
 
 func PublishCommandQueued(pub pubsub.Publisher, udid, uuid string) error {
+	return publishQueuedCommand(pub, CommandQueuedTopic, udid, uuid)
+}
+
+// PublishCommandSent notifies subscribers that a command was handed to a device.
+func PublishCommandSent(pub pubsub.Publisher, udid, uuid string) error {
+	return publishQueuedCommand(pub, CommandSentTopic, udid, uuid)
+}
+
+func publishQueuedCommand(pub pubsub.Publisher, topic, udid, uuid string) error {
 	cq := new(QueueCommandQueued)
 	cq.DeviceUDID = udid
 	cq.CommandUUID = uuid
@@ -418,5 +545,5 @@ func PublishCommandQueued(pub pubsub.Publisher, udid, uuid string) error {
 		return err
 	}
 
-	return pub.Publish(context.TODO(), CommandQueuedTopic, msgBytes)
+	return pub.Publish(context.TODO(), topic, msgBytes)
 }
@@ -13,7 +13,10 @@ import (
 )
 
 func (svc *RemoveService) BlockDevice(ctx context.Context, udid string) error {
-	return svc.store.Save(&Device{UDID: udid})
+	if err := svc.store.Save(&Device{UDID: udid}); err != nil {
+		return err
+	}
+	return svc.publish(ctx, BlockTopic, udid)
 }
 
 type blockDeviceRequest struct {
@@ -1,6 +1,7 @@
 package builtin
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/boltdb/bolt"
@@ -73,6 +74,15 @@ func (db *DB) Delete(udid string) error {
 	return errors.Wrapf(err, "delete device with udid %s", udid)
 }
 
+// PurgeDevice removes the device from the block list. It does not return an
+// error if the device is not blocked.
+func (db *DB) PurgeDevice(ctx context.Context, udid string) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(RemoveBucket)).Delete([]byte(udid))
+	})
+	return errors.Wrapf(err, "purge device with udid %s", udid)
+}
+
 type notFound struct {
 	ResourceType string
 	Message      string
@@ -4,6 +4,17 @@ import (
 	"context"
 
 	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/pubsub"
+)
+
+const (
+	// BlockTopic is published with the device UDID when a device is blocked.
+	BlockTopic = "mdm.DeviceBlocked"
+
+	// UnblockTopic is published with the device UDID when a device is unblocked.
+	UnblockTopic = "mdm.DeviceUnblocked"
 )
 
 type Service interface {
@@ -19,10 +30,32 @@ type Store interface {
 
 type RemoveService struct {
 	store Store
+	pub   pubsub.Publisher
+}
+
+type Option func(*RemoveService)
+
+// WithPublisher publishes block and unblock events.
+func WithPublisher(pub pubsub.Publisher) Option {
+	return func(svc *RemoveService) {
+		svc.pub = pub
+	}
+}
+
+func New(store Store, opts ...Option) (*RemoveService, error) {
+	svc := &RemoveService{store: store}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc, nil
 }
 
-func New(store Store) (*RemoveService, error) {
-	return &RemoveService{store: store}, nil
+func (svc *RemoveService) publish(ctx context.Context, topic, udid string) error {
+	if svc.pub == nil {
+		return nil
+	}
+	err := svc.pub.Publish(ctx, topic, []byte(udid))
+	return errors.Wrapf(err, "publish on topic %s", topic)
 }
 
 type Middleware func(next Service) Service
@@ -13,7 +13,10 @@ import (
 )
 
 func (svc *RemoveService) UnblockDevice(ctx context.Context, udid string) error {
-	return svc.store.Delete(udid)
+	if err := svc.store.Delete(udid); err != nil {
+		return err
+	}
+	return svc.publish(ctx, UnblockTopic, udid)
 }
 
 type unblockDeviceRequest struct {
@@ -0,0 +1,178 @@
+package builtin
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/timeline"
+)
+
+// EventBucket holds a nested bucket of events for every device UDID.
+const EventBucket = "mdm.DeviceEvents"
+
+const (
+	// DefaultMaxEvents is the default number of events kept per device.
+	DefaultMaxEvents = 1000
+
+	// DefaultMaxAge is the default age after which events are discarded.
+	DefaultMaxAge = 90 * 24 * time.Hour
+)
+
+type DB struct {
+	*bolt.DB
+	maxEvents int
+	maxAge    time.Duration
+}
+
+type Option func(*DB)
+
+// WithMaxEvents limits the number of events kept per device.
+// A value of 0 keeps all events.
+func WithMaxEvents(n int) Option {
+	return func(db *DB) {
+		db.maxEvents = n
+	}
+}
+
+// WithMaxAge discards events older than d. A value of 0 keeps all events.
+func WithMaxAge(d time.Duration) Option {
+	return func(db *DB) {
+		db.maxAge = d
+	}
+}
+
+func NewDB(db *bolt.DB, opts ...Option) (*DB, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(EventBucket))
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating %s bucket", EventBucket)
+	}
+	datastore := &DB{
+		DB:        db,
+		maxEvents: DefaultMaxEvents,
+		maxAge:    DefaultMaxAge,
+	}
+	for _, opt := range opts {
+		opt(datastore)
+	}
+	return datastore, nil
+}
+
+// Record appends an event to the timeline of the device and discards
+// the oldest events beyond the retention limits.
+func (db *DB) Record(ctx context.Context, ev *timeline.Event) error {
+	pb, err := timeline.MarshalEvent(ev)
+	if err != nil {
+		return errors.Wrap(err, "marshalling event")
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(EventBucket))
+		if bkt == nil {
+			return fmt.Errorf("bucket %q not found!", EventBucket)
+		}
+		b, err := bkt.CreateBucketIfNotExists([]byte(ev.UDID))
+		if err != nil {
+			return errors.Wrapf(err, "create event bucket for udid %s", ev.UDID)
+		}
+		seq, err := b.NextSequence()
+		if err != nil {
+			return errors.Wrap(err, "next event sequence")
+		}
+		if err := b.Put(itob(seq), pb); err != nil {
+			return errors.Wrap(err, "put event to boltdb")
+		}
+		return db.prune(b, seq)
+	})
+}
+
+// prune removes events from the front of the device bucket until both
+// retention limits are satisfied. Events are only ever removed from the
+// front, so the keys between the first key and last are contiguous.
+func (db *DB) prune(b *bolt.Bucket, last uint64) error {
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.First() {
+		if count := last - binary.BigEndian.Uint64(k) + 1; db.maxEvents > 0 && count > uint64(db.maxEvents) {
+			if err := c.Delete(); err != nil {
+				return errors.Wrap(err, "delete event over retention count")
+			}
+			continue
+		}
+		if db.maxAge == 0 {
+			break
+		}
+		var ev timeline.Event
+		if err := timeline.UnmarshalEvent(v, &ev); err != nil {
+			return err
+		}
+		if !db.expired(ev) {
+			break
+		}
+		if err := c.Delete(); err != nil {
+			return errors.Wrap(err, "delete expired event")
+		}
+	}
+	return nil
+}
+
+func (db *DB) expired(ev timeline.Event) bool {
+	return db.maxAge > 0 && time.Since(ev.Time) > db.maxAge
+}
+
+// DeviceEvents returns the events of a device, oldest first. Events are
+// stored in the order they were recorded, which may differ from the order
+// they happened in, so they are sorted by Time.
+func (db *DB) DeviceEvents(ctx context.Context, udid string) ([]timeline.Event, error) {
+	var events []timeline.Event
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EventBucket)).Bucket([]byte(udid))
+		if b == nil {
+			return &notFound{"DeviceEvents", fmt.Sprintf("udid %s", udid)}
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var ev timeline.Event
+			if err := timeline.UnmarshalEvent(v, &ev); err != nil {
+				return err
+			}
+			// events of devices which have been quiet for a while are only
+			// pruned on the next write.
+			if db.expired(ev) {
+				return nil
+			}
+			events = append(events, ev)
+			return nil
+		})
+	})
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events, err
+}
+
+// itob returns an 8-byte big endian representation of v, which keeps the
+// events of a device bucket in insertion order.
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+type notFound struct {
+	ResourceType string
+	Message      string
+}
+
+func (e *notFound) Error() string {
+	return fmt.Sprintf("not found: %s %s", e.ResourceType, e.Message)
+}
+
+func (e *notFound) NotFound() bool {
+	return true
+}
@@ -0,0 +1,106 @@
+package builtin
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/micromdm/micromdm/platform/timeline"
+)
+
+func TestRecordMaxEvents(t *testing.T) {
+	db := setupDB(t, WithMaxEvents(3))
+	ctx := context.Background()
+
+	types := []string{timeline.Authenticate, timeline.TokenUpdate, timeline.Enrolled, timeline.CommandQueued, timeline.PushSent}
+	for _, typ := range types {
+		ev := &timeline.Event{UDID: "UDID-FOO", Time: time.Now(), Type: typ}
+		if err := db.Record(ctx, ev); err != nil {
+			t.Fatalf("recording event: %s", err)
+		}
+	}
+	if err := db.Record(ctx, &timeline.Event{UDID: "UDID-BAR", Time: time.Now(), Type: timeline.CheckOut}); err != nil {
+		t.Fatalf("recording event: %s", err)
+	}
+
+	events, err := db.DeviceEvents(ctx, "UDID-FOO")
+	if err != nil {
+		t.Fatalf("getting device events: %s", err)
+	}
+	if have, want := len(events), 3; have != want {
+		t.Fatalf("have %d events, want %d", have, want)
+	}
+	for i, typ := range types[2:] {
+		if have, want := events[i].Type, typ; have != want {
+			t.Errorf("event %d: have %s, want %s", i, have, want)
+		}
+	}
+}
+
+func TestDeviceEventsSorted(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	// the TokenUpdate is recorded before the Authenticate which preceded it.
+	for _, ev := range []*timeline.Event{
+		{UDID: "UDID-FOO", Time: now.Add(time.Second), Type: timeline.TokenUpdate},
+		{UDID: "UDID-FOO", Time: now, Type: timeline.Authenticate},
+	} {
+		if err := db.Record(ctx, ev); err != nil {
+			t.Fatalf("recording event: %s", err)
+		}
+	}
+
+	events, err := db.DeviceEvents(ctx, "UDID-FOO")
+	if err != nil {
+		t.Fatalf("getting device events: %s", err)
+	}
+	if have, want := events[0].Type, timeline.Authenticate; have != want {
+		t.Errorf("have first event %s, want %s", have, want)
+	}
+}
+
+func TestRecordMaxAge(t *testing.T) {
+	db := setupDB(t, WithMaxAge(time.Hour))
+	ctx := context.Background()
+
+	old := &timeline.Event{UDID: "UDID-FOO", Time: time.Now().Add(-2 * time.Hour), Type: timeline.Authenticate}
+	if err := db.Record(ctx, old); err != nil {
+		t.Fatalf("recording event: %s", err)
+	}
+	if err := db.Record(ctx, &timeline.Event{UDID: "UDID-FOO", Time: time.Now(), Type: timeline.TokenUpdate}); err != nil {
+		t.Fatalf("recording event: %s", err)
+	}
+
+	events, err := db.DeviceEvents(ctx, "UDID-FOO")
+	if err != nil {
+		t.Fatalf("getting device events: %s", err)
+	}
+	if have, want := len(events), 1; have != want {
+		t.Fatalf("have %d events, want %d", have, want)
+	}
+	if have, want := events[0].Type, timeline.TokenUpdate; have != want {
+		t.Errorf("have %s, want %s", have, want)
+	}
+}
+
+func setupDB(t *testing.T, opts ...Option) *DB {
+	f, _ := ioutil.TempFile("", "bolt-")
+	f.Close()
+	os.Remove(f.Name())
+
+	db, err := bolt.Open(f.Name(), 0777, nil)
+	if err != nil {
+		t.Fatalf("couldn't open bolt, err %s\n", err)
+	}
+	timelineDB, err := NewDB(db, opts...)
+	if err != nil {
+		t.Fatalf("couldn't create timeline DB, err %s\n", err)
+	}
+	return timelineDB
+}
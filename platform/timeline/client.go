@@ -0,0 +1,33 @@
+package timeline
+
+import (
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func NewHTTPClient(instance, token string, logger log.Logger, opts ...httptransport.ClientOption) (Service, error) {
+	u, err := url.Parse(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	var deviceEventsEndpoint endpoint.Endpoint
+	{
+		deviceEventsEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, ""), // empty path, modified by the encodeRequest func
+			httputil.EncodeRequestWithToken(token, encodeDeviceEventsRequest),
+			decodeDeviceEventsResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	return Endpoints{
+		DeviceEventsEndpoint: deviceEventsEndpoint,
+	}, nil
+}
@@ -0,0 +1,65 @@
+package timeline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+func (svc *TimelineService) DeviceEvents(ctx context.Context, udid string) ([]Event, error) {
+	return svc.store.DeviceEvents(ctx, udid)
+}
+
+type deviceEventsRequest struct {
+	UDID string
+}
+
+type deviceEventsResponse struct {
+	Events []Event `json:"events"`
+	Err    error   `json:"err,omitempty"`
+}
+
+func (r deviceEventsResponse) Failed() error { return r.Err }
+
+func decodeDeviceEventsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	udid, ok := mux.Vars(r)["udid"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	return deviceEventsRequest{UDID: udid}, nil
+}
+
+func encodeDeviceEventsRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(deviceEventsRequest)
+	r.URL.Path = "/v1/devices/" + url.PathEscape(req.UDID) + "/events"
+	return nil
+}
+
+func decodeDeviceEventsResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp deviceEventsResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeDeviceEventsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(deviceEventsRequest)
+		events, err := svc.DeviceEvents(ctx, req.UDID)
+		return deviceEventsResponse{Events: events, Err: err}, nil
+	}
+}
+
+func (e Endpoints) DeviceEvents(ctx context.Context, udid string) ([]Event, error) {
+	resp, err := e.DeviceEventsEndpoint(ctx, deviceEventsRequest{UDID: udid})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(deviceEventsResponse)
+	return response.Events, response.Err
+}
@@ -0,0 +1,3 @@
+package timelineproto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative timeline.proto
@@ -0,0 +1,30 @@
+package timeline
+
+import (
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+type Endpoints struct {
+	DeviceEventsEndpoint endpoint.Endpoint
+}
+
+func MakeServerEndpoints(s Service, outer endpoint.Middleware, others ...endpoint.Middleware) Endpoints {
+	return Endpoints{
+		DeviceEventsEndpoint: endpoint.Chain(outer, others...)(MakeDeviceEventsEndpoint(s)),
+	}
+}
+
+func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.ServerOption) {
+	// GET     /v1/devices/:udid/events		get the event timeline of a device
+
+	r.Methods("GET").Path("/v1/devices/{udid}/events").Handler(httptransport.NewServer(
+		e.DeviceEventsEndpoint,
+		decodeDeviceEventsRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+}
@@ -0,0 +1,21 @@
+package timeline
+
+import (
+	"context"
+)
+
+type Service interface {
+	DeviceEvents(ctx context.Context, udid string) ([]Event, error)
+}
+
+type Store interface {
+	DeviceEvents(ctx context.Context, udid string) ([]Event, error)
+}
+
+type TimelineService struct {
+	store Store
+}
+
+func New(store Store) *TimelineService {
+	return &TimelineService{store: store}
+}
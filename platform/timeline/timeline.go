@@ -0,0 +1,69 @@
+// Package timeline keeps a per-device audit log of MDM activity.
+package timeline
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/micromdm/micromdm/platform/timeline/internal/timelineproto"
+)
+
+// Event types recorded in a device timeline.
+const (
+	Authenticate     = "Authenticate"
+	TokenUpdate      = "TokenUpdate"
+	Enrolled         = "Enrolled"
+	Stale            = "Stale"
+	CheckOut         = "CheckOut"
+	DEPSync          = "DEPSync"
+	CommandQueued    = "CommandQueued"
+	CommandSent      = "CommandSent"
+	CommandResponse  = "CommandResponse"
+	PushSent         = "PushSent"
+	Blocked          = "Blocked"
+	Unblocked        = "Unblocked"
+	BlueprintApplied = "BlueprintApplied"
+	Purged           = "Purged"
+	// EnrollmentRejected has the reasons of the admission policy as Detail.
+	EnrollmentRejected = "EnrollmentRejected"
+)
+
+// Event is a single entry in a device timeline.
+type Event struct {
+	UDID        string    `json:"udid"`
+	Time        time.Time `json:"time"`
+	Type        string    `json:"type"`
+	CommandUUID string    `json:"command_uuid,omitempty"`
+	RequestType string    `json:"request_type,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	Detail      string    `json:"detail,omitempty"`
+}
+
+func MarshalEvent(ev *Event) ([]byte, error) {
+	return proto.Marshal(&timelineproto.Event{
+		Udid:        ev.UDID,
+		Time:        ev.Time.UnixNano(),
+		Type:        ev.Type,
+		CommandUuid: ev.CommandUUID,
+		RequestType: ev.RequestType,
+		Status:      ev.Status,
+		Detail:      ev.Detail,
+	})
+}
+
+func UnmarshalEvent(data []byte, ev *Event) error {
+	var pb timelineproto.Event
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return errors.Wrap(err, "timeline: unmarshal proto to event")
+	}
+	ev.UDID = pb.GetUdid()
+	ev.Time = time.Unix(0, pb.GetTime()).UTC()
+	ev.Type = pb.GetType()
+	ev.CommandUUID = pb.GetCommandUuid()
+	ev.RequestType = pb.GetRequestType()
+	ev.Status = pb.GetStatus()
+	ev.Detail = pb.GetDetail()
+	return nil
+}
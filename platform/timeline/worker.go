@@ -0,0 +1,298 @@
+package timeline
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/mdm"
+	"github.com/micromdm/micromdm/platform/apns"
+	"github.com/micromdm/micromdm/platform/blueprint"
+	"github.com/micromdm/micromdm/platform/command"
+	"github.com/micromdm/micromdm/platform/dep/sync"
+	"github.com/micromdm/micromdm/platform/device"
+	"github.com/micromdm/micromdm/platform/pubsub"
+	"github.com/micromdm/micromdm/platform/queue"
+	block "github.com/micromdm/micromdm/platform/remove"
+)
+
+type WorkerStore interface {
+	Record(ctx context.Context, ev *Event) error
+}
+
+// DeviceStore resolves the serial numbers in DEP sync events to UDIDs.
+type DeviceStore interface {
+	DeviceBySerial(ctx context.Context, serial string) (*device.Device, error)
+}
+
+// Worker records device events published by other services.
+type Worker struct {
+	db      WorkerStore
+	devices DeviceStore
+	sub     pubsub.Subscriber
+	logger  log.Logger
+}
+
+func NewWorker(db WorkerStore, devices DeviceStore, sub pubsub.Subscriber, logger log.Logger) *Worker {
+	return &Worker{
+		db:      db,
+		devices: devices,
+		sub:     sub,
+		logger:  logger,
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) error {
+	const subscription = "device_timeline_worker"
+
+	topics := []string{
+		mdm.AuthenticateTopic,
+		mdm.TokenUpdateTopic,
+		mdm.CheckoutTopic,
+		mdm.ConnectTopic,
+		device.DeviceEnrolledTopic,
+		device.DeviceStaleTopic,
+		device.DevicePurgedTopic,
+		sync.SyncTopic,
+		command.CommandTopic,
+		command.RawCommandTopic,
+		queue.CommandSentTopic,
+		apns.PushSentTopic,
+		block.BlockTopic,
+		block.UnblockTopic,
+		blueprint.AppliedTopic,
+		mdm.EnrollmentRejectedTopic,
+	}
+
+	// all topics share a single channel so that events are recorded by a
+	// single goroutine. Events of different topics may arrive out of order,
+	// the store sorts them by time when the timeline is read.
+	events := make(chan pubsub.Event)
+	for _, topic := range topics {
+		c, err := w.sub.Subscribe(ctx, subscription, topic)
+		if err != nil {
+			return errors.Wrapf(err, "subscribing %s to %s", subscription, topic)
+		}
+		go func(c <-chan pubsub.Event) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case ev := <-c:
+					select {
+					case events <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}(c)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev := <-events:
+			if err := w.handle(ctx, ev.Topic, ev.Message); err != nil {
+				level.Info(w.logger).Log(
+					"msg", "record device event",
+					"topic", ev.Topic,
+					"err", err,
+				)
+			}
+		}
+	}
+}
+
+func (w *Worker) handle(ctx context.Context, topic string, message []byte) error {
+	var events []*Event
+	switch topic {
+	case mdm.AuthenticateTopic, mdm.TokenUpdateTopic, mdm.CheckoutTopic, device.DeviceEnrolledTopic:
+		ev, err := checkinEvent(topic, message)
+		if err != nil {
+			return err
+		}
+		events = append(events, ev)
+	case device.DeviceStaleTopic:
+		var dev device.Device
+		if err := device.UnmarshalDevice(message, &dev); err != nil {
+			return errors.Wrap(err, "unmarshal stale device")
+		}
+		events = append(events, &Event{
+			UDID:   dev.UDID,
+			Time:   time.Now().UTC(),
+			Type:   Stale,
+			Detail: "last seen " + dev.LastSeen.Format(time.RFC3339),
+		})
+	case device.DevicePurgedTopic:
+		var dev device.Device
+		if err := device.UnmarshalDevice(message, &dev); err != nil {
+			return errors.Wrap(err, "unmarshal purged device")
+		}
+		if dev.UDID == "" {
+			return nil
+		}
+		events = append(events, &Event{
+			UDID:   dev.UDID,
+			Time:   time.Now().UTC(),
+			Type:   Purged,
+			Detail: "serial " + dev.SerialNumber,
+		})
+	case mdm.ConnectTopic:
+		var ev mdm.AcknowledgeEvent
+		if err := mdm.UnmarshalAcknowledgeEvent(message, &ev); err != nil {
+			return errors.Wrap(err, "unmarshal acknowledge event")
+		}
+		// Idle responses are sent every time the device checks for
+		// commands, which would quickly flood the timeline.
+		if ev.Response.Status == "Idle" {
+			return nil
+		}
+		events = append(events, &Event{
+			UDID:        ev.Response.UDID,
+			Time:        ev.Time,
+			Type:        CommandResponse,
+			CommandUUID: ev.Response.CommandUUID,
+			RequestType: ev.Response.RequestType,
+			Status:      ev.Response.Status,
+		})
+	case sync.SyncTopic:
+		var err error
+		events, err = w.depSyncEvents(ctx, message)
+		if err != nil {
+			return err
+		}
+	case command.CommandTopic:
+		var ev command.Event
+		if err := command.UnmarshalEvent(message, &ev); err != nil {
+			return errors.Wrap(err, "unmarshal command event")
+		}
+		events = append(events, &Event{
+			UDID:        ev.DeviceUDID,
+			Time:        ev.Time,
+			Type:        CommandQueued,
+			CommandUUID: ev.Payload.CommandUUID,
+			RequestType: ev.Payload.Command.RequestType,
+		})
+	case command.RawCommandTopic:
+		var ev command.RawEvent
+		if err := command.UnmarshalRawEvent(message, &ev); err != nil {
+			return errors.Wrap(err, "unmarshal raw command event")
+		}
+		events = append(events, &Event{
+			UDID:        ev.DeviceUDID,
+			Time:        ev.Time,
+			Type:        CommandQueued,
+			CommandUUID: ev.CommandUUID,
+		})
+	case queue.CommandSentTopic:
+		cs, err := queue.UnmarshalQueuedCommand(message)
+		if err != nil {
+			return errors.Wrap(err, "unmarshal command sent event")
+		}
+		events = append(events, &Event{
+			UDID:        cs.DeviceUDID,
+			Time:        time.Now().UTC(),
+			Type:        CommandSent,
+			CommandUUID: cs.CommandUUID,
+		})
+	case apns.PushSentTopic:
+		events = append(events, &Event{UDID: string(message), Time: time.Now().UTC(), Type: PushSent})
+	case block.BlockTopic:
+		events = append(events, &Event{UDID: string(message), Time: time.Now().UTC(), Type: Blocked})
+	case block.UnblockTopic:
+		events = append(events, &Event{UDID: string(message), Time: time.Now().UTC(), Type: Unblocked})
+	case blueprint.AppliedTopic:
+		var ev blueprint.AppliedEvent
+		if err := json.Unmarshal(message, &ev); err != nil {
+			return errors.Wrap(err, "unmarshal blueprint applied event")
+		}
+		events = append(events, &Event{
+			UDID:   ev.UDID,
+			Time:   time.Now().UTC(),
+			Type:   BlueprintApplied,
+			Detail: ev.BlueprintName,
+		})
+	case mdm.EnrollmentRejectedTopic:
+		var ev mdm.EnrollmentRejectedEvent
+		if err := json.Unmarshal(message, &ev); err != nil {
+			return errors.Wrap(err, "unmarshal enrollment rejected event")
+		}
+		events = append(events, &Event{
+			UDID:   ev.UDID,
+			Time:   ev.Time,
+			Type:   EnrollmentRejected,
+			Detail: strings.Join(ev.Reasons, "; "),
+		})
+	default:
+		return errors.Errorf("unexpected topic %s", topic)
+	}
+
+	for _, ev := range events {
+		if ev.UDID == "" {
+			continue
+		}
+		if err := w.db.Record(ctx, ev); err != nil {
+			return errors.Wrapf(err, "record %s event for udid %s", ev.Type, ev.UDID)
+		}
+	}
+	return nil
+}
+
+func checkinEvent(topic string, message []byte) (*Event, error) {
+	var ev mdm.CheckinEvent
+	if err := mdm.UnmarshalCheckinEvent(message, &ev); err != nil {
+		return nil, errors.Wrap(err, "unmarshal checkin event")
+	}
+	event := &Event{
+		UDID: ev.Command.UDID,
+		Time: ev.Time,
+	}
+	if event.UDID == "" {
+		event.UDID = ev.Command.EnrollmentID
+	}
+	switch topic {
+	case mdm.AuthenticateTopic:
+		event.Type = Authenticate
+	case mdm.TokenUpdateTopic:
+		event.Type = TokenUpdate
+	case mdm.CheckoutTopic:
+		event.Type = CheckOut
+	case device.DeviceEnrolledTopic:
+		event.Type = Enrolled
+	}
+	if ev.Command.UserID != "" {
+		event.Detail = "user " + ev.Command.UserShortName
+	}
+	return event, nil
+}
+
+// depSyncEvents returns an event for every device in a DEP sync which has
+// already been enrolled. Devices which never checked in have no UDID yet.
+func (w *Worker) depSyncEvents(ctx context.Context, message []byte) ([]*Event, error) {
+	var ev sync.Event
+	if err := sync.UnmarshalEvent(message, &ev); err != nil {
+		return nil, errors.Wrap(err, "unmarshal depsync event")
+	}
+	var events []*Event
+	for _, dd := range ev.Devices {
+		dev, err := w.devices.DeviceBySerial(ctx, dd.SerialNumber)
+		if err != nil {
+			continue
+		}
+		events = append(events, &Event{
+			UDID:   dev.UDID,
+			Time:   ev.Time,
+			Type:   DEPSync,
+			Status: dd.OpType,
+			Detail: dd.ProfileStatus,
+		})
+	}
+	return events, nil
+}
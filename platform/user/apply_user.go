@@ -10,6 +10,14 @@ import (
 )
 
 func (svc *UserService) ApplyUser(ctx context.Context, u User) (*User, error) {
+	if u.Password != "" {
+		hash, err := HashPassword(u.Password)
+		if err != nil {
+			return nil, err
+		}
+		u.PasswordHash = hash
+		u.Password = ""
+	}
 	toSave := &u
 	if u.UUID == "" { //newUser
 		usr, err := NewFromRequest(u)
@@ -49,6 +57,9 @@ func MakeApplyUserEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req := request.(applyUserRequest)
 		u, err := svc.ApplyUser(ctx, req.User)
+		if err != nil {
+			return applyUserResponse{Err: err}, nil
+		}
 		return applyUserResponse{
 			User: *u,
 			Err:  err,
@@ -1,6 +1,7 @@
 package builtin
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 
@@ -43,21 +44,60 @@ func NewDB(db *bolt.DB) (*DB, error) {
 
 func (db *DB) List() ([]user.User, error) {
 	var users []user.User
-	err := db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(UserBucket))
-		c := b.Cursor()
-		for k, v := c.First(); k != nil; k, v = c.Next() {
-			var u user.User
-			if err := user.UnmarshalUser(v, &u); err != nil {
-				return err
-			}
-			users = append(users, u)
-		}
+	err := db.ForEach(func(u *user.User) error {
+		users = append(users, *u)
 		return nil
 	})
 	return users, errors.Wrap(err, "list users")
 }
 
+// forEachBatchSize is the number of users ForEach reads in one transaction.
+const forEachBatchSize = 100
+
+// ForEach calls fn for every user. Users are read in batches, and fn is
+// called outside of the read transaction, so a slow fn does not hold the
+// transaction open.
+func (db *DB) ForEach(fn func(*user.User) error) error {
+	var (
+		after []byte
+		done  bool
+	)
+	for !done {
+		var batch []user.User
+		err := db.View(func(tx *bolt.Tx) error {
+			c := tx.Bucket([]byte(UserBucket)).Cursor()
+			k, v := c.First()
+			if after != nil {
+				if k, v = c.Seek(after); k != nil && bytes.Equal(k, after) {
+					k, v = c.Next()
+				}
+			}
+			for ; k != nil; k, v = c.Next() {
+				if len(batch) == forEachBatchSize {
+					return nil
+				}
+				after = append(after[:0], k...)
+				var u user.User
+				if err := user.UnmarshalUser(v, &u); err != nil {
+					return err
+				}
+				batch = append(batch, u)
+			}
+			done = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (db *DB) Save(u *user.User) error {
 	tx, err := db.DB.Begin(true)
 	if err != nil {
@@ -155,6 +195,24 @@ func (db *DB) DeviceUsers(udid string) ([]user.User, error) {
 	return users, nil
 }
 
+// PurgeDeviceFunc returns a function which removes the users of a purged
+// device. The command queues of the users are purged with purgeQueue before
+// the users are deleted, so the purge can be retried if it fails.
+func (db *DB) PurgeDeviceFunc(purgeQueue func(ctx context.Context, id string) error) func(ctx context.Context, udid string) error {
+	return func(ctx context.Context, udid string) error {
+		users, err := db.DeviceUsers(udid)
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			if err := purgeQueue(ctx, u.UserID); err != nil {
+				return errors.Wrapf(err, "purge command queue of user %s", u.UserID)
+			}
+		}
+		return db.DeleteDeviceUsers(udid)
+	}
+}
+
 func (db *DB) DeleteDeviceUsers(udid string) error {
 	err := db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(UserBucket))
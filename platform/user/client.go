@@ -37,8 +37,81 @@ func NewHTTPClient(instance, token string, logger log.Logger, opts ...httptransp
 		).Endpoint()
 	}
 
+	var exportUsersEndpoint endpoint.Endpoint
+	{
+		exportUsersEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/users/export"),
+			httputil.EncodeRequestWithToken(token, encodeExportUsersRequest),
+			decodeExportUsersResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var verifyPasswordEndpoint endpoint.Endpoint
+	{
+		verifyPasswordEndpoint = httptransport.NewClient(
+			"POST",
+			httputil.CopyURL(u, "/v1/users"),
+			httputil.EncodeRequestWithToken(token, encodeVerifyPasswordRequest),
+			decodeVerifyPasswordResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var rotatePasswordEndpoint endpoint.Endpoint
+	{
+		rotatePasswordEndpoint = httptransport.NewClient(
+			"POST",
+			httputil.CopyURL(u, "/v1/users"),
+			httputil.EncodeRequestWithToken(token, encodeRotatePasswordRequest),
+			decodeRotatePasswordResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var deviceUsersEndpoint endpoint.Endpoint
+	{
+		deviceUsersEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/devices"),
+			httputil.EncodeRequestWithToken(token, encodeDeviceUsersRequest),
+			decodeDeviceUsersResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var syncDirectoryEndpoint endpoint.Endpoint
+	{
+		syncDirectoryEndpoint = httptransport.NewClient(
+			"POST",
+			httputil.CopyURL(u, "/v1/users/directory-sync"),
+			httputil.EncodeRequestWithToken(token, httptransport.EncodeJSONRequest),
+			decodeDirectorySyncResponse,
+			opts...,
+		).Endpoint()
+	}
+
+	var directorySyncReportEndpoint endpoint.Endpoint
+	{
+		directorySyncReportEndpoint = httptransport.NewClient(
+			"GET",
+			httputil.CopyURL(u, "/v1/users/directory-sync"),
+			httputil.EncodeRequestWithToken(token, encodeDirectorySyncReportRequest),
+			decodeDirectorySyncResponse,
+			opts...,
+		).Endpoint()
+	}
+
 	return Endpoints{
-		ApplyUserEndpoint: applyUserEndpoint,
-		ListUsersEndpoint: listUsersEndpoint,
+		ApplyUserEndpoint:      applyUserEndpoint,
+		ListUsersEndpoint:      listUsersEndpoint,
+		ExportUsersEndpoint:    exportUsersEndpoint,
+		VerifyPasswordEndpoint: verifyPasswordEndpoint,
+		RotatePasswordEndpoint: rotatePasswordEndpoint,
+		DeviceUsersEndpoint:    deviceUsersEndpoint,
+
+		SyncDirectoryEndpoint:       syncDirectoryEndpoint,
+		DirectorySyncReportEndpoint: directorySyncReportEndpoint,
 	}, nil
 }
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// DeviceUsers returns the managed users of a device, the users which checked
+// in on the user channel of the device. Auth tokens are not returned.
+func (svc *UserService) DeviceUsers(ctx context.Context, udid string) ([]User, error) {
+	users, err := svc.store.DeviceUsers(udid)
+	if err != nil {
+		return nil, err
+	}
+	managed := []User{}
+	for _, u := range users {
+		if u.UserID == "" {
+			continue
+		}
+		u.AuthToken = ""
+		managed = append(managed, u)
+	}
+	return managed, nil
+}
+
+type deviceUsersRequest struct {
+	UDID string
+}
+
+type deviceUsersResponse struct {
+	Users []User `json:"users"`
+	Err   error  `json:"err,omitempty"`
+}
+
+func (r deviceUsersResponse) Failed() error { return r.Err }
+
+func decodeDeviceUsersRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	udid, ok := mux.Vars(r)["udid"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	return deviceUsersRequest{UDID: udid}, nil
+}
+
+func encodeDeviceUsersRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(deviceUsersRequest)
+	r.Method, r.URL.Path = "GET", "/v1/devices/"+url.PathEscape(req.UDID)+"/users"
+	return nil
+}
+
+func decodeDeviceUsersResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp deviceUsersResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeDeviceUsersEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(deviceUsersRequest)
+		users, err := svc.DeviceUsers(ctx, req.UDID)
+		return deviceUsersResponse{Users: users, Err: err}, nil
+	}
+}
+
+func (e Endpoints) DeviceUsers(ctx context.Context, udid string) ([]User, error) {
+	resp, err := e.DeviceUsersEndpoint(ctx, deviceUsersRequest{UDID: udid})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(deviceUsersResponse)
+	return response.Users, response.Err
+}
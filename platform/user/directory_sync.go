@@ -0,0 +1,211 @@
+package user
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// DirectoryEntry is a member of the directory group synced to users.
+type DirectoryEntry struct {
+	DN            string
+	UserShortname string
+	UserLongname  string
+}
+
+// DirectorySource returns the members of the directory group synced to
+// users.
+type DirectorySource interface {
+	Members(ctx context.Context) ([]DirectoryEntry, error)
+}
+
+// DirectorySyncReport is the result of a directory sync. Created, Updated
+// and Disabled are the short names of the changed users.
+type DirectorySyncReport struct {
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	DryRun      bool      `json:"dry_run"`
+	Members     int       `json:"members"`
+	Created     []string  `json:"created,omitempty"`
+	Updated     []string  `json:"updated,omitempty"`
+	Disabled    []string  `json:"disabled,omitempty"`
+	Err         string    `json:"error,omitempty"`
+}
+
+// DirectorySync creates and updates a user for every member of a directory
+// group, and disables the users which left the group. Users are matched by
+// the DN of their directory entry. Users created by the API with the short
+// name of a new member are adopted by the sync. A group without members is
+// treated as a directory error rather than disabling every synced user.
+type DirectorySync struct {
+	store    Store
+	source   DirectorySource
+	logger   log.Logger
+	interval time.Duration
+	dryRun   bool
+
+	// mu serializes syncs and guards last.
+	mu   sync.Mutex
+	last *DirectorySyncReport
+}
+
+type DirectorySyncOption func(*DirectorySync)
+
+// WithDirectorySyncInterval syncs the directory on a schedule. A value of 0,
+// the default, only syncs on demand.
+func WithDirectorySyncInterval(d time.Duration) DirectorySyncOption {
+	return func(s *DirectorySync) {
+		s.interval = d
+	}
+}
+
+// WithDirectorySyncDryRun makes scheduled syncs only report the changes.
+func WithDirectorySyncDryRun(dryRun bool) DirectorySyncOption {
+	return func(s *DirectorySync) {
+		s.dryRun = dryRun
+	}
+}
+
+func NewDirectorySync(store Store, source DirectorySource, logger log.Logger, opts ...DirectorySyncOption) *DirectorySync {
+	s := &DirectorySync{store: store, source: source, logger: logger}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run syncs the directory on the interval until the context is done.
+func (s *DirectorySync) Run(ctx context.Context) error {
+	if s.interval <= 0 {
+		return nil
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Sync(ctx, s.dryRun); err != nil {
+			level.Info(s.logger).Log("msg", "sync users from directory", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// LastReport returns the report of the last sync, or nil if the directory was
+// not synced yet.
+func (s *DirectorySync) LastReport() *DirectorySyncReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Sync syncs the users with the members of the directory group. With dryRun
+// the changes are reported but not saved.
+func (s *DirectorySync) Sync(ctx context.Context, dryRun bool) (*DirectorySyncReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report := &DirectorySyncReport{StartedAt: time.Now().UTC(), DryRun: dryRun}
+	err := s.sync(ctx, report)
+	if err != nil {
+		report.Err = err.Error()
+	}
+	report.CompletedAt = time.Now().UTC()
+	s.last = report
+	return report, err
+}
+
+func (s *DirectorySync) sync(ctx context.Context, report *DirectorySyncReport) error {
+	members, err := s.source.Members(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get directory group members")
+	}
+	report.Members = len(members)
+	users, err := s.store.List()
+	if err != nil {
+		return errors.Wrap(err, "list users")
+	}
+
+	// only users created by the API or a sync are matched, not the managed
+	// users of devices.
+	byDN := make(map[string]*User)
+	byShortname := make(map[string]*User)
+	for i := range users {
+		u := &users[i]
+		if u.UDID != "" {
+			continue
+		}
+		if u.DirectoryDN != "" {
+			byDN[u.DirectoryDN] = u
+		} else {
+			byShortname[u.UserShortname] = u
+		}
+	}
+
+	save := func(u *User) error {
+		if report.DryRun {
+			return nil
+		}
+		return errors.Wrapf(s.store.Save(u), "save user %s", u.UserShortname)
+	}
+
+	if len(members) == 0 && len(byDN) > 0 {
+		return errors.Errorf("directory group has no members, not disabling %d synced users", len(byDN))
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range members {
+		if m.DN == "" || m.UserShortname == "" || seen[m.DN] {
+			continue
+		}
+		seen[m.DN] = true
+		u, ok := byDN[m.DN]
+		if !ok {
+			u, ok = byShortname[m.UserShortname]
+		}
+		if !ok {
+			u = &User{
+				UUID:          uuid.New().String(),
+				UserShortname: m.UserShortname,
+				UserLongname:  m.UserLongname,
+				DirectoryDN:   m.DN,
+			}
+			if err := save(u); err != nil {
+				return err
+			}
+			report.Created = append(report.Created, u.UserShortname)
+			continue
+		}
+		if u.DirectoryDN == m.DN && u.UserShortname == m.UserShortname && u.UserLongname == m.UserLongname && !u.Disabled {
+			continue
+		}
+		u.DirectoryDN = m.DN
+		u.UserShortname = m.UserShortname
+		u.UserLongname = m.UserLongname
+		u.Disabled = false
+		if err := save(u); err != nil {
+			return err
+		}
+		report.Updated = append(report.Updated, u.UserShortname)
+	}
+
+	for dn, u := range byDN {
+		if seen[dn] || u.Disabled {
+			continue
+		}
+		u.Disabled = true
+		if err := save(u); err != nil {
+			return err
+		}
+		report.Disabled = append(report.Disabled, u.UserShortname)
+	}
+	sort.Strings(report.Disabled)
+	return nil
+}
@@ -0,0 +1,222 @@
+package user
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-kit/kit/log"
+)
+
+// testLDAPServer is an in-process LDAP server which answers simple binds and
+// searches with an equality filter on memberOf.
+type testLDAPServer struct {
+	ln       net.Listener
+	password string
+	entries  []testLDAPEntry
+	// hang leaves searches unanswered.
+	hang bool
+}
+
+type testLDAPEntry struct {
+	dn    string
+	attrs map[string]string
+}
+
+func newTestLDAPServer(t *testing.T, password string, entries ...testLDAPEntry) *testLDAPServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &testLDAPServer{ln: ln, password: password, entries: entries}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return srv
+}
+
+func (srv *testLDAPServer) URL() string { return "ldap://" + srv.ln.Addr().String() }
+
+func (srv *testLDAPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case 0: // BindRequest
+			code := int64(0)
+			if op.Children[2].Data.String() != srv.password {
+				code = 49 // invalidCredentials
+			}
+			conn.Write(ldapMessage(id, ldapResult(1, code)).Bytes())
+		case 2: // UnbindRequest
+			return
+		case 3: // SearchRequest
+			if srv.hang {
+				continue
+			}
+			group := ""
+			if filter := op.Children[6]; filter.Tag == 3 && filter.Children[0].Value == "memberOf" {
+				group = filter.Children[1].Value.(string)
+			}
+			for _, e := range srv.entries {
+				if e.attrs["memberOf"] != group {
+					continue
+				}
+				entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, 4, nil, "SearchResultEntry")
+				entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "objectName"))
+				attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+				for name, value := range e.attrs {
+					attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+					attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+					vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+					vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+					attr.AppendChild(vals)
+					attrs.AppendChild(attr)
+				}
+				entry.AppendChild(attrs)
+				conn.Write(ldapMessage(id, entry).Bytes())
+			}
+			conn.Write(ldapMessage(id, ldapResult(5, 0)).Bytes())
+		default:
+			return
+		}
+	}
+}
+
+func ldapMessage(id int64, op *ber.Packet) *ber.Packet {
+	msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAPMessage")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "messageID"))
+	msg.AppendChild(op)
+	return msg
+}
+
+func ldapResult(tag ber.Tag, code int64) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "LDAPResult")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "resultCode"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return result
+}
+
+const testGroupDN = "cn=mdm-admins,ou=groups,dc=example,dc=com"
+
+func testLDAPMember(uid, cn string) testLDAPEntry {
+	return testLDAPEntry{
+		dn:    "uid=" + uid + ",ou=people,dc=example,dc=com",
+		attrs: map[string]string{"uid": uid, "cn": cn, "memberOf": testGroupDN},
+	}
+}
+
+func TestDirectorySync(t *testing.T) {
+	srv := newTestLDAPServer(t, "secret",
+		testLDAPMember("alice", "Alice Admin"),
+		testLDAPMember("bob", "Bob Admin"),
+		testLDAPEntry{dn: "uid=eve,ou=people,dc=example,dc=com", attrs: map[string]string{"uid": "eve", "cn": "Eve"}},
+	)
+	source := NewLDAPSource(LDAPConfig{
+		URL:          srv.URL(),
+		BindDN:       "cn=micromdm,dc=example,dc=com",
+		BindPassword: "secret",
+		BaseDN:       "dc=example,dc=com",
+		GroupDN:      testGroupDN,
+	})
+	store := &passwordTestStore{users: map[string]*User{
+		// an user created by the API is adopted by the sync.
+		"api-bob": {UUID: "api-bob", UserShortname: "bob", PasswordHash: []byte("hash")},
+		// managed users of devices are left alone.
+		"device-alice": {UUID: "device-alice", UDID: "udid", UserID: "id", UserShortname: "alice"},
+	}}
+	sync := NewDirectorySync(store, source, log.NewNopLogger())
+	ctx := context.Background()
+
+	report, err := sync.Sync(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.Members != 2 || !reflect.DeepEqual(report.Created, []string{"alice"}) || !reflect.DeepEqual(report.Updated, []string{"bob"}) {
+		t.Errorf("unexpected dry run report %+v", report)
+	}
+	if len(store.users) != 2 || store.users["api-bob"].DirectoryDN != "" {
+		t.Error("dry run changed users")
+	}
+
+	if _, err := sync.Sync(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.users) != 3 {
+		t.Fatalf("have %d users, want 3", len(store.users))
+	}
+	bob := store.users["api-bob"]
+	if bob.UserLongname != "Bob Admin" || bob.DirectoryDN != "uid=bob,ou=people,dc=example,dc=com" || string(bob.PasswordHash) != "hash" {
+		t.Errorf("bob was not adopted: %+v", bob)
+	}
+
+	// bob leaves the group.
+	srv.entries = srv.entries[:1]
+	report, err = sync.Sync(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Created) != 0 || len(report.Updated) != 0 || !reflect.DeepEqual(report.Disabled, []string{"bob"}) {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if !store.users["api-bob"].Disabled {
+		t.Error("bob was not disabled")
+	}
+	if sync.LastReport() != report {
+		t.Error("last report was not kept")
+	}
+
+	// an empty group does not disable every synced user.
+	srv.entries = nil
+	report, err = sync.Sync(ctx, false)
+	if err == nil || report.Err == "" || len(report.Disabled) != 0 {
+		t.Errorf("have err %v, report %+v, want an empty group error", err, report)
+	}
+	for _, u := range store.users {
+		if u.DirectoryDN != "" && u.UserShortname == "alice" && u.Disabled {
+			t.Error("alice was disabled by an empty group")
+		}
+	}
+}
+
+func TestLDAPSourceMembersCanceled(t *testing.T) {
+	srv := newTestLDAPServer(t, "")
+	srv.hang = true
+	source := NewLDAPSource(LDAPConfig{URL: srv.URL(), GroupDN: testGroupDN})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := source.Members(ctx); err != context.DeadlineExceeded {
+		t.Errorf("have err %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("search was not canceled, took %s", elapsed)
+	}
+}
+
+func TestDirectorySyncBindError(t *testing.T) {
+	srv := newTestLDAPServer(t, "secret")
+	source := NewLDAPSource(LDAPConfig{URL: srv.URL(), BindDN: "cn=micromdm", BindPassword: "wrong", GroupDN: testGroupDN})
+	sync := NewDirectorySync(&passwordTestStore{users: map[string]*User{}}, source, log.NewNopLogger())
+	report, err := sync.Sync(context.Background(), false)
+	if err == nil || report.Err == "" {
+		t.Errorf("have err %v, report %+v, want a bind error", err, report)
+	}
+}
@@ -0,0 +1,122 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/micromdm/micromdm/pkg/export"
+)
+
+// UserRecord is a user as written by the export endpoint.
+// Auth tokens and password hashes are not exported.
+type UserRecord struct {
+	UUID          string `json:"uuid"`
+	UDID          string `json:"udid"`
+	UserID        string `json:"user_id"`
+	UserShortname string `json:"user_shortname"`
+	UserLongname  string `json:"user_longname"`
+	Hidden        bool   `json:"hidden"`
+}
+
+var userRecordHeader = []string{"uuid", "udid", "user_id", "user_shortname", "user_longname", "hidden"}
+
+// CSVRecord implements export.Record.
+func (r UserRecord) CSVRecord() []string {
+	return []string{r.UUID, r.UDID, r.UserID, r.UserShortname, r.UserLongname, strconv.FormatBool(r.Hidden)}
+}
+
+// ExportUsers calls fn for every user. The users are read from the store one
+// at a time.
+func (svc *UserService) ExportUsers(ctx context.Context, fn func(UserRecord) error) error {
+	return svc.store.ForEach(func(u *User) error {
+		return fn(UserRecord{
+			UUID:          u.UUID,
+			UDID:          u.UDID,
+			UserID:        u.UserID,
+			UserShortname: u.UserShortname,
+			UserLongname:  u.UserLongname,
+			Hidden:        u.Hidden,
+		})
+	})
+}
+
+type exportUsersRequest struct {
+	Format string
+}
+
+type exportUsersResponse struct {
+	// Users is set when the response is decoded by the client.
+	Users []UserRecord
+	// records streams the users on the server.
+	records func(fn func(UserRecord) error) error
+	format  string
+	Err     error
+}
+
+func (r exportUsersResponse) Failed() error    { return r.Err }
+func (r exportUsersResponse) Format() string   { return r.format }
+func (r exportUsersResponse) Header() []string { return userRecordHeader }
+
+func (r exportUsersResponse) WriteRecords(w *export.Writer) error {
+	return r.records(func(u UserRecord) error {
+		return w.Write(u)
+	})
+}
+
+func decodeExportUsersRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	format, err := export.ParseFormat(r.URL.Query().Get("format"))
+	return exportUsersRequest{Format: format}, err
+}
+
+func encodeExportUsersRequest(_ context.Context, r *http.Request, request interface{}) error {
+	// the client always reads NDJSON.
+	r.URL.RawQuery = url.Values{"format": []string{export.NDJSON}}.Encode()
+	return nil
+}
+
+func decodeExportUsersResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp exportUsersResponse
+	err := export.DecodeNDJSON(r, func(dec *json.Decoder) error {
+		var u UserRecord
+		if err := dec.Decode(&u); err != nil {
+			return err
+		}
+		resp.Users = append(resp.Users, u)
+		return nil
+	})
+	return resp, err
+}
+
+func MakeExportUsersEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(exportUsersRequest)
+		return exportUsersResponse{
+			records: func(fn func(UserRecord) error) error {
+				return svc.ExportUsers(ctx, fn)
+			},
+			format: req.Format,
+		}, nil
+	}
+}
+
+func (e Endpoints) ExportUsers(ctx context.Context, fn func(UserRecord) error) error {
+	resp, err := e.ExportUsersEndpoint(ctx, exportUsersRequest{})
+	if err != nil {
+		return err
+	}
+	response := resp.(exportUsersResponse)
+	if response.Err != nil {
+		return response.Err
+	}
+	for _, u := range response.Users {
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package user
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+// LDAPConfig configures the LDAP directory synced to users.
+type LDAPConfig struct {
+	// URL of the server, like ldaps://ldap.example.com.
+	URL          string
+	BindDN       string
+	BindPassword string
+	// BaseDN is the subtree searched for the members of the group.
+	BaseDN string
+	// GroupDN is the group whose members are synced.
+	GroupDN string
+	// Filter selects the members of the group. Every {group} is replaced
+	// with the escaped GroupDN. The default is (memberOf={group}).
+	Filter string
+	// ShortnameAttribute and LongnameAttribute are the attributes mapped
+	// to the short and long name of users. The defaults are uid and cn.
+	ShortnameAttribute string
+	LongnameAttribute  string
+	Timeout            time.Duration
+}
+
+// LDAPSource reads the members of an LDAP group.
+type LDAPSource struct {
+	config LDAPConfig
+}
+
+func NewLDAPSource(config LDAPConfig) *LDAPSource {
+	if config.Filter == "" {
+		config.Filter = "(memberOf={group})"
+	}
+	if config.ShortnameAttribute == "" {
+		config.ShortnameAttribute = "uid"
+	}
+	if config.LongnameAttribute == "" {
+		config.LongnameAttribute = "cn"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &LDAPSource{config: config}
+}
+
+// Members searches the BaseDN for the members of the group. Entries without
+// a short name are skipped. The connection is closed when the context is
+// done, which stops a bind or search in progress.
+func (s *LDAPSource) Members(ctx context.Context) ([]DirectoryEntry, error) {
+	c := s.config
+	conn, err := ldap.DialURL(c.URL, ldap.DialWithDialer(&net.Dialer{Timeout: c.Timeout}))
+	if err != nil {
+		return nil, errors.Wrapf(err, "connect to %s", c.URL)
+	}
+	defer conn.Close()
+	conn.SetTimeout(c.Timeout)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	entries, err := s.members(conn)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return entries, err
+}
+
+func (s *LDAPSource) members(conn *ldap.Conn) ([]DirectoryEntry, error) {
+	c := s.config
+
+	if c.BindDN != "" {
+		if err := conn.Bind(c.BindDN, c.BindPassword); err != nil {
+			return nil, errors.Wrapf(err, "bind as %s", c.BindDN)
+		}
+	}
+
+	filter := strings.ReplaceAll(c.Filter, "{group}", ldap.EscapeFilter(c.GroupDN))
+	req := ldap.NewSearchRequest(
+		c.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter,
+		[]string{c.ShortnameAttribute, c.LongnameAttribute},
+		nil,
+	)
+	result, err := conn.SearchWithPaging(req, 500)
+	if err != nil {
+		return nil, errors.Wrapf(err, "search %s", filter)
+	}
+
+	var entries []DirectoryEntry
+	for _, e := range result.Entries {
+		shortname := e.GetAttributeValue(c.ShortnameAttribute)
+		if shortname == "" {
+			continue
+		}
+		entries = append(entries, DirectoryEntry{
+			DN:            e.DN,
+			UserShortname: shortname,
+			UserLongname:  e.GetAttributeValue(c.LongnameAttribute),
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,37 @@
+package user
+
+import (
+	"github.com/micromdm/plist"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/crypto/password"
+)
+
+// passwordHash is the property list of a User PasswordHash, as used by the
+// AccountConfiguration and SetAutoAdminPassword commands.
+type passwordHash struct {
+	SaltedSHA512PBKDF2 password.SaltedSHA512PBKDF2Dictionary `plist:"SALTED-SHA512-PBKDF2"`
+}
+
+// HashPassword creates the PasswordHash of a plaintext password.
+func HashPassword(plaintext string) ([]byte, error) {
+	salted, err := password.SaltedSHA512PBKDF2(plaintext)
+	if err != nil {
+		return nil, errors.Wrap(err, "salting plaintext password")
+	}
+	hash, err := plist.Marshal(passwordHash{SaltedSHA512PBKDF2: salted})
+	return hash, errors.Wrap(err, "marshal salted password to plist")
+}
+
+// VerifyPassword verifies a plaintext password against a PasswordHash. It
+// returns password.ErrNoMatch if the password does not match.
+func VerifyPassword(hash []byte, plaintext string) error {
+	var h passwordHash
+	if err := plist.Unmarshal(hash, &h); err != nil {
+		return errors.Wrap(err, "unmarshal password hash")
+	}
+	if len(h.SaltedSHA512PBKDF2.Entropy) == 0 {
+		return errors.New("password hash is not a SALTED-SHA512-PBKDF2 dictionary")
+	}
+	return password.Verify(plaintext, h.SaltedSHA512PBKDF2)
+}
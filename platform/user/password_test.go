@@ -0,0 +1,61 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micromdm/micromdm/pkg/crypto/password"
+)
+
+type passwordTestStore struct {
+	Store
+	users map[string]*User
+}
+
+func (s *passwordTestStore) User(_ context.Context, uuid string) (*User, error) {
+	return s.users[uuid], nil
+}
+
+func (s *passwordTestStore) Save(u *User) error {
+	s.users[u.UUID] = u
+	return nil
+}
+
+func (s *passwordTestStore) List() ([]User, error) {
+	var users []User
+	for _, u := range s.users {
+		users = append(users, *u)
+	}
+	return users, nil
+}
+
+func (s *passwordTestStore) DeviceUsers(udid string) ([]User, error) { return nil, nil }
+
+func TestApplyUserPassword(t *testing.T) {
+	store := &passwordTestStore{users: make(map[string]*User)}
+	svc := New(store)
+	ctx := context.Background()
+
+	u, err := svc.ApplyUser(ctx, User{UserShortname: "admin", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Password != "" || store.users[u.UUID].Password != "" {
+		t.Error("plaintext password was kept")
+	}
+	if err := VerifyPassword(u.PasswordHash, "secret"); err != nil {
+		t.Errorf("verify password hash: %s", err)
+	}
+	if err := VerifyPassword(u.PasswordHash, "wrong"); err != password.ErrNoMatch {
+		t.Errorf("have err %v, want %v", err, password.ErrNoMatch)
+	}
+
+	match, err := svc.VerifyPassword(ctx, u.UUID, "secret")
+	if err != nil || !match {
+		t.Errorf("have match %v, err %v, want a match", match, err)
+	}
+	match, err = svc.VerifyPassword(ctx, u.UUID, "wrong")
+	if err != nil || match {
+		t.Errorf("have match %v, err %v, want no match", match, err)
+	}
+}
@@ -0,0 +1,103 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+type RotatePasswordResult struct {
+	User *User `json:"user"`
+	// Queued are the UDIDs of the devices a SetAutoAdminPassword command
+	// was queued for.
+	Queued []string `json:"queued,omitempty"`
+	// Pending are the UDIDs of the devices which are sent the new password
+	// once they respond to a UserList command with the GUID of the account.
+	Pending []string `json:"pending,omitempty"`
+}
+
+// RotatePassword replaces the password of the user and changes it on the
+// devices which have the user as an admin account.
+func (svc *UserService) RotatePassword(ctx context.Context, uuid, plaintext string) (*RotatePasswordResult, error) {
+	if plaintext == "" {
+		return nil, errors.New("password must not be empty")
+	}
+	u, err := svc.store.User(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if u.PasswordHash, err = HashPassword(plaintext); err != nil {
+		return nil, err
+	}
+	if err := svc.store.Save(u); err != nil {
+		return nil, errors.Wrap(err, "save user")
+	}
+	result := &RotatePasswordResult{User: u}
+	if svc.rotator == nil {
+		return result, errors.New("server does not support changing passwords on devices")
+	}
+	result.Queued, result.Pending, err = svc.rotator.RotateAdminPassword(ctx, u)
+	return result, err
+}
+
+type rotatePasswordRequest struct {
+	UUID     string `json:"-"`
+	Password string `json:"password"`
+}
+
+type rotatePasswordResponse struct {
+	*RotatePasswordResult
+	Err error `json:"err,omitempty"`
+}
+
+func (r rotatePasswordResponse) Failed() error { return r.Err }
+
+func decodeRotatePasswordRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req rotatePasswordRequest
+	if err := httputil.DecodeJSONRequest(r, &req); err != nil {
+		return nil, err
+	}
+	uuid, ok := mux.Vars(r)["uuid"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	req.UUID = uuid
+	return req, nil
+}
+
+func encodeRotatePasswordRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(rotatePasswordRequest)
+	r.Method, r.URL.Path = "POST", "/v1/users/"+url.PathEscape(req.UUID)+"/rotate-password"
+	return httptransport.EncodeJSONRequest(ctx, r, request)
+}
+
+func decodeRotatePasswordResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp rotatePasswordResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeRotatePasswordEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(rotatePasswordRequest)
+		result, err := svc.RotatePassword(ctx, req.UUID, req.Password)
+		return rotatePasswordResponse{RotatePasswordResult: result, Err: err}, nil
+	}
+}
+
+func (e Endpoints) RotatePassword(ctx context.Context, uuid, password string) (*RotatePasswordResult, error) {
+	request := rotatePasswordRequest{UUID: uuid, Password: password}
+	resp, err := e.RotatePasswordEndpoint(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(rotatePasswordResponse)
+	return response.RotatePasswordResult, response.Err
+}
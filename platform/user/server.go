@@ -5,24 +5,47 @@ import (
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 
+	"github.com/micromdm/micromdm/pkg/export"
 	"github.com/micromdm/micromdm/pkg/httputil"
 )
 
 type Endpoints struct {
-	ApplyUserEndpoint endpoint.Endpoint
-	ListUsersEndpoint endpoint.Endpoint
+	ApplyUserEndpoint   endpoint.Endpoint
+	ListUsersEndpoint   endpoint.Endpoint
+	ExportUsersEndpoint endpoint.Endpoint
+
+	VerifyPasswordEndpoint endpoint.Endpoint
+	RotatePasswordEndpoint endpoint.Endpoint
+	DeviceUsersEndpoint    endpoint.Endpoint
+
+	SyncDirectoryEndpoint       endpoint.Endpoint
+	DirectorySyncReportEndpoint endpoint.Endpoint
 }
 
 func MakeServerEndpoints(s Service, outer endpoint.Middleware, others ...endpoint.Middleware) Endpoints {
 	return Endpoints{
-		ApplyUserEndpoint: endpoint.Chain(outer, others...)(MakeApplyUserEndpoint(s)),
-		ListUsersEndpoint: endpoint.Chain(outer, others...)(MakeListUsersEndpoint(s)),
+		ApplyUserEndpoint:   endpoint.Chain(outer, others...)(MakeApplyUserEndpoint(s)),
+		ListUsersEndpoint:   endpoint.Chain(outer, others...)(MakeListUsersEndpoint(s)),
+		ExportUsersEndpoint: endpoint.Chain(outer, others...)(MakeExportUsersEndpoint(s)),
+
+		VerifyPasswordEndpoint: endpoint.Chain(outer, others...)(MakeVerifyPasswordEndpoint(s)),
+		RotatePasswordEndpoint: endpoint.Chain(outer, others...)(MakeRotatePasswordEndpoint(s)),
+		DeviceUsersEndpoint:    endpoint.Chain(outer, others...)(MakeDeviceUsersEndpoint(s)),
+
+		SyncDirectoryEndpoint:       endpoint.Chain(outer, others...)(MakeSyncDirectoryEndpoint(s)),
+		DirectorySyncReportEndpoint: endpoint.Chain(outer, others...)(MakeDirectorySyncReportEndpoint(s)),
 	}
 }
 
 func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.ServerOption) {
 	// PUT     /v1/users		create or replace an user
 	// POST    /v1/users		get a list of users managed by the server
+	// GET     /v1/users/export	export all users as CSV or NDJSON
+	// POST    /v1/users/{uuid}/verify-password	check a plaintext password against the password of an user
+	// POST    /v1/users/{uuid}/rotate-password	replace the password of an user and change it on devices
+	// GET     /v1/devices/{udid}/users		get the managed users of a device
+	// POST    /v1/users/directory-sync	sync users from the directory group
+	// GET     /v1/users/directory-sync	get the report of the last directory sync
 
 	r.Methods("PUT").Path("/v1/users").Handler(httptransport.NewServer(
 		e.ApplyUserEndpoint,
@@ -37,4 +60,46 @@ func RegisterHTTPHandlers(r *mux.Router, e Endpoints, options ...httptransport.S
 		httputil.EncodeJSONResponse,
 		options...,
 	))
+
+	r.Methods("GET").Path("/v1/users/export").Handler(httptransport.NewServer(
+		e.ExportUsersEndpoint,
+		decodeExportUsersRequest,
+		export.EncodeResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/v1/users/{uuid}/verify-password").Handler(httptransport.NewServer(
+		e.VerifyPasswordEndpoint,
+		decodeVerifyPasswordRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/v1/users/{uuid}/rotate-password").Handler(httptransport.NewServer(
+		e.RotatePasswordEndpoint,
+		decodeRotatePasswordRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/devices/{udid}/users").Handler(httptransport.NewServer(
+		e.DeviceUsersEndpoint,
+		decodeDeviceUsersRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("POST").Path("/v1/users/directory-sync").Handler(httptransport.NewServer(
+		e.SyncDirectoryEndpoint,
+		decodeSyncDirectoryRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
+
+	r.Methods("GET").Path("/v1/users/directory-sync").Handler(httptransport.NewServer(
+		e.DirectorySyncReportEndpoint,
+		decodeDirectorySyncReportRequest,
+		httputil.EncodeJSONResponse,
+		options...,
+	))
 }
@@ -15,18 +15,56 @@ type ListUsersOption struct {
 type Service interface {
 	ApplyUser(ctx context.Context, u User) (*User, error)
 	ListUsers(ctx context.Context, opt ListUsersOption) ([]User, error)
+	ExportUsers(ctx context.Context, fn func(UserRecord) error) error
+	VerifyPassword(ctx context.Context, uuid, password string) (bool, error)
+	RotatePassword(ctx context.Context, uuid, password string) (*RotatePasswordResult, error)
+	DeviceUsers(ctx context.Context, udid string) ([]User, error)
+	SyncDirectory(ctx context.Context, dryRun bool) (*DirectorySyncReport, error)
+	DirectorySyncReport(ctx context.Context) (*DirectorySyncReport, error)
 }
 
 type Store interface {
 	User(context.Context, string) (*User, error)
 	Save(*User) error
 	List() ([]User, error)
+	// ForEach calls fn for every user.
+	ForEach(fn func(*User) error) error
+	DeviceUsers(udid string) ([]User, error)
+}
+
+// PasswordRotator queues SetAutoAdminPassword commands for the devices which
+// have the user as an admin account.
+type PasswordRotator interface {
+	RotateAdminPassword(ctx context.Context, u *User) (queued, pending []string, err error)
 }
 
 type UserService struct {
-	store Store
+	store     Store
+	rotator   PasswordRotator
+	directory *DirectorySync
+}
+
+type Option func(*UserService)
+
+// WithPasswordRotator changes the passwords of admin accounts on devices when
+// the password of the user is rotated.
+func WithPasswordRotator(rotator PasswordRotator) Option {
+	return func(svc *UserService) {
+		svc.rotator = rotator
+	}
+}
+
+// WithDirectorySync allows syncing users from a directory through the API.
+func WithDirectorySync(sync *DirectorySync) Option {
+	return func(svc *UserService) {
+		svc.directory = sync
+	}
 }
 
-func New(store Store) *UserService {
-	return &UserService{store: store}
+func New(store Store, opts ...Option) *UserService {
+	svc := &UserService{store: store}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
 }
@@ -0,0 +1,96 @@
+package user
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+var errNoDirectorySync = errors.New("server is not configured to sync users from a directory")
+
+// SyncDirectory syncs the users with the members of the directory group and
+// returns the report of the sync. With dryRun the changes are only reported.
+func (svc *UserService) SyncDirectory(ctx context.Context, dryRun bool) (*DirectorySyncReport, error) {
+	if svc.directory == nil {
+		return nil, errNoDirectorySync
+	}
+	return svc.directory.Sync(ctx, dryRun)
+}
+
+// DirectorySyncReport returns the report of the last directory sync.
+func (svc *UserService) DirectorySyncReport(ctx context.Context) (*DirectorySyncReport, error) {
+	if svc.directory == nil {
+		return nil, errNoDirectorySync
+	}
+	return svc.directory.LastReport(), nil
+}
+
+type syncDirectoryRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+type directorySyncResponse struct {
+	Report *DirectorySyncReport `json:"report,omitempty"`
+	Err    error                `json:"err,omitempty"`
+}
+
+func (r directorySyncResponse) Failed() error { return r.Err }
+
+func decodeSyncDirectoryRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req syncDirectoryRequest
+	err := httputil.DecodeJSONRequest(r, &req)
+	return req, err
+}
+
+type directorySyncReportRequest struct{}
+
+func decodeDirectorySyncReportRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return directorySyncReportRequest{}, nil
+}
+
+func encodeDirectorySyncReportRequest(_ context.Context, r *http.Request, request interface{}) error {
+	return nil
+}
+
+func decodeDirectorySyncResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp directorySyncResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeSyncDirectoryEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(syncDirectoryRequest)
+		report, err := svc.SyncDirectory(ctx, req.DryRun)
+		return directorySyncResponse{Report: report, Err: err}, nil
+	}
+}
+
+func MakeDirectorySyncReportEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		report, err := svc.DirectorySyncReport(ctx)
+		return directorySyncResponse{Report: report, Err: err}, nil
+	}
+}
+
+func (e Endpoints) SyncDirectory(ctx context.Context, dryRun bool) (*DirectorySyncReport, error) {
+	resp, err := e.SyncDirectoryEndpoint(ctx, syncDirectoryRequest{DryRun: dryRun})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(directorySyncResponse)
+	return response.Report, response.Err
+}
+
+func (e Endpoints) DirectorySyncReport(ctx context.Context) (*DirectorySyncReport, error) {
+	resp, err := e.DirectorySyncReportEndpoint(ctx, directorySyncReportRequest{})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(directorySyncResponse)
+	return response.Report, response.Err
+}
@@ -2,6 +2,8 @@
 package user
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"google.golang.org/protobuf/proto"
@@ -18,6 +20,18 @@ type User struct {
 	AuthToken     string `json:"auth_token"`
 	PasswordHash  []byte `json:"password_hash"`
 	Hidden        bool   `json:"hidden"`
+	// LastTokenUpdate is when the user last sent a TokenUpdate on the user
+	// channel of the device. It is zero for users created by the API.
+	LastTokenUpdate time.Time `json:"last_token_update"`
+	// Disabled users are not created on devices by blueprints.
+	Disabled bool `json:"disabled,omitempty"`
+	// DirectoryDN is the distinguished name of the directory entry of users
+	// created by a directory sync.
+	DirectoryDN string `json:"directory_dn,omitempty"`
+
+	// Password is a plaintext password, hashed into PasswordHash by the
+	// server when the user is applied. It is never stored or returned.
+	Password string `json:"password,omitempty"`
 }
 
 func NewFromRequest(u User) (*User, error) {
@@ -27,6 +41,7 @@ func NewFromRequest(u User) (*User, error) {
 		UserLongname:  u.UserLongname,
 		PasswordHash:  u.PasswordHash,
 		Hidden:        u.Hidden,
+		Disabled:      u.Disabled,
 	}
 	return &newUser, nil
 }
@@ -41,6 +56,11 @@ func MarshalUser(u *User) ([]byte, error) {
 		AuthToken:     u.AuthToken,
 		PasswordHash:  u.PasswordHash,
 		Hidden:        u.Hidden,
+		Disabled:      u.Disabled,
+		DirectoryDn:   u.DirectoryDN,
+	}
+	if !u.LastTokenUpdate.IsZero() {
+		pb.LastTokenUpdate = u.LastTokenUpdate.UnixNano()
 	}
 	return proto.Marshal(&pb)
 }
@@ -58,5 +78,10 @@ func UnmarshalUser(data []byte, u *User) error {
 	u.AuthToken = pb.GetAuthToken()
 	u.PasswordHash = pb.GetPasswordHash()
 	u.Hidden = pb.GetHidden()
+	u.Disabled = pb.GetDisabled()
+	u.DirectoryDN = pb.GetDirectoryDn()
+	if pb.GetLastTokenUpdate() != 0 {
+		u.LastTokenUpdate = time.Unix(0, pb.GetLastTokenUpdate()).UTC()
+	}
 	return nil
 }
@@ -0,0 +1,87 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/pkg/crypto/password"
+	"github.com/micromdm/micromdm/pkg/httputil"
+)
+
+// VerifyPassword reports whether the plaintext password matches the password
+// hash of the user.
+func (svc *UserService) VerifyPassword(ctx context.Context, uuid, plaintext string) (bool, error) {
+	u, err := svc.store.User(ctx, uuid)
+	if err != nil {
+		return false, err
+	}
+	if len(u.PasswordHash) == 0 {
+		return false, errors.Errorf("user %s has no password", uuid)
+	}
+	err = VerifyPassword(u.PasswordHash, plaintext)
+	if err == password.ErrNoMatch {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type verifyPasswordRequest struct {
+	UUID     string `json:"-"`
+	Password string `json:"password"`
+}
+
+type verifyPasswordResponse struct {
+	Match bool  `json:"match"`
+	Err   error `json:"err,omitempty"`
+}
+
+func (r verifyPasswordResponse) Failed() error { return r.Err }
+
+func decodeVerifyPasswordRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req verifyPasswordRequest
+	if err := httputil.DecodeJSONRequest(r, &req); err != nil {
+		return nil, err
+	}
+	uuid, ok := mux.Vars(r)["uuid"]
+	if !ok {
+		return nil, errors.New("bad route")
+	}
+	req.UUID = uuid
+	return req, nil
+}
+
+func encodeVerifyPasswordRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	req := request.(verifyPasswordRequest)
+	r.Method, r.URL.Path = "POST", "/v1/users/"+url.PathEscape(req.UUID)+"/verify-password"
+	return httptransport.EncodeJSONRequest(ctx, r, request)
+}
+
+func decodeVerifyPasswordResponse(_ context.Context, r *http.Response) (interface{}, error) {
+	var resp verifyPasswordResponse
+	err := httputil.DecodeJSONResponse(r, &resp)
+	return resp, err
+}
+
+func MakeVerifyPasswordEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(verifyPasswordRequest)
+		match, err := svc.VerifyPassword(ctx, req.UUID, req.Password)
+		return verifyPasswordResponse{Match: match, Err: err}, nil
+	}
+}
+
+func (e Endpoints) VerifyPassword(ctx context.Context, uuid, password string) (bool, error) {
+	request := verifyPasswordRequest{UUID: uuid, Password: password}
+	resp, err := e.VerifyPasswordEndpoint(ctx, request)
+	if err != nil {
+		return false, err
+	}
+	response := resp.(verifyPasswordResponse)
+	return response.Match, response.Err
+}
@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -90,6 +91,7 @@ func (w *Worker) updateUserFromTokenUpdate(ctx context.Context, message []byte)
 	usr.UserLongname = ev.Command.UserLongName
 	usr.UserShortname = ev.Command.UserShortName
 	usr.AuthToken = ev.Command.Token.String()
+	usr.LastTokenUpdate = time.Now().UTC()
 	err = w.db.Save(usr)
 	return errors.Wrapf(err, "saving user %s to device %s", ev.Command.UserID, ev.Command.UDID)
 }
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/mdm"
+	"github.com/micromdm/micromdm/platform/config"
+	"github.com/micromdm/micromdm/platform/device"
+)
+
+type admissionPolicyStore interface {
+	AdmissionPolicy() (*config.AdmissionPolicy, error)
+}
+
+type depDeviceStore interface {
+	DeviceBySerial(ctx context.Context, serial string) (*device.Device, error)
+}
+
+// admissionPolicy admits devices with the saved admission policy. Serial
+// numbers of devices which were seen in a DEP sync are known.
+type admissionPolicy struct {
+	policies admissionPolicyStore
+	devices  depDeviceStore
+}
+
+func (a *admissionPolicy) AdmitDevice(ctx context.Context, cmd mdm.CheckinCommand) ([]string, error) {
+	policy, err := a.policies.AdmissionPolicy()
+	if err != nil {
+		return nil, errors.Wrap(err, "get admission policy")
+	}
+	req := config.AdmissionRequest{
+		SerialNumber: cmd.SerialNumber,
+		ProductName:  cmd.ProductName,
+		OSVersion:    cmd.OSVersion,
+	}
+	if policy.KnownSerialsOnly && cmd.SerialNumber != "" {
+		dev, err := a.devices.DeviceBySerial(ctx, cmd.SerialNumber)
+		switch {
+		case err == nil:
+			req.KnownSerial = dev.DEPProfileStatus != ""
+		case !isNotFound(err):
+			return nil, errors.Wrap(err, "get device by serial")
+		}
+	}
+	return policy.Evaluate(req), nil
+}
+
+func isNotFound(err error) bool {
+	type notFoundErr interface {
+		error
+		NotFound() bool
+	}
+	e, ok := errors.Cause(err).(notFoundErr)
+	return ok && e.NotFound()
+}
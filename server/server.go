@@ -21,6 +21,7 @@ import (
 	syncbuiltin "github.com/micromdm/micromdm/platform/dep/sync/builtin"
 	"github.com/micromdm/micromdm/platform/device"
 	devicebuiltin "github.com/micromdm/micromdm/platform/device/builtin"
+	invitebuiltin "github.com/micromdm/micromdm/platform/invite/builtin"
 	"github.com/micromdm/micromdm/platform/profile"
 	profilebuiltin "github.com/micromdm/micromdm/platform/profile/builtin"
 	"github.com/micromdm/micromdm/platform/pubsub"
@@ -57,7 +58,9 @@ type Server struct {
 	SCEPChallengeDepot     challenge.Store
 	ProfileDB              profile.Store
 	ConfigDB               config.Store
-	RemoveDB               block.Store
+	RemoveDB               *blockbuiltin.DB
+	DeviceDB               *devicebuiltin.DB
+	PushDB                 *apnsbuiltin.DB
 	CommandWebhookURL      string
 	DEPClient              *dep.Client
 	SyncDB                 *syncbuiltin.DB
@@ -67,6 +70,12 @@ type Server struct {
 	UDIDCertAuthWarnOnly   bool
 	Queue                  string
 	DMURL                  string
+	// EnrollmentSigner signs the enrollment profiles if set.
+	EnrollmentSigner *profile.Signer
+	// InviteRequired only serves the enrollment profile for a valid
+	// enrollment invite token.
+	InviteRequired bool
+	InviteDB       *invitebuiltin.DB
 
 	APNSPushService apns.Service
 	CommandService  command.Service
@@ -76,6 +85,10 @@ type Server struct {
 	ConfigService   config.Service
 
 	CommandQueue mdm.Queue
+	// PurgeCommandQueue removes the queue and command history of a device.
+	PurgeCommandQueue device.PurgeFunc
+	// CommandHistory is only available with the builtin command queue.
+	CommandHistory command.History
 
 	WebhooksHTTPClient *http.Client
 }
@@ -93,6 +106,10 @@ func (c *Server) Setup(logger log.Logger) error {
 		return err
 	}
 
+	if err := c.setupDeviceDB(); err != nil {
+		return err
+	}
+
 	if err := c.setupConfigStore(); err != nil {
 		return err
 	}
@@ -109,6 +126,10 @@ func (c *Server) Setup(logger log.Logger) error {
 		return err
 	}
 
+	if err := c.setupInviteDB(); err != nil {
+		return err
+	}
+
 	if err := c.setupCommandQueue(logger); err != nil {
 		return err
 	}
@@ -139,6 +160,15 @@ func (c *Server) setupProfileDB() error {
 	return nil
 }
 
+func (c *Server) setupInviteDB() error {
+	inviteDB, err := invitebuiltin.NewDB(c.DB)
+	if err != nil {
+		return err
+	}
+	c.InviteDB = inviteDB
+	return nil
+}
+
 func (c *Server) setupPubSub() error {
 	c.PubClient = inmem.NewPubSub()
 	return nil
@@ -150,7 +180,11 @@ func (c *Server) setupWebhooks(logger log.Logger) error {
 	}
 
 	ctx := context.Background()
-	ww := webhook.New(c.CommandWebhookURL, c.PubClient, webhook.WithLogger(logger), webhook.WithHTTPClient(c.WebhooksHTTPClient))
+	ww := webhook.New(c.CommandWebhookURL, c.PubClient,
+		webhook.WithLogger(logger),
+		webhook.WithHTTPClient(c.WebhooksHTTPClient),
+		webhook.WithDeviceStore(c.DeviceDB),
+	)
 	go ww.Run(ctx)
 	return nil
 }
@@ -164,8 +198,21 @@ func (c *Server) setupRemoveService() error {
 	return nil
 }
 
+func (c *Server) setupDeviceDB() error {
+	devDB, err := devicebuiltin.NewDB(c.DB)
+	if err != nil {
+		return errors.Wrap(err, "new device db")
+	}
+	c.DeviceDB = devDB
+	return nil
+}
+
 func (c *Server) setupCommandService() error {
-	commandService, err := command.New(c.PubClient, c.CommandQueue)
+	var opts []command.Option
+	if c.CommandHistory != nil {
+		opts = append(opts, command.WithHistory(c.CommandHistory))
+	}
+	commandService, err := command.New(c.PubClient, c.CommandQueue, opts...)
 	if err != nil {
 		return err
 	}
@@ -177,17 +224,21 @@ func (c *Server) setupCommandQueue(logger log.Logger) error {
 	var q mdm.Queue
 	switch c.Queue {
 	case "inmem":
-		q = queueinmem.New(c.PubClient, logger)
+		inmemQueue := queueinmem.New(c.PubClient, logger)
+		c.PurgeCommandQueue = inmemQueue.DeleteDeviceCommand
+		q = inmemQueue
 	case "builtin":
 		opts := []queue.Option{queue.WithLogger(logger)}
 		if c.NoCmdHistory {
 			opts = append(opts, queue.WithoutHistory())
 		}
-		var err error
-		q, err = queue.NewQueue(c.DB, c.PubClient, opts...)
+		boltQueue, err := queue.NewQueue(c.DB, c.PubClient, opts...)
 		if err != nil {
 			return err
 		}
+		c.PurgeCommandQueue = boltQueue.DeleteDeviceCommand
+		c.CommandHistory = boltQueue
+		q = boltQueue
 	case "":
 		return errors.New("empty command queue type")
 	default:
@@ -195,23 +246,24 @@ func (c *Server) setupCommandQueue(logger log.Logger) error {
 	}
 
 	c.CommandQueue = q
-
-	devDB, err := devicebuiltin.NewDB(c.DB)
-	if err != nil {
-		return errors.Wrap(err, "new device db")
-	}
+	devDB := c.DeviceDB
 
 	var mdmService mdm.Service
 	{
 		var dm mdm.DeclarativeManagement
 		if c.DMURL != "" {
+			var err error
 			dm, err = NewDeclarativeManagementHTTPCaller(c.DMURL, http.DefaultClient)
 			if err != nil {
 				return fmt.Errorf("setting up declarative management: %w", err)
 			}
 		}
 
-		svc := mdm.NewService(c.PubClient, q, devDB, dm)
+		admitter := &admissionPolicy{policies: c.ConfigDB, devices: devDB}
+		svc := mdm.NewService(c.PubClient, q, devDB, dm,
+			mdm.WithAdmitter(admitter),
+			mdm.WithInvites(c.InviteDB),
+		)
 		mdmService = svc
 		mdmService = block.RemoveMiddleware(c.RemoveDB)(mdmService)
 
@@ -258,8 +310,9 @@ func (c *Server) setupPushService(logger log.Logger) error {
 	if err != nil {
 		return err
 	}
+	c.PushDB = db
 
-	service, err := apns.New(db, c.ConfigDB, c.PubClient)
+	service, err := apns.New(db, c.ConfigDB, c.PubClient, apns.WithPublisher(c.PubClient))
 	if err != nil {
 		return errors.Wrap(err, "starting micromdm push service")
 	}
@@ -286,6 +339,14 @@ func (c *Server) setupEnrollmentService() error {
 
 	// TODO: clean up order of inputs. Maybe pass *SCEPConfig as an arg?
 	// but if you do, the packages are coupled, better not.
+	opts := []enroll.Option{
+		enroll.WithEnrollmentConfig(c.ConfigDB),
+		enroll.WithInvites(c.InviteDB, c.InviteRequired),
+	}
+	if c.EnrollmentSigner != nil {
+		opts = append(opts, enroll.WithProfileSigner(c.EnrollmentSigner))
+	}
+
 	c.EnrollService, err = enroll.NewService(
 		c.ConfigDB,
 		c.PubClient,
@@ -296,6 +357,7 @@ func (c *Server) setupEnrollmentService() error {
 		SCEPCertificateSubject,
 		c.ProfileDB,
 		chalStore,
+		opts...,
 	)
 	return errors.Wrap(err, "setting up enrollment service")
 }
@@ -1,6 +1,9 @@
 package webhook
 
 import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
 	"github.com/pkg/errors"
 
 	"github.com/micromdm/micromdm/mdm"
@@ -11,6 +14,10 @@ type CheckinEvent struct {
 	EnrollmentID string            `json:"enrollment_id,omitempty"`
 	Params       map[string]string `json:"url_params"`
 	RawPayload   []byte            `json:"raw_payload"`
+
+	// DeviceAttributes are the custom attributes of the device record,
+	// if the worker was configured with a device store.
+	DeviceAttributes map[string]string `json:"device_attributes,omitempty"`
 }
 
 func checkinEvent(topic string, data []byte) (*Event, error) {
@@ -34,3 +41,20 @@ func checkinEvent(topic string, data []byte) (*Event, error) {
 
 	return &webhookEvent, nil
 }
+
+func (w *Worker) addDeviceAttributes(ctx context.Context, event *Event) {
+	if w.devices == nil || event.CheckinEvent == nil || event.CheckinEvent.UDID == "" {
+		return
+	}
+	dev, err := w.devices.DeviceByUDID(ctx, event.CheckinEvent.UDID)
+	if err != nil {
+		// a device which is enrolling for the first time has no record yet.
+		level.Debug(w.logger).Log(
+			"msg", "get device attributes for webhook",
+			"udid", event.CheckinEvent.UDID,
+			"err", err,
+		)
+		return
+	}
+	event.CheckinEvent.DeviceAttributes = dev.Attributes
+}
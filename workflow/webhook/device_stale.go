@@ -0,0 +1,39 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/micromdm/micromdm/platform/device"
+)
+
+type DeviceStaleEvent struct {
+	UDID             string            `json:"udid"`
+	SerialNumber     string            `json:"serial_number,omitempty"`
+	LastSeen         time.Time         `json:"last_seen"`
+	DeviceAttributes map[string]string `json:"device_attributes,omitempty"`
+}
+
+func deviceStaleEvent(topic string, data []byte) (*Event, error) {
+	var dev device.Device
+	if err := device.UnmarshalDevice(data, &dev); err != nil {
+		return nil, errors.Wrap(err, "unmarshal device stale event for webhook")
+	}
+
+	webhookEvent := Event{
+		Topic:     topic,
+		EventID:   uuid.New().String(),
+		CreatedAt: time.Now().UTC(),
+
+		DeviceStaleEvent: &DeviceStaleEvent{
+			UDID:             dev.UDID,
+			SerialNumber:     dev.SerialNumber,
+			LastSeen:         dev.LastSeen,
+			DeviceAttributes: dev.Attributes,
+		},
+	}
+
+	return &webhookEvent, nil
+}
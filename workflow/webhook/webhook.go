@@ -10,6 +10,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/micromdm/micromdm/mdm"
+	"github.com/micromdm/micromdm/platform/device"
 	"github.com/micromdm/micromdm/platform/pubsub"
 )
 
@@ -20,6 +21,7 @@ type Event struct {
 
 	AcknowledgeEvent *AcknowledgeEvent `json:"acknowledge_event,omitempty"`
 	CheckinEvent     *CheckinEvent     `json:"checkin_event,omitempty"`
+	DeviceStaleEvent *DeviceStaleEvent `json:"device_stale_event,omitempty"`
 }
 
 type Worker struct {
@@ -27,6 +29,13 @@ type Worker struct {
 	url    string
 	client *http.Client
 	sub    pubsub.Subscriber
+
+	devices DeviceStore
+}
+
+// DeviceStore looks up device records to enrich checkin events.
+type DeviceStore interface {
+	DeviceByUDID(ctx context.Context, udid string) (*device.Device, error)
 }
 
 type Option func(*Worker)
@@ -43,6 +52,13 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
+// WithDeviceStore adds the device attributes to checkin events.
+func WithDeviceStore(store DeviceStore) Option {
+	return func(w *Worker) {
+		w.devices = store
+	}
+}
+
 func New(url string, sub pubsub.Subscriber, opts ...Option) *Worker {
 	worker := &Worker{
 		url:    url,
@@ -91,6 +107,11 @@ func (w *Worker) Run(ctx context.Context) error {
 		return errors.Wrapf(err, "subscribe %s to %s", subscription, mdm.SetBootstrapTokenTopic)
 	}
 
+	deviceStaleEvents, err := w.sub.Subscribe(ctx, subscription, device.DeviceStaleTopic)
+	if err != nil {
+		return errors.Wrapf(err, "subscribe %s to %s", subscription, device.DeviceStaleTopic)
+	}
+
 	for {
 		var (
 			event *Event
@@ -111,6 +132,8 @@ func (w *Worker) Run(ctx context.Context) error {
 			event, err = checkinEvent(ev.Topic, ev.Message)
 		case ev := <-setBootstrapTokenEvents:
 			event, err = checkinEvent(ev.Topic, ev.Message)
+		case ev := <-deviceStaleEvents:
+			event, err = deviceStaleEvent(ev.Topic, ev.Message)
 		}
 
 		if err != nil {
@@ -121,6 +144,8 @@ func (w *Worker) Run(ctx context.Context) error {
 			continue
 		}
 
+		w.addDeviceAttributes(ctx, event)
+
 		if err := postWebhookEvent(ctx, w.client, w.url, event); err != nil {
 			level.Info(w.logger).Log(
 				"msg", "post webhook event",